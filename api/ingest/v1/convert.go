@@ -0,0 +1,36 @@
+package ingestv1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// ToDomain converts a protobuf LogEvent into the domain type. Metadata must be a JSON
+// object so that the PII redactor can walk it.
+func (x *LogEvent) ToDomain() (domain.LogEvent, error) {
+	event := domain.LogEvent{
+		ID:      x.GetEventId(),
+		Source:  x.GetSource(),
+		Level:   x.GetLevel(),
+		Message: x.GetMessage(),
+	}
+	if ts := x.GetEventTime(); ts != nil {
+		if err := ts.CheckValid(); err != nil {
+			return domain.LogEvent{}, fmt.Errorf("invalid event_time: %w", err)
+		}
+		event.EventTime = ts.AsTime()
+	}
+	if md := x.GetMetadata(); len(md) > 0 {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(md, &obj); err != nil {
+			return domain.LogEvent{}, fmt.Errorf("metadata must be a JSON object: %w", err)
+		}
+		event.Metadata = md
+	}
+	event.RawEvent, _ = protojson.Marshal(x)
+	return event, nil
+}
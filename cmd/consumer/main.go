@@ -5,26 +5,35 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/pipeline"
+	"github.com/V4T54L/watch-tower/internal/adapter/repository/elasticsearch"
+	kafkarepo "github.com/V4T54L/watch-tower/internal/adapter/repository/kafka"
 	"github.com/V4T54L/watch-tower/internal/adapter/repository/postgres"
 	redisrepo "github.com/V4T54L/watch-tower/internal/adapter/repository/redis"
+	"github.com/V4T54L/watch-tower/internal/adapter/repository/wal"
+	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/V4T54L/watch-tower/internal/pkg/config"
 	"github.com/V4T54L/watch-tower/internal/pkg/logger"
 	"github.com/V4T54L/watch-tower/internal/usecase"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	_ "github.com/lib/pq"
 )
 
 const (
-	consumerGroup      = "log-processors"
-	processingInterval = 1 * time.Second
+	consumerGroup = "log-processors"
+	errorBackoff  = 1 * time.Second
 )
 
 func main() {
@@ -45,38 +54,138 @@ func main() {
 	}
 	defer db.Close()
 
-	// Redis Client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: cfg.RedisAddr,
-	})
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		log.Fatalf("failed to connect to redis: %v", err)
-	}
+	ingestMetrics := metrics.NewIngestMetrics(prometheus.DefaultRegisterer)
+	consumerMetrics := metrics.NewConsumerMetrics(prometheus.DefaultRegisterer)
 
-	ingestMetrics := metrics.NewIngestMetrics()
+	// Graceful Shutdown Context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Metrics Server
+	if cfg.ConsumerMetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsServer := &http.Server{Addr: cfg.ConsumerMetricsAddr, Handler: metricsMux}
+		go func() {
+			appLogger.Info("starting metrics server", "addr", metricsServer.Addr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Error("metrics server failed", "error", err)
+			}
+		}()
+		defer metricsServer.Close()
+	}
 
 	// Repositories
-	// The consumer doesn't need a WAL, so we pass nil.
-	redisBufferRepo, err := redisrepo.NewLogRepository(redisClient, appLogger, consumerGroup, consumerName, cfg.RedisDLQStream, nil, ingestMetrics)
-	if err != nil {
-		log.Fatalf("failed to create redis buffer repository: %v", err)
+	var bufferRepo domain.LogRepository
+	var claimer usecase.StaleLogClaimer // Only Redis leaves messages pending for other consumers.
+	var control domain.ConsumerControlRepository
+	switch cfg.BufferBackend {
+	case "kafka":
+		if len(cfg.KafkaBrokers) == 0 {
+			log.Fatalf("KAFKA_BROKERS is required when BUFFER_BACKEND=kafka")
+		}
+		kafkaBufferRepo := kafkarepo.NewLogRepository(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaDLQTopic, consumerGroup, appLogger)
+		defer kafkaBufferRepo.Close()
+		bufferRepo = kafkaBufferRepo
+	case "redis":
+		// Redis Client
+		redisClient, err := redisrepo.NewClient(cfg.RedisAddr, redisrepo.ClientOptions{
+			Username: cfg.RedisUsername,
+			Password: cfg.RedisPassword,
+			CAFile:   cfg.RedisTLSCAFile,
+			CertFile: cfg.RedisTLSCertFile,
+			KeyFile:  cfg.RedisTLSKeyFile,
+		})
+		if err != nil {
+			log.Fatalf("failed to create redis client: %v", err)
+		}
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			log.Fatalf("failed to connect to redis: %v", err)
+		}
+
+		streamOptions := redisrepo.StreamOptions{
+			Shards:      cfg.RedisStreamShards,
+			ShardPolicy: cfg.RedisShardPolicy,
+			MaxLen:      cfg.RedisStreamMaxLen,
+			MaxAge:      cfg.RedisStreamMaxAge,
+			Codec:       cfg.RedisStreamCodec,
+		}
+		// The consumer doesn't need a WAL, so we pass nil.
+		redisBufferRepo, err := redisrepo.NewLogRepository(redisClient, appLogger, consumerGroup, consumerName, cfg.RedisDLQStream, nil, redisrepo.ReplayPacing{}, streamOptions, ingestMetrics)
+		if err != nil {
+			log.Fatalf("failed to create redis buffer repository: %v", err)
+		}
+		bufferRepo = redisBufferRepo
+		claimer = redisBufferRepo
+		control = redisrepo.NewAdminRepository(redisClient, appLogger)
+		go redisBufferRepo.MonitorLag(ctx, consumerGroup, cfg.ConsumerLagInterval, consumerMetrics)
+	case "memory":
+		log.Fatalf("BUFFER_BACKEND=memory lives inside the ingest service, which also consumes it")
+	default:
+		log.Fatalf("unknown BUFFER_BACKEND %q", cfg.BufferBackend)
 	}
-	pgSinkRepo := postgres.NewLogRepository(db, appLogger)
-
-	// Use Case
-	processUseCase := usecase.NewProcessLogsUseCase(
-		redisBufferRepo,
-		pgSinkRepo,
-		appLogger,
-		consumerGroup,
-		consumerName,
-		cfg.ConsumerRetryCount,
-		cfg.ConsumerRetryBackoff,
-	)
 
-	// Graceful Shutdown Context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Sink
+	var sinkRepo domain.LogRepository
+	switch cfg.SinkBackend {
+	case "postgres":
+		pgSinkRepo := postgres.NewLogRepository(db, appLogger)
+		if cfg.ConsumerExactlyOnce {
+			if cfg.BufferBackend != "redis" {
+				// Redis stream IDs are never reused, so a recorded ID always means the same message.
+				log.Fatalf("CONSUMER_EXACTLY_ONCE requires BUFFER_BACKEND=redis")
+			}
+			pgSinkRepo.EnableExactlyOnce(consumerGroup)
+			go pgSinkRepo.PruneWrittenMessages(ctx, time.Hour, cfg.ConsumerWrittenTTL)
+		}
+		sinkRepo = pgSinkRepo
+	case "elasticsearch":
+		if cfg.ElasticsearchURL == "" {
+			log.Fatalf("ELASTICSEARCH_URL is required when SINK_BACKEND=elasticsearch")
+		}
+		if cfg.ConsumerExactlyOnce {
+			log.Fatalf("CONSUMER_EXACTLY_ONCE requires SINK_BACKEND=postgres")
+		}
+		sinkRepo = elasticsearch.NewLogRepository(elasticsearch.Options{
+			URL:          cfg.ElasticsearchURL,
+			Username:     cfg.ElasticsearchUser,
+			Password:     cfg.ElasticsearchPass,
+			APIKey:       cfg.ElasticsearchAPIKey,
+			IndexPrefix:  cfg.ElasticsearchIndex,
+			MaxRetries:   cfg.ElasticsearchRetries,
+			RetryBackoff: cfg.ElasticsearchBackoff,
+		}, appLogger)
+	default:
+		log.Fatalf("unknown SINK_BACKEND %q", cfg.SinkBackend)
+	}
+
+	// Spool for batches the sink rejects, written once it recovers instead of going to the DLQ.
+	var spool domain.WALRepository
+	if cfg.ConsumerSpoolPath != "" {
+		spoolRepo, err := wal.NewWALRepository(cfg.ConsumerSpoolPath, cfg.WALSegmentSize, cfg.ConsumerSpoolMaxSize, wal.Backpressure{Policy: wal.PolicyReject}, wal.AgeLimits{}, appLogger, ingestMetrics)
+		if err != nil {
+			log.Fatalf("failed to create consumer spool: %v", err)
+		}
+		defer spoolRepo.Close()
+		spool = spoolRepo
+	}
+
+	// Pipeline, shared by all workers and reloaded when its file changes.
+	var transformer *pipeline.Reloader
+	if cfg.ConsumerPipelineFile != "" {
+		transformer, err = pipeline.NewReloader(cfg.ConsumerPipelineFile, appLogger)
+		if err != nil {
+			log.Fatalf("failed to load consumer pipeline: %v", err)
+		}
+		go transformer.Run(ctx, cfg.ConsumerPipelinePoll)
+	}
+
+	workers := max(cfg.ConsumerWorkers, 1)
+	if workers > 1 && cfg.BufferBackend != "redis" {
+		// Kafka offsets are committed per partition, so concurrent batches from one reader
+		// could commit past a batch that is still being written.
+		log.Fatalf("CONSUMER_WORKERS above 1 requires BUFFER_BACKEND=redis")
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -87,23 +196,85 @@ func main() {
 		cancel()
 	}()
 
-	appLogger.Info("Starting consumer worker")
-	ticker := time.NewTicker(processingInterval)
-	defer ticker.Stop()
+	var wg sync.WaitGroup
+	for i := range workers {
+		// Each worker reads as its own consumer, so the messages it holds are pending
+		// under its own name.
+		workerName, workerLogger := consumerName, appLogger
+		if workers > 1 {
+			workerName = fmt.Sprintf("%s-%d", consumerName, i)
+			workerLogger = appLogger.With("worker", workerName)
+		}
+
+		// Use Case
+		processUseCase := usecase.NewProcessLogsUseCase(
+			metrics.InstrumentBuffer(bufferRepo, consumerMetrics),
+			metrics.InstrumentSink(sinkRepo, consumerMetrics, cfg.SLODeliveryLatency),
+			spool,
+			workerLogger,
+			consumerGroup,
+			workerName,
+			cfg.ConsumerRetryCount,
+			cfg.ConsumerRetryBackoff,
+		)
+		if control != nil {
+			processUseCase.EnablePauseControl(control)
+		}
+		if transformer != nil {
+			processUseCase.EnableTransform(transformer)
+		}
+		if cfg.ConsumerSinkLatency > 0 {
+			processUseCase.EnableAdaptiveBatching(cfg.ConsumerBatchMin, cfg.ConsumerBatchMax, cfg.ConsumerSinkLatency)
+		}
+		// One worker is enough to take over the messages of consumers that went away.
+		var claimTick <-chan time.Time
+		if i == 0 && claimer != nil && cfg.ConsumerClaimMinIdle > 0 {
+			processUseCase.EnableStaleReclaim(claimer, cfg.ConsumerClaimMinIdle, cfg.ConsumerMaxDelivery)
+			claimTicker := time.NewTicker(cfg.ConsumerClaimEvery)
+			defer claimTicker.Stop()
+			claimTick = claimTicker.C
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWorker(ctx, processUseCase, claimTick, workerLogger)
+		}()
+	}
 
-	for {
+	appLogger.Info("Started consumer workers", "workers", workers)
+	wg.Wait()
+	appLogger.Info("Consumer stopped")
+}
+
+// runWorker processes batches back to back until ctx is done. Reads block until events
+// arrive, so an idle worker does not spin.
+func runWorker(ctx context.Context, processUseCase *usecase.ProcessLogsUseCase, claimTick <-chan time.Time, logger *slog.Logger) {
+	for ctx.Err() == nil {
 		select {
-		case <-ctx.Done():
-			appLogger.Info("Consumer stopped")
-			return
-		case <-ticker.C:
-			processed, err := processUseCase.ProcessBatch(ctx)
-			if err != nil {
-				appLogger.Error("Error processing batch", "error", err)
+		case <-claimTick:
+			// Runs between this worker's batches, so reclaimed and freshly read events of
+			// this worker are never processed at the same time.
+			if _, err := processUseCase.ReclaimStale(ctx); err != nil {
+				logger.Error("Error reclaiming stale messages", "error", err)
 			}
-			if processed > 0 {
-				appLogger.Debug("Processed batch", "count", processed)
+		default:
+		}
+
+		processed, err := processUseCase.ProcessBatch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
 			}
+			logger.Error("Error processing batch", "error", err)
+			// Back off instead of retrying a failing buffer in a tight loop.
+			select {
+			case <-time.After(errorBackoff):
+			case <-ctx.Done():
+			}
+		}
+		if processed > 0 {
+			logger.Debug("Processed batch", "count", processed)
 		}
 	}
 }
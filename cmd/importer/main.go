@@ -0,0 +1,133 @@
+// Command importer backfills historical logs from NDJSON objects in S3 into watch-tower.
+//
+//	importer -bucket my-archive -prefix logs/2024/ -checkpoint-file importer-checkpoints.json
+//
+// Objects may be gzip or zstd compressed. Events pass through PII redaction and are
+// buffered exactly like live traffic. Rerunning with the same checkpoint file resumes an
+// interrupted import.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	kafkarepo "github.com/V4T54L/watch-tower/internal/adapter/repository/kafka"
+	redisrepo "github.com/V4T54L/watch-tower/internal/adapter/repository/redis"
+	"github.com/V4T54L/watch-tower/internal/adapter/s3import"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/pkg/config"
+	"github.com/V4T54L/watch-tower/internal/pkg/logger"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+func main() {
+	bucket := flag.String("bucket", "", "S3 bucket to import from (required)")
+	prefix := flag.String("prefix", "", "Only import objects under this key prefix")
+	checkpointFile := flag.String("checkpoint-file", "importer-checkpoints.json", "File recording per-object progress, used to resume")
+	flag.Parse()
+	if *bucket == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	appLogger := logger.New(cfg.LogLevel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// --- Buffer Repository ---
+	var bufferRepo domain.LogRepository
+	switch cfg.BufferBackend {
+	case "kafka":
+		if len(cfg.KafkaBrokers) == 0 {
+			log.Fatalf("KAFKA_BROKERS is required when BUFFER_BACKEND=kafka")
+		}
+		kafkaBufferRepo := kafkarepo.NewLogRepository(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaDLQTopic, "log-processors", appLogger)
+		defer kafkaBufferRepo.Close()
+		bufferRepo = kafkaBufferRepo
+	case "redis":
+		redisClient, err := redisrepo.NewClient(cfg.RedisAddr, redisrepo.ClientOptions{
+			Username: cfg.RedisUsername,
+			Password: cfg.RedisPassword,
+			CAFile:   cfg.RedisTLSCAFile,
+			CertFile: cfg.RedisTLSCertFile,
+			KeyFile:  cfg.RedisTLSKeyFile,
+		})
+		if err != nil {
+			log.Fatalf("failed to create redis client: %v", err)
+		}
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			log.Fatalf("failed to connect to redis: %v", err)
+		}
+		streamOptions := redisrepo.StreamOptions{
+			Shards:      cfg.RedisStreamShards,
+			ShardPolicy: cfg.RedisShardPolicy,
+			MaxLen:      cfg.RedisStreamMaxLen,
+			MaxAge:      cfg.RedisStreamMaxAge,
+			Codec:       cfg.RedisStreamCodec,
+		}
+		// A backfill can simply be rerun, so it does not need a WAL.
+		redisBufferRepo, err := redisrepo.NewLogRepository(redisClient, appLogger, "log-processors", "importer", cfg.RedisDLQStream, nil, redisrepo.ReplayPacing{}, streamOptions, metrics.NewIngestMetrics(prometheus.DefaultRegisterer))
+		if err != nil {
+			log.Fatalf("failed to create redis buffer repository: %v", err)
+		}
+		bufferRepo = redisBufferRepo
+	case "memory":
+		log.Fatalf("BUFFER_BACKEND=memory lives inside the ingest service, which also consumes it")
+	default:
+		log.Fatalf("unknown BUFFER_BACKEND %q", cfg.BufferBackend)
+	}
+
+	piiHashKey, err := pii.LoadHashKey(cfg.PIIHashKey, cfg.PIIHashKeyFile)
+	if err != nil {
+		log.Fatalf("failed to load PII hash key: %v", err)
+	}
+	piiRedactor, err := pii.LoadRedactor(pii.Options{
+		Fields:       strings.Split(cfg.PIIRedactionFields, ","),
+		Detectors:    strings.Split(cfg.PIIDetectors, ","),
+		RulesFile:    cfg.PIIRulesFile,
+		HashKey:      piiHashKey,
+		DropRawEvent: cfg.PIIDropRawEvent,
+	}, appLogger)
+	if err != nil {
+		log.Fatalf("failed to load PII redaction rules: %v", err)
+	}
+	ingestUseCase := usecase.NewIngestLogUseCase(bufferRepo, piiRedactor, appLogger)
+
+	// --- Importer ---
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		log.Fatalf("failed to load AWS config: %v", err)
+	}
+	checkpoints, err := s3import.NewFileCheckpoints(*checkpointFile)
+	if err != nil {
+		log.Fatalf("failed to load checkpoints: %v", err)
+	}
+	importer := s3import.NewImporter(awss3.NewFromConfig(awsCfg), s3import.ImporterConfig{
+		Bucket:      *bucket,
+		Prefix:      *prefix,
+		MaxLineSize: int(cfg.MaxEventSize),
+	}, checkpoints, ingestUseCase, appLogger)
+
+	res, err := importer.Run(ctx)
+	appLogger.Info("Import finished", "objects", res.Objects, "skipped", res.Skipped, "events", res.Events, "bad_lines", res.BadLines)
+	if err != nil {
+		appLogger.Error("Import stopped, rerun to resume from the last checkpoint", "error", err)
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,17 +13,38 @@ import (
 	"syscall"
 	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/hamba/avro/v2/registry"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 
 	"github.com/V4T54L/watch-tower/internal/adapter/api"
 	"github.com/V4T54L/watch-tower/internal/adapter/api/handler"
 	"github.com/V4T54L/watch-tower/internal/adapter/api/middleware"
+	"github.com/V4T54L/watch-tower/internal/adapter/enrich"
+	"github.com/V4T54L/watch-tower/internal/adapter/fieldmap"
+	"github.com/V4T54L/watch-tower/internal/adapter/grpcapi"
+	"github.com/V4T54L/watch-tower/internal/adapter/journald"
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/normalize"
+	"github.com/V4T54L/watch-tower/internal/adapter/notifier"
 	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/adapter/pipeline"
+	kafkarepo "github.com/V4T54L/watch-tower/internal/adapter/repository/kafka"
+	"github.com/V4T54L/watch-tower/internal/adapter/repository/memory"
 	"github.com/V4T54L/watch-tower/internal/adapter/repository/postgres"
 	redisrepo "github.com/V4T54L/watch-tower/internal/adapter/repository/redis"
 	"github.com/V4T54L/watch-tower/internal/adapter/repository/wal"
+	"github.com/V4T54L/watch-tower/internal/adapter/selflog"
+	"github.com/V4T54L/watch-tower/internal/adapter/servertls"
+	"github.com/V4T54L/watch-tower/internal/adapter/sqs"
+	"github.com/V4T54L/watch-tower/internal/adapter/syslog"
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
+	"github.com/V4T54L/watch-tower/internal/adapter/udpjson"
+	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/V4T54L/watch-tower/internal/pkg/config"
 	"github.com/V4T54L/watch-tower/internal/pkg/logger"
 	"github.com/V4T54L/watch-tower/internal/usecase"
@@ -38,9 +60,20 @@ func main() {
 	}
 
 	logger := logger.New(cfg.LogLevel)
+	var selfLog *selflog.Handler
+	if cfg.SelfLogEnabled {
+		selfLog = selflog.NewHandler(logger.Handler(), selflog.Options{
+			Service:      "ingest",
+			Level:        cfg.SelfLogLevel,
+			SampleRate:   cfg.SelfLogSampleRate,
+			QueueSize:    cfg.SelfLogQueueSize,
+			ErrorBackoff: 5 * time.Second,
+		})
+		logger = slog.New(selfLog)
+	}
 	slog.SetDefault(logger)
 
-	m := metrics.NewIngestMetrics()
+	m := metrics.NewIngestMetrics(prometheus.DefaultRegisterer)
 
 	// --- Start Admin and Metrics Server ---
 	adminMux := http.NewServeMux()
@@ -70,49 +103,368 @@ func main() {
 	}
 	defer db.Close()
 
-	redisOpts, err := redis.ParseURL(cfg.RedisAddr)
+	redisClient, err := redisrepo.NewClient(cfg.RedisAddr, redisrepo.ClientOptions{
+		Username: cfg.RedisUsername,
+		Password: cfg.RedisPassword,
+		CAFile:   cfg.RedisTLSCAFile,
+		CertFile: cfg.RedisTLSCertFile,
+		KeyFile:  cfg.RedisTLSKeyFile,
+	})
 	if err != nil {
-		logger.Error("failed to parse redis url", "error", err)
+		logger.Error("failed to create redis client", "error", err)
 		os.Exit(1)
 	}
-	redisClient := redis.NewClient(redisOpts)
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		logger.Warn("could not connect to redis, will proceed in WAL-only mode", "error", err)
 	}
 
 	// --- Initialize Repositories ---
-	walRepo, err := wal.NewWALRepository(cfg.WALPath, cfg.WALSegmentSize, cfg.WALMaxDiskSize, logger)
+	backpressurePolicy, err := wal.ParsePolicy(cfg.BackpressurePolicy)
+	if err != nil {
+		logger.Error("invalid BACKPRESSURE_POLICY", "error", err)
+		os.Exit(1)
+	}
+	walRepo, err := wal.NewWALRepository(cfg.WALPath, cfg.WALSegmentSize, cfg.WALMaxDiskSize, wal.Backpressure{
+		Policy:       backpressurePolicy,
+		BlockTimeout: cfg.BackpressureTimeout,
+	}, wal.AgeLimits{
+		SegmentMaxAge: cfg.WALSegmentMaxAge,
+		Retention:     cfg.WALRetention,
+	}, logger, m)
 	if err != nil {
 		logger.Error("failed to initialize WAL repository", "error", err)
 		os.Exit(1)
 	}
 	defer walRepo.Close()
+	walKey, err := wal.LoadEncryptionKey(cfg.WALEncryptionKey, cfg.WALEncryptionKeyFile)
+	if err != nil {
+		logger.Error("invalid WAL encryption key", "error", err)
+		os.Exit(1)
+	}
+	if walKey != nil {
+		if err := walRepo.EnableEncryption(walKey); err != nil {
+			logger.Error("failed to enable WAL encryption", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	apiKeyRepo := postgres.NewAPIKeyRepository(db, logger, postgres.APIKeyCacheOptions{
+		TTL:         cfg.APIKeyCacheTTL,
+		NegativeTTL: cfg.APIKeyCacheNegTTL,
+		Size:        cfg.APIKeyCacheSize,
+	}, m)
+	var apiKeyInvalidator usecase.APIKeyInvalidator
+	if cfg.APIKeyInvalidations != "" {
+		go redisrepo.SubscribeAPIKeyInvalidations(ctx, redisClient, cfg.APIKeyInvalidations, apiKeyRepo.Invalidate, logger)
+		apiKeyInvalidator = redisrepo.NewAPIKeyInvalidationPublisher(redisClient, cfg.APIKeyInvalidations)
+	}
 
-	apiKeyRepo := postgres.NewAPIKeyRepository(db, logger, cfg.APIKeyCacheTTL, m)
-	redisLogRepo, err := redisrepo.NewLogRepository(redisClient, logger, "log-processors", "ingest-service", cfg.RedisDLQStream, walRepo, m)
+	// With the memory buffer the WAL holds its overflow, which must not be replayed into Redis.
+	var memoryLogRepo *memory.LogRepository
+	var redisWAL domain.WALRepository = walRepo
+	var walReplayer usecase.WALReplayer
+	if cfg.BufferBackend == "memory" {
+		memoryLogRepo = memory.NewLogRepository(cfg.MemoryBufferSize, walRepo, logger)
+		redisWAL = nil
+		walReplayer = memoryLogRepo
+	}
+
+	redisLogRepo, err := redisrepo.NewLogRepository(redisClient, logger, "log-processors", "ingest-service", cfg.RedisDLQStream, redisWAL, redisrepo.ReplayPacing{
+		EventsPerSecond: cfg.WALReplayRate,
+		BytesPerSecond:  cfg.WALReplayBytesRate,
+		Concurrency:     cfg.WALReplayConcurrency,
+	}, redisrepo.StreamOptions{
+		Shards:      cfg.RedisStreamShards,
+		ShardPolicy: cfg.RedisShardPolicy,
+		MaxLen:      cfg.RedisStreamMaxLen,
+		MaxAge:      cfg.RedisStreamMaxAge,
+		Codec:       cfg.RedisStreamCodec,
+	}, m)
 	if err != nil && !errors.Is(err, redisrepo.ErrRedisNotAvailable) {
 		logger.Error("failed to initialize redis log repository", "error", err)
 		os.Exit(1)
 	}
+	if walReplayer == nil {
+		walReplayer = redisLogRepo
+	}
+	if cfg.RedisDedupWindow > 0 {
+		redisLogRepo.EnableDedup(cfg.RedisDedupWindow)
+	}
 
 	// Start Redis health check and WAL replay loop
 	go redisLogRepo.StartHealthCheck(ctx, 5*time.Second)
+	if cfg.RedisStreamMaxLen > 0 || cfg.RedisStreamMaxAge > 0 {
+		go redisLogRepo.MonitorEvictions(ctx, "log-processors", cfg.RedisEvictionCheck)
+	}
+	if cfg.RedisXAddBatchSize > 1 {
+		go redisLogRepo.RunXAddBatcher(ctx, redisrepo.XAddBatching{
+			Size:     cfg.RedisXAddBatchSize,
+			MaxDelay: cfg.RedisXAddBatchDelay,
+		})
+	}
 
 	// --- Initialize Admin API ---
 	redisAdminRepo := redisrepo.NewAdminRepository(redisClient, logger)
 	adminUseCase := usecase.NewAdminStreamUseCase(redisAdminRepo)
-	adminRouter := api.NewAdminRouter(adminUseCase, logger)
-	adminMux.Handle("/", adminRouter) // Mount admin router at the root of the admin server
+	walUseCase := usecase.NewAdminWALUseCase(walRepo, walReplayer)
+	// The DLQ only lives in Redis; the other backends keep their dead letters elsewhere.
+	var dlqUseCase *usecase.AdminDLQUseCase
+	if cfg.BufferBackend == "redis" {
+		dlqUseCase = usecase.NewAdminDLQUseCase(redisLogRepo)
+	}
+
+	// Every replica would sample the DLQ and send its own copy of each alert, so the
+	// monitor only runs where it is explicitly enabled.
+	var dlqMonitor *usecase.DLQMonitorUseCase
+	if cfg.DLQMonitorEnabled {
+		notifierConfig := notifier.Config{
+			Channel:       cfg.DLQAlertChannel,
+			WebhookURL:    cfg.DLQAlertWebhookURL,
+			WebhookSecret: cfg.DLQAlertWebhookKey,
+			SMTPAddr:      cfg.DLQAlertSMTPAddr,
+			SMTPUsername:  cfg.DLQAlertSMTPUser,
+			SMTPPassword:  cfg.DLQAlertSMTPPass,
+			EmailFrom:     cfg.DLQAlertEmailFrom,
+			EmailTo:       cfg.DLQAlertEmailTo,
+			PagerDutyKey:  cfg.DLQAlertPagerDuty,
+			Template:      cfg.DLQAlertTemplate,
+			Timeout:       10 * time.Second,
+		}
+		dlqNotifier, err := notifier.New(notifierConfig)
+		if err != nil {
+			logger.Error("failed to create DLQ notifier", "error", err)
+			os.Exit(1)
+		}
+		if dlqNotifier != nil {
+			dlqNotifier = metrics.InstrumentNotifier(notifier.WithRetry(dlqNotifier, cfg.DLQAlertRetries, time.Second), cfg.DLQAlertChannel, m)
+			dlqMonitor = usecase.NewDLQMonitorUseCase(metrics.InstrumentAlertQuery(redisAdminRepo, "dlq", m), dlqNotifier, usecase.DLQMonitorConfig{
+				Stream:           cfg.RedisDLQStream,
+				DepthThreshold:   cfg.DLQAlertDepth,
+				GrowthThreshold:  cfg.DLQAlertGrowth,
+				BrowserURL:       cfg.DLQBrowserURL,
+				RenotifyInterval: cfg.DLQAlertRenotify,
+				AckURL:           cfg.DLQAlertAckURL,
+			}, logger)
+
+			if cfg.DLQAlertEscalateIn > 0 {
+				notifierConfig.Channel = cfg.DLQAlertEscalateTo
+				if cfg.DLQAlertEscalateURL != "" {
+					notifierConfig.WebhookURL = cfg.DLQAlertEscalateURL
+				}
+				escalation, err := notifier.New(notifierConfig)
+				if err != nil || escalation == nil {
+					logger.Error("failed to create DLQ escalation notifier", "channel", cfg.DLQAlertEscalateTo, "error", err)
+					os.Exit(1)
+				}
+				escalation = metrics.InstrumentNotifier(notifier.WithRetry(escalation, cfg.DLQAlertRetries, time.Second), cfg.DLQAlertEscalateTo, m)
+				dlqMonitor.EnableEscalation(escalation, cfg.DLQAlertEscalateIn)
+			}
+			go dlqMonitor.Run(ctx, cfg.DLQAlertInterval)
+		}
+	}
 
 	// --- Initialize Use Cases and Services ---
-	piiRedactor := pii.NewRedactor(strings.Split(cfg.PIIRedactionFields, ","), logger)
-	ingestUseCase := usecase.NewIngestLogUseCase(redisLogRepo, piiRedactor, logger)
+	piiHashKey, err := pii.LoadHashKey(cfg.PIIHashKey, cfg.PIIHashKeyFile)
+	if err != nil {
+		logger.Error("failed to load PII hash key", "error", err)
+		os.Exit(1)
+	}
+	piiRedactor, err := pii.LoadRedactor(pii.Options{
+		Fields:       strings.Split(cfg.PIIRedactionFields, ","),
+		Detectors:    strings.Split(cfg.PIIDetectors, ","),
+		RulesFile:    cfg.PIIRulesFile,
+		HashKey:      piiHashKey,
+		DropRawEvent: cfg.PIIDropRawEvent,
+		Hits:         m.PIIRedactionsTotal,
+	}, logger)
+	if err != nil {
+		logger.Error("failed to load PII redaction rules", "error", err)
+		os.Exit(1)
+	}
+	piiPolicyRepo := postgres.NewPIIPolicyRepository(db)
+	piiPolicies := pii.NewPolicyRedactor(piiRedactor, piiPolicyRepo, piiHashKey, logger)
+	// Without its policy, the events of a key would be redacted less than required.
+	if err := piiPolicies.Reload(ctx); err != nil {
+		logger.Error("failed to load PII policies", "error", err)
+		os.Exit(1)
+	}
+	go piiPolicies.Run(ctx, cfg.PIIPolicyReload)
+	fieldMappingRepo := postgres.NewFieldMappingRepository(db)
+	fieldMapper := fieldmap.NewMapper(fieldMappingRepo, logger)
+	if err := fieldMapper.Reload(ctx); err != nil {
+		logger.Error("failed to load field mappings", "error", err)
+		os.Exit(1)
+	}
+	go fieldMapper.Run(ctx, cfg.FieldMappingReload)
+	var bufferRepo domain.LogRepository = redisLogRepo
+	switch cfg.BufferBackend {
+	case "kafka":
+		if len(cfg.KafkaBrokers) == 0 {
+			logger.Error("KAFKA_BROKERS is required when BUFFER_BACKEND=kafka")
+			os.Exit(1)
+		}
+		kafkaLogRepo := kafkarepo.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopic, logger)
+		defer kafkaLogRepo.Close()
+		bufferRepo = kafkaLogRepo
+	case "memory":
+		// Nothing else can read the buffer, so this process also runs the consumer.
+		bufferRepo = memoryLogRepo
+		processUseCase := usecase.NewProcessLogsUseCase(memoryLogRepo, postgres.NewLogRepository(db, logger), nil, logger, "log-processors", "ingest-service", cfg.ConsumerRetryCount, cfg.ConsumerRetryBackoff)
+		go func() {
+			for ctx.Err() == nil {
+				if _, err := processUseCase.ProcessBatch(ctx); err != nil && ctx.Err() == nil {
+					logger.Error("Error processing batch", "error", err)
+					time.Sleep(time.Second)
+				}
+			}
+		}()
+	}
+	enricherSpecs, err := enrich.ParseSpecs(cfg.Enrichers)
+	if err != nil {
+		logger.Error("failed to parse ENRICHERS", "error", err)
+		os.Exit(1)
+	}
+	if cfg.EnrichOrder != usecase.EnrichBeforeRedaction && cfg.EnrichOrder != usecase.EnrichAfterRedaction {
+		logger.Error("invalid ENRICH_ORDER", "order", cfg.EnrichOrder)
+		os.Exit(1)
+	}
+	enricher, err := enrich.New(enricherSpecs)
+	if err != nil {
+		logger.Error("failed to create enrichers", "error", err)
+		os.Exit(1)
+	}
+	defer enricher.Close()
+	ingestUseCase := usecase.NewEnrichedIngestLogUseCase(metrics.InstrumentIngestBuffer(bufferRepo, m), piiPolicies, enricher, cfg.EnrichOrder, logger)
+	normalizer, err := normalize.New(normalize.Config{
+		TimeFields: strings.Split(cfg.EventTimeFields, ","),
+		MaxPast:    cfg.EventTimeMaxPast,
+		MaxFuture:  cfg.EventTimeMaxFuture,
+		SkewPolicy: cfg.EventTimeSkewPolicy,
+		Levels:     cfg.NormalizeLevels,
+	})
+	if err != nil {
+		logger.Error("failed to create event normalizer", "error", err)
+		os.Exit(1)
+	}
+	// Behind the ingest pipeline, whose parsers may extract the time and level.
+	ingestUseCase = usecase.NewNormalizeIngestUseCase(ingestUseCase, normalizer)
+	if cfg.IngestPipelineFile != "" {
+		ingestPipeline, err := pipeline.NewReloader(cfg.IngestPipelineFile, logger)
+		if err != nil {
+			logger.Error("failed to load ingest pipeline", "error", err)
+			os.Exit(1)
+		}
+		go ingestPipeline.Run(ctx, cfg.IngestPipelinePoll)
+		ingestUseCase = usecase.NewTransformIngestUseCase(ingestUseCase, metrics.InstrumentPipeline(ingestPipeline, m))
+	}
+
+	multilineRules, err := usecase.ParseMultilineRules(cfg.MultilineRules)
+	if err != nil {
+		logger.Error("failed to parse MULTILINE_RULES", "error", err)
+		os.Exit(1)
+	}
+	var multiline *usecase.MultilineUseCase
+	if len(multilineRules) > 0 {
+		multiline = usecase.NewMultilineUseCase(ingestUseCase, multilineRules, logger)
+		ingestUseCase = multiline
+		go multiline.Run(ctx)
+	}
+	// Mapped in front of multiline stitching and the ingest pipeline, which work on the
+	// canonical fields.
+	ingestUseCase = usecase.NewMapFieldsIngestUseCase(ingestUseCase, fieldMapper)
+
+	// Counted in front of multiline stitching, so every line a key sends counts, and behind
+	// the usage meter, so events over the hard quota do not.
+	if len(cfg.MetricsAPIKeyLabels) > 0 {
+		ingestUseCase = metrics.InstrumentAPIKeyUsage(ingestUseCase, cfg.MetricsAPIKeyLabels, m)
+	}
+
+	// The drain gate goes in front of everything else, so it sees every event a listener
+	// hands over, and flushes the multiline events behind it.
+	var drainFlushers []func(ctx context.Context) error
+	if multiline != nil {
+		drainFlushers = append(drainFlushers, multiline.Close)
+	}
+	if memoryLogRepo != nil {
+		// Events in the memory buffer are lost on stop, so the drain waits for the
+		// in-process consumer to process them, including those spilled to the WAL.
+		drainFlushers = append(drainFlushers, memoryLogRepo.Flushed)
+	}
+	// Usage is metered behind the drain gate, so events it rejects are not counted.
+	var usageMeter *usecase.UsageMeterUseCase
+	var quota middleware.QuotaChecker
+	if cfg.UsageMeteringEnabled {
+		usageMeter = usecase.NewUsageMeterUseCase(ingestUseCase, postgres.NewUsageRepository(db), usecase.QuotaConfig{
+			SoftEvents: cfg.QuotaSoftEvents,
+			HardEvents: cfg.QuotaHardEvents,
+			SoftBytes:  cfg.QuotaSoftBytes,
+			HardBytes:  cfg.QuotaHardBytes,
+		}, logger)
+		ingestUseCase = usageMeter
+		quota = usageMeter
+		go usageMeter.Run(ctx, cfg.UsageFlushInterval)
+	}
+
+	// SLIs are counted behind the drain gate and the quotas, whose rejections are planned.
+	ingestUseCase = metrics.InstrumentSLO(ingestUseCase, cfg.SLOIngestLatency, m)
+
+	drainUseCase := usecase.NewDrainUseCase(ingestUseCase, walRepo, walReplayer, logger, drainFlushers...)
+	ingestUseCase = drainUseCase
+	if selfLog != nil {
+		// Queued since startup; shipped behind the drain gate like any other events.
+		go selfLog.Run(ctx, ingestUseCase)
+	}
+
+	consumerUseCase := usecase.NewAdminConsumerUseCase(redisAdminRepo)
+	apiKeyUseCase := usecase.NewAdminAPIKeyUseCase(apiKeyRepo, apiKeyInvalidator)
+	piiPolicyUseCase := usecase.NewAdminPIIPolicyUseCase(piiPolicyRepo, piiPolicies)
+	fieldMappingUseCase := usecase.NewAdminFieldMappingUseCase(fieldMappingRepo, fieldMapper)
+	adminRouter := api.NewAdminRouter(adminUseCase, walUseCase, dlqUseCase, dlqMonitor, consumerUseCase, drainUseCase, usageMeter, apiKeyUseCase, piiPolicyUseCase, fieldMappingUseCase, logger)
+	adminMux.Handle("/", middleware.Metrics(m)(adminRouter)) // Mount admin router at the root of the admin server
 
 	// --- Initialize SSE Broker ---
 	sseBroker := handler.NewSSEBroker(ctx, logger)
 
 	// --- Initialize Ingest Server ---
-	ingestRouter := api.NewRouter(cfg, logger, apiKeyRepo, ingestUseCase, m, sseBroker)
+	rateLimiter := redisrepo.NewRateLimiter(redisClient)
+	textParserSpecs, err := textparser.ParseSpecs(cfg.TextParsers)
+	if err != nil {
+		logger.Error("failed to parse TEXT_PARSERS", "error", err)
+		os.Exit(1)
+	}
+	textParser, err := textparser.NewChain(textParserSpecs)
+	if err != nil {
+		logger.Error("failed to build text parsers", "error", err)
+		os.Exit(1)
+	}
+	var schemaRegistry handler.AvroSchemaRegistry
+	if cfg.SchemaRegistryURL != "" {
+		registryClient, err := registry.NewClient(cfg.SchemaRegistryURL)
+		if err != nil {
+			logger.Error("failed to create schema registry client", "error", err)
+			os.Exit(1)
+		}
+		schemaRegistry = registryClient
+	}
+	webhookSources, err := handler.ParseWebhookSources(cfg.WebhookSources)
+	if err != nil {
+		logger.Error("failed to parse WEBHOOK_SOURCES", "error", err)
+		os.Exit(1)
+	}
+	keyRateLimits, err := middleware.ParseKeyRateLimits(cfg.RateLimitKeyLimits)
+	if err != nil {
+		logger.Error("failed to parse RATE_LIMIT_KEY_LIMITS", "error", err)
+		os.Exit(1)
+	}
+	clientIdentities, err := middleware.ParseClientCertIdentities(cfg.IngestTLSIdentities)
+	if err != nil {
+		logger.Error("failed to parse INGEST_TLS_CLIENT_IDENTITIES", "error", err)
+		os.Exit(1)
+	}
+	var ingestRouter http.Handler = api.NewRouter(cfg, logger, apiKeyRepo, ingestUseCase, m, sseBroker, rateLimiter, textParser, schemaRegistry, webhookSources, keyRateLimits, quota, drainUseCase.Draining)
+	if len(clientIdentities) > 0 {
+		ingestRouter = middleware.ClientCert(clientIdentities, apiKeyRepo, logger)(ingestRouter)
+	}
 	ingestServer := &http.Server{
 		Addr:         cfg.IngestServerAddr,
 		Handler:      middleware.Logging(logger)(ingestRouter),
@@ -120,15 +472,112 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
 	}
+	if cfg.IngestTLSCertFile != "" {
+		reloader, err := servertls.NewReloader(servertls.Files{
+			CertFile:     cfg.IngestTLSCertFile,
+			KeyFile:      cfg.IngestTLSKeyFile,
+			ClientCAFile: cfg.IngestTLSClientCA,
+		}, logger)
+		if err != nil {
+			logger.Error("failed to load ingest server TLS", "error", err)
+			os.Exit(1)
+		}
+		ingestServer.TLSConfig = reloader.TLSConfig()
+		go reloader.Run(ctx, cfg.IngestTLSReload)
+	} else if len(clientIdentities) > 0 {
+		logger.Warn("INGEST_TLS_CLIENT_IDENTITIES is set without INGEST_TLS_CERT_FILE; client certificates are never presented")
+	}
 
 	go func() {
-		logger.Info("starting ingest server", "addr", ingestServer.Addr)
-		if err := ingestServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("starting ingest server", "addr", ingestServer.Addr, "tls", ingestServer.TLSConfig != nil)
+		var err error
+		if ingestServer.TLSConfig != nil {
+			err = ingestServer.ListenAndServeTLS("", "")
+		} else {
+			err = ingestServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("ingest server failed", "error", err)
 			stop() // Trigger shutdown on server error
 		}
 	}()
 
+	// --- Initialize Syslog Listeners ---
+	var syslogServer *syslog.Server
+	if cfg.SyslogUDPAddr != "" || cfg.SyslogTCPAddr != "" {
+		syslogServer = syslog.NewServer(syslog.ServerConfig{
+			UDPAddr:        cfg.SyslogUDPAddr,
+			TCPAddr:        cfg.SyslogTCPAddr,
+			MaxMessageSize: cfg.SyslogMaxMessageSize,
+			TCPIdleTimeout: cfg.SyslogTCPIdleTimeout,
+			MaxTCPConns:    cfg.SyslogMaxTCPConns,
+		}, ingestUseCase, logger, m)
+		if err := syslogServer.Start(ctx); err != nil {
+			logger.Error("failed to start syslog server", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// --- Initialize UDP JSON Listener ---
+	var udpJSONServer *udpjson.Server
+	if cfg.UDPJSONAddr != "" {
+		udpJSONServer = udpjson.NewServer(udpjson.ServerConfig{
+			Addr:        cfg.UDPJSONAddr,
+			Workers:     cfg.UDPJSONWorkers,
+			QueueSize:   cfg.UDPJSONQueueSize,
+			MaxDatagram: int(cfg.MaxEventSize),
+		}, ingestUseCase, logger, m)
+		if err := udpJSONServer.Start(ctx); err != nil {
+			logger.Error("failed to start UDP JSON listener", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// --- Initialize SQS Worker ---
+	if cfg.SQSQueueURL != "" {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			logger.Error("failed to load AWS config", "error", err)
+			os.Exit(1)
+		}
+		sqsWorker := sqs.NewWorker(awssqs.NewFromConfig(awsCfg), awss3.NewFromConfig(awsCfg), sqs.WorkerConfig{
+			QueueURL:          cfg.SQSQueueURL,
+			MaxMessages:       cfg.SQSMaxMessages,
+			WaitTime:          cfg.SQSWaitTime,
+			VisibilityTimeout: cfg.SQSVisibilityTimeout,
+			MaxLineSize:       int(cfg.MaxEventSize),
+		}, ingestUseCase, logger, m)
+		go sqsWorker.Run(ctx)
+	}
+
+	// --- Initialize Journald Reader ---
+	if cfg.JournaldGatewayURL != "" {
+		journaldReader := journald.NewReader(journald.ReaderConfig{
+			GatewayURL: cfg.JournaldGatewayURL,
+			Units:      cfg.JournaldUnits,
+			CursorFile: cfg.JournaldCursorFile,
+		}, ingestUseCase, logger, m)
+		go journaldReader.Run(ctx)
+	}
+
+	// --- Initialize gRPC Ingest Server ---
+	var grpcServer *grpc.Server
+	if cfg.GRPCServerAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCServerAddr)
+		if err != nil {
+			logger.Error("failed to listen for gRPC", "error", err)
+			os.Exit(1)
+		}
+		grpcServer = grpcapi.NewServer(ingestUseCase, apiKeyRepo, int(cfg.MaxEventSize), logger, m)
+		go func() {
+			logger.Info("starting gRPC ingest server", "addr", lis.Addr().String())
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Error("gRPC ingest server failed", "error", err)
+				stop()
+			}
+		}()
+	}
+
 	// --- Wait for shutdown signal ---
 	<-ctx.Done()
 	logger.Info("shutting down servers...")
@@ -143,5 +592,31 @@ func main() {
 		logger.Error("ingest server shutdown failed", "error", err)
 	}
 
+	if grpcServer != nil {
+		// GracefulStop waits for open client streams, so fall back to a hard stop on timeout.
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			logger.Error("gRPC server shutdown timed out, forcing stop")
+			grpcServer.Stop()
+		}
+	}
+	if syslogServer != nil {
+		syslogServer.Wait()
+	}
+	if udpJSONServer != nil {
+		udpJSONServer.Wait()
+	}
+	if multiline != nil {
+		if err := multiline.Close(shutdownCtx); err != nil {
+			logger.Error("failed to flush pending multiline events", "error", err)
+		}
+	}
+
 	logger.Info("servers shut down gracefully")
 }
@@ -0,0 +1,152 @@
+// Command wtctl is an operator CLI for the ingest service's admin API.
+//
+//	wtctl [-addr http://localhost:9091] wal status
+//	wtctl wal peek [-count 10]
+//	wtctl wal replay
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func main() {
+	addr := flag.String("addr", envOr("WTCTL_ADMIN_ADDR", "http://localhost:9091"), "Base URL of the ingest admin server")
+	timeout := flag.Duration("timeout", 5*time.Minute, "Request timeout; replays of a large WAL can take a while")
+	flag.Usage = usage
+	flag.Parse()
+
+	c := &client{base: *addr, http: &http.Client{Timeout: *timeout}}
+	args := flag.Args()
+	if len(args) < 2 || args[0] != "wal" {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch args[1] {
+	case "status":
+		err = walStatus(c)
+	case "peek":
+		fs := flag.NewFlagSet("peek", flag.ExitOnError)
+		count := fs.Int("count", 10, "Number of events to show")
+		fs.Parse(args[2:])
+		err = walPeek(c, *count)
+	case "replay":
+		err = walReplay(c)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wtctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: wtctl [flags] <command>
+
+Commands:
+  wal status          Show WAL segments, quarantined segments and backlog age
+  wal peek [-count N] Print the oldest events waiting in the WAL
+  wal replay          Replay the WAL into the buffer now
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func walStatus(c *client) error {
+	var status domain.WALStatus
+	if err := c.do(http.MethodGet, "/admin/wal/status", &status); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Segments:\t%d\n", len(status.Segments))
+	fmt.Fprintf(tw, "Total size:\t%d bytes\n", status.TotalSizeBytes)
+	if status.OldestEventTime.IsZero() {
+		fmt.Fprintf(tw, "Oldest event:\t-\n")
+	} else {
+		age := time.Duration(status.OldestEventAgeSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(tw, "Oldest event:\t%s (%s ago)\n", status.OldestEventTime.Format(time.RFC3339), age)
+	}
+	if status.CursorSegment != "" {
+		fmt.Fprintf(tw, "Replay cursor:\t%s @ %d\n", status.CursorSegment, status.CursorOffset)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SEGMENT\tSIZE\tMODIFIED")
+	for _, s := range status.Segments {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.SizeBytes, s.ModifiedAt.Format(time.RFC3339))
+	}
+	for _, s := range status.Quarantined {
+		fmt.Fprintf(tw, "%s (quarantined)\t%d\t%s\n", s.Name, s.SizeBytes, s.ModifiedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func walPeek(c *client, count int) error {
+	var events []json.RawMessage
+	if err := c.do(http.MethodGet, "/admin/wal/peek?count="+url.QueryEscape(strconv.Itoa(count)), &events); err != nil {
+		return err
+	}
+	for _, event := range events {
+		var buf bytes.Buffer
+		json.Indent(&buf, event, "", "  ")
+		fmt.Println(buf.String())
+	}
+	return nil
+}
+
+func walReplay(c *client) error {
+	if err := c.do(http.MethodPost, "/admin/wal/replay", nil); err != nil {
+		return err
+	}
+	fmt.Println("WAL replayed")
+	return nil
+}
+
+type client struct {
+	base string
+	http *http.Client
+}
+
+// do sends a request to the admin API and decodes a JSON response into out, if given.
+func (c *client) do(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(body))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -8,9 +8,11 @@ import (
 	"github.com/V4T54L/watch-tower/internal/usecase"
 )
 
-// NewAdminRouter creates and configures the HTTP router for admin operations.
+// NewAdminRouter creates and configures the HTTP router for admin operations. The WAL,
+// DLQ, DLQ alert, consumer and drain endpoints are only registered when their use cases
+// are not nil.
 // Note: This router uses path patterns (e.g., "/{streamName}/") available in Go 1.22+.
-func NewAdminRouter(adminUseCase *usecase.AdminStreamUseCase, logger *slog.Logger) http.Handler {
+func NewAdminRouter(adminUseCase *usecase.AdminStreamUseCase, walUseCase *usecase.AdminWALUseCase, dlqUseCase *usecase.AdminDLQUseCase, dlqMonitor *usecase.DLQMonitorUseCase, consumerUseCase *usecase.AdminConsumerUseCase, drainUseCase *usecase.DrainUseCase, usageMeter *usecase.UsageMeterUseCase, apiKeyUseCase *usecase.AdminAPIKeyUseCase, piiPolicyUseCase *usecase.AdminPIIPolicyUseCase, fieldMappingUseCase *usecase.AdminFieldMappingUseCase, logger *slog.Logger) http.Handler {
 	mux := http.NewServeMux()
 	adminHandler := handler.NewAdminHandler(adminUseCase, logger)
 
@@ -29,5 +31,81 @@ func NewAdminRouter(adminUseCase *usecase.AdminStreamUseCase, logger *slog.Logge
 	mux.HandleFunc("POST /admin/streams/{streamName}/groups/{groupName}/ack", adminHandler.AcknowledgeMessages)
 	mux.HandleFunc("POST /admin/streams/{streamName}/trim", adminHandler.TrimStream)
 
+	// Write-Ahead Log
+	if walUseCase != nil {
+		walHandler := handler.NewAdminWALHandler(walUseCase, logger)
+		mux.HandleFunc("GET /admin/wal/status", walHandler.Status)
+		mux.HandleFunc("GET /admin/wal/peek", walHandler.Peek)
+		mux.HandleFunc("POST /admin/wal/replay", walHandler.Replay)
+	}
+
+	// Dead-Letter Queue
+	if dlqUseCase != nil {
+		dlqHandler := handler.NewAdminDLQHandler(dlqUseCase, logger)
+		mux.HandleFunc("GET /admin/dlq", dlqHandler.List)
+		mux.HandleFunc("GET /admin/dlq/{id}", dlqHandler.Get)
+		mux.HandleFunc("POST /admin/dlq/redrive", dlqHandler.Redrive)
+		mux.HandleFunc("POST /admin/dlq/purge", dlqHandler.Purge)
+	}
+
+	// DLQ Alert
+	if dlqMonitor != nil {
+		dlqMonitorHandler := handler.NewAdminDLQMonitorHandler(dlqMonitor, logger)
+		mux.HandleFunc("GET /admin/dlq/alert", dlqMonitorHandler.Status)
+		mux.HandleFunc("POST /admin/dlq/alert/test", dlqMonitorHandler.Test)
+		mux.HandleFunc("POST /admin/dlq/alert/ack", dlqMonitorHandler.Acknowledge)
+		mux.HandleFunc("GET /admin/dlq/alert/ack", dlqMonitorHandler.ConfirmAcknowledge)
+	}
+
+	// Consumer Control
+	if consumerUseCase != nil {
+		consumerHandler := handler.NewAdminConsumerHandler(consumerUseCase, logger)
+		mux.HandleFunc("GET /admin/groups/{groupName}/pause", consumerHandler.Status)
+		mux.HandleFunc("POST /admin/groups/{groupName}/pause", consumerHandler.Pause)
+		mux.HandleFunc("POST /admin/groups/{groupName}/resume", consumerHandler.Resume)
+	}
+
+	// Drain
+	if drainUseCase != nil {
+		drainHandler := handler.NewAdminDrainHandler(drainUseCase, logger)
+		mux.HandleFunc("GET /admin/drain", drainHandler.Status)
+		mux.HandleFunc("POST /admin/drain", drainHandler.Drain)
+	}
+
+	// API Keys
+	if apiKeyUseCase != nil {
+		apiKeyHandler := handler.NewAdminAPIKeyHandler(apiKeyUseCase, logger)
+		mux.HandleFunc("GET /admin/api-keys", apiKeyHandler.List)
+		mux.HandleFunc("POST /admin/api-keys", apiKeyHandler.Create)
+		mux.HandleFunc("PATCH /admin/api-keys/{keyHash}", apiKeyHandler.Update)
+		mux.HandleFunc("POST /admin/api-keys/{keyHash}/rotate", apiKeyHandler.Rotate)
+		mux.HandleFunc("DELETE /admin/api-keys/{keyHash}", apiKeyHandler.Revoke)
+	}
+
+	// PII Policies
+	if piiPolicyUseCase != nil {
+		piiPolicyHandler := handler.NewAdminPIIPolicyHandler(piiPolicyUseCase, logger)
+		mux.HandleFunc("GET /admin/pii-policies", piiPolicyHandler.List)
+		mux.HandleFunc("GET /admin/pii-policies/{keyHash}", piiPolicyHandler.Get)
+		mux.HandleFunc("PUT /admin/pii-policies/{keyHash}", piiPolicyHandler.Put)
+		mux.HandleFunc("DELETE /admin/pii-policies/{keyHash}", piiPolicyHandler.Delete)
+	}
+
+	// Field Mappings
+	if fieldMappingUseCase != nil {
+		fieldMappingHandler := handler.NewAdminFieldMappingHandler(fieldMappingUseCase, logger)
+		mux.HandleFunc("GET /admin/field-mappings", fieldMappingHandler.List)
+		mux.HandleFunc("GET /admin/field-mappings/{keyHash}", fieldMappingHandler.Get)
+		mux.HandleFunc("PUT /admin/field-mappings/{keyHash}", fieldMappingHandler.Put)
+		mux.HandleFunc("DELETE /admin/field-mappings/{keyHash}", fieldMappingHandler.Delete)
+	}
+
+	// Usage
+	if usageMeter != nil {
+		usageHandler := handler.NewAdminUsageHandler(usageMeter, logger)
+		mux.HandleFunc("GET /admin/usage", usageHandler.List)
+		mux.HandleFunc("GET /admin/usage/live", usageHandler.Live)
+	}
+
 	return mux
 }
@@ -0,0 +1,115 @@
+// Package apierror writes the error responses of the HTTP APIs in one machine-readable
+// envelope, {"error": {"code": "...", "message": "...", "details": ...}}, so clients can
+// branch on the code instead of parsing the message.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// CodeHeader carries the code of an error response, for clients and middleware that do
+// not read the body.
+const CodeHeader = "X-Error-Code"
+
+// Error codes. Each is always returned with the same status code.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeBufferFull           = "buffer_full"
+	CodeInternal             = "internal"
+	CodeDraining             = "draining"
+	CodeUnavailable          = "unavailable"
+)
+
+var statuses = map[string]int{
+	CodeInvalidRequest:       http.StatusBadRequest,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeNotFound:             http.StatusNotFound,
+	CodeMethodNotAllowed:     http.StatusMethodNotAllowed,
+	CodeConflict:             http.StatusConflict,
+	CodePayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	CodeUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	CodeRateLimited:          http.StatusTooManyRequests,
+	CodeQuotaExceeded:        http.StatusTooManyRequests,
+	CodeBufferFull:           http.StatusTooManyRequests,
+	CodeInternal:             http.StatusInternalServerError,
+	CodeDraining:             http.StatusServiceUnavailable,
+	CodeUnavailable:          http.StatusServiceUnavailable,
+}
+
+// Error is an error response.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// New creates an Error.
+func New(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Status returns the HTTP status code of the error.
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// From maps an error to the response for it. Errors without a response of their own are
+// internal errors, whose message is not passed on to clients.
+func From(err error) *Error {
+	var apiErr *Error
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &maxBytesErr):
+		return New(CodePayloadTooLarge, maxBytesErr.Error())
+	case errors.Is(err, domain.ErrBufferFull):
+		return New(CodeBufferFull, domain.ErrBufferFull.Error())
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		return New(CodeQuotaExceeded, domain.ErrQuotaExceeded.Error())
+	case errors.Is(err, domain.ErrEventTimeOutOfRange):
+		return New(CodeInvalidRequest, err.Error())
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		return New(CodeNotFound, domain.ErrAPIKeyNotFound.Error())
+	case errors.Is(err, usecase.ErrDraining):
+		return New(CodeDraining, usecase.ErrDraining.Error())
+	}
+	return New(CodeInternal, "Internal server error")
+}
+
+// Write writes err as the response, mapped by From.
+func Write(w http.ResponseWriter, err error) {
+	e := From(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set(CodeHeader, e.Code)
+	w.WriteHeader(e.Status())
+	json.NewEncoder(w).Encode(struct {
+		Error *Error `json:"error"`
+	}{e})
+}
+
+// Respond writes an error response with the code and message.
+func Respond(w http.ResponseWriter, code, message string) {
+	Write(w, New(code, message))
+}
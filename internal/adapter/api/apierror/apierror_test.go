@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "API error", err: New(CodeInvalidRequest, "bad"), expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidRequest},
+		{name: "Buffer full", err: fmt.Errorf("failed to write: %w", domain.ErrBufferFull), expectedStatus: http.StatusTooManyRequests, expectedCode: CodeBufferFull},
+		{name: "Quota exceeded", err: domain.ErrQuotaExceeded, expectedStatus: http.StatusTooManyRequests, expectedCode: CodeQuotaExceeded},
+		{name: "Event time out of range", err: fmt.Errorf("%w: too old", domain.ErrEventTimeOutOfRange), expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidRequest},
+		{name: "API key not found", err: domain.ErrAPIKeyNotFound, expectedStatus: http.StatusNotFound, expectedCode: CodeNotFound},
+		{name: "Draining", err: usecase.ErrDraining, expectedStatus: http.StatusServiceUnavailable, expectedCode: CodeDraining},
+		{name: "Body too large", err: &http.MaxBytesError{Limit: 10}, expectedStatus: http.StatusRequestEntityTooLarge, expectedCode: CodePayloadTooLarge},
+		{name: "Other error", err: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError, expectedCode: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			Write(rr, tt.err)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get(CodeHeader); got != tt.expectedCode {
+				t.Errorf("expected %s header %q, got %q", CodeHeader, tt.expectedCode, got)
+			}
+			var body struct {
+				Error Error `json:"error"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected a JSON envelope, got %q: %v", rr.Body.String(), err)
+			}
+			if body.Error.Code != tt.expectedCode || body.Error.Message == "" {
+				t.Errorf("expected code %q with a message, got %+v", tt.expectedCode, body.Error)
+			}
+		})
+	}
+}
+
+func TestWriteHidesInternalErrors(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Write(rr, errors.New("password authentication failed for user postgres"))
+	if want := `{"error":{"code":"internal","message":"Internal server error"}}` + "\n"; rr.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, rr.Body.String())
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminAPIKeyHandler handles HTTP requests for managing API keys. Keys are addressed by
+// their digest; the key itself is only returned when it is created.
+type AdminAPIKeyHandler struct {
+	uc     *usecase.AdminAPIKeyUseCase
+	logger *slog.Logger
+}
+
+// NewAdminAPIKeyHandler creates a new AdminAPIKeyHandler.
+func NewAdminAPIKeyHandler(uc *usecase.AdminAPIKeyUseCase, logger *slog.Logger) *AdminAPIKeyHandler {
+	return &AdminAPIKeyHandler{uc: uc, logger: logger}
+}
+
+// apiKeyRequest is the request body for creating or updating an API key.
+type apiKeyRequest struct {
+	Description *string    `json:"description"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// List handles requests for every API key.
+// GET /admin/api-keys
+func (h *AdminAPIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.uc.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list API keys", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, keys)
+}
+
+// Create handles requests to create an API key.
+// POST /admin/api-keys
+func (h *AdminAPIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+	var description string
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	key, err := h.uc.Create(r.Context(), description, req.ExpiresAt)
+	if err != nil {
+		h.logger.Error("failed to create API key", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, key)
+}
+
+// Update handles requests to change the description or expiry of an API key.
+// PATCH /admin/api-keys/{keyHash}
+func (h *AdminAPIKeyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req apiKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	key, err := h.uc.Update(r.Context(), r.PathValue("keyHash"), req.Description, req.ExpiresAt)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to update API key", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, key)
+}
+
+// Rotate handles requests to replace an API key with a new one. The old key keeps
+// working for the grace period, which defaults to revoking it at once.
+// POST /admin/api-keys/{keyHash}/rotate?grace=1h
+func (h *AdminAPIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	var grace time.Duration
+	if graceStr := r.URL.Query().Get("grace"); graceStr != "" {
+		var err error
+		grace, err = time.ParseDuration(graceStr)
+		if err != nil {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid grace parameter")
+			return
+		}
+	}
+
+	key, err := h.uc.Rotate(r.Context(), r.PathValue("keyHash"), grace)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to rotate API key", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusCreated, key)
+}
+
+// Revoke handles requests to deactivate an API key, which blocks its ingestion at once.
+// DELETE /admin/api-keys/{keyHash}
+func (h *AdminAPIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := h.uc.Revoke(r.Context(), r.PathValue("keyHash"))
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to revoke API key", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminAPIKeyHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
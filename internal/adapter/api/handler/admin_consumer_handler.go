@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminConsumerHandler handles HTTP requests for pausing and resuming consumer groups.
+type AdminConsumerHandler struct {
+	uc     *usecase.AdminConsumerUseCase
+	logger *slog.Logger
+}
+
+// NewAdminConsumerHandler creates a new AdminConsumerHandler.
+func NewAdminConsumerHandler(uc *usecase.AdminConsumerUseCase, logger *slog.Logger) *AdminConsumerHandler {
+	return &AdminConsumerHandler{uc: uc, logger: logger}
+}
+
+// Status handles requests for whether a consumer group is paused.
+// GET /admin/groups/{groupName}/pause
+func (h *AdminConsumerHandler) Status(w http.ResponseWriter, r *http.Request) {
+	pause, err := h.uc.Status(r.Context(), r.PathValue("groupName"))
+	h.respond(w, pause, err)
+}
+
+// Pause handles requests to stop the consumers of a group from processing batches.
+// POST /admin/groups/{groupName}/pause
+func (h *AdminConsumerHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	pause, err := h.uc.Pause(r.Context(), r.PathValue("groupName"))
+	h.respond(w, pause, err)
+}
+
+// Resume handles requests to let the consumers of a group process batches again.
+// POST /admin/groups/{groupName}/resume
+func (h *AdminConsumerHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	pause, err := h.uc.Resume(r.Context(), r.PathValue("groupName"))
+	h.respond(w, pause, err)
+}
+
+func (h *AdminConsumerHandler) respond(w http.ResponseWriter, pause *domain.GroupPause, err error) {
+	if err != nil {
+		h.logger.Error("failed to control consumer group", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(pause); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminDLQHandler handles HTTP requests for dead-letter queue administration.
+type AdminDLQHandler struct {
+	uc     *usecase.AdminDLQUseCase
+	logger *slog.Logger
+}
+
+// NewAdminDLQHandler creates a new AdminDLQHandler.
+func NewAdminDLQHandler(uc *usecase.AdminDLQUseCase, logger *slog.Logger) *AdminDLQHandler {
+	return &AdminDLQHandler{uc: uc, logger: logger}
+}
+
+// dlqSelection is the request body for re-driving or purging DLQ entries.
+type dlqSelection struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
+
+// List handles requests to page through the DLQ, oldest entries first.
+// GET /admin/dlq?after={id}&count={count}
+func (h *AdminDLQHandler) List(w http.ResponseWriter, r *http.Request) {
+	var count int64
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		var err error
+		count, err = strconv.ParseInt(countStr, 10, 64)
+		if err != nil {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid count parameter")
+			return
+		}
+	}
+
+	page, err := h.uc.List(r.Context(), r.URL.Query().Get("after"), count)
+	if err != nil {
+		h.logger.Error("failed to list DLQ entries", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, page)
+}
+
+// Get handles requests for a single DLQ entry, including its payload.
+// GET /admin/dlq/{id}
+func (h *AdminDLQHandler) Get(w http.ResponseWriter, r *http.Request) {
+	entry, err := h.uc.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, domain.ErrDLQEntryNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "DLQ entry not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get DLQ entry", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, entry)
+}
+
+// Redrive handles requests to send DLQ entries back to the buffer.
+// POST /admin/dlq/redrive
+func (h *AdminDLQHandler) Redrive(w http.ResponseWriter, r *http.Request) {
+	var req dlqSelection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	redriven, err := h.uc.Redrive(r.Context(), req.IDs, req.All)
+	if errors.Is(err, usecase.ErrNoDLQEntriesSelected) {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "either ids or all is required")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to re-drive DLQ entries", "error", err, "redriven", redriven)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]int64{"redriven": redriven})
+}
+
+// Purge handles requests to delete DLQ entries.
+// POST /admin/dlq/purge
+func (h *AdminDLQHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	var req dlqSelection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	purged, err := h.uc.Purge(r.Context(), req.IDs, req.All)
+	if errors.Is(err, usecase.ErrNoDLQEntriesSelected) {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "either ids or all is required")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to purge DLQ entries", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]int64{"purged": purged})
+}
+
+func (h *AdminDLQHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminDLQMonitorHandler handles HTTP requests for the DLQ alert.
+type AdminDLQMonitorHandler struct {
+	uc     *usecase.DLQMonitorUseCase
+	logger *slog.Logger
+}
+
+// NewAdminDLQMonitorHandler creates a new AdminDLQMonitorHandler.
+func NewAdminDLQMonitorHandler(uc *usecase.DLQMonitorUseCase, logger *slog.Logger) *AdminDLQMonitorHandler {
+	return &AdminDLQMonitorHandler{uc: uc, logger: logger}
+}
+
+// Status handles requests for the state of the DLQ alert and its last evaluation.
+// GET /admin/dlq/alert
+func (h *AdminDLQMonitorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.uc.Status()); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
+
+// Test handles requests to evaluate the DLQ alert now, optionally sending a test
+// notification, without changing whether it is firing.
+// POST /admin/dlq/alert/test?notify=true
+func (h *AdminDLQMonitorHandler) Test(w http.ResponseWriter, r *http.Request) {
+	var notify bool
+	if notifyStr := r.URL.Query().Get("notify"); notifyStr != "" {
+		var err error
+		notify, err = strconv.ParseBool(notifyStr)
+		if err != nil {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid notify parameter")
+			return
+		}
+	}
+
+	result, err := h.uc.Test(r.Context(), notify)
+	if err != nil {
+		h.logger.Error("failed to test DLQ alert", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
+
+// ackConfirmPage is served for the acknowledge link in notifications. Chat link previews
+// and mail scanners fetch such links on delivery, so opening it only offers a form that
+// POSTs the acknowledgement.
+const ackConfirmPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Acknowledge DLQ alert</title></head>
+<body><form method="post"><p>Acknowledging the DLQ alert stops it from being renotified and escalated.</p>
+<button type="submit">Acknowledge</button></form></body></html>
+`
+
+// ackDonePage answers acknowledgements submitted from ackConfirmPage.
+const ackDonePage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>DLQ alert acknowledged</title></head>
+<body><p>The DLQ alert has been acknowledged.</p></body></html>
+`
+
+// ConfirmAcknowledge handles the acknowledge link in notifications, answering with a page
+// that asks to confirm the acknowledgement. It does not change the alert.
+// GET /admin/dlq/alert/ack
+func (h *AdminDLQMonitorHandler) ConfirmAcknowledge(w http.ResponseWriter, r *http.Request) {
+	h.respondWithHTML(w, http.StatusOK, ackConfirmPage)
+}
+
+// Acknowledge handles requests to acknowledge the firing DLQ alert, which stops it from
+// being renotified and escalated. Submissions of the confirmation page are answered with
+// a page rather than no content.
+// POST /admin/dlq/alert/ack
+func (h *AdminDLQMonitorHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	err := h.uc.Acknowledge(r.Context())
+	if errors.Is(err, usecase.ErrDLQAlertNotFiring) {
+		apierror.Respond(w, apierror.CodeConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to acknowledge DLQ alert", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		h.respondWithHTML(w, http.StatusOK, ackDonePage)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminDLQMonitorHandler) respondWithHTML(w http.ResponseWriter, code int, page string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	if _, err := io.WriteString(w, page); err != nil {
+		h.logger.Error("failed to write HTML response", "error", err)
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+type deepDLQRepo struct {
+	domain.StreamAdminRepository
+}
+
+func (deepDLQRepo) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	return 150, nil
+}
+
+type discardNotifier struct{}
+
+func (discardNotifier) Notify(ctx context.Context, n domain.Notification) error { return nil }
+
+func TestAdminDLQMonitorHandler_Acknowledge(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	uc := usecase.NewDLQMonitorUseCase(deepDLQRepo{}, discardNotifier{}, usecase.DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100}, logger)
+	if err := uc.Check(context.Background()); err != nil || !uc.Status().Firing {
+		t.Fatalf("expected the alert to fire, got %+v, %v", uc.Status(), err)
+	}
+	h := NewAdminDLQMonitorHandler(uc, logger)
+
+	// Link previews and mail scanners fetch the acknowledge link on delivery.
+	rec := httptest.NewRecorder()
+	h.ConfirmAcknowledge(rec, httptest.NewRequest(http.MethodGet, "/admin/dlq/alert/ack", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `method="post"`) {
+		t.Fatalf("expected a confirmation form, got %d: %s", rec.Code, rec.Body)
+	}
+	if uc.Status().Acknowledged {
+		t.Fatal("expected GET to leave the alert unacknowledged")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/dlq/alert/ack", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.Acknowledge(rec, req)
+	if rec.Code != http.StatusOK || !uc.Status().Acknowledged {
+		t.Errorf("expected the submitted form to acknowledge the alert, got %d, %+v", rec.Code, uc.Status())
+	}
+}
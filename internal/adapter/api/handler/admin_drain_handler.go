@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminDrainHandler handles HTTP requests for draining the ingest service before a deploy.
+type AdminDrainHandler struct {
+	uc     *usecase.DrainUseCase
+	logger *slog.Logger
+}
+
+// NewAdminDrainHandler creates a new AdminDrainHandler.
+func NewAdminDrainHandler(uc *usecase.DrainUseCase, logger *slog.Logger) *AdminDrainHandler {
+	return &AdminDrainHandler{uc: uc, logger: logger}
+}
+
+// Drain handles requests to stop accepting events and flush the ones held in process.
+// It returns at once; poll Status until safe_to_stop is true.
+// POST /admin/drain
+func (h *AdminDrainHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	// The drain outlives the request.
+	h.uc.Start(context.WithoutCancel(r.Context()))
+	h.respondWithStatus(w, r, http.StatusAccepted)
+}
+
+// Status handles requests for the progress of a drain.
+// GET /admin/drain
+func (h *AdminDrainHandler) Status(w http.ResponseWriter, r *http.Request) {
+	h.respondWithStatus(w, r, http.StatusOK)
+}
+
+func (h *AdminDrainHandler) respondWithStatus(w http.ResponseWriter, r *http.Request, code int) {
+	status, err := h.uc.Status(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get drain status", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminFieldMappingHandler handles HTTP requests for managing the field mappings of API
+// keys, addressed by the key digest.
+type AdminFieldMappingHandler struct {
+	uc     *usecase.AdminFieldMappingUseCase
+	logger *slog.Logger
+}
+
+// NewAdminFieldMappingHandler creates a new AdminFieldMappingHandler.
+func NewAdminFieldMappingHandler(uc *usecase.AdminFieldMappingUseCase, logger *slog.Logger) *AdminFieldMappingHandler {
+	return &AdminFieldMappingHandler{uc: uc, logger: logger}
+}
+
+// fieldMappingRequest is the request body for putting a field mapping.
+type fieldMappingRequest struct {
+	Fields map[string]string `json:"fields"`
+}
+
+// List handles requests for every field mapping.
+// GET /admin/field-mappings
+func (h *AdminFieldMappingHandler) List(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.uc.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list field mappings", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, mappings)
+}
+
+// Get handles requests for the field mapping of a key.
+// GET /admin/field-mappings/{keyHash}
+func (h *AdminFieldMappingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	mapping, err := h.uc.Get(r.Context(), r.PathValue("keyHash"))
+	if errors.Is(err, domain.ErrFieldMappingNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "field mapping not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get field mapping", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, mapping)
+}
+
+// Put handles requests to create or replace the field mapping of a key, which maps the
+// keys of its payloads onto the fields of its events.
+// PUT /admin/field-mappings/{keyHash}
+func (h *AdminFieldMappingHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var req fieldMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	mapping, err := h.uc.Put(r.Context(), r.PathValue("keyHash"), req.Fields)
+	if errors.Is(err, domain.ErrInvalidFieldMapping) {
+		apierror.Respond(w, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to put field mapping", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, mapping)
+}
+
+// Delete handles requests to delete the field mapping of a key, whose payloads are then
+// taken as they are.
+// DELETE /admin/field-mappings/{keyHash}
+func (h *AdminFieldMappingHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.uc.Delete(r.Context(), r.PathValue("keyHash"))
+	if errors.Is(err, domain.ErrFieldMappingNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "field mapping not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete field mapping", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminFieldMappingHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
 	"github.com/V4T54L/watch-tower/internal/usecase"
 )
 
@@ -33,14 +34,14 @@ func (h *AdminHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 func (h *AdminHandler) GetGroupInfo(w http.ResponseWriter, r *http.Request) {
 	streamName := r.PathValue("streamName")
 	if streamName == "" {
-		http.Error(w, "streamName is required", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "streamName is required")
 		return
 	}
 
 	groups, err := h.uc.GetGroupInfo(r.Context(), streamName)
 	if err != nil {
 		h.logger.Error("failed to get group info", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -56,7 +57,7 @@ func (h *AdminHandler) GetConsumerInfo(w http.ResponseWriter, r *http.Request) {
 	consumers, err := h.uc.GetConsumerInfo(r.Context(), streamName, groupName)
 	if err != nil {
 		h.logger.Error("failed to get consumer info", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -72,7 +73,7 @@ func (h *AdminHandler) GetPendingSummary(w http.ResponseWriter, r *http.Request)
 	summary, err := h.uc.GetPendingSummary(r.Context(), streamName, groupName)
 	if err != nil {
 		h.logger.Error("failed to get pending summary", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -93,7 +94,7 @@ func (h *AdminHandler) GetPendingMessages(w http.ResponseWriter, r *http.Request
 		var err error
 		count, err = strconv.ParseInt(countStr, 10, 64)
 		if err != nil {
-			http.Error(w, "invalid count parameter", http.StatusBadRequest)
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid count parameter")
 			return
 		}
 	}
@@ -101,7 +102,7 @@ func (h *AdminHandler) GetPendingMessages(w http.ResponseWriter, r *http.Request
 	messages, err := h.uc.GetPendingMessages(r.Context(), streamName, groupName, consumerName, startID, count)
 	if err != nil {
 		h.logger.Error("failed to get pending messages", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -120,20 +121,20 @@ func (h *AdminHandler) ClaimMessages(w http.ResponseWriter, r *http.Request) {
 		MessageIDs  []string `json:"message_ids"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	minIdle, err := time.ParseDuration(payload.MinIdleTime)
 	if err != nil {
-		http.Error(w, "invalid min_idle_time format", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid min_idle_time format")
 		return
 	}
 
 	claimed, err := h.uc.ClaimMessages(r.Context(), streamName, groupName, payload.Consumer, minIdle, payload.MessageIDs)
 	if err != nil {
 		h.logger.Error("failed to claim messages", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -150,19 +151,19 @@ func (h *AdminHandler) AcknowledgeMessages(w http.ResponseWriter, r *http.Reques
 		MessageIDs []string `json:"message_ids"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
 		return
 	}
 
 	if len(payload.MessageIDs) == 0 {
-		http.Error(w, "message_ids cannot be empty", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "message_ids cannot be empty")
 		return
 	}
 
 	count, err := h.uc.AcknowledgeMessages(r.Context(), streamName, groupName, payload.MessageIDs...)
 	if err != nil {
 		h.logger.Error("failed to acknowledge messages", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -178,18 +179,18 @@ func (h *AdminHandler) TrimStream(w http.ResponseWriter, r *http.Request) {
 		MaxLen int64 `json:"maxlen"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
 		return
 	}
 	if payload.MaxLen <= 0 {
-		http.Error(w, "maxlen must be a positive integer", http.StatusBadRequest)
+		apierror.Respond(w, apierror.CodeInvalidRequest, "maxlen must be a positive integer")
 		return
 	}
 
 	trimmedCount, err := h.uc.TrimStream(r.Context(), streamName, payload.MaxLen)
 	if err != nil {
 		h.logger.Error("failed to trim stream", "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 		return
 	}
 
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminPIIPolicyHandler handles HTTP requests for managing the PII policies of API keys,
+// addressed by the key digest.
+type AdminPIIPolicyHandler struct {
+	uc     *usecase.AdminPIIPolicyUseCase
+	logger *slog.Logger
+}
+
+// NewAdminPIIPolicyHandler creates a new AdminPIIPolicyHandler.
+func NewAdminPIIPolicyHandler(uc *usecase.AdminPIIPolicyUseCase, logger *slog.Logger) *AdminPIIPolicyHandler {
+	return &AdminPIIPolicyHandler{uc: uc, logger: logger}
+}
+
+// piiPolicyRequest is the request body for putting a PII policy.
+type piiPolicyRequest struct {
+	Rules []domain.PIIRule `json:"rules"`
+}
+
+// List handles requests for every PII policy.
+// GET /admin/pii-policies
+func (h *AdminPIIPolicyHandler) List(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.uc.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list PII policies", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, policies)
+}
+
+// Get handles requests for the PII policy of a key.
+// GET /admin/pii-policies/{keyHash}
+func (h *AdminPIIPolicyHandler) Get(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.uc.Get(r.Context(), r.PathValue("keyHash"))
+	if errors.Is(err, domain.ErrPIIPolicyNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "PII policy not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get PII policy", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, policy)
+}
+
+// Put handles requests to create or replace the PII policy of a key, which replaces the
+// global redaction rules for its events.
+// PUT /admin/pii-policies/{keyHash}
+func (h *AdminPIIPolicyHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var req piiPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Respond(w, apierror.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	policy, err := h.uc.Put(r.Context(), r.PathValue("keyHash"), req.Rules)
+	if errors.Is(err, domain.ErrInvalidPIIPolicy) {
+		apierror.Respond(w, apierror.CodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to put PII policy", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	h.respondWithJSON(w, http.StatusOK, policy)
+}
+
+// Delete handles requests to delete the PII policy of a key, whose events are then
+// redacted by the global rules.
+// DELETE /admin/pii-policies/{keyHash}
+func (h *AdminPIIPolicyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.uc.Delete(r.Context(), r.PathValue("keyHash"))
+	if errors.Is(err, domain.ErrPIIPolicyNotFound) {
+		apierror.Respond(w, apierror.CodeNotFound, "PII policy not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete PII policy", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminPIIPolicyHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
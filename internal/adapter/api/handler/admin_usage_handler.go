@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminUsageHandler handles HTTP requests for the ingestion usage of API keys.
+type AdminUsageHandler struct {
+	uc     *usecase.UsageMeterUseCase
+	logger *slog.Logger
+}
+
+// NewAdminUsageHandler creates a new AdminUsageHandler.
+func NewAdminUsageHandler(uc *usecase.UsageMeterUseCase, logger *slog.Logger) *AdminUsageHandler {
+	return &AdminUsageHandler{uc: uc, logger: logger}
+}
+
+// List handles requests for the daily usage of every API key, or of the one whose
+// digest is given, between two UTC days inclusive. Both days default to today.
+// GET /admin/usage?from=2006-01-02&to=2006-01-02&key_hash={sha256}
+func (h *AdminUsageHandler) List(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().UTC().Format(time.DateOnly)
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" {
+		from = today
+	}
+	if to == "" {
+		to = today
+	}
+	for _, day := range []string{from, to} {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid from or to parameter, expected YYYY-MM-DD")
+			return
+		}
+	}
+
+	usage, err := h.uc.Usage(r.Context(), from, to, r.URL.Query().Get("key_hash"))
+	if err != nil {
+		h.logger.Error("failed to list usage", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
+
+// Live handles requests for today's usage of the API keys ingesting the most, including
+// events this replica has not flushed yet, to find the key behind a traffic spike.
+// GET /admin/usage/live?top={n}
+func (h *AdminUsageHandler) Live(w http.ResponseWriter, r *http.Request) {
+	top := 20
+	if s := r.URL.Query().Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid top parameter")
+			return
+		}
+		top = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.uc.LiveUsage(top)); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
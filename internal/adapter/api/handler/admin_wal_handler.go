@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// AdminWALHandler handles HTTP requests for Write-Ahead Log administration.
+type AdminWALHandler struct {
+	uc     *usecase.AdminWALUseCase
+	logger *slog.Logger
+}
+
+// NewAdminWALHandler creates a new AdminWALHandler.
+func NewAdminWALHandler(uc *usecase.AdminWALUseCase, logger *slog.Logger) *AdminWALHandler {
+	return &AdminWALHandler{uc: uc, logger: logger}
+}
+
+// Status handles requests for the WAL's segments and backlog age.
+// GET /admin/wal/status
+func (h *AdminWALHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.uc.Status(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get WAL status", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, status)
+}
+
+// Peek handles requests to list the oldest events in the WAL without replaying them.
+// GET /admin/wal/peek?count={count}
+func (h *AdminWALHandler) Peek(w http.ResponseWriter, r *http.Request) {
+	var count int
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		var err error
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			apierror.Respond(w, apierror.CodeInvalidRequest, "invalid count parameter")
+			return
+		}
+	}
+
+	events, err := h.uc.Peek(r.Context(), count)
+	if err != nil {
+		h.logger.Error("failed to peek WAL", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, events)
+}
+
+// Replay handles requests to replay the WAL into the buffer immediately.
+// POST /admin/wal/replay
+func (h *AdminWALHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	if err := h.uc.Replay(r.Context()); err != nil {
+		h.logger.Error("manual WAL replay failed", "error", err)
+		apierror.Respond(w, apierror.CodeUnavailable, "WAL replay failed: "+err.Error())
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "replayed"})
+}
+
+func (h *AdminWALHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		h.logger.Error("failed to write JSON response", "error", err)
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/hamba/avro/v2/registry"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const contentTypeAvro = "application/avro"
+
+// avroContainerMagic starts every Avro object container file.
+var avroContainerMagic = []byte{'O', 'b', 'j', 1}
+
+// AvroSchemaRegistry resolves writer schemas by their registry ID. The registry.Client of
+// github.com/hamba/avro/v2 implements it for Confluent-compatible registries and caches
+// schemas after the first lookup.
+type AvroSchemaRegistry interface {
+	GetSchema(ctx context.Context, id int) (avro.Schema, error)
+}
+
+// handleAvro accepts either an Avro object container file, which carries its writer schema,
+// or a stream of records in the Confluent wire format (a zero magic byte and a big-endian
+// schema ID before each record), whose schemas are resolved from the schema registry.
+// Records use the same field names as the JSON payload and take the same path as JSON
+// ingestion once converted.
+func (h *IngestHandler) handleAvro(ctx context.Context, body io.Reader) error {
+	br := bufio.NewReader(body)
+	magic, err := br.Peek(len(avroContainerMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	var records []map[string]interface{}
+	if bytes.Equal(magic, avroContainerMagic) {
+		records, err = h.decodeAvroContainer(br)
+	} else {
+		records, err = h.decodeAvroWireFormat(ctx, br)
+	}
+	if err != nil {
+		return err
+	}
+
+	events := make([]domain.LogEvent, len(records))
+	for i, record := range records {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return &badRequestError{msg: fmt.Sprintf("Invalid Avro record %d", i), err: err}
+		}
+		if err := json.Unmarshal(raw, &events[i]); err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return &badRequestError{msg: fmt.Sprintf("Invalid Avro record %d", i), err: err}
+		}
+		events[i].RawEvent = raw
+	}
+
+	return h.ingestBatch(ctx, events)
+}
+
+func (h *IngestHandler) decodeAvroContainer(body io.Reader) ([]map[string]interface{}, error) {
+	dec, err := ocf.NewDecoder(body)
+	if err != nil {
+		return nil, h.avroDecodeErr(err)
+	}
+
+	var records []map[string]interface{}
+	for dec.HasNext() {
+		if h.cfg.MaxBatchEvents > 0 && len(records) >= h.cfg.MaxBatchEvents {
+			return nil, fmt.Errorf("%w of %d", errBatchTooLarge, h.cfg.MaxBatchEvents)
+		}
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			return nil, h.avroDecodeErr(err)
+		}
+		records = append(records, record)
+	}
+	if err := dec.Error(); err != nil {
+		return nil, h.avroDecodeErr(err)
+	}
+	return records, nil
+}
+
+func (h *IngestHandler) decodeAvroWireFormat(ctx context.Context, body io.Reader) ([]map[string]interface{}, error) {
+	r := avro.NewReader(body, 4096)
+	header := make([]byte, 5)
+
+	var records []map[string]interface{}
+	for {
+		if r.Peek(); errors.Is(r.Error, io.EOF) && len(records) > 0 {
+			return records, nil
+		}
+		if h.cfg.MaxBatchEvents > 0 && len(records) >= h.cfg.MaxBatchEvents {
+			return nil, fmt.Errorf("%w of %d", errBatchTooLarge, h.cfg.MaxBatchEvents)
+		}
+
+		if r.Read(header); r.Error != nil {
+			return nil, h.avroDecodeErr(r.Error)
+		}
+		if header[0] != 0 {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: "Failed to decode Avro", err: fmt.Errorf("invalid magic byte %#x", header[0])}
+		}
+		schema, err := h.resolveAvroSchema(ctx, int(binary.BigEndian.Uint32(header[1:])))
+		if err != nil {
+			return nil, err
+		}
+
+		var record map[string]interface{}
+		if r.ReadVal(schema, &record); r.Error != nil {
+			return nil, h.avroDecodeErr(r.Error)
+		}
+		records = append(records, record)
+	}
+}
+
+// resolveAvroSchema looks up a writer schema. Unknown IDs are the client's fault; an
+// unreachable registry is reported as a server error so that producers retry.
+func (h *IngestHandler) resolveAvroSchema(ctx context.Context, id int) (avro.Schema, error) {
+	if h.cfg.SchemaRegistry == nil {
+		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return nil, &badRequestError{msg: "Failed to decode Avro", err: errors.New("no schema registry configured, send an object container file instead")}
+	}
+	schema, err := h.cfg.SchemaRegistry.GetSchema(ctx, id)
+	if err != nil {
+		var regErr registry.Error
+		if errors.As(err, &regErr) && regErr.StatusCode == http.StatusNotFound {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: fmt.Sprintf("Unknown Avro schema ID %d", id), err: err}
+		}
+		return nil, fmt.Errorf("failed to fetch Avro schema %d: %w", id, err)
+	}
+	return schema, nil
+}
+
+func (h *IngestHandler) avroDecodeErr(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var badReqErr *badRequestError
+	if errors.As(err, &maxBytesErr) || errors.As(err, &badReqErr) {
+		return err
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+	return &badRequestError{msg: "Failed to decode Avro", err: err}
+}
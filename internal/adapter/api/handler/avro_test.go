@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/hamba/avro/v2/registry"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "LogEvent",
+	"fields": [
+		{"name": "message", "type": "string"},
+		{"name": "level", "type": ["null", "string"], "default": null},
+		{"name": "event_time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "metadata", "type": {"type": "map", "values": "string"}}
+	]
+}`
+
+// stubSchemaRegistry serves schemas from memory.
+type stubSchemaRegistry struct {
+	schemas map[int]avro.Schema
+	err     error
+}
+
+func (s *stubSchemaRegistry) GetSchema(ctx context.Context, id int) (avro.Schema, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	schema, ok := s.schemas[id]
+	if !ok {
+		return nil, registry.Error{StatusCode: http.StatusNotFound, Code: 40403, Message: "Schema not found"}
+	}
+	return schema, nil
+}
+
+func TestIngestHandler_Avro(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockSSEBroker := NewSSEBroker(context.Background(), logger)
+	schema := avro.MustParse(testAvroSchema)
+
+	eventTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := map[string]interface{}{"message": "a", "level": "info", "event_time": eventTime, "metadata": map[string]interface{}{"user": "bob"}}
+
+	containerBody := func(n int) []byte {
+		var buf bytes.Buffer
+		enc, err := ocf.NewEncoder(testAvroSchema, &buf)
+		if err != nil {
+			t.Fatalf("ocf encoder failed: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			if err := enc.Encode(record); err != nil {
+				t.Fatalf("ocf encode failed: %v", err)
+			}
+		}
+		enc.Close()
+		return buf.Bytes()
+	}
+	wireBody := func(id uint32, n int) []byte {
+		var buf bytes.Buffer
+		for i := 0; i < n; i++ {
+			data, err := avro.Marshal(schema, record)
+			if err != nil {
+				t.Fatalf("avro marshal failed: %v", err)
+			}
+			buf.WriteByte(0)
+			binary.Write(&buf, binary.BigEndian, id)
+			buf.Write(data)
+		}
+		return buf.Bytes()
+	}
+	stub := &stubSchemaRegistry{schemas: map[int]avro.Schema{7: schema}}
+
+	tests := []struct {
+		name           string
+		registry       AvroSchemaRegistry
+		body           []byte
+		expectedStatus int
+		expectedEvents int
+	}{
+		{name: "Container file", body: containerBody(2), expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Container file batch too large", body: containerBody(4), expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Container file truncated", body: containerBody(1)[:40], expectedStatus: http.StatusBadRequest},
+		{name: "Wire format", registry: stub, body: wireBody(7, 3), expectedStatus: http.StatusAccepted, expectedEvents: 3},
+		{name: "Wire format truncated", registry: stub, body: wireBody(7, 1)[:8], expectedStatus: http.StatusBadRequest},
+		{name: "Wire format bad magic", registry: stub, body: append([]byte{1}, wireBody(7, 1)[1:]...), expectedStatus: http.StatusBadRequest},
+		{name: "Unknown schema ID", registry: stub, body: wireBody(8, 1), expectedStatus: http.StatusBadRequest},
+		{name: "Registry unavailable", registry: &stubSchemaRegistry{err: errors.New("connection refused")}, body: wireBody(7, 1), expectedStatus: http.StatusInternalServerError},
+		{name: "Wire format without registry", body: wireBody(7, 1), expectedStatus: http.StatusBadRequest},
+		{name: "Empty body", registry: stub, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ingested []domain.LogEvent
+			mockUseCase := &MockIngestUseCase{
+				IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+					ingested = append(ingested, *event)
+					return nil
+				},
+			}
+			cfg := IngestHandlerConfig{MaxEventSize: 4096, MaxBatchEvents: 3, SchemaRegistry: tt.registry}
+			handler := NewIngestHandler(mockUseCase, logger, cfg, testMetrics, mockSSEBroker)
+
+			req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/avro")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (body %q)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if len(ingested) != tt.expectedEvents {
+				t.Fatalf("expected %d ingested events, got %d", tt.expectedEvents, len(ingested))
+			}
+			if len(ingested) > 0 {
+				got := ingested[0]
+				if got.Message != "a" || got.Level != "info" || !got.EventTime.Equal(eventTime) || string(got.Metadata) != `{"user":"bob"}` {
+					t.Errorf("unexpected event: %+v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// cloudWatchLogsData is the payload a CloudWatch Logs subscription filter delivers,
+// gzip-compressed, to its destination.
+type cloudWatchLogsData struct {
+	MessageType         string   `json:"messageType"`
+	Owner               string   `json:"owner"`
+	LogGroup            string   `json:"logGroup"`
+	LogStream           string   `json:"logStream"`
+	SubscriptionFilters []string `json:"subscriptionFilters"`
+	LogEvents           []struct {
+		ID        string `json:"id"`
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"logEvents"`
+}
+
+// cloudWatchEnvelope covers the wrappers CloudWatch Logs data arrives in: a Firehose HTTP
+// endpoint request ("records") or a Lambda subscription event ("awslogs").
+type cloudWatchEnvelope struct {
+	RequestID string `json:"requestId"`
+	Records   []struct {
+		Data string `json:"data"`
+	} `json:"records"`
+	AWSLogs *struct {
+		Data string `json:"data"`
+	} `json:"awslogs"`
+}
+
+// CloudWatchHandler accepts CloudWatch Logs subscription payloads (POST /v1/cloudwatch),
+// either as raw gzip data, a Lambda "awslogs" event, or a Firehose HTTP endpoint delivery.
+// The log group is recorded as the event source and the log stream in metadata.
+type CloudWatchHandler struct {
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
+}
+
+// NewCloudWatchHandler creates a new CloudWatchHandler.
+func NewCloudWatchHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *CloudWatchHandler {
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = cfg.MaxEventSize
+	}
+	return &CloudWatchHandler{
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
+	}
+}
+
+// ServeHTTP unpacks the subscription payload and ingests every embedded log event.
+func (h *CloudWatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Respond(w, apierror.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxEventSize)
+
+	body, err := decodeBody(w, r, h.cfg.MaxDecompressedSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+
+	// Firehose identifies each delivery with a request ID that must be echoed back.
+	var env cloudWatchEnvelope
+	isFirehose := json.Unmarshal(data, &env) == nil && env.RequestID != ""
+
+	accepted, err := h.ingestPayload(r, data, &env)
+	if accepted > 0 {
+		h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(accepted))
+		h.sseBroker.ReportEvents(accepted)
+	}
+
+	if isFirehose {
+		h.writeFirehoseResponse(w, env.RequestID, err)
+		return
+	}
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *CloudWatchHandler) ingestPayload(r *http.Request, data []byte, env *cloudWatchEnvelope) (int, error) {
+	var blobs [][]byte
+	switch {
+	case isGzip(data):
+		blobs = [][]byte{data}
+	case len(env.Records) > 0:
+		for _, rec := range env.Records {
+			blob, err := base64.StdEncoding.DecodeString(rec.Data)
+			if err != nil {
+				h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+				return 0, &badRequestError{msg: "Failed to decode Firehose record", err: err}
+			}
+			blobs = append(blobs, blob)
+		}
+	case env.AWSLogs != nil:
+		blob, err := base64.StdEncoding.DecodeString(env.AWSLogs.Data)
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return 0, &badRequestError{msg: "Failed to decode awslogs data", err: err}
+		}
+		blobs = [][]byte{blob}
+	default:
+		// Already decompressed via Content-Encoding.
+		blobs = [][]byte{data}
+	}
+
+	var accepted int
+	for _, blob := range blobs {
+		n, err := h.ingestBlob(r, blob)
+		accepted += n
+		if err != nil {
+			return accepted, err
+		}
+	}
+	return accepted, nil
+}
+
+// ingestBlob decodes one or more concatenated CloudWatch Logs messages, gunzipping first
+// if needed, and ingests their log events. Control messages are skipped.
+func (h *CloudWatchHandler) ingestBlob(r *http.Request, blob []byte) (int, error) {
+	var reader io.Reader = bytes.NewReader(blob)
+	if isGzip(blob) {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return 0, &badRequestError{msg: "Failed to decompress CloudWatch data", err: err}
+		}
+		defer gz.Close()
+		// Bound the expanded size the same way decodeBody does for Content-Encoding.
+		reader = &limitedReader{r: decompressReader{gz}, limit: h.cfg.MaxDecompressedSize}
+	}
+
+	var accepted int
+	dec := json.NewDecoder(reader)
+	for {
+		var msg cloudWatchLogsData
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return accepted, nil
+			}
+			var maxBytesErr *http.MaxBytesError
+			var badReqErr *badRequestError
+			if errors.As(err, &maxBytesErr) || errors.As(err, &badReqErr) {
+				return accepted, err
+			}
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return accepted, &badRequestError{msg: "Failed to decode CloudWatch Logs data", err: err}
+		}
+		if msg.MessageType != "DATA_MESSAGE" {
+			continue
+		}
+
+		for _, le := range msg.LogEvents {
+			metadata, _ := json.Marshal(map[string]interface{}{
+				"log_group":            msg.LogGroup,
+				"log_stream":           msg.LogStream,
+				"aws_account_id":       msg.Owner,
+				"subscription_filters": msg.SubscriptionFilters,
+				"cloudwatch_event_id":  le.ID,
+			})
+			event := domain.LogEvent{
+				EventTime: time.UnixMilli(le.Timestamp).UTC(),
+				Source:    msg.LogGroup,
+				Message:   le.Message,
+				Metadata:  metadata,
+			}
+			event.RawEvent, _ = json.Marshal(le)
+
+			if err := h.useCase.Ingest(r.Context(), &event); err != nil {
+				h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+				return accepted, err
+			}
+			accepted++
+		}
+	}
+}
+
+// writeFirehoseResponse writes the JSON response a Firehose HTTP endpoint destination
+// expects. Firehose retries the whole delivery on any non-200 response.
+func (h *CloudWatchHandler) writeFirehoseResponse(w http.ResponseWriter, requestID string, err error) {
+	resp := map[string]interface{}{
+		"requestId": requestID,
+		"timestamp": time.Now().UnixMilli(),
+	}
+	status := http.StatusOK
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		var badReqErr *badRequestError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
+			status = http.StatusRequestEntityTooLarge
+		case errors.As(err, &badReqErr):
+			h.logger.Warn("Rejected Firehose delivery", "error", err, "request_id", requestID)
+			status = http.StatusBadRequest
+		default:
+			h.logger.Error("Failed to process Firehose delivery", "error", err, "request_id", requestID)
+			status = http.StatusInternalServerError
+		}
+		resp["errorMessage"] = err.Error()
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// limitedReader fails with an *http.MaxBytesError once more than limit bytes are read.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read > l.limit {
+		return 0, &http.MaxBytesError{Limit: l.limit}
+	}
+	if max := l.limit - l.read + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, &http.MaxBytesError{Limit: l.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestCloudWatchHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	gz := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+	dataMessage := `{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/aws/lambda/checkout","logStream":"2024/05/01/[$LATEST]abc","subscriptionFilters":["all"],"logEvents":[` +
+		`{"id":"1","timestamp":1714564800000,"message":"START RequestId: 1"},` +
+		`{"id":"2","timestamp":1714564800500,"message":"payment failed"}]}`
+	controlMessage := `{"messageType":"CONTROL_MESSAGE","owner":"CloudwatchLogs","logGroup":"","logStream":"","subscriptionFilters":[],"logEvents":[{"id":"","timestamp":1714564800000,"message":"CWL CONTROL MESSAGE"}]}`
+	b64 := func(b []byte) string { return base64.StdEncoding.EncodeToString(b) }
+
+	firehoseBody, _ := json.Marshal(map[string]interface{}{
+		"requestId": "req-1",
+		"timestamp": 1714564801000,
+		"records": []map[string]string{
+			{"data": b64(gz(dataMessage))},
+			{"data": b64(gz(controlMessage))},
+		},
+	})
+	lambdaBody, _ := json.Marshal(map[string]interface{}{"awslogs": map[string]string{"data": b64(gz(dataMessage))}})
+
+	tests := []struct {
+		name           string
+		body           []byte
+		ingestErr      error
+		expectedStatus int
+		expectedEvents int
+		firehose       bool
+	}{
+		{name: "Raw gzip", body: gz(dataMessage), expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Lambda awslogs event", body: lambdaBody, expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Firehose delivery", body: firehoseBody, expectedStatus: http.StatusOK, expectedEvents: 2, firehose: true},
+		{name: "Firehose buffer failure", body: firehoseBody, ingestErr: errors.New("buffer full"), expectedStatus: http.StatusInternalServerError, firehose: true},
+		{name: "Corrupt data", body: []byte(`{"awslogs":{"data":"!!"}}`), expectedStatus: http.StatusBadRequest},
+		{name: "Decompressed size limit", body: gz(dataMessage + strings.Repeat(" ", 8192)), expectedStatus: http.StatusRequestEntityTooLarge, expectedEvents: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []domain.LogEvent
+			uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+				if tt.ingestErr != nil {
+					return tt.ingestErr
+				}
+				events = append(events, *event)
+				return nil
+			}}
+			h := NewCloudWatchHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20, MaxDecompressedSize: 4096}, testMetrics, sse)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/cloudwatch", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if len(events) != tt.expectedEvents {
+				t.Fatalf("expected %d events, got %d", tt.expectedEvents, len(events))
+			}
+			if tt.firehose {
+				var resp map[string]interface{}
+				if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp["requestId"] != "req-1" {
+					t.Errorf("expected Firehose response echoing the request ID, got %s", rr.Body.String())
+				}
+			}
+			if len(events) > 0 {
+				ev := events[1]
+				if ev.Source != "/aws/lambda/checkout" || ev.Message != "payment failed" || ev.EventTime.UnixMilli() != 1714564800500 {
+					t.Errorf("unexpected event: %+v", ev)
+				}
+				var md map[string]interface{}
+				json.Unmarshal(ev.Metadata, &md)
+				if md["log_stream"] != "2024/05/01/[$LATEST]abc" {
+					t.Errorf("expected log stream in metadata, got %s", ev.Metadata)
+				}
+			}
+		})
+	}
+}
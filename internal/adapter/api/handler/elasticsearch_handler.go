@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// esCompatVersion is the Elasticsearch version reported to clients. Beats and Vector
+// pick their request format from it.
+const esCompatVersion = "8.11.0"
+
+// defaultMaxBulkSize matches Elasticsearch's default http.max_content_length.
+const defaultMaxBulkSize = 100 << 20
+
+// ElasticsearchHandler implements enough of the Elasticsearch HTTP API for shippers using
+// an Elasticsearch output (Vector, Filebeat, Fluent Bit) to write to watch-tower: the
+// _bulk endpoint, plus the root and cluster health endpoints they probe on startup.
+// Index templates and ILM are not supported, so template setup must be disabled.
+type ElasticsearchHandler struct {
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
+}
+
+// NewElasticsearchHandler creates a new ElasticsearchHandler.
+func NewElasticsearchHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *ElasticsearchHandler {
+	if cfg.MaxBulkSize <= 0 {
+		cfg.MaxBulkSize = defaultMaxBulkSize
+	}
+	return &ElasticsearchHandler{
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
+	}
+}
+
+// esBulkAction is the action/metadata line preceding each document in a _bulk body.
+type esBulkAction struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// esBulkItemError follows the error object Elasticsearch reports for a failed item.
+type esBulkItemError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// esBulkItem is the per-action result in a _bulk response.
+type esBulkItem struct {
+	Index   string           `json:"_index"`
+	ID      string           `json:"_id,omitempty"`
+	Version int              `json:"_version,omitempty"`
+	Result  string           `json:"result,omitempty"`
+	Status  int              `json:"status"`
+	Error   *esBulkItemError `json:"error,omitempty"`
+}
+
+// Info answers GET / with the cluster information clients use for version detection.
+func (h *ElasticsearchHandler) Info(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":         "watch-tower",
+		"cluster_name": "watch-tower",
+		"version": map[string]interface{}{
+			"number":                              esCompatVersion,
+			"build_flavor":                        "default",
+			"minimum_wire_compatibility_version":  "7.17.0",
+			"minimum_index_compatibility_version": "7.0.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+// Health answers GET /_cluster/health, which Vector uses as its healthcheck.
+func (h *ElasticsearchHandler) Health(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"cluster_name": "watch-tower",
+		"status":       "green",
+	})
+}
+
+// Bulk ingests a _bulk request. index and create actions are ingested; update and delete
+// cannot be applied to an append-only log store and are rejected per item. Like
+// Elasticsearch, the response is 200 with per-item statuses; buffer failures are
+// reported as 429 so clients retry only those items, and documents larger than
+// MaxEventSize as 413.
+//
+// The body as a whole is limited by MaxBulkSize, before and after decompression. Items
+// are ingested as they are read, so a declared Content-Length over the limit is rejected
+// before anything is ingested.
+func (h *ElasticsearchHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r.ContentLength > h.cfg.MaxBulkSize {
+		writeIngestError(w, &http.MaxBytesError{Limit: h.cfg.MaxBulkSize}, h.logger, h.metrics)
+		return
+	}
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxBulkSize)
+
+	body, err := decodeBody(w, r, h.cfg.MaxBulkSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
+
+	items, err := h.processBulk(r.Context(), body, r.PathValue("index"))
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+
+	hasErrors := false
+	for _, item := range items {
+		for _, res := range item {
+			if res.Error != nil {
+				hasErrors = true
+			}
+		}
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"took":   time.Since(start).Milliseconds(),
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+func (h *ElasticsearchHandler) processBulk(ctx context.Context, body io.Reader, defaultIndex string) ([]map[string]*esBulkItem, error) {
+	br := bufio.NewReaderSize(body, 64*1024)
+	var readErr error
+
+	var items []map[string]*esBulkItem
+	var accepted int
+	defer func() {
+		if accepted > 0 {
+			h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(accepted))
+			h.sseBroker.ReportEvents(accepted)
+		}
+	}()
+
+	// nextLine returns the next non-empty line. Lines longer than MaxEventSize are
+	// consumed but returned as nil with tooLong set.
+	nextLine := func() (line []byte, tooLong, ok bool) {
+		for readErr == nil {
+			line, tooLong, readErr = readBulkLine(br, h.cfg.MaxEventSize)
+			if len(line) > 0 || tooLong {
+				return line, tooLong, true
+			}
+		}
+		return nil, false, false
+	}
+
+	for {
+		line, tooLong, ok := nextLine()
+		if !ok {
+			break
+		}
+		if tooLong {
+			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
+			return nil, &badRequestError{msg: "Malformed bulk action line", err: fmt.Errorf("action line exceeds %d bytes", h.cfg.MaxEventSize)}
+		}
+		var actionLine map[string]esBulkAction
+		if err := json.Unmarshal(line, &actionLine); err != nil || len(actionLine) != 1 {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			if err == nil {
+				err = errors.New("expected exactly one action")
+			}
+			return nil, &badRequestError{msg: "Malformed bulk action line", err: err}
+		}
+
+		var op string
+		var action esBulkAction
+		for op, action = range actionLine {
+		}
+		if action.Index == "" {
+			action.Index = defaultIndex
+		}
+		item := &esBulkItem{Index: action.Index, ID: action.ID}
+		items = append(items, map[string]*esBulkItem{op: item})
+
+		switch op {
+		case "index", "create":
+		case "delete":
+			item.fail(http.StatusBadRequest, "action_request_validation_exception", "delete is not supported by watch-tower")
+			continue
+		case "update":
+			nextLine() // The partial document is ignored.
+			item.fail(http.StatusBadRequest, "action_request_validation_exception", "update is not supported by watch-tower")
+			continue
+		default:
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: "Malformed bulk action line", err: fmt.Errorf("unknown action %q", op)}
+		}
+
+		doc, tooLong, ok := nextLine()
+		if !ok {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: "Malformed bulk request", err: errors.New("action line without document")}
+		}
+		if tooLong {
+			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
+			item.fail(http.StatusRequestEntityTooLarge, "document_too_large_exception", fmt.Sprintf("document exceeds %d bytes", h.cfg.MaxEventSize))
+			continue
+		}
+		event, err := esDocToLogEvent(doc, action)
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			item.fail(http.StatusBadRequest, "document_parsing_exception", err.Error())
+			continue
+		}
+		if err := h.useCase.Ingest(ctx, &event); err != nil {
+			h.logger.Error("Failed to ingest event from bulk request", "error", err)
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			item.fail(http.StatusTooManyRequests, "es_rejected_execution_exception", "failed to buffer event")
+			continue
+		}
+		accepted++
+		item.ID = event.ID
+		item.Version = 1
+		item.Result = "created"
+		item.Status = http.StatusCreated
+	}
+	if !errors.Is(readErr, io.EOF) {
+		return nil, readErr
+	}
+	return items, nil
+}
+
+// readBulkLine reads one line without its line ending. A line longer than maxSize is
+// read to its end but not returned. It returns io.EOF only after the last line.
+func readBulkLine(br *bufio.Reader, maxSize int64) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !tooLong && int64(len(line)+len(chunk)) <= maxSize+2 { // Allow for "\r\n".
+			line = append(line, chunk...)
+		} else {
+			tooLong, line = true, nil
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if errors.Is(err, io.EOF) && (len(line) > 0 || tooLong) {
+			err = nil
+		}
+		if int64(len(line)) > maxSize {
+			tooLong, line = true, nil
+		}
+		return line, tooLong, err
+	}
+}
+
+func (i *esBulkItem) fail(status int, errType, reason string) {
+	i.Status = status
+	i.Error = &esBulkItemError{Type: errType, Reason: reason}
+}
+
+// esDocToLogEvent maps a document onto a LogEvent, understanding both flat documents and
+// the Elastic Common Schema used by Beats: "message", "@timestamp", "log.level" and
+// "service.name" populate the event (falling back to the index name as source) and the
+// rest of the document becomes metadata.
+func esDocToLogEvent(doc []byte, action esBulkAction) (domain.LogEvent, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return domain.LogEvent{}, err
+	}
+
+	event := domain.LogEvent{ID: action.ID}
+	if msg, ok := fields["message"].(string); ok {
+		event.Message = msg
+		delete(fields, "message")
+	}
+	for _, key := range []string{"@timestamp", "timestamp"} {
+		if ts, ok := fields[key].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				event.EventTime = t
+				delete(fields, key)
+				break
+			}
+		}
+	}
+	if level, ok := fields["level"].(string); ok {
+		event.Level = level
+	} else if logField, ok := fields["log"].(map[string]interface{}); ok {
+		event.Level, _ = logField["level"].(string)
+	}
+	if service, ok := fields["service"].(map[string]interface{}); ok {
+		event.Source, _ = service["name"].(string)
+	}
+	if event.Source == "" {
+		event.Source, _ = fields["source"].(string)
+	}
+	if event.Source == "" {
+		event.Source = action.Index
+	}
+
+	fields["es_index"] = action.Index
+	event.Metadata, _ = json.Marshal(fields)
+	event.RawEvent = append([]byte(nil), doc...)
+	return event, nil
+}
+
+func (h *ElasticsearchHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	// Elasticsearch clients since 7.14 refuse to talk to servers without this header.
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
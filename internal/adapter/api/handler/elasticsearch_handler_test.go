@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestElasticsearchHandler_Bulk(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	body := `{"index":{"_index":"filebeat-8.11.0"}}
+{"@timestamp":"2023-11-14T10:00:00.123Z","message":"user logged in","log":{"level":"info"},"service":{"name":"auth"},"host":{"name":"web-1"}}
+{"create":{"_id":"abc"}}
+{"message":"disk full","level":"error"}
+
+{"delete":{"_index":"logs","_id":"1"}}
+{"update":{"_index":"logs","_id":"1"}}
+{"doc":{"message":"x"}}
+{"index":{}}
+not json
+`
+
+	var events []domain.LogEvent
+	uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+		if event.ID == "" {
+			event.ID = "generated"
+		}
+		events = append(events, *event)
+		return nil
+	}}
+	h := NewElasticsearchHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+
+	req := httptest.NewRequest(http.MethodPost, "/es/vector/_bulk", strings.NewReader(body))
+	req.SetPathValue("index", "vector")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	h.Bulk(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Elastic-Product") != "Elasticsearch" {
+		t.Error("missing X-Elastic-Product header")
+	}
+	var resp struct {
+		Errors bool                    `json:"errors"`
+		Items  []map[string]esBulkItem `json:"items"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if !resp.Errors || len(resp.Items) != 5 {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+	wantStatus := []struct {
+		op     string
+		status int
+	}{{"index", 201}, {"create", 201}, {"delete", 400}, {"update", 400}, {"index", 400}}
+	for i, want := range wantStatus {
+		if item, ok := resp.Items[i][want.op]; !ok || item.Status != want.status {
+			t.Errorf("item %d: expected %s with status %d, got %+v", i, want.op, want.status, resp.Items[i])
+		}
+	}
+	if resp.Items[0]["index"].ID != "generated" || resp.Items[1]["create"].ID != "abc" {
+		t.Errorf("unexpected ids: %+v", resp.Items)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	first := events[0]
+	if first.Message != "user logged in" || first.Level != "info" || first.Source != "auth" || first.EventTime.UnixMilli() != 1699956000123 {
+		t.Errorf("unexpected event: %+v", first)
+	}
+	var md map[string]interface{}
+	json.Unmarshal(first.Metadata, &md)
+	if md["es_index"] != "filebeat-8.11.0" || md["host"] == nil || md["message"] != nil {
+		t.Errorf("unexpected metadata: %s", first.Metadata)
+	}
+	if events[1].Source != "vector" || events[1].Level != "error" {
+		t.Errorf("expected path index as source, got %+v", events[1])
+	}
+}
+
+func TestElasticsearchHandler_BulkErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	t.Run("Malformed action line", func(t *testing.T) {
+		h := NewElasticsearchHandler(&MockIngestUseCase{}, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+		req := httptest.NewRequest(http.MethodPost, "/es/_bulk", strings.NewReader("{\"message\":\"no action\"}\n"))
+		rr := httptest.NewRecorder()
+		h.Bulk(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Body larger than one event", func(t *testing.T) {
+		var events []domain.LogEvent
+		uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+			events = append(events, *event)
+			return nil
+		}}
+		h := NewElasticsearchHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 64}, testMetrics, sse)
+		body := strings.Repeat("{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"hi\"}\n", 10) +
+			"{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"" + strings.Repeat("x", 100) + "\"}\n"
+		req := httptest.NewRequest(http.MethodPost, "/es/_bulk", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		h.Bulk(rr, req)
+		if rr.Code != http.StatusOK || len(events) != 10 || !strings.Contains(rr.Body.String(), `"status":413`) {
+			t.Errorf("expected 10 documents and a per-item 413, got %d events, %d (%s)", len(events), rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Declared length over the bulk limit", func(t *testing.T) {
+		var ingested int
+		uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+			ingested++
+			return nil
+		}}
+		h := NewElasticsearchHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20, MaxBulkSize: 32}, testMetrics, sse)
+		req := httptest.NewRequest(http.MethodPost, "/es/_bulk", strings.NewReader("{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"hi\"}\n"))
+		rr := httptest.NewRecorder()
+		h.Bulk(rr, req)
+		if rr.Code != http.StatusRequestEntityTooLarge || ingested != 0 {
+			t.Errorf("expected 413 before ingesting, got %d after %d events", rr.Code, ingested)
+		}
+	})
+
+	t.Run("Buffer failure is retryable per item", func(t *testing.T) {
+		uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+			return errors.New("buffer down")
+		}}
+		h := NewElasticsearchHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+		req := httptest.NewRequest(http.MethodPost, "/es/_bulk", strings.NewReader("{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"hi\"}\n"))
+		rr := httptest.NewRecorder()
+		h.Bulk(rr, req)
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":429`) {
+			t.Errorf("expected per-item 429, got %d (%s)", rr.Code, rr.Body.String())
+		}
+	})
+}
@@ -2,82 +2,323 @@ package handler
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"google.golang.org/protobuf/proto"
+
+	ingestv1 "github.com/V4T54L/watch-tower/api/ingest/v1"
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/V4T54L/watch-tower/internal/usecase"
 )
 
 const (
-	contentTypeJSON   = "application/json"
-	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeJSON     = "application/json"
+	contentTypeNDJSON   = "application/x-ndjson"
+	contentTypeText     = "text/plain"
+	contentTypeMsgpack  = "application/msgpack"
+	contentTypeXMsgpack = "application/x-msgpack"
 )
 
+// badRequestError marks errors caused by the client's payload rather than the server.
+type badRequestError struct {
+	msg string
+	err error
+}
+
+func (e *badRequestError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *badRequestError) Unwrap() error { return e.err }
+
+// errUnsupportedEncoding is returned for Content-Encoding values the handler cannot decode.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// errBatchTooLarge is returned when a JSON array payload holds more events than allowed.
+var errBatchTooLarge = errors.New("batch exceeds maximum event count")
+
+// IngestHandlerConfig holds the request limits and parsing options of the IngestHandler.
+type IngestHandlerConfig struct {
+	MaxEventSize        int64              // Max size of the request body as received on the wire.
+	MaxDecompressedSize int64              // Max size of the body after Content-Encoding is removed.
+	MaxBatchEvents      int                // Max events in a JSON array payload, 0 disables the limit.
+	TextParser          *textparser.Chain  // Parses text/plain lines; nil keeps each line as the message.
+	SchemaRegistry      AvroSchemaRegistry // Resolves writer schemas of Avro records; nil accepts only container files.
+	MaxStreamSize       int64              // Max size of a streamed NDJSON upload, whose lines are capped at MaxEventSize.
+	MaxBulkSize         int64              // Max size of an Elasticsearch _bulk body, whose documents are capped at MaxEventSize.
+}
+
 // IngestHandler handles HTTP requests for log ingestion.
 type IngestHandler struct {
-	useCase      usecase.IngestLogUseCase
-	logger       *slog.Logger
-	maxEventSize int64
-	metrics      *metrics.IngestMetrics
-	sseBroker    *SSEBroker
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
 }
 
 // NewIngestHandler creates a new IngestHandler.
-func NewIngestHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, maxEventSize int64, m *metrics.IngestMetrics, sse *SSEBroker) *IngestHandler {
+func NewIngestHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *IngestHandler {
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = cfg.MaxEventSize
+	}
+	if cfg.MaxStreamSize <= 0 {
+		cfg.MaxStreamSize = cfg.MaxDecompressedSize
+	}
 	return &IngestHandler{
-		useCase:      uc,
-		logger:       logger,
-		maxEventSize: maxEventSize,
-		metrics:      m,
-		sseBroker:    sse,
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
 	}
 }
 
 // ServeHTTP processes incoming log ingestion requests.
 func (h *IngestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		apierror.Respond(w, apierror.CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	h.metrics.BytesTotal.Add(float64(r.ContentLength))
-	// Enforce max body size
-	r.Body = http.MaxBytesReader(w, r.Body, h.maxEventSize)
-
 	contentType := r.Header.Get("Content-Type")
-	var err error
+	if !isSupportedIngestType(contentType) {
+		h.metrics.EventsTotal.WithLabelValues("error_media_type").Inc()
+		apierror.Respond(w, apierror.CodeUnsupportedMediaType, "unsupported media type: "+contentType)
+		return
+	}
+
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+
+	if strings.HasPrefix(contentType, contentTypeNDJSON) && wantsNDJSONProgress(r) {
+		h.streamNDJSON(w, r)
+		return
+	}
+
+	// Enforce max body size on the wire
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxEventSize)
+
+	body, err := decodeBody(w, r, h.cfg.MaxDecompressedSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
 
 	switch {
-	case strings.HasPrefix(contentType, contentTypeJSON):
-		err = h.handleSingleJSON(r.Context(), r.Body)
 	case strings.HasPrefix(contentType, contentTypeNDJSON):
-		err = h.handleNDJSON(r.Context(), r.Body)
+		err = h.handleNDJSON(r.Context(), body)
+	case strings.HasPrefix(contentType, contentTypeText):
+		err = h.handleText(r.Context(), body)
+	case strings.HasPrefix(contentType, contentTypeMsgpack), strings.HasPrefix(contentType, contentTypeXMsgpack):
+		err = h.handleMsgpack(r.Context(), body)
+	case strings.HasPrefix(contentType, contentTypeProtobuf):
+		err = h.handleProtobuf(r.Context(), body)
+	case strings.HasPrefix(contentType, contentTypeAvro):
+		err = h.handleAvro(r.Context(), body)
 	default:
-		h.metrics.EventsTotal.WithLabelValues("error_media_type").Inc()
-		http.Error(w, "Unsupported Content-Type. Use application/json or application/x-ndjson.", http.StatusUnsupportedMediaType)
+		err = h.handleJSON(r.Context(), body)
+	}
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
 		return
 	}
 
-	if err != nil {
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func isSupportedIngestType(contentType string) bool {
+	for _, t := range []string{contentTypeJSON, contentTypeNDJSON, contentTypeText, contentTypeMsgpack, contentTypeXMsgpack, contentTypeProtobuf, contentTypeAvro} {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody removes any Content-Encoding from the request body. The decompressed stream
+// is wrapped in its own MaxBytesReader so that small compressed bodies cannot expand
+// beyond maxDecompressedSize.
+func decodeBody(w http.ResponseWriter, r *http.Request, maxDecompressedSize int64) (io.ReadCloser, error) {
+	var decoded io.ReadCloser
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return r.Body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, &badRequestError{msg: "Failed to decompress body", err: err}
+		}
+		decoded = gz
+	case "zstd":
+		zr, err := zstd.NewReader(r.Body)
+		if err != nil {
+			return nil, &badRequestError{msg: "Failed to decompress body", err: err}
+		}
+		decoded = zr.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedEncoding, encoding)
+	}
+
+	return http.MaxBytesReader(w, decompressReader{decoded}, maxDecompressedSize), nil
+}
+
+// decompressReader reports corrupt compressed data as a client error.
+type decompressReader struct {
+	io.ReadCloser
+}
+
+func (d decompressReader) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
 		var maxBytesErr *http.MaxBytesError
-		if errors.As(err, &maxBytesErr) {
-			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
-			http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
-		} else {
-			h.logger.Error("Failed to process request", "error", err)
-			http.Error(w, "Failed to process request", http.StatusBadRequest)
+		if !errors.As(err, &maxBytesErr) {
+			err = &badRequestError{msg: "Failed to decompress body", err: err}
 		}
-		return
 	}
+	return n, err
+}
 
-	w.WriteHeader(http.StatusAccepted)
+// countingReadCloser adds the bytes read through it to a counter. Handlers wrap request
+// bodies in it instead of counting Content-Length, which chunked uploads do not have.
+type countingReadCloser struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (c countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+// writeIngestError maps errors from the ingest handlers to HTTP responses.
+func writeIngestError(w http.ResponseWriter, err error, logger *slog.Logger, m *metrics.IngestMetrics) {
+	var maxBytesErr *http.MaxBytesError
+	var badReqErr *badRequestError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		m.EventsTotal.WithLabelValues("error_size").Inc()
+		apierror.Respond(w, apierror.CodePayloadTooLarge, maxBytesErr.Error())
+	case errors.Is(err, errBatchTooLarge):
+		m.EventsTotal.WithLabelValues("error_size").Inc()
+		apierror.Respond(w, apierror.CodePayloadTooLarge, err.Error())
+	case errors.Is(err, errUnsupportedEncoding):
+		m.EventsTotal.WithLabelValues("error_encoding").Inc()
+		apierror.Respond(w, apierror.CodeUnsupportedMediaType, err.Error())
+	case errors.As(err, &badReqErr):
+		logger.Warn("Rejected ingest request", "error", err)
+		apierror.Respond(w, apierror.CodeInvalidRequest, badReqErr.msg)
+	case errors.Is(err, domain.ErrBufferFull):
+		logger.Warn("Rejected ingest request, buffer is full", "error", err)
+		apierror.Write(w, err)
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		m.EventsTotal.WithLabelValues("error_quota").Inc()
+		apierror.Write(w, err)
+	default:
+		logger.Error("Failed to process request", "error", err)
+		apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+	}
+}
+
+// handleJSON accepts either a single JSON object or a JSON array of objects, as sent by
+// several logging SDKs, and dispatches on the first non-whitespace byte.
+func (h *IngestHandler) handleJSON(ctx context.Context, body io.Reader) error {
+	br := bufio.NewReader(body)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+				return &badRequestError{msg: "Failed to decode JSON", err: io.ErrUnexpectedEOF}
+			}
+			return err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.Discard(1)
+			continue
+		case '[':
+			return h.handleJSONArray(ctx, br)
+		}
+		return h.handleSingleJSON(ctx, br)
+	}
+}
+
+// handleJSONArray decodes the array element by element so that large batches are never
+// held in memory in full. Elements decoded before an error are still ingested, matching
+// the NDJSON path.
+func (h *IngestHandler) handleJSONArray(ctx context.Context, body io.Reader) error {
+	dec := json.NewDecoder(body)
+	var processedCount, failedCount int
+	var lastErr error
+	defer func() {
+		if processedCount > 0 {
+			h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(processedCount))
+			h.sseBroker.ReportEvents(processedCount)
+		}
+	}()
+
+	decodeErr := func(err error) error {
+		// Read errors (size limits, corrupt compression) keep their own status codes.
+		var maxBytesErr *http.MaxBytesError
+		var badReqErr *badRequestError
+		if errors.As(err, &maxBytesErr) || errors.As(err, &badReqErr) {
+			return err
+		}
+		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return &badRequestError{msg: "Failed to decode JSON array", err: err}
+	}
+
+	if _, err := dec.Token(); err != nil { // opening '['
+		return decodeErr(err)
+	}
+	for dec.More() {
+		if h.cfg.MaxBatchEvents > 0 && processedCount+failedCount >= h.cfg.MaxBatchEvents {
+			return fmt.Errorf("%w of %d", errBatchTooLarge, h.cfg.MaxBatchEvents)
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return decodeErr(err)
+		}
+		var event domain.LogEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return decodeErr(err)
+		}
+		event.RawEvent = raw
+
+		if err := h.useCase.Ingest(ctx, &event); err != nil {
+			h.logger.Error("Failed to ingest event from JSON array", "error", err)
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			failedCount++
+			lastErr = err
+			continue
+		}
+		processedCount++
+	}
+	if _, err := dec.Token(); err != nil { // closing ']'
+		return decodeErr(err)
+	}
+
+	if processedCount == 0 && failedCount > 0 {
+		return lastErr
+	}
+	return nil
 }
 
 func (h *IngestHandler) handleSingleJSON(ctx context.Context, body io.Reader) error {
@@ -89,7 +330,7 @@ func (h *IngestHandler) handleSingleJSON(ctx context.Context, body io.Reader) er
 	var event domain.LogEvent
 	if err := json.Unmarshal(bodyBytes, &event); err != nil {
 		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
-		return err
+		return &badRequestError{msg: "Failed to decode JSON", err: err}
 	}
 	event.RawEvent = bodyBytes
 
@@ -103,9 +344,19 @@ func (h *IngestHandler) handleSingleJSON(ctx context.Context, body io.Reader) er
 	return nil
 }
 
+// handleNDJSON ingests each line of the body, continuing past buffer failures like the
+// JSON array path. It only fails with the buffer error if no line could be buffered.
 func (h *IngestHandler) handleNDJSON(ctx context.Context, body io.Reader) error {
 	scanner := bufio.NewScanner(body)
-	var processedCount int
+	var processedCount, failedCount int
+	var lastErr error
+	defer func() {
+		if processedCount > 0 {
+			h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(processedCount))
+			h.sseBroker.ReportEvents(processedCount)
+		}
+	}()
+
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		if len(line) == 0 {
@@ -114,16 +365,22 @@ func (h *IngestHandler) handleNDJSON(ctx context.Context, body io.Reader) error
 
 		var event domain.LogEvent
 		if err := json.Unmarshal(line, &event); err != nil {
-			h.logger.Warn("Failed to unmarshal NDJSON line, skipping", "error", err)
+			// A read error truncates the final line; report the read error instead.
+			if readErr := scanner.Err(); readErr != nil {
+				return readErr
+			}
 			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
-			continue
+			return &badRequestError{msg: "Failed to decode NDJSON line", err: err}
 		}
-		event.RawEvent = line
+		// The scanner reuses its buffer, so keep a copy of the raw line.
+		event.RawEvent = append([]byte(nil), line...)
 
 		if err := h.useCase.Ingest(ctx, &event); err != nil {
 			h.logger.Error("Failed to ingest event from NDJSON stream", "error", err)
 			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
 			// Continue processing other lines
+			failedCount++
+			lastErr = err
 			continue
 		}
 		processedCount++
@@ -132,11 +389,164 @@ func (h *IngestHandler) handleNDJSON(ctx context.Context, body io.Reader) error
 	if err := scanner.Err(); err != nil {
 		return err
 	}
+	if processedCount == 0 && failedCount > 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// handleText ingests each non-empty line of a text/plain body, running it through the
+// configured parser chain to extract structured fields. Buffer failures are handled as in
+// handleNDJSON.
+func (h *IngestHandler) handleText(ctx context.Context, body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	var processedCount, failedCount int
+	var lastErr error
+	defer func() {
+		if processedCount > 0 {
+			h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(processedCount))
+			h.sseBroker.ReportEvents(processedCount)
+		}
+	}()
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		event := h.cfg.TextParser.Parse(line)
+		event.RawEvent, _ = json.Marshal(line)
+
+		if err := h.useCase.Ingest(ctx, &event); err != nil {
+			h.logger.Error("Failed to ingest event from text body", "error", err)
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			failedCount++
+			lastErr = err
+			continue
+		}
+		processedCount++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if processedCount == 0 && failedCount > 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// handleMsgpack accepts a MessagePack map with the same keys as the JSON payload, an array
+// of such maps, or a stream of consecutive maps. Each map is converted to JSON so that
+// events take exactly the same path as JSON ingestion.
+func (h *IngestHandler) handleMsgpack(ctx context.Context, body io.Reader) error {
+	dec := msgpack.NewDecoder(body)
+	decodeErr := func(err error) error {
+		var maxBytesErr *http.MaxBytesError
+		var badReqErr *badRequestError
+		if errors.As(err, &maxBytesErr) || errors.As(err, &badReqErr) {
+			return err
+		}
+		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return &badRequestError{msg: "Failed to decode MessagePack", err: err}
+	}
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return decodeErr(err)
+	}
+
+	remaining := -1 // -1 reads maps until the end of the body
+	if msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32 {
+		if remaining, err = dec.DecodeArrayLen(); err != nil {
+			return decodeErr(err)
+		}
+	}
+
+	var events []domain.LogEvent
+	for remaining != 0 {
+		if remaining < 0 {
+			if _, err := dec.PeekCode(); errors.Is(err, io.EOF) {
+				break
+			}
+		} else {
+			remaining--
+		}
+		if h.cfg.MaxBatchEvents > 0 && len(events) >= h.cfg.MaxBatchEvents {
+			return fmt.Errorf("%w of %d", errBatchTooLarge, h.cfg.MaxBatchEvents)
+		}
+
+		fields, err := dec.DecodeMap()
+		if err != nil {
+			return decodeErr(err)
+		}
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return decodeErr(err)
+		}
+		var event domain.LogEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return decodeErr(err)
+		}
+		event.RawEvent = raw
+		events = append(events, event)
+	}
+
+	return h.ingestBatch(ctx, events)
+}
+
+// handleProtobuf accepts an ingest.v1.IngestRequest, the message used by the gRPC service.
+func (h *IngestHandler) handleProtobuf(ctx context.Context, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	var req ingestv1.IngestRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return &badRequestError{msg: "Failed to decode protobuf", err: err}
+	}
+	if h.cfg.MaxBatchEvents > 0 && len(req.GetEvents()) > h.cfg.MaxBatchEvents {
+		return fmt.Errorf("%w of %d", errBatchTooLarge, h.cfg.MaxBatchEvents)
+	}
+
+	events := make([]domain.LogEvent, len(req.GetEvents()))
+	for i, pb := range req.GetEvents() {
+		event, err := pb.ToDomain()
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return &badRequestError{msg: fmt.Sprintf("Invalid event %d", i), err: err}
+		}
+		events[i] = event
+	}
+
+	return h.ingestBatch(ctx, events)
+}
+
+// ingestBatch ingests decoded events, continuing past buffer failures like the JSON
+// array path. It only fails if no event could be buffered.
+func (h *IngestHandler) ingestBatch(ctx context.Context, events []domain.LogEvent) error {
+	var processedCount int
+	var lastErr error
+	for i := range events {
+		if err := h.useCase.Ingest(ctx, &events[i]); err != nil {
+			h.logger.Error("Failed to ingest event from batch", "error", err)
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			lastErr = err
+			continue
+		}
+		processedCount++
+	}
 
 	if processedCount > 0 {
 		h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(processedCount))
 		h.sseBroker.ReportEvents(processedCount)
+		return nil
 	}
-
-	return nil
+	return lastErr
 }
@@ -2,18 +2,34 @@ package handler
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ingestv1 "github.com/V4T54L/watch-tower/api/ingest/v1"
 
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
 	"github.com/V4T54L/watch-tower/internal/domain"
 )
 
+// testMetrics is registered with its own registry, shared by all handler tests.
+var testMetrics = metrics.NewIngestMetrics(prometheus.NewRegistry())
+
 // MockIngestUseCase is a mock implementation of the IngestLogUseCase.
 type MockIngestUseCase struct {
 	IngestFunc func(ctx context.Context, event *domain.LogEvent) error
@@ -28,7 +44,7 @@ func (m *MockIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent)
 
 func TestIngestHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	mockMetrics := metrics.NewIngestMetrics()
+	mockMetrics := testMetrics
 	mockSSEBroker := NewSSEBroker(context.Background(), logger)
 
 	tests := []struct {
@@ -62,15 +78,15 @@ func TestIngestHandler(t *testing.T) {
 			contentType:    "application/json",
 			body:           `{}`,
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method Not Allowed\n",
+			expectedBody:   `{"error":{"code":"method_not_allowed","message":"Method not allowed"}}` + "\n",
 		},
 		{
 			name:           "Unsupported Content-Type",
 			method:         http.MethodPost,
-			contentType:    "text/plain",
-			body:           `hello`,
+			contentType:    "application/xml",
+			body:           `<hello/>`,
 			expectedStatus: http.StatusUnsupportedMediaType,
-			expectedBody:   "Unsupported Media Type: text/plain\n",
+			expectedBody:   `{"error":{"code":"unsupported_media_type","message":"unsupported media type: application/xml"}}` + "\n",
 		},
 		{
 			name:           "Bad JSON",
@@ -78,7 +94,7 @@ func TestIngestHandler(t *testing.T) {
 			contentType:    "application/json",
 			body:           `{"message": "hello"`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad Request: Failed to decode JSON\n",
+			expectedBody:   `{"error":{"code":"invalid_request","message":"Failed to decode JSON"}}` + "\n",
 		},
 		{
 			name:           "Bad NDJSON line",
@@ -86,7 +102,7 @@ func TestIngestHandler(t *testing.T) {
 			contentType:    "application/x-ndjson",
 			body:           `{"message": "line 1"}` + "\n" + `{"message": "bad`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Bad Request: Failed to decode NDJSON line\n",
+			expectedBody:   `{"error":{"code":"invalid_request","message":"Failed to decode NDJSON line"}}` + "\n",
 		},
 		{
 			name:           "Ingest Use Case Error",
@@ -95,7 +111,34 @@ func TestIngestHandler(t *testing.T) {
 			body:           `{"message": "fail me"}`,
 			mockIngestErr:  errors.New("internal buffer error"),
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "Internal Server Error\n",
+			expectedBody:   `{"error":{"code":"internal","message":"Internal server error"}}` + "\n",
+		},
+		{
+			name:           "Buffer Full",
+			method:         http.MethodPost,
+			contentType:    "application/json",
+			body:           `{"message": "full"}`,
+			mockIngestErr:  fmt.Errorf("%w: WAL max total size exceeded", domain.ErrBufferFull),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"error":{"code":"buffer_full","message":"log buffer is full"}}` + "\n",
+		},
+		{
+			name:           "Buffer Full NDJSON",
+			method:         http.MethodPost,
+			contentType:    "application/x-ndjson",
+			body:           `{"message": "full 1"}` + "\n" + `{"message": "full 2"}`,
+			mockIngestErr:  fmt.Errorf("%w: WAL max total size exceeded", domain.ErrBufferFull),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"error":{"code":"buffer_full","message":"log buffer is full"}}` + "\n",
+		},
+		{
+			name:           "Buffer Full Plain Text",
+			method:         http.MethodPost,
+			contentType:    "text/plain",
+			body:           "full 1\nfull 2\n",
+			mockIngestErr:  fmt.Errorf("%w: WAL max total size exceeded", domain.ErrBufferFull),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"error":{"code":"buffer_full","message":"log buffer is full"}}` + "\n",
 		},
 		{
 			name:           "Payload Too Large",
@@ -103,7 +146,7 @@ func TestIngestHandler(t *testing.T) {
 			contentType:    "application/json",
 			body:           `{"message": "this payload is definitely too large for the test limit"}`,
 			expectedStatus: http.StatusRequestEntityTooLarge,
-			expectedBody:   "http: request body too large\n",
+			expectedBody:   `{"error":{"code":"payload_too_large","message":"http: request body too large"}}` + "\n",
 		},
 	}
 
@@ -120,7 +163,7 @@ func TestIngestHandler(t *testing.T) {
 				maxSize = 50
 			}
 
-			handler := NewIngestHandler(mockUseCase, logger, maxSize, mockMetrics, mockSSEBroker)
+			handler := NewIngestHandler(mockUseCase, logger, IngestHandlerConfig{MaxEventSize: maxSize}, mockMetrics, mockSSEBroker)
 
 			req := httptest.NewRequest(tt.method, "/ingest", bytes.NewBufferString(tt.body))
 			req.Header.Set("Content-Type", tt.contentType)
@@ -138,3 +181,239 @@ func TestIngestHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestIngestHandler_ContentEncoding(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockMetrics := testMetrics
+	mockSSEBroker := NewSSEBroker(context.Background(), logger)
+
+	gzipBody := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+	zstdBody := func(s string) []byte {
+		enc, _ := zstd.NewWriter(nil)
+		defer enc.Close()
+		return enc.EncodeAll([]byte(s), nil)
+	}
+
+	ndjson := `{"message": "line 1"}` + "\n" + `{"message": "line 2"}`
+
+	tests := []struct {
+		name            string
+		encoding        string
+		body            []byte
+		maxDecompressed int64
+		expectedStatus  int
+		expectedEvents  int
+	}{
+		{name: "Gzip NDJSON", encoding: "gzip", body: gzipBody(ndjson), expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Zstd NDJSON", encoding: "zstd", body: zstdBody(ndjson), expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Corrupt gzip", encoding: "gzip", body: []byte("not gzip"), expectedStatus: http.StatusBadRequest},
+		{name: "Unsupported encoding", encoding: "br", body: []byte(ndjson), expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Decompressed size limit", encoding: "gzip", body: gzipBody(strings.Repeat(" ", 4096) + ndjson), maxDecompressed: 1024, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ingested int
+			mockUseCase := &MockIngestUseCase{
+				IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+					ingested++
+					return nil
+				},
+			}
+			handler := NewIngestHandler(mockUseCase, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxDecompressedSize: tt.maxDecompressed}, mockMetrics, mockSSEBroker)
+
+			req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-ndjson")
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (body %q)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if ingested != tt.expectedEvents {
+				t.Errorf("expected %d ingested events, got %d", tt.expectedEvents, ingested)
+			}
+		})
+	}
+}
+
+func TestIngestHandler_JSONArray(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockSSEBroker := NewSSEBroker(context.Background(), logger)
+
+	tests := []struct {
+		name           string
+		body           string
+		ingestErr      error
+		expectedStatus int
+		expectedEvents int
+	}{
+		{name: "Array", body: ` [{"message": "a"}, {"message": "b"}, {"message": "c"}]`, expectedStatus: http.StatusAccepted, expectedEvents: 3},
+		{name: "Empty array", body: `[]`, expectedStatus: http.StatusAccepted},
+		{name: "Batch too large", body: `[{"message": "a"}, {"message": "b"}, {"message": "c"}, {"message": "d"}, {"message": "e"}]`, expectedStatus: http.StatusRequestEntityTooLarge, expectedEvents: 3},
+		{name: "Malformed element", body: `[{"message": "a"}, {"message": 1}]`, expectedStatus: http.StatusBadRequest, expectedEvents: 1},
+		{name: "Unterminated array", body: `[{"message": "a"}`, expectedStatus: http.StatusBadRequest, expectedEvents: 1},
+		{name: "All elements fail to buffer", body: `[{"message": "a"}]`, ingestErr: errors.New("buffer full"), expectedStatus: http.StatusInternalServerError},
+		{name: "Empty body", body: `  `, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ingested []domain.LogEvent
+			mockUseCase := &MockIngestUseCase{
+				IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+					if tt.ingestErr != nil {
+						return tt.ingestErr
+					}
+					ingested = append(ingested, *event)
+					return nil
+				},
+			}
+			handler := NewIngestHandler(mockUseCase, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxBatchEvents: 3}, testMetrics, mockSSEBroker)
+
+			req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (body %q)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if len(ingested) != tt.expectedEvents {
+				t.Fatalf("expected %d ingested events, got %d", tt.expectedEvents, len(ingested))
+			}
+			if len(ingested) > 0 && string(ingested[0].RawEvent) != `{"message": "a"}` {
+				t.Errorf("unexpected raw event %q", ingested[0].RawEvent)
+			}
+		})
+	}
+}
+
+func TestIngestHandler_PlainText(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockSSEBroker := NewSSEBroker(context.Background(), logger)
+
+	chain, err := textparser.NewChain([]textparser.Spec{{Type: "logfmt"}})
+	if err != nil {
+		t.Fatalf("NewChain failed: %v", err)
+	}
+
+	var ingested []domain.LogEvent
+	mockUseCase := &MockIngestUseCase{
+		IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+			ingested = append(ingested, *event)
+			return nil
+		},
+	}
+	handler := NewIngestHandler(mockUseCase, logger, IngestHandlerConfig{MaxEventSize: 1024, TextParser: chain}, testMetrics, mockSSEBroker)
+
+	body := "level=error msg=\"payment failed\" order=42\r\n\nplain line\n"
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	if len(ingested) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(ingested))
+	}
+	if ingested[0].Level != "error" || ingested[0].Message != "payment failed" || string(ingested[0].Metadata) != `{"order":"42"}` {
+		t.Errorf("unexpected parsed event: %+v", ingested[0])
+	}
+	if ingested[1].Message != "plain line" || string(ingested[1].RawEvent) != `"plain line"` {
+		t.Errorf("unexpected unparsed event: %+v", ingested[1])
+	}
+}
+
+func TestIngestHandler_BinaryContentTypes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockSSEBroker := NewSSEBroker(context.Background(), logger)
+
+	msgpackBody := func(vs ...interface{}) []byte {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		for _, v := range vs {
+			if err := enc.Encode(v); err != nil {
+				t.Fatalf("msgpack encode failed: %v", err)
+			}
+		}
+		return buf.Bytes()
+	}
+	protoBody := func(events ...*ingestv1.LogEvent) []byte {
+		b, err := proto.Marshal(&ingestv1.IngestRequest{Events: events})
+		if err != nil {
+			t.Fatalf("proto marshal failed: %v", err)
+		}
+		return b
+	}
+	eventTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := map[string]interface{}{"message": "a", "level": "info", "event_time": eventTime, "metadata": map[string]interface{}{"user": "bob"}}
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           []byte
+		expectedStatus int
+		expectedEvents int
+	}{
+		{name: "Msgpack map", contentType: "application/msgpack", body: msgpackBody(event), expectedStatus: http.StatusAccepted, expectedEvents: 1},
+		{name: "Msgpack array", contentType: "application/msgpack", body: msgpackBody([]interface{}{event, event, event}), expectedStatus: http.StatusAccepted, expectedEvents: 3},
+		{name: "Msgpack stream", contentType: "application/x-msgpack", body: msgpackBody(event, event), expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Msgpack batch too large", contentType: "application/msgpack", body: msgpackBody([]interface{}{event, event, event, event}), expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Msgpack not a map", contentType: "application/msgpack", body: msgpackBody("hello"), expectedStatus: http.StatusBadRequest},
+		{name: "Msgpack truncated", contentType: "application/msgpack", body: msgpackBody(event)[:10], expectedStatus: http.StatusBadRequest},
+		{
+			name:           "Protobuf",
+			contentType:    "application/x-protobuf",
+			body:           protoBody(&ingestv1.LogEvent{Message: "a", Level: "info", EventTime: timestamppb.New(eventTime), Metadata: []byte(`{"user":"bob"}`)}, &ingestv1.LogEvent{Message: "b"}),
+			expectedStatus: http.StatusAccepted,
+			expectedEvents: 2,
+		},
+		{name: "Protobuf bad metadata", contentType: "application/x-protobuf", body: protoBody(&ingestv1.LogEvent{Message: "a", Metadata: []byte(`[1]`)}), expectedStatus: http.StatusBadRequest},
+		{name: "Protobuf corrupt", contentType: "application/x-protobuf", body: []byte{0xff, 0xff}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ingested []domain.LogEvent
+			mockUseCase := &MockIngestUseCase{
+				IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+					ingested = append(ingested, *event)
+					return nil
+				},
+			}
+			handler := NewIngestHandler(mockUseCase, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxBatchEvents: 3}, testMetrics, mockSSEBroker)
+
+			req := httptest.NewRequest(http.MethodPost, "/ingest", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (body %q)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if len(ingested) != tt.expectedEvents {
+				t.Fatalf("expected %d ingested events, got %d", tt.expectedEvents, len(ingested))
+			}
+			if len(ingested) > 0 {
+				got := ingested[0]
+				if got.Message != "a" || got.Level != "info" || !got.EventTime.Equal(eventTime) || string(got.Metadata) != `{"user":"bob"}` {
+					t.Errorf("unexpected event: %+v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/syslog"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const (
+	contentTypeLogplex = "application/logplex-1"
+
+	logplexMsgCountHeader   = "Logplex-Msg-Count"
+	logplexFrameIDHeader    = "Logplex-Frame-Id"
+	logplexDrainTokenHeader = "Logplex-Drain-Token"
+)
+
+// LogplexHandler accepts Heroku Logplex HTTPS drain deliveries (POST /v1/logplex).
+// Each request carries Logplex-Msg-Count octet-counted RFC5424 syslog frames.
+type LogplexHandler struct {
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
+}
+
+// NewLogplexHandler creates a new LogplexHandler.
+func NewLogplexHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *LogplexHandler {
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = cfg.MaxEventSize
+	}
+	return &LogplexHandler{
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
+	}
+}
+
+// ServeHTTP parses every frame of the delivery before ingesting any of them, so that a
+// malformed delivery is rejected as a whole.
+func (h *LogplexHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Respond(w, apierror.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, contentTypeLogplex) {
+		h.metrics.EventsTotal.WithLabelValues("error_media_type").Inc()
+		apierror.Respond(w, apierror.CodeUnsupportedMediaType, "unsupported media type: "+contentType)
+		return
+	}
+
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxEventSize)
+
+	body, err := decodeBody(w, r, h.cfg.MaxDecompressedSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
+
+	events, err := h.parseFrames(r, body)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+
+	for i := range events {
+		if err := h.useCase.Ingest(r.Context(), &events[i]); err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			if i > 0 {
+				h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(i))
+				h.sseBroker.ReportEvents(i)
+			}
+			writeIngestError(w, err, h.logger, h.metrics)
+			return
+		}
+	}
+	h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(len(events)))
+	h.sseBroker.ReportEvents(len(events))
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// parseFrames reads the octet-counted syslog frames of a delivery and converts them to
+// LogEvents, checking the frame count against the Logplex-Msg-Count header when present.
+func (h *LogplexHandler) parseFrames(r *http.Request, body io.Reader) ([]domain.LogEvent, error) {
+	extra := map[string]interface{}{}
+	if token := r.Header.Get(logplexDrainTokenHeader); token != "" {
+		extra["logplex_drain_token"] = token
+	}
+	if frameID := r.Header.Get(logplexFrameIDHeader); frameID != "" {
+		extra["logplex_frame_id"] = frameID
+	}
+
+	now := time.Now()
+	br := bufio.NewReader(body)
+	var events []domain.LogEvent
+	for {
+		// Frames are usually newline-terminated in addition to being octet-counted.
+		b, err := br.Peek(1)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil && (b[0] == '\n' || b[0] == '\r' || b[0] == ' ') {
+			br.Discard(1)
+			continue
+		}
+
+		frame, err := syslog.ReadOctetCounted(br, int(h.cfg.MaxDecompressedSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, err
+			}
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: "Invalid Logplex frame", err: err}
+		}
+
+		msg, err := syslog.Parse(frame, now)
+		if err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{msg: "Invalid syslog message in Logplex frame", err: err}
+		}
+		event := msg.ToLogEvent()
+		if len(extra) > 0 {
+			metadata := map[string]interface{}{}
+			json.Unmarshal(event.Metadata, &metadata)
+			for k, v := range extra {
+				metadata[k] = v
+			}
+			event.Metadata, _ = json.Marshal(metadata)
+		}
+		event.RawEvent, _ = json.Marshal(string(frame))
+		events = append(events, event)
+	}
+
+	if countHeader := r.Header.Get(logplexMsgCountHeader); countHeader != "" {
+		count, err := strconv.Atoi(countHeader)
+		if err != nil || count != len(events) {
+			h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return nil, &badRequestError{
+				msg: "Logplex frame count mismatch",
+				err: fmt.Errorf("%s is %q but body has %d frames", logplexMsgCountHeader, countHeader, len(events)),
+			}
+		}
+	}
+	return events, nil
+}
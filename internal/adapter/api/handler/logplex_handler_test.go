@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestLogplexHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	frame := func(msg string) string { return fmt.Sprintf("%d %s", len(msg), msg) }
+	body := frame("<190>1 2012-11-30T06:45:29+00:00 host app web.3 - State changed from starting to up\n") +
+		frame("<158>1 2012-11-30T06:45:26.123456+00:00 host heroku router - at=info method=GET path=\"/\" status=200\n")
+
+	tests := []struct {
+		name           string
+		contentType    string
+		msgCount       string
+		body           string
+		expectedStatus int
+		expectedEvents int
+	}{
+		{name: "Valid drain", contentType: contentTypeLogplex, msgCount: "2", body: body, expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Without count header", contentType: contentTypeLogplex, body: body, expectedStatus: http.StatusAccepted, expectedEvents: 2},
+		{name: "Count mismatch", contentType: contentTypeLogplex, msgCount: "3", body: body, expectedStatus: http.StatusBadRequest},
+		{name: "Bad octet count", contentType: contentTypeLogplex, body: "abc <40>1 - - - - - hi", expectedStatus: http.StatusBadRequest},
+		{name: "Truncated frame", contentType: contentTypeLogplex, body: "500 <40>1 2012-11-30T06:45:29+00:00 host app web.3 - hi", expectedStatus: http.StatusBadRequest},
+		{name: "Wrong content type", contentType: contentTypeJSON, body: body, expectedStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []domain.LogEvent
+			uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+				events = append(events, *event)
+				return nil
+			}}
+			h := NewLogplexHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logplex", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set(logplexDrainTokenHeader, "d.8bf587e9-29d1-43c8-bd0e-36cdfaf35259")
+			if tt.msgCount != "" {
+				req.Header.Set(logplexMsgCountHeader, tt.msgCount)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if len(events) != tt.expectedEvents {
+				t.Fatalf("expected %d events, got %d", tt.expectedEvents, len(events))
+			}
+			if len(events) == 0 {
+				return
+			}
+
+			first := events[0]
+			if first.Source != "app" || first.Message != "State changed from starting to up" || first.Level != "info" {
+				t.Errorf("unexpected event: %+v", first)
+			}
+			var md map[string]interface{}
+			json.Unmarshal(first.Metadata, &md)
+			if md["proc_id"] != "web.3" || md["logplex_drain_token"] != "d.8bf587e9-29d1-43c8-bd0e-36cdfaf35259" {
+				t.Errorf("unexpected metadata: %s", first.Metadata)
+			}
+			if events[1].Source != "heroku" || events[1].EventTime.Nanosecond() != 123456000 {
+				t.Errorf("unexpected router event: %+v", events[1])
+			}
+		})
+	}
+}
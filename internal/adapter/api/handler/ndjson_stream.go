@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const (
+	// A progress message is sent after this many lines or this much time, whichever
+	// comes first; time is checked as lines arrive.
+	ndjsonProgressLines    = 1000
+	ndjsonProgressInterval = time.Second
+	// ndjsonStreamIdleTimeout replaces the server's read and write timeouts, which are
+	// sized for ordinary requests, every time progress is reported.
+	ndjsonStreamIdleTimeout = 30 * time.Second
+)
+
+// ndjsonLineError reports a rejected line of a streamed upload.
+type ndjsonLineError struct {
+	Line   int    `json:"line"`
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// ndjsonProgress is one line of the multi-status response body. Counters are cumulative;
+// Errors lists the lines rejected since the previous message. The decisions of the ingest
+// pipeline are reported here because the response header, where other uploads find them
+// (see middleware.DecisionsHeader), is sent before any event is ingested.
+type ndjsonProgress struct {
+	Type     string            `json:"type"` // "progress", "summary", or "error" if the upload was aborted
+	Lines    int               `json:"lines"`
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Kept     int               `json:"kept,omitempty"`
+	Sampled  int               `json:"sampled,omitempty"`
+	Dropped  int               `json:"dropped,omitempty"`
+	Errors   []ndjsonLineError `json:"errors,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// wantsNDJSONProgress reports whether the client asked for a streamed multi-status
+// response by accepting NDJSON.
+func wantsNDJSONProgress(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), contentTypeNDJSON)
+}
+
+// streamNDJSON ingests an NDJSON upload of up to MaxStreamSize bytes while answering with
+// 207 Multi-Status and an NDJSON body of periodic progress messages, so producers of very
+// large uploads learn which lines failed as they go. Unlike the buffered path, a malformed
+// line is rejected on its own instead of failing the request.
+func (h *IngestHandler) streamNDJSON(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// HTTP/1.x servers stop reading the request body once the response has started unless
+	// full duplex is enabled. HTTP/2 is always full duplex and reports ErrNotSupported.
+	_ = rc.EnableFullDuplex()
+	extendDeadlines := func() {
+		deadline := time.Now().Add(ndjsonStreamIdleTimeout)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+	}
+	extendDeadlines()
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxStreamSize)
+	body, err := decodeBody(w, r, h.cfg.MaxStreamSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+	w.WriteHeader(http.StatusMultiStatus)
+	enc := json.NewEncoder(w)
+
+	var total ndjsonProgress
+	var pending []ndjsonLineError
+	var sinceReport, acceptedSinceReport int
+	lastReport := time.Now()
+	report := func(typ, errMsg string) {
+		if acceptedSinceReport > 0 {
+			h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(acceptedSinceReport))
+			h.sseBroker.ReportEvents(acceptedSinceReport)
+		}
+		msg := total
+		msg.Type, msg.Errors, msg.Error = typ, pending, errMsg
+		if recorder := domain.DecisionRecorderFromContext(r.Context()); recorder != nil {
+			counts := recorder.Counts()
+			msg.Kept, msg.Sampled, msg.Dropped = counts[domain.DecisionKept], counts[domain.DecisionSampled], counts[domain.DecisionDropped]
+		}
+		if err := enc.Encode(msg); err != nil {
+			h.logger.Warn("Failed to write NDJSON progress", "error", err)
+		}
+		_ = rc.Flush()
+		pending, sinceReport, acceptedSinceReport = nil, 0, 0
+		lastReport = time.Now()
+		extendDeadlines()
+	}
+	reject := func(status int, metric string, err error) {
+		h.metrics.EventsTotal.WithLabelValues(metric).Inc()
+		total.Rejected++
+		pending = append(pending, ndjsonLineError{Line: total.Lines, Status: status, Error: err.Error()})
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, min(64*1024, int(h.cfg.MaxEventSize))), int(h.cfg.MaxEventSize))
+	for scanner.Scan() {
+		total.Lines++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		sinceReport++
+
+		var event domain.LogEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			reject(http.StatusBadRequest, "error_parse", err)
+		} else {
+			event.RawEvent = append([]byte(nil), line...)
+			if err := h.useCase.Ingest(r.Context(), &event); err != nil {
+				h.logger.Error("Failed to ingest event from NDJSON stream", "error", err)
+				if errors.Is(err, domain.ErrBufferFull) {
+					reject(http.StatusTooManyRequests, "error_buffer", domain.ErrBufferFull)
+				} else if errors.Is(err, domain.ErrQuotaExceeded) {
+					reject(http.StatusTooManyRequests, "error_quota", domain.ErrQuotaExceeded)
+				} else {
+					reject(http.StatusServiceUnavailable, "error_buffer", errors.New("failed to buffer event"))
+				}
+			} else {
+				total.Accepted++
+				acceptedSinceReport++
+			}
+		}
+
+		if sinceReport >= ndjsonProgressLines || time.Since(lastReport) >= ndjsonProgressInterval {
+			report("progress", "")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, bufio.ErrTooLong):
+			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
+			err = fmt.Errorf("line %d exceeds %d bytes", total.Lines+1, h.cfg.MaxEventSize)
+		case errors.As(err, &maxBytesErr):
+			h.metrics.EventsTotal.WithLabelValues("error_size").Inc()
+		}
+		h.logger.Warn("Aborted NDJSON stream", "error", err, "lines", total.Lines)
+		report("error", err.Error())
+		return
+	}
+	report("summary", "")
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/middleware"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func readProgress(t *testing.T, body io.Reader) []ndjsonProgress {
+	t.Helper()
+	var msgs []ndjsonProgress
+	dec := json.NewDecoder(body)
+	for {
+		var msg ndjsonProgress
+		if err := dec.Decode(&msg); err == io.EOF {
+			return msgs
+		} else if err != nil {
+			t.Fatalf("invalid progress message: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestIngestHandler_NDJSONStream(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	var body strings.Builder
+	for i := 0; i < 1500; i++ {
+		switch i {
+		case 10:
+			body.WriteString("not json\n")
+		case 20:
+			body.WriteString("\n")
+		case 1200:
+			body.WriteString(`{"message":"fail me"}` + "\n")
+		default:
+			fmt.Fprintf(&body, `{"message":"line %d"}`+"\n", i+1)
+		}
+	}
+
+	var ingested int
+	uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+		if event.Message == "fail me" {
+			return errors.New("buffer down")
+		}
+		ingested++
+		return nil
+	}}
+	h := NewIngestHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxStreamSize: 1 << 20}, testMetrics, sse)
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", contentTypeNDJSON)
+	req.Header.Set("Accept", contentTypeNDJSON)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	msgs := readProgress(t, rr.Body)
+	if len(msgs) != 2 || msgs[0].Type != "progress" || msgs[1].Type != "summary" {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+	first, summary := msgs[0], msgs[1]
+	if len(first.Errors) != 1 || first.Errors[0].Line != 11 || first.Errors[0].Status != http.StatusBadRequest {
+		t.Errorf("unexpected first progress errors: %+v", first.Errors)
+	}
+	if len(summary.Errors) != 1 || summary.Errors[0].Line != 1201 || summary.Errors[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("unexpected summary errors: %+v", summary.Errors)
+	}
+	if summary.Lines != 1500 || summary.Accepted != 1497 || summary.Rejected != 2 || ingested != 1497 {
+		t.Errorf("unexpected summary: %+v (ingested %d)", summary, ingested)
+	}
+}
+
+func TestIngestHandler_NDJSONStreamDecisions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+	uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+		decision := domain.DecisionKept
+		if event.Message == "noise" {
+			decision = domain.DecisionDropped
+		}
+		domain.DecisionRecorderFromContext(ctx).Record(decision)
+		return nil
+	}}
+	h := middleware.PipelineDecisions(NewIngestHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxStreamSize: 1 << 20}, testMetrics, sse))
+
+	body := `{"message":"ok"}` + "\n" + `{"message":"noise"}` + "\n" + `{"message":"ok"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeNDJSON)
+	req.Header.Set("Accept", contentTypeNDJSON)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	msgs := readProgress(t, rr.Body)
+	if len(msgs) != 1 || msgs[0].Kept != 2 || msgs[0].Dropped != 1 || msgs[0].Sampled != 0 {
+		t.Fatalf("expected the decisions in the summary, got %+v", msgs)
+	}
+}
+
+func TestIngestHandler_NDJSONStreamLineTooLong(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+	h := NewIngestHandler(&MockIngestUseCase{}, logger, IngestHandlerConfig{MaxEventSize: 64, MaxStreamSize: 1 << 20}, testMetrics, sse)
+
+	body := `{"message":"ok"}` + "\n" + `{"message":"` + strings.Repeat("x", 100) + `"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeNDJSON)
+	req.Header.Set("Accept", contentTypeNDJSON)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	msgs := readProgress(t, rr.Body)
+	if len(msgs) != 1 || msgs[0].Type != "error" || msgs[0].Accepted != 1 || !strings.Contains(msgs[0].Error, "line 2") {
+		t.Fatalf("unexpected messages: %+v", msgs)
+	}
+}
+
+// TestIngestHandler_NDJSONStreamIncremental checks that progress reaches the client while
+// it is still uploading, over a real HTTP/1.1 connection and through the logging middleware.
+func TestIngestHandler_NDJSONStreamIncremental(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+	h := NewIngestHandler(&MockIngestUseCase{}, logger, IngestHandlerConfig{MaxEventSize: 1024, MaxStreamSize: 1 << 20}, testMetrics, sse)
+	srv := httptest.NewServer(middleware.Logging(logger)(h))
+	defer srv.Close()
+
+	pr, pw := io.Pipe()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/ingest", pr)
+	req.Header.Set("Content-Type", contentTypeNDJSON)
+	req.Header.Set("Accept", contentTypeNDJSON)
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		done <- result{resp, err}
+	}()
+
+	for i := 0; i < ndjsonProgressLines; i++ {
+		fmt.Fprintf(pw, `{"message":"line %d"}`+"\n", i)
+	}
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response while the upload is still open")
+	}
+	if res.err != nil {
+		t.Fatalf("request failed: %v", res.err)
+	}
+	defer res.resp.Body.Close()
+	if res.resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d", res.resp.StatusCode)
+	}
+
+	reader := bufio.NewReader(res.resp.Body)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("failed to read progress: %v", err)
+	}
+	var progress ndjsonProgress
+	json.Unmarshal(line, &progress)
+	if progress.Type != "progress" || progress.Accepted != ndjsonProgressLines {
+		t.Fatalf("unexpected progress: %s", line)
+	}
+
+	fmt.Fprintln(pw, `{"message":"last"}`)
+	pw.Close()
+	msgs := readProgress(t, reader)
+	if len(msgs) != 1 || msgs[0].Type != "summary" || msgs[0].Accepted != ndjsonProgressLines+1 {
+		t.Fatalf("unexpected summary: %+v", msgs)
+	}
+}
@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const contentTypeProtobuf = "application/x-protobuf"
+
+// OTLPHandler implements the OTLP/HTTP logs endpoint (POST /v1/logs).
+// ExportLogsServiceRequest is wire-compatible with logsv1.LogsData, so requests are
+// decoded into LogsData without pulling in the collector service packages.
+type OTLPHandler struct {
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
+}
+
+// NewOTLPHandler creates a new OTLPHandler.
+func NewOTLPHandler(uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *OTLPHandler {
+	if cfg.MaxDecompressedSize <= 0 {
+		cfg.MaxDecompressedSize = cfg.MaxEventSize
+	}
+	return &OTLPHandler{
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
+	}
+}
+
+// ServeHTTP decodes an OTLP logs export request and ingests every log record.
+func (h *OTLPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Respond(w, apierror.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	isJSON := strings.HasPrefix(contentType, contentTypeJSON)
+	if !isJSON && !strings.HasPrefix(contentType, contentTypeProtobuf) {
+		h.metrics.EventsTotal.WithLabelValues("error_media_type").Inc()
+		apierror.Respond(w, apierror.CodeUnsupportedMediaType, "unsupported media type: "+contentType)
+		return
+	}
+
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxEventSize)
+
+	body, err := decodeBody(w, r, h.cfg.MaxDecompressedSize)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+
+	var logsData logsv1.LogsData
+	if isJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, &logsData)
+	} else {
+		err = proto.Unmarshal(data, &logsData)
+	}
+	if err != nil {
+		h.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		writeIngestError(w, &badRequestError{msg: "Failed to decode OTLP logs", err: err}, h.logger, h.metrics)
+		return
+	}
+
+	events := otlpToLogEvents(&logsData, isJSON)
+	var accepted, rejected int64
+	var lastErr error
+	for i := range events {
+		if err := h.useCase.Ingest(r.Context(), &events[i]); err != nil {
+			h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			rejected++
+			lastErr = err
+			continue
+		}
+		accepted++
+	}
+
+	if accepted > 0 {
+		h.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(accepted))
+		h.sseBroker.ReportEvents(int(accepted))
+	}
+	if accepted == 0 && rejected > 0 {
+		writeIngestError(w, lastErr, h.logger, h.metrics)
+		return
+	}
+
+	h.writeExportResponse(w, isJSON, rejected, lastErr)
+}
+
+// writeExportResponse writes an ExportLogsServiceResponse, reporting partial success
+// when some records could not be buffered.
+func (h *OTLPHandler) writeExportResponse(w http.ResponseWriter, isJSON bool, rejected int64, err error) {
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	if isJSON {
+		resp := map[string]interface{}{}
+		if rejected > 0 {
+			resp["partialSuccess"] = map[string]interface{}{
+				"rejectedLogRecords": rejected,
+				"errorMessage":       errMsg,
+			}
+		}
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	var resp []byte
+	if rejected > 0 {
+		// ExportLogsPartialSuccess { int64 rejected_log_records = 1; string error_message = 2; }
+		var partial []byte
+		partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+		partial = protowire.AppendVarint(partial, uint64(rejected))
+		partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+		partial = protowire.AppendString(partial, errMsg)
+		// ExportLogsServiceResponse { ExportLogsPartialSuccess partial_success = 1; }
+		resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+		resp = protowire.AppendBytes(resp, partial)
+	}
+	w.Header().Set("Content-Type", contentTypeProtobuf)
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp)
+}
+
+// otlpToLogEvents flattens OTLP resource/scope/log records into LogEvents.
+// Log record attributes become top-level metadata keys so they are subject to PII
+// redaction; resource and scope attributes are nested under "resource" and "scope".
+func otlpToLogEvents(data *logsv1.LogsData, fromJSON bool) []domain.LogEvent {
+	var events []domain.LogEvent
+	for _, rl := range data.GetResourceLogs() {
+		resourceAttrs := attributesToMap(rl.GetResource().GetAttributes())
+		source, _ := resourceAttrs["service.name"].(string)
+
+		for _, sl := range rl.GetScopeLogs() {
+			var scope map[string]interface{}
+			if s := sl.GetScope(); s != nil && (s.GetName() != "" || s.GetVersion() != "") {
+				scope = map[string]interface{}{"name": s.GetName(), "version": s.GetVersion()}
+				for k, v := range attributesToMap(s.GetAttributes()) {
+					scope[k] = v
+				}
+			}
+
+			for _, rec := range sl.GetLogRecords() {
+				metadata := attributesToMap(rec.GetAttributes())
+				if len(resourceAttrs) > 0 {
+					metadata["resource"] = resourceAttrs
+				}
+				if scope != nil {
+					metadata["scope"] = scope
+				}
+				if traceID := otlpID(rec.GetTraceId(), 16, fromJSON); traceID != "" {
+					metadata["trace_id"] = traceID
+				}
+				if spanID := otlpID(rec.GetSpanId(), 8, fromJSON); spanID != "" {
+					metadata["span_id"] = spanID
+				}
+				if rec.GetEventName() != "" {
+					metadata["event_name"] = rec.GetEventName()
+				}
+
+				event := domain.LogEvent{
+					EventTime: otlpTime(rec),
+					Source:    source,
+					Level:     otlpLevel(rec),
+					Message:   anyValueToString(rec.GetBody()),
+				}
+				if len(metadata) > 0 {
+					event.Metadata, _ = json.Marshal(metadata)
+				}
+				event.RawEvent, _ = protojson.Marshal(rec)
+				events = append(events, event)
+			}
+		}
+	}
+	return events
+}
+
+func otlpTime(rec *logsv1.LogRecord) time.Time {
+	ts := rec.GetTimeUnixNano()
+	if ts == 0 {
+		ts = rec.GetObservedTimeUnixNano()
+	}
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ts)).UTC()
+}
+
+// otlpLevel prefers the sender's severity text and falls back to the severity number ranges
+// defined by the OpenTelemetry log data model.
+func otlpLevel(rec *logsv1.LogRecord) string {
+	if text := rec.GetSeverityText(); text != "" {
+		return strings.ToLower(text)
+	}
+	switch n := rec.GetSeverityNumber(); {
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return "fatal"
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return "error"
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return "warn"
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return "info"
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_DEBUG:
+		return "debug"
+	case n >= logsv1.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return "trace"
+	default:
+		return ""
+	}
+}
+
+// otlpID renders a trace or span ID as lowercase hex. OTLP/JSON encodes IDs as hex strings
+// while protojson decodes bytes fields as base64, so IDs from JSON payloads arrive with the
+// wrong length; re-encoding them as base64 recovers the original hex string.
+func otlpID(id []byte, size int, fromJSON bool) string {
+	if len(id) == 0 {
+		return ""
+	}
+	if fromJSON && len(id) != size {
+		return strings.ToLower(base64.StdEncoding.EncodeToString(id))
+	}
+	return hex.EncodeToString(id)
+}
+
+func attributesToMap(attrs []*commonv1.KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		m[kv.GetKey()] = anyValueToInterface(kv.GetValue())
+	}
+	return m
+}
+
+func anyValueToInterface(v *commonv1.AnyValue) interface{} {
+	switch val := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return val.StringValue
+	case *commonv1.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonv1.AnyValue_IntValue:
+		return val.IntValue
+	case *commonv1.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonv1.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(val.BytesValue)
+	case *commonv1.AnyValue_ArrayValue:
+		values := val.ArrayValue.GetValues()
+		arr := make([]interface{}, len(values))
+		for i, item := range values {
+			arr[i] = anyValueToInterface(item)
+		}
+		return arr
+	case *commonv1.AnyValue_KvlistValue:
+		return attributesToMap(val.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
+
+func anyValueToString(v *commonv1.AnyValue) string {
+	if s, ok := v.GetValue().(*commonv1.AnyValue_StringValue); ok {
+		return s.StringValue
+	}
+	iv := anyValueToInterface(v)
+	if iv == nil {
+		return ""
+	}
+	b, err := json.Marshal(iv)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
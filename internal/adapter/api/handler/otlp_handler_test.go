@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestOTLPHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+
+	strVal := func(s string) *commonv1.AnyValue {
+		return &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: s}}
+	}
+	request := &logsv1.LogsData{
+		ResourceLogs: []*logsv1.ResourceLogs{{
+			Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{
+				{Key: "service.name", Value: strVal("checkout")},
+			}},
+			ScopeLogs: []*logsv1.ScopeLogs{{
+				LogRecords: []*logsv1.LogRecord{
+					{
+						TimeUnixNano:   1700000000000000000,
+						SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR2,
+						Body:           strVal("payment failed"),
+						Attributes:     []*commonv1.KeyValue{{Key: "order_id", Value: strVal("42")}},
+						TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+					},
+					{SeverityText: "WARN", Body: strVal("slow response")},
+				},
+			}},
+		}},
+	}
+
+	run := func(t *testing.T, contentType string, body []byte) ([]domain.LogEvent, *httptest.ResponseRecorder) {
+		var events []domain.LogEvent
+		uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+			events = append(events, *event)
+			return nil
+		}}
+		h := NewOTLPHandler(uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return events, rr
+	}
+
+	t.Run("Protobuf", func(t *testing.T) {
+		body, err := proto.Marshal(request)
+		if err != nil {
+			t.Fatalf("failed to marshal request: %v", err)
+		}
+		events, rr := run(t, "application/x-protobuf", body)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if len(events) != 2 {
+			t.Fatalf("expected 2 events, got %d", len(events))
+		}
+		if events[0].Source != "checkout" || events[0].Level != "error" || events[0].Message != "payment failed" {
+			t.Errorf("unexpected first event: %+v", events[0])
+		}
+		if events[0].EventTime.Unix() != 1700000000 {
+			t.Errorf("unexpected event time: %v", events[0].EventTime)
+		}
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(events[0].Metadata, &metadata); err != nil {
+			t.Fatalf("failed to unmarshal metadata: %v", err)
+		}
+		if metadata["order_id"] != "42" || metadata["trace_id"] != "5b8efff798038103d269b633813fc60c" {
+			t.Errorf("unexpected metadata: %v", metadata)
+		}
+		if events[1].Level != "warn" {
+			t.Errorf("expected severity text to map to warn, got %q", events[1].Level)
+		}
+	})
+
+	t.Run("JSON with hex IDs", func(t *testing.T) {
+		body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+			"scopeLogs":[{"logRecords":[{"timeUnixNano":"1700000000000000000","severityNumber":9,
+			"body":{"stringValue":"hello"},"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174"}]}]}]}`
+		events, rr := run(t, "application/json", []byte(body))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d (%s)", rr.Code, rr.Body.String())
+		}
+		if len(events) != 1 || events[0].Level != "info" || events[0].Source != "api" {
+			t.Fatalf("unexpected events: %+v", events)
+		}
+		var metadata map[string]interface{}
+		json.Unmarshal(events[0].Metadata, &metadata)
+		if metadata["trace_id"] != "5b8efff798038103d269b633813fc60c" || metadata["span_id"] != "eee19b7ec3c1b174" {
+			t.Errorf("unexpected trace context: %v", metadata)
+		}
+	})
+
+	t.Run("Invalid payload", func(t *testing.T) {
+		_, rr := run(t, "application/x-protobuf", []byte{0xff, 0xff, 0xff})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status 400, got %d", rr.Code)
+		}
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
 )
 
 // SSEMessage defines the structure of the message sent to the frontend.
@@ -38,7 +40,7 @@ func NewSSEBroker(ctx context.Context, logger *slog.Logger) *SSEBroker {
 func (b *SSEBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		apierror.Respond(w, apierror.CodeInternal, "Streaming unsupported!")
 		return
 	}
 
@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// Webhook signature schemes.
+const (
+	WebhookSchemeGitHub = "github"      // X-Hub-Signature-256: sha256=HMAC-SHA256(body)
+	WebhookSchemeStripe = "stripe"      // Stripe-Signature: t=TS,v1=HMAC-SHA256(TS.body)
+	WebhookSchemeHMAC   = "hmac-sha256" // Hex HMAC-SHA256(body) in a configurable header, optionally "sha256="-prefixed
+	WebhookSchemeToken  = "token"       // Shared secret sent verbatim in a configurable header, as GitLab does
+)
+
+// stripeSignatureTolerance is how old a Stripe signature timestamp may be, matching
+// Stripe's own libraries; it bounds replays of captured deliveries.
+const stripeSignatureTolerance = 5 * time.Minute
+
+// WebhookSource configures one provider accepted on /webhooks/{source}.
+type WebhookSource struct {
+	Name            string `json:"source"`
+	Scheme          string `json:"scheme"`
+	Secret          string `json:"secret"`
+	SignatureHeader string `json:"signature_header,omitempty"` // Required for hmac-sha256 and token.
+	EventHeader     string `json:"event_header,omitempty"`     // Header naming the event type, if the provider sends one.
+}
+
+// ParseWebhookSources parses the JSON array held in WEBHOOK_SOURCES. An empty string
+// configures no sources.
+func ParseWebhookSources(s string) ([]WebhookSource, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var sources []WebhookSource
+	if err := json.Unmarshal([]byte(s), &sources); err != nil {
+		return nil, fmt.Errorf("invalid webhook sources: %w", err)
+	}
+	seen := make(map[string]bool, len(sources))
+	for i, src := range sources {
+		switch {
+		case src.Name == "":
+			return nil, fmt.Errorf("webhook source %d: source name is required", i)
+		case seen[src.Name]:
+			return nil, fmt.Errorf("webhook source %q is configured twice", src.Name)
+		case src.Secret == "":
+			return nil, fmt.Errorf("webhook source %q: secret is required", src.Name)
+		}
+		switch src.Scheme {
+		case WebhookSchemeGitHub, WebhookSchemeStripe:
+		case WebhookSchemeHMAC, WebhookSchemeToken:
+			if src.SignatureHeader == "" {
+				return nil, fmt.Errorf("webhook source %q: signature_header is required for scheme %s", src.Name, src.Scheme)
+			}
+		default:
+			return nil, fmt.Errorf("webhook source %q: unknown scheme %q", src.Name, src.Scheme)
+		}
+		seen[src.Name] = true
+	}
+	return sources, nil
+}
+
+// errInvalidSignature is returned when a webhook delivery fails verification.
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookHandler turns provider webhooks into log events, making watch-tower an audit sink
+// for them. Providers cannot send an API key, so the signature is the only credential.
+type WebhookHandler struct {
+	sources   map[string]WebhookSource
+	useCase   usecase.IngestLogUseCase
+	logger    *slog.Logger
+	cfg       IngestHandlerConfig
+	metrics   *metrics.IngestMetrics
+	sseBroker *SSEBroker
+	now       func() time.Time
+}
+
+// NewWebhookHandler creates a new WebhookHandler.
+func NewWebhookHandler(sources []WebhookSource, uc usecase.IngestLogUseCase, logger *slog.Logger, cfg IngestHandlerConfig, m *metrics.IngestMetrics, sse *SSEBroker) *WebhookHandler {
+	bySource := make(map[string]WebhookSource, len(sources))
+	for _, src := range sources {
+		bySource[src.Name] = src
+	}
+	return &WebhookHandler{
+		sources:   bySource,
+		useCase:   uc,
+		logger:    logger,
+		cfg:       cfg,
+		metrics:   m,
+		sseBroker: sse,
+		now:       time.Now,
+	}
+}
+
+// ServeHTTP verifies and ingests a webhook delivery for the source named in the path.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	src, ok := h.sources[r.PathValue("source")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	r.Body = countingReadCloser{r.Body, h.metrics.BytesTotal}
+	r.Body = http.MaxBytesReader(w, r.Body, h.cfg.MaxEventSize)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+
+	if err := h.verify(src, r.Header, body); err != nil {
+		h.logger.Warn("Rejected webhook delivery", "source", src.Name, "error", err, "remote_addr", r.RemoteAddr)
+		apierror.Respond(w, apierror.CodeUnauthorized, errInvalidSignature.Error())
+		return
+	}
+
+	event := h.toLogEvent(src, r.Header, body)
+	if err := h.useCase.Ingest(r.Context(), &event); err != nil {
+		h.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+		writeIngestError(w, err, h.logger, h.metrics)
+		return
+	}
+	h.metrics.EventsTotal.WithLabelValues("accepted").Inc()
+	h.sseBroker.ReportEvents(1)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) verify(src WebhookSource, header http.Header, body []byte) error {
+	switch src.Scheme {
+	case WebhookSchemeGitHub:
+		sig, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return fmt.Errorf("%w: missing X-Hub-Signature-256", errInvalidSignature)
+		}
+		return checkHMAC(src.Secret, body, sig)
+	case WebhookSchemeStripe:
+		return h.verifyStripe(src.Secret, header.Get("Stripe-Signature"), body)
+	case WebhookSchemeHMAC:
+		sig := strings.TrimPrefix(header.Get(src.SignatureHeader), "sha256=")
+		return checkHMAC(src.Secret, body, sig)
+	case WebhookSchemeToken:
+		if subtle.ConstantTimeCompare([]byte(header.Get(src.SignatureHeader)), []byte(src.Secret)) != 1 {
+			return fmt.Errorf("%w: token mismatch", errInvalidSignature)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown scheme %q", errInvalidSignature, src.Scheme)
+}
+
+// verifyStripe checks a "t=TIMESTAMP,v1=SIG[,v1=SIG...]" header. Several v1 signatures
+// are sent while a secret is being rolled; any of them may match.
+func (h *WebhookHandler) verifyStripe(secret, header string, body []byte) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed Stripe-Signature", errInvalidSignature)
+	}
+	if age := h.now().Sub(time.Unix(unix, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", errInvalidSignature)
+	}
+
+	signed := append([]byte(ts+"."), body...)
+	for _, sig := range sigs {
+		if checkHMAC(secret, signed, sig) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no matching v1 signature", errInvalidSignature)
+}
+
+func checkHMAC(secret string, data []byte, hexSig string) error {
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return fmt.Errorf("%w: signature is not hex", errInvalidSignature)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return fmt.Errorf("%w: digest mismatch", errInvalidSignature)
+	}
+	return nil
+}
+
+// toLogEvent wraps the delivery in an event whose message names the event type. The
+// payload is kept in metadata, as JSON when it is JSON and as a string otherwise.
+func (h *WebhookHandler) toLogEvent(src WebhookSource, header http.Header, body []byte) domain.LogEvent {
+	metadata := map[string]interface{}{"webhook_source": src.Name}
+	raw := json.RawMessage(body)
+	var payload map[string]interface{}
+	if json.Unmarshal(body, &payload) == nil {
+		metadata["payload"] = payload
+	} else {
+		metadata["payload"] = string(body)
+		raw, _ = json.Marshal(string(body))
+	}
+
+	eventTime := h.now().UTC()
+	var eventType string
+	switch src.Scheme {
+	case WebhookSchemeGitHub:
+		eventType = header.Get("X-GitHub-Event")
+		if delivery := header.Get("X-GitHub-Delivery"); delivery != "" {
+			metadata["webhook_delivery_id"] = delivery
+		}
+	case WebhookSchemeStripe:
+		// Stripe events carry their type, ID and creation time in the body.
+		eventType, _ = payload["type"].(string)
+		if id, ok := payload["id"].(string); ok {
+			metadata["webhook_delivery_id"] = id
+		}
+		if created, ok := payload["created"].(float64); ok {
+			eventTime = time.Unix(int64(created), 0).UTC()
+		}
+	}
+	if src.EventHeader != "" {
+		eventType = header.Get(src.EventHeader)
+	}
+
+	message := src.Name + " webhook"
+	if eventType != "" {
+		metadata["webhook_event"] = eventType
+		message = src.Name + " webhook: " + eventType
+	}
+
+	md, _ := json.Marshal(metadata)
+	return domain.LogEvent{
+		EventTime: eventTime,
+		Source:    src.Name,
+		Level:     "info",
+		Message:   message,
+		Metadata:  md,
+		RawEvent:  raw,
+	}
+}
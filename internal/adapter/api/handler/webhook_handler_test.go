@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+func TestParseWebhookSources(t *testing.T) {
+	sources, err := ParseWebhookSources(`[{"source":"github","scheme":"github","secret":"s"},{"source":"gitlab","scheme":"token","secret":"t","signature_header":"X-Gitlab-Token"}]`)
+	if err != nil || len(sources) != 2 {
+		t.Fatalf("unexpected result: %v, %v", sources, err)
+	}
+	if sources, err := ParseWebhookSources(""); err != nil || sources != nil {
+		t.Errorf("expected no sources for empty config, got %v, %v", sources, err)
+	}
+
+	for _, invalid := range []string{
+		`{`,
+		`[{"scheme":"github","secret":"s"}]`,
+		`[{"source":"a","scheme":"github"}]`,
+		`[{"source":"a","scheme":"rot13","secret":"s"}]`,
+		`[{"source":"a","scheme":"hmac-sha256","secret":"s"}]`,
+		`[{"source":"a","scheme":"github","secret":"s"},{"source":"a","scheme":"stripe","secret":"s"}]`,
+	} {
+		if _, err := ParseWebhookSources(invalid); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sse := NewSSEBroker(context.Background(), logger)
+	now := time.Unix(1700000000, 0)
+
+	sign := func(secret, data string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(data))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sources := []WebhookSource{
+		{Name: "github", Scheme: WebhookSchemeGitHub, Secret: "gh-secret"},
+		{Name: "stripe", Scheme: WebhookSchemeStripe, Secret: "whsec_test"},
+		{Name: "ci", Scheme: WebhookSchemeHMAC, Secret: "ci-secret", SignatureHeader: "X-Signature", EventHeader: "X-Event"},
+		{Name: "gitlab", Scheme: WebhookSchemeToken, Secret: "gl-token", SignatureHeader: "X-Gitlab-Token", EventHeader: "X-Gitlab-Event"},
+	}
+
+	githubBody := `{"ref":"refs/heads/main","pusher":{"name":"octocat"}}`
+	stripeBody := `{"id":"evt_1","type":"invoice.paid","created":1699999990}`
+	stripeTS := fmt.Sprint(now.Unix())
+
+	tests := []struct {
+		name            string
+		source          string
+		body            string
+		headers         map[string]string
+		expectedStatus  int
+		expectedMessage string
+	}{
+		{
+			name:   "GitHub",
+			source: "github",
+			body:   githubBody,
+			headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=" + sign("gh-secret", githubBody),
+				"X-GitHub-Event":      "push",
+				"X-GitHub-Delivery":   "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+			},
+			expectedStatus:  http.StatusAccepted,
+			expectedMessage: "github webhook: push",
+		},
+		{name: "GitHub wrong secret", source: "github", body: githubBody, headers: map[string]string{"X-Hub-Signature-256": "sha256=" + sign("other", githubBody)}, expectedStatus: http.StatusUnauthorized},
+		{name: "GitHub missing signature", source: "github", body: githubBody, expectedStatus: http.StatusUnauthorized},
+		{
+			name:            "Stripe with rolled secret",
+			source:          "stripe",
+			body:            stripeBody,
+			headers:         map[string]string{"Stripe-Signature": "t=" + stripeTS + ",v1=" + sign("old", stripeTS+"."+stripeBody) + ",v1=" + sign("whsec_test", stripeTS+"."+stripeBody)},
+			expectedStatus:  http.StatusAccepted,
+			expectedMessage: "stripe webhook: invoice.paid",
+		},
+		{
+			name:           "Stripe replayed",
+			source:         "stripe",
+			body:           stripeBody,
+			headers:        map[string]string{"Stripe-Signature": "t=1699990000,v1=" + sign("whsec_test", "1699990000."+stripeBody)},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:            "Generic HMAC with form body",
+			source:          "ci",
+			body:            "build=42&status=ok",
+			headers:         map[string]string{"X-Signature": sign("ci-secret", "build=42&status=ok"), "X-Event": "build"},
+			expectedStatus:  http.StatusAccepted,
+			expectedMessage: "ci webhook: build",
+		},
+		{
+			name:            "Token",
+			source:          "gitlab",
+			body:            `{"object_kind":"push"}`,
+			headers:         map[string]string{"X-Gitlab-Token": "gl-token", "X-Gitlab-Event": "Push Hook"},
+			expectedStatus:  http.StatusAccepted,
+			expectedMessage: "gitlab webhook: Push Hook",
+		},
+		{name: "Wrong token", source: "gitlab", body: `{}`, headers: map[string]string{"X-Gitlab-Token": "nope"}, expectedStatus: http.StatusUnauthorized},
+		{name: "Unknown source", source: "bitbucket", body: `{}`, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []domain.LogEvent
+			uc := &MockIngestUseCase{IngestFunc: func(ctx context.Context, event *domain.LogEvent) error {
+				events = append(events, *event)
+				return nil
+			}}
+			h := NewWebhookHandler(sources, uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, sse)
+			h.now = func() time.Time { return now }
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/"+tt.source, strings.NewReader(tt.body))
+			req.SetPathValue("source", tt.source)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusAccepted {
+				if len(events) != 0 {
+					t.Errorf("expected no events, got %d", len(events))
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(events))
+			}
+			event := events[0]
+			if event.Source != tt.source || event.Message != tt.expectedMessage {
+				t.Errorf("unexpected event: %+v", event)
+			}
+			var md map[string]interface{}
+			if err := json.Unmarshal(event.Metadata, &md); err != nil || md["webhook_source"] != tt.source || md["payload"] == nil {
+				t.Errorf("unexpected metadata: %s", event.Metadata)
+			}
+			if !json.Valid(event.RawEvent) {
+				t.Errorf("raw event is not valid JSON: %s", event.RawEvent)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_RedactsPayload(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &mocks.MockLogRepository{}
+	uc := usecase.NewIngestLogUseCase(repo, pii.NewRedactor([]string{"email"}, logger), logger)
+	h := NewWebhookHandler([]WebhookSource{{Name: "github", Scheme: WebhookSchemeGitHub, Secret: "gh-secret"}}, uc, logger, IngestHandlerConfig{MaxEventSize: 1 << 20}, testMetrics, NewSSEBroker(context.Background(), logger))
+
+	body := `{"ref":"refs/heads/main","pusher":{"name":"octocat","email":"octocat@github.com"}}`
+	mac := hmac.New(sha256.New, []byte("gh-secret"))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+	req.SetPathValue("source", "github")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("X-GitHub-Event", "push")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d (%s)", http.StatusAccepted, rr.Code, rr.Body.String())
+	}
+	if len(repo.BufferedEvents) != 1 {
+		t.Fatalf("expected 1 buffered event, got %d", len(repo.BufferedEvents))
+	}
+	event := repo.BufferedEvents[0]
+	if !event.PIIRedacted {
+		t.Error("expected the event to be marked as redacted")
+	}
+	for name, data := range map[string][]byte{"metadata": event.Metadata, "raw event": event.RawEvent} {
+		if strings.Contains(string(data), "octocat@github.com") || !strings.Contains(string(data), pii.RedactedPlaceholder) {
+			t.Errorf("expected the email to be redacted from the %s, got %s", name, data)
+		}
+	}
+}
@@ -4,37 +4,73 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
 	"github.com/V4T54L/watch-tower/internal/domain"
 )
 
 const APIKeyHeader = "X-API-Key"
 
+const firehoseAccessKeyHeader = "X-Amz-Firehose-Access-Key"
+
 // Auth is a middleware factory that returns a new authentication middleware.
-// It checks for a valid API key in the X-API-Key header.
+// It checks for a valid API key in the X-API-Key header, and passes the key's digest on
+// in the request context (see domain.APIKeyHashFromContext). Requests ClientCert already
+// authenticated are let through.
 func Auth(repo domain.APIKeyRepository, logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if domain.APIKeyHashFromContext(r.Context()) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			apiKey := r.Header.Get(APIKeyHeader)
 			if apiKey == "" {
 				logger.Warn("API key missing from request", "remote_addr", r.RemoteAddr)
-				http.Error(w, "Unauthorized: API key required", http.StatusUnauthorized)
+				apierror.Respond(w, apierror.CodeUnauthorized, "API key required")
 				return
 			}
 
 			isValid, err := repo.IsValid(r.Context(), apiKey)
 			if err != nil {
 				logger.Error("failed to validate API key", "error", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				apierror.Respond(w, apierror.CodeInternal, "Internal server error")
 				return
 			}
 
 			if !isValid {
 				logger.Warn("invalid API key provided", "remote_addr", r.RemoteAddr)
-				http.Error(w, "Unauthorized: Invalid API key", http.StatusUnauthorized)
+				apierror.Respond(w, apierror.CodeUnauthorized, "invalid API key")
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(domain.WithAPIKeyHash(r.Context(), domain.HashAPIKey(apiKey))))
 		})
 	}
 }
+
+// FirehoseAccessKey lets Firehose HTTP endpoint deliveries authenticate with their access key
+// header, which Firehose sends instead of X-API-Key.
+func FirehoseAccessKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIKeyHeader) == "" {
+			if key := r.Header.Get(firehoseAccessKeyHeader); key != "" {
+				r.Header.Set(APIKeyHeader, key)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BasicAuthKey lets senders that can only embed credentials in a URL, such as Heroku log
+// drains (https://:KEY@host/v1/logplex), authenticate with the API key as the basic auth password.
+func BasicAuthKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIKeyHeader) == "" {
+			if _, key, ok := r.BasicAuth(); ok && key != "" {
+				r.Header.Set(APIKeyHeader, key)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
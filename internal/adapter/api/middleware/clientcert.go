@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// ClientCertIdentity maps the client certificates it matches to the API key their
+// requests authenticate as. A certificate matches when it has the subject alternative
+// name and the subject organizational unit that are set.
+type ClientCertIdentity struct {
+	SAN     string `json:"san,omitempty"` // DNS name, email address, IP address or URI.
+	OU      string `json:"ou,omitempty"`
+	KeyHash string `json:"key_hash"` // SHA-256 digest of the key, as stored in api_keys.key_hash.
+}
+
+// ParseClientCertIdentities parses the JSON array held in INGEST_TLS_CLIENT_IDENTITIES.
+// An empty string configures no identities.
+func ParseClientCertIdentities(s string) ([]ClientCertIdentity, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var identities []ClientCertIdentity
+	if err := json.Unmarshal([]byte(s), &identities); err != nil {
+		return nil, fmt.Errorf("invalid client certificate identities: %w", err)
+	}
+	for i, id := range identities {
+		switch {
+		case id.SAN == "" && id.OU == "":
+			return nil, fmt.Errorf("client certificate identity %d: san or ou is required", i)
+		case !validKeyHash(id.KeyHash):
+			return nil, fmt.Errorf("client certificate identity %d: key_hash must be a hex-encoded SHA-256 digest", i)
+		}
+		identities[i].KeyHash = strings.ToLower(id.KeyHash)
+	}
+	return identities, nil
+}
+
+func (id ClientCertIdentity) matches(cert *x509.Certificate) bool {
+	if id.OU != "" && !slices.Contains(cert.Subject.OrganizationalUnit, id.OU) {
+		return false
+	}
+	if id.SAN == "" {
+		return true
+	}
+	if slices.Contains(cert.DNSNames, id.SAN) || slices.Contains(cert.EmailAddresses, id.SAN) {
+		return true
+	}
+	for _, ip := range cert.IPAddresses {
+		if ip.String() == id.SAN {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == id.SAN {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyHashValidator validates API keys by their digest.
+type APIKeyHashValidator interface {
+	IsValidHash(ctx context.Context, keyHash string) (bool, error)
+}
+
+// ClientCert is a middleware factory that authenticates requests by their verified TLS
+// client certificate, as an alternative to an API key for agents that hold one. A
+// certificate matching an identity authenticates as that identity's API key, which must
+// be valid like any other, and Auth lets the request through. Requests without a
+// matching certificate are left to Auth.
+func ClientCert(identities []ClientCertIdentity, repo APIKeyHashValidator, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cert := r.TLS.VerifiedChains[0][0]
+			i := slices.IndexFunc(identities, func(id ClientCertIdentity) bool { return id.matches(cert) })
+			if i < 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keyHash := identities[i].KeyHash
+			isValid, err := repo.IsValidHash(r.Context(), keyHash)
+			if err != nil {
+				logger.Error("failed to validate API key of client certificate", "error", err)
+				apierror.Respond(w, apierror.CodeInternal, "Internal server error")
+				return
+			}
+			if !isValid {
+				logger.Warn("client certificate maps to an invalid API key", "subject", cert.Subject.String(), "remote_addr", r.RemoteAddr)
+				apierror.Respond(w, apierror.CodeUnauthorized, "invalid API key")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithAPIKeyHash(r.Context(), keyHash)))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeHashValidator map[string]bool
+
+func (f fakeHashValidator) IsValidHash(ctx context.Context, keyHash string) (bool, error) {
+	return f[keyHash], nil
+}
+
+func TestClientCert(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	agentKey, revokedKey := domain.HashAPIKey("agent"), domain.HashAPIKey("revoked")
+	identities, err := ParseClientCertIdentities(`[
+		{"san": "agent.example.com", "ou": "payments", "key_hash": "` + agentKey + `"},
+		{"ou": "legacy", "key_hash": "` + revokedKey + `"}
+	]`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var gotKeyHash string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKeyHash = domain.APIKeyHashFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+	h := ClientCert(identities, fakeHashValidator{agentKey: true}, logger)(next)
+
+	for _, tc := range []struct {
+		name        string
+		cert        *x509.Certificate
+		wantStatus  int
+		wantKeyHash string
+	}{
+		{name: "no certificate", wantStatus: http.StatusAccepted},
+		{
+			name:        "matching SAN and OU",
+			cert:        &x509.Certificate{DNSNames: []string{"agent.example.com"}, Subject: pkix.Name{OrganizationalUnit: []string{"payments"}}},
+			wantStatus:  http.StatusAccepted,
+			wantKeyHash: agentKey,
+		},
+		{
+			name:       "SAN without OU falls back to the API key",
+			cert:       &x509.Certificate{DNSNames: []string{"agent.example.com"}},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "revoked key",
+			cert:       &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: []string{"legacy"}}},
+			wantStatus: http.StatusUnauthorized,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gotKeyHash = ""
+			req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+			if tc.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if gotKeyHash != tc.wantKeyHash {
+				t.Errorf("expected key hash %q, got %q", tc.wantKeyHash, gotKeyHash)
+			}
+		})
+	}
+}
+
+func TestParseClientCertIdentities(t *testing.T) {
+	for _, s := range []string{
+		`[{"key_hash": "` + domain.HashAPIKey("k") + `"}]`,
+		`[{"san": "agent.example.com", "key_hash": "abc"}]`,
+		`{}`,
+	} {
+		if _, err := ParseClientCertIdentities(s); err == nil {
+			t.Errorf("expected an error for %s", s)
+		}
+	}
+}
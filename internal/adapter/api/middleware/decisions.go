@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// DecisionsHeader echoes how many of the events of a request the ingest pipeline kept,
+// sampled and dropped, e.g. "kept=8, sampled=2". Streamed NDJSON uploads send their
+// header before ingesting, so they report the decisions in their progress messages.
+const DecisionsHeader = "X-Pipeline-Decisions"
+
+// PipelineDecisions is a middleware that records the decisions the ingest pipeline takes
+// on the events of every request, see domain.WithDecisionRecorder, and echoes them in the
+// DecisionsHeader of the response. Like RequestInfo, it must wrap the Metrics middleware.
+func PipelineDecisions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &domain.DecisionRecorder{}
+		dw := &decisionWriter{ResponseWriter: w, recorder: recorder}
+		next.ServeHTTP(dw, r.WithContext(domain.WithDecisionRecorder(r.Context(), recorder)))
+	})
+}
+
+// decisionWriter sets the DecisionsHeader just before the response header is written,
+// once the events of the request have been ingested.
+type decisionWriter struct {
+	http.ResponseWriter
+	recorder    *domain.DecisionRecorder
+	wroteHeader bool
+}
+
+func (w *decisionWriter) setHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	counts := w.recorder.Counts()
+	var decisions []string
+	for _, action := range []string{domain.DecisionKept, domain.DecisionSampled, domain.DecisionDropped} {
+		if n := counts[action]; n > 0 {
+			decisions = append(decisions, fmt.Sprintf("%s=%d", action, n))
+		}
+	}
+	if len(decisions) > 0 {
+		w.Header().Set(DecisionsHeader, strings.Join(decisions, ", "))
+	}
+}
+
+func (w *decisionWriter) WriteHeader(code int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *decisionWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *decisionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush lets handlers that assert http.Flusher, such as the SSE broker, stream through
+// the wrapper.
+func (w *decisionWriter) Flush() {
+	w.setHeader()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestPipelineDecisions(t *testing.T) {
+	handler := PipelineDecisions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if recorder := domain.DecisionRecorderFromContext(r.Context()); recorder != nil && r.URL.Path == "/ingest" {
+			for _, action := range []string{domain.DecisionKept, domain.DecisionKept, domain.DecisionDropped} {
+				recorder.Record(action)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/ingest", nil))
+	if got, want := rr.Header().Get(DecisionsHeader), "kept=2, dropped=1"; got != want {
+		t.Errorf("expected %s %q, got %q", DecisionsHeader, want, got)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if got := rr.Header().Get(DecisionsHeader); got != "" {
+		t.Errorf("expected no %s without ingested events, got %q", DecisionsHeader, got)
+	}
+}
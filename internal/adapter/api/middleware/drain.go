@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+)
+
+// drainRetryAfter is the Retry-After, in seconds, sent while draining; by then the load
+// balancer should route the retry to another instance.
+const drainRetryAfter = "5"
+
+// Drain is a middleware factory that rejects every request with 503 Service Unavailable
+// once draining reports true, including health checks, so load balancers take the
+// instance out of rotation.
+func Drain(draining func() bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if draining() {
+				w.Header().Set("Retry-After", drainRetryAfter)
+				apierror.Respond(w, apierror.CodeDraining, "instance is draining")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+)
+
+// Metrics is a middleware factory that records the duration of the requests a
+// http.ServeMux handles, by the pattern of the route they matched, so the label stays
+// bounded, and counts error responses by their apierror code. It must wrap the mux
+// itself, which sets the pattern on the request.
+func Metrics(m *metrics.IngestMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			m.RequestDuration.WithLabelValues(route, strconv.Itoa(rw.statusCode)).Observe(time.Since(start).Seconds())
+			if code := rw.Header().Get(apierror.CodeHeader); code != "" {
+				m.ErrorsTotal.WithLabelValues(code).Inc()
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.NewIngestMetrics(prometheus.NewRegistry())
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/{source}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("source") == "unknown" {
+			apierror.Respond(w, apierror.CodeNotFound, "unknown webhook source")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	h := Metrics(m)(mux)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks/github", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks/stripe", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhooks/unknown", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	if got := testutil.CollectAndCount(m.RequestDuration); got != 3 {
+		t.Errorf("expected a series per status of the route and one for unmatched requests, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.ErrorsTotal.WithLabelValues(apierror.CodeNotFound)); got != 1 {
+		t.Errorf("expected 1 not_found error counted, got %v", got)
+	}
+	var metric dto.Metric
+	if err := m.RequestDuration.WithLabelValues("POST /webhooks/{source}", "202").(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := metric.GetHistogram().GetSampleCount(); got != 2 {
+		t.Errorf("expected both webhooks observed under the route pattern, got %d", got)
+	}
+}
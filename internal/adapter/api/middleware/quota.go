@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// QuotaWarningHeader is set on responses to API keys over their soft daily quota.
+const QuotaWarningHeader = "X-Quota-Warning"
+
+// QuotaChecker reports how far an API key is into its daily quotas.
+type QuotaChecker interface {
+	// Quota returns one of the domain.Quota levels for the key's digest.
+	Quota(keyHash string) string
+}
+
+// Quota is a middleware factory that enforces daily quotas on requests authenticated by
+// Auth. Keys over their hard quota are rejected with 429 and a Retry-After of the next UTC
+// day; keys over their soft quota get a warning header. Requests are counted per level in
+// m, which may be nil.
+func Quota(checker QuotaChecker, m *metrics.IngestMetrics, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyHash := domain.APIKeyHashFromContext(r.Context())
+			if keyHash == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			level := checker.Quota(keyHash)
+			if level != domain.QuotaOK && m != nil {
+				m.QuotaExceededTotal.WithLabelValues(level).Inc()
+			}
+			switch level {
+			case domain.QuotaHard:
+				now := time.Now().UTC()
+				tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+				retryAfter := ceilSeconds(tomorrow.Sub(now))
+				w.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+				logger.Warn("daily quota exceeded", "key_hash", keyHash[:16], "remote_addr", r.RemoteAddr)
+				apierror.Write(w, &apierror.Error{
+					Code:    apierror.CodeQuotaExceeded,
+					Message: domain.ErrQuotaExceeded.Error(),
+					Details: map[string]any{"retry_after_seconds": retryAfter},
+				})
+				return
+			case domain.QuotaSoft:
+				w.Header().Set(QuotaWarningHeader, "soft daily ingestion quota exceeded")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeQuotaChecker map[string]string
+
+func (f fakeQuotaChecker) Quota(keyHash string) string { return f[keyHash] }
+
+func TestQuota(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	checker := fakeQuotaChecker{domain.HashAPIKey("soft"): domain.QuotaSoft, domain.HashAPIKey("hard"): domain.QuotaHard}
+	h := Quota(checker, nil, logger)(next)
+
+	for _, tc := range []struct {
+		key         string
+		wantStatus  int
+		wantWarning bool
+	}{
+		{key: "ok", wantStatus: http.StatusAccepted},
+		{key: "soft", wantStatus: http.StatusAccepted, wantWarning: true},
+		{key: "hard", wantStatus: http.StatusTooManyRequests},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+		req = req.WithContext(domain.WithAPIKeyHash(context.Background(), domain.HashAPIKey(tc.key)))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != tc.wantStatus {
+			t.Errorf("key %s: expected status %d, got %d", tc.key, tc.wantStatus, rr.Code)
+		}
+		if got := rr.Header().Get(QuotaWarningHeader) != ""; got != tc.wantWarning {
+			t.Errorf("key %s: expected warning %t, got %t", tc.key, tc.wantWarning, got)
+		}
+		if tc.wantStatus == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+			t.Errorf("key %s: expected a Retry-After header", tc.key)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/api/apierror"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// RateLimitTier describes one level of rate limiting (e.g. global or per API key).
+// KeyFunc returns the bucket identifier for a request; an empty string skips the tier.
+type RateLimitTier struct {
+	Name    string
+	Rate    float64 // Tokens refilled per second.
+	Burst   int64   // Bucket capacity.
+	KeyFunc func(r *http.Request) string
+	// Limits overrides Rate and Burst for the bucket identifiers it holds.
+	Limits map[string]BucketLimit
+}
+
+// BucketLimit is the rate and burst of a bucket.
+type BucketLimit struct {
+	Rate  float64
+	Burst int64
+}
+
+// KeyRateLimit overrides the per-key rate and burst for one API key, identified by its
+// SHA-256 digest as stored in api_keys.key_hash so that the key itself stays out of the
+// configuration.
+type KeyRateLimit struct {
+	KeyHash string  `json:"key_hash"`
+	Rate    float64 `json:"rate"`  // Requests/sec, 0 leaves the key unlimited.
+	Burst   int64   `json:"burst"` // Bucket capacity.
+}
+
+// ParseKeyRateLimits parses the JSON array held in RATE_LIMIT_KEY_LIMITS. An empty string
+// configures no overrides.
+func ParseKeyRateLimits(s string) ([]KeyRateLimit, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var limits []KeyRateLimit
+	if err := json.Unmarshal([]byte(s), &limits); err != nil {
+		return nil, fmt.Errorf("invalid key rate limits: %w", err)
+	}
+	seen := make(map[string]bool, len(limits))
+	for i, l := range limits {
+		l.KeyHash = strings.ToLower(l.KeyHash)
+		switch {
+		case !validKeyHash(l.KeyHash):
+			return nil, fmt.Errorf("key rate limit %d: key_hash must be a hex-encoded SHA-256 digest", i)
+		case seen[l.KeyHash]:
+			return nil, fmt.Errorf("key rate limit %d: key_hash %s is configured twice", i, l.KeyHash)
+		case l.Rate < 0 || l.Burst < 0:
+			return nil, fmt.Errorf("key rate limit %d: rate and burst must not be negative", i)
+		}
+		seen[l.KeyHash] = true
+		limits[i] = l
+	}
+	return limits, nil
+}
+
+// validKeyHash reports whether s is a hex-encoded SHA-256 digest, as API keys are
+// identified by in configuration.
+func validKeyHash(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && len(s) == sha256.Size*2
+}
+
+// GlobalTier returns a tier shared by every request across all replicas.
+func GlobalTier(rate float64, burst int64) RateLimitTier {
+	return RateLimitTier{
+		Name:    "global",
+		Rate:    rate,
+		Burst:   burst,
+		KeyFunc: func(r *http.Request) string { return "all" },
+	}
+}
+
+// APIKeyTier returns a tier keyed by the request's API key, with rate and burst for keys
+// that have no override in limits.
+// The key is hashed so raw credentials are never stored in the limiter backend.
+func APIKeyTier(rate float64, burst int64, limits []KeyRateLimit) RateLimitTier {
+	tier := RateLimitTier{
+		Name:  "key",
+		Rate:  rate,
+		Burst: burst,
+		KeyFunc: func(r *http.Request) string {
+			// Behind Auth or ClientCert the digest is already known, and the first half
+			// of it is the same identifier as below.
+			if keyHash := domain.APIKeyHashFromContext(r.Context()); keyHash != "" {
+				return keyHash[:32]
+			}
+			apiKey := r.Header.Get(APIKeyHeader)
+			if apiKey == "" {
+				return ""
+			}
+			sum := sha256.Sum256([]byte(apiKey))
+			return hex.EncodeToString(sum[:16])
+		},
+	}
+	if len(limits) > 0 {
+		tier.Limits = make(map[string]BucketLimit, len(limits))
+		for _, l := range limits {
+			// Bucket identifiers are the first half of the digest.
+			tier.Limits[l.KeyHash[:32]] = BucketLimit{Rate: l.Rate, Burst: l.Burst}
+		}
+	}
+	return tier
+}
+
+// RateLimit is a middleware factory that enforces the given tiers using a shared limiter.
+// Every tier must allow the request; the most restrictive tier is reported in the
+// X-RateLimit-* response headers. If the limiter backend fails, requests are let through
+// so that ingestion keeps working while Redis is unavailable. Rejections are counted per
+// tier in m, which may be nil.
+func RateLimit(limiter domain.RateLimiter, tiers []RateLimitTier, m *metrics.IngestMetrics, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tightest *domain.RateLimitResult
+
+			for _, tier := range tiers {
+				id := tier.KeyFunc(r)
+				if id == "" {
+					continue
+				}
+				limit := BucketLimit{Rate: tier.Rate, Burst: tier.Burst}
+				if l, ok := tier.Limits[id]; ok {
+					limit = l
+				}
+				if limit.Rate <= 0 || limit.Burst <= 0 {
+					continue
+				}
+
+				res, err := limiter.Allow(r.Context(), tier.Name+":"+id, limit.Rate, limit.Burst, 1)
+				if err != nil {
+					logger.Warn("rate limiter unavailable, allowing request", "tier", tier.Name, "error", err)
+					continue
+				}
+
+				if !res.Allowed {
+					setRateLimitHeaders(w, res)
+					w.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+					logger.Warn("rate limit exceeded", "tier", tier.Name, "remote_addr", r.RemoteAddr)
+					if m != nil {
+						m.RateLimitedTotal.WithLabelValues(tier.Name).Inc()
+					}
+					apierror.Write(w, &apierror.Error{
+						Code:    apierror.CodeRateLimited,
+						Message: "rate limit exceeded",
+						Details: map[string]any{"tier": tier.Name, "retry_after_seconds": ceilSeconds(res.RetryAfter)},
+					})
+					return
+				}
+
+				if tightest == nil || res.Remaining < tightest.Remaining {
+					tightest = res
+				}
+			}
+
+			if tightest != nil {
+				setRateLimitHeaders(w, tightest)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, res *domain.RateLimitResult) {
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.ResetAfter), 10))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
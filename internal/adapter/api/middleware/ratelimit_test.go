@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeRateLimiter struct {
+	results map[string]*domain.RateLimitResult
+	err     error
+	keys    []string
+	bursts  []int64
+}
+
+func (f *fakeRateLimiter) Allow(ctx context.Context, key string, ratePerSecond float64, burst int64, cost int64) (*domain.RateLimitResult, error) {
+	f.keys = append(f.keys, key)
+	f.bursts = append(f.bursts, burst)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if res, ok := f.results[key]; ok {
+		return res, nil
+	}
+	return &domain.RateLimitResult{Allowed: true, Limit: burst, Remaining: burst - 1}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	t.Run("Allowed reports tightest tier", func(t *testing.T) {
+		limiter := &fakeRateLimiter{}
+		tiers := []RateLimitTier{GlobalTier(1000, 2000), APIKeyTier(10, 20, nil)}
+		h := RateLimit(limiter, tiers, nil, logger)(next)
+
+		req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+		req.Header.Set(APIKeyHeader, "secret")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, rr.Code)
+		}
+		if got := rr.Header().Get("X-RateLimit-Limit"); got != "20" {
+			t.Errorf("expected X-RateLimit-Limit 20, got %q", got)
+		}
+		if got := rr.Header().Get("X-RateLimit-Remaining"); got != "19" {
+			t.Errorf("expected X-RateLimit-Remaining 19, got %q", got)
+		}
+		for _, key := range limiter.keys {
+			if key == "key:secret" {
+				t.Error("raw API key must not be used as a limiter key")
+			}
+		}
+	})
+
+	t.Run("Rejected with Retry-After", func(t *testing.T) {
+		limiter := &fakeRateLimiter{results: map[string]*domain.RateLimitResult{
+			"global:all": {Allowed: false, Limit: 5, Remaining: 0, RetryAfter: 1500 * time.Millisecond, ResetAfter: 5 * time.Second},
+		}}
+		h := RateLimit(limiter, []RateLimitTier{GlobalTier(1, 5)}, nil, logger)(next)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/ingest", nil))
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+		if got := rr.Header().Get("Retry-After"); got != "2" {
+			t.Errorf("expected Retry-After 2, got %q", got)
+		}
+	})
+
+	t.Run("Rejections are counted by tier", func(t *testing.T) {
+		limiter := &fakeRateLimiter{results: map[string]*domain.RateLimitResult{
+			"global:all": {Allowed: false, Limit: 5},
+		}}
+		m := metrics.NewIngestMetrics(prometheus.NewRegistry())
+		h := RateLimit(limiter, []RateLimitTier{GlobalTier(1, 5)}, m, logger)(next)
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/ingest", nil))
+
+		if got := testutil.ToFloat64(m.RateLimitedTotal.WithLabelValues("global")); got != 1 {
+			t.Errorf("expected 1 rejection counted, got %v", got)
+		}
+	})
+
+	t.Run("Per-key overrides", func(t *testing.T) {
+		sum := sha256.Sum256([]byte("big"))
+		limits, err := ParseKeyRateLimits(`[{"key_hash":"` + hex.EncodeToString(sum[:]) + `","rate":1000,"burst":5000},{"key_hash":"` + hex.EncodeToString(make([]byte, 32)) + `","rate":0,"burst":0}]`)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		limiter := &fakeRateLimiter{}
+		h := RateLimit(limiter, []RateLimitTier{APIKeyTier(10, 20, limits)}, nil, logger)(next)
+
+		for _, key := range []string{"big", "small"} {
+			req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+			req.Header.Set(APIKeyHeader, key)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		if len(limiter.bursts) != 2 || limiter.bursts[0] != 5000 || limiter.bursts[1] != 20 {
+			t.Errorf("expected the override for one key and the default for the other, got bursts %v", limiter.bursts)
+		}
+	})
+
+	t.Run("Limiter failure fails open", func(t *testing.T) {
+		limiter := &fakeRateLimiter{err: errors.New("redis down")}
+		h := RateLimit(limiter, []RateLimitTier{GlobalTier(1, 5)}, nil, logger)(next)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/ingest", nil))
+
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, rr.Code)
+		}
+	})
+
+	t.Run("Disabled and keyless tiers are skipped", func(t *testing.T) {
+		limiter := &fakeRateLimiter{}
+		h := RateLimit(limiter, []RateLimitTier{GlobalTier(0, 0), APIKeyTier(10, 20, nil)}, nil, logger)(next)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/ingest", nil))
+
+		if len(limiter.keys) != 0 {
+			t.Errorf("expected no limiter calls, got %v", limiter.keys)
+		}
+	})
+}
+
+func TestParseKeyRateLimits(t *testing.T) {
+	for _, s := range []string{
+		`[{"key_hash":"abc","rate":1,"burst":1}]`,
+		`[{"key_hash":"` + hex.EncodeToString(make([]byte, 32)) + `","rate":-1,"burst":1}]`,
+		`{}`,
+	} {
+		if _, err := ParseKeyRateLimits(s); err == nil {
+			t.Errorf("expected an error for %s", s)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// RequestInfo is a middleware that records the client address, user agent and headers of
+// every request in its context, for the enrichers of the events it carries. It must wrap
+// the Metrics middleware, which needs the very request the mux handled.
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			clientIP = r.RemoteAddr
+		}
+		ctx := domain.WithRequestInfo(r.Context(), domain.RequestInfo{
+			ClientIP:  clientIP,
+			UserAgent: r.UserAgent(),
+			Header:    r.Header,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
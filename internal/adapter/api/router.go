@@ -7,12 +7,14 @@ import (
 	"github.com/V4T54L/watch-tower/internal/adapter/api/handler"
 	"github.com/V4T54L/watch-tower/internal/adapter/api/middleware"
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/V4T54L/watch-tower/internal/pkg/config"
 	"github.com/V4T54L/watch-tower/internal/usecase"
 )
 
-// NewRouter creates and configures the main HTTP router for the ingest service.
+// NewRouter creates and configures the main HTTP router for the ingest service. Every
+// request is rejected once draining reports true, see middleware.Drain.
 func NewRouter(
 	cfg *config.Config,
 	logger *slog.Logger,
@@ -20,16 +22,64 @@ func NewRouter(
 	ingestUseCase usecase.IngestLogUseCase,
 	m *metrics.IngestMetrics,
 	sseBroker *handler.SSEBroker,
+	rateLimiter domain.RateLimiter,
+	textParser *textparser.Chain,
+	schemaRegistry handler.AvroSchemaRegistry,
+	webhookSources []handler.WebhookSource,
+	keyRateLimits []middleware.KeyRateLimit,
+	quota middleware.QuotaChecker,
+	draining func() bool,
 ) http.Handler {
 	mux := http.NewServeMux()
 
 	authMiddleware := middleware.Auth(apiKeyRepo, logger)
+	rateLimitMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.RateLimitEnabled && rateLimiter != nil {
+		rateLimitMiddleware = middleware.RateLimit(rateLimiter, []middleware.RateLimitTier{
+			middleware.GlobalTier(cfg.RateLimitGlobalRate, cfg.RateLimitGlobalBurst),
+			middleware.APIKeyTier(cfg.RateLimitKeyRate, cfg.RateLimitKeyBurst, keyRateLimits),
+		}, m, logger)
+	}
+	if quota != nil {
+		// Daily quotas are enforced along with the rate limits, on every route that has them.
+		rateLimit, quotaMiddleware := rateLimitMiddleware, middleware.Quota(quota, m, logger)
+		rateLimitMiddleware = func(next http.Handler) http.Handler { return rateLimit(quotaMiddleware(next)) }
+	}
 
-	// Ingest Handler
-	ingestHandler := handler.NewIngestHandler(ingestUseCase, logger, cfg.MaxEventSize, m, sseBroker)
+	// Ingest Handlers
+	handlerCfg := handler.IngestHandlerConfig{
+		MaxEventSize:        cfg.MaxEventSize,
+		MaxDecompressedSize: cfg.MaxDecompressedSize,
+		MaxBatchEvents:      cfg.MaxBatchEvents,
+		MaxStreamSize:       cfg.MaxStreamSize,
+		MaxBulkSize:         cfg.MaxBulkSize,
+		TextParser:          textParser,
+		SchemaRegistry:      schemaRegistry,
+	}
+	ingestHandler := handler.NewIngestHandler(ingestUseCase, logger, handlerCfg, m, sseBroker)
+	otlpHandler := handler.NewOTLPHandler(ingestUseCase, logger, handlerCfg, m, sseBroker)
+	cloudWatchHandler := handler.NewCloudWatchHandler(ingestUseCase, logger, handlerCfg, m, sseBroker)
+	logplexHandler := handler.NewLogplexHandler(ingestUseCase, logger, handlerCfg, m, sseBroker)
+	esHandler := handler.NewElasticsearchHandler(ingestUseCase, logger, handlerCfg, m, sseBroker)
+	webhookHandler := handler.NewWebhookHandler(webhookSources, ingestUseCase, logger, handlerCfg, m, sseBroker)
 
 	// Routes
-	mux.Handle("POST /ingest", authMiddleware(ingestHandler))
+	mux.Handle("POST /ingest", authMiddleware(rateLimitMiddleware(ingestHandler)))
+	mux.Handle("POST /v1/logs", authMiddleware(rateLimitMiddleware(otlpHandler)))
+	mux.Handle("POST /v1/cloudwatch", middleware.FirehoseAccessKey(authMiddleware(rateLimitMiddleware(cloudWatchHandler))))
+	mux.Handle("POST /v1/logplex", middleware.BasicAuthKey(authMiddleware(rateLimitMiddleware(logplexHandler))))
+	// Webhooks authenticate with their provider signature instead of an API key.
+	mux.Handle("POST /webhooks/{source}", rateLimitMiddleware(webhookHandler))
+
+	// Elasticsearch-compatible API for shippers with an Elasticsearch output; point them at
+	// http://host:port/es and authenticate with the API key as the basic auth password.
+	esAuth := func(h http.HandlerFunc) http.Handler { return middleware.BasicAuthKey(authMiddleware(h)) }
+	mux.Handle("GET /es", esAuth(esHandler.Info))
+	mux.Handle("GET /es/{$}", esAuth(esHandler.Info))
+	mux.Handle("GET /es/_cluster/health", esAuth(esHandler.Health))
+	mux.Handle("POST /es/_bulk", middleware.BasicAuthKey(authMiddleware(rateLimitMiddleware(http.HandlerFunc(esHandler.Bulk)))))
+	mux.Handle("POST /es/{index}/_bulk", middleware.BasicAuthKey(authMiddleware(rateLimitMiddleware(http.HandlerFunc(esHandler.Bulk)))))
+
 	mux.Handle("/events", sseBroker)
 
 	// Health check
@@ -38,5 +88,6 @@ func NewRouter(
 		w.Write([]byte("OK"))
 	})
 
-	return mux
+	// The drain gate is inside the Metrics middleware, so its rejections are counted too.
+	return middleware.RequestInfo(middleware.PipelineDecisions(middleware.Metrics(m)(middleware.Drain(draining)(mux))))
 }
@@ -0,0 +1,259 @@
+// Package enrich adds metadata about where events came from to them as they are
+// ingested: the location and network of the client, its user agent and the Kubernetes
+// workload that shipped them.
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/mssola/useragent"
+)
+
+// Spec describes one enricher, e.g.
+//
+//	{"type":"geoip","database":"/var/lib/GeoIP/GeoLite2-City.mmdb","ip_header":"X-Forwarded-For"}
+//	{"type":"user_agent","sources":["web-*"],"field":"user_agent_string"}
+//	{"type":"kubernetes","sources":["k8s-*"]}
+type Spec struct {
+	Type     string            `json:"type"`                // "geoip", "user_agent" or "kubernetes"
+	Sources  []string          `json:"sources,omitempty"`   // path.Match patterns of the event sources it applies to; empty applies to all.
+	Target   string            `json:"target,omitempty"`    // Metadata field its results are merged into; defaults to the type, with geoip's being "geo".
+	Field    string            `json:"field,omitempty"`     // geoip, user_agent: metadata field holding the IP or user agent, instead of the request's.
+	IPHeader string            `json:"ip_header,omitempty"` // geoip: header holding the client IP, e.g. X-Forwarded-For, of which the first address is used. Only trust it behind a proxy setting it.
+	Database string            `json:"database,omitempty"`  // geoip: MaxMind City, Country or ASN database; required.
+	Headers  map[string]string `json:"headers,omitempty"`   // kubernetes: request header to result field, replacing DefaultKubernetesHeaders.
+}
+
+// DefaultKubernetesHeaders are the headers the kubernetes enricher reads by default, as
+// set by log shippers from the downward API. Headers starting with
+// KubernetesLabelHeaderPrefix are added to the "labels" field too.
+var DefaultKubernetesHeaders = map[string]string{
+	"X-Kubernetes-Namespace": "namespace",
+	"X-Kubernetes-Pod":       "pod",
+	"X-Kubernetes-Node":      "node",
+	"X-Kubernetes-Container": "container",
+}
+
+// KubernetesLabelHeaderPrefix prefixes the headers carrying pod labels, e.g.
+// X-Kubernetes-Label-App: billing.
+const KubernetesLabelHeaderPrefix = "X-Kubernetes-Label-"
+
+// ParseSpecs decodes a JSON array of enricher specs. An empty string means no enrichers.
+func ParseSpecs(s string) ([]Spec, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var specs []Spec
+	if err := json.Unmarshal([]byte(s), &specs); err != nil {
+		return nil, fmt.Errorf("invalid enricher specs: %w", err)
+	}
+	return specs, nil
+}
+
+// enricher returns the fields to merge into the target field of an event, or nil if it
+// has none to add.
+type enricher func(info domain.RequestInfo, metadata map[string]interface{}) map[string]interface{}
+
+type stage struct {
+	sources []string
+	target  string
+	enrich  enricher
+}
+
+func (s stage) appliesTo(source string) bool {
+	if len(s.sources) == 0 {
+		return true
+	}
+	for _, pattern := range s.sources {
+		if ok, _ := path.Match(pattern, source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline runs its enrichers, in order, on every event it is given.
+type Pipeline struct {
+	stages  []stage
+	closers []io.Closer
+}
+
+// New builds a Pipeline, opening the databases of its enrichers.
+func New(specs []Spec) (*Pipeline, error) {
+	p := &Pipeline{}
+	for i, spec := range specs {
+		st, err := p.newStage(spec)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("enricher %d: %w", i, err)
+		}
+		p.stages = append(p.stages, st)
+	}
+	return p, nil
+}
+
+func (p *Pipeline) newStage(spec Spec) (stage, error) {
+	for _, pattern := range spec.Sources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return stage{}, fmt.Errorf("invalid source pattern %q", pattern)
+		}
+	}
+	st := stage{sources: spec.Sources, target: spec.Target}
+	switch strings.ToLower(spec.Type) {
+	case "geoip":
+		g, err := openGeoIP(spec)
+		if err != nil {
+			return stage{}, err
+		}
+		p.closers = append(p.closers, g)
+		st.enrich = g.enrich
+		st.target = orDefault(st.target, "geo")
+	case "user_agent":
+		st.enrich = func(info domain.RequestInfo, metadata map[string]interface{}) map[string]interface{} {
+			return parseUserAgent(value(spec.Field, info.UserAgent, metadata))
+		}
+		st.target = orDefault(st.target, "user_agent")
+	case "kubernetes":
+		headers := spec.Headers
+		if len(headers) == 0 {
+			headers = DefaultKubernetesHeaders
+		}
+		st.enrich = func(info domain.RequestInfo, _ map[string]interface{}) map[string]interface{} {
+			return kubernetesFields(info.Header, headers)
+		}
+		st.target = orDefault(st.target, "kubernetes")
+	default:
+		return stage{}, fmt.Errorf("unknown enricher type %q", spec.Type)
+	}
+	return st, nil
+}
+
+// orDefault returns s, or def if s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// value returns the string in the metadata field, if field is set, or else the value
+// from the request.
+func value(field, fromRequest string, metadata map[string]interface{}) string {
+	if field == "" {
+		return fromRequest
+	}
+	s, _ := metadata[field].(string)
+	return s
+}
+
+// Enrich merges the results of the enrichers applying to the event's source into its
+// metadata, using the request the event was received in (see domain.WithRequestInfo);
+// events without one are only enriched from their metadata. Events stitched from several
+// lines are enriched from the request of the first, see usecase.MultilineUseCase. Events
+// whose metadata is not a JSON object are left alone.
+func (p *Pipeline) Enrich(ctx context.Context, event *domain.LogEvent) error {
+	if len(p.stages) == 0 {
+		return nil
+	}
+	info, _ := domain.RequestInfoFromContext(ctx)
+	metadata := map[string]interface{}{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil || metadata == nil {
+			return fmt.Errorf("metadata is not a JSON object")
+		}
+	}
+
+	enriched := false
+	for _, st := range p.stages {
+		if !st.appliesTo(event.Source) {
+			continue
+		}
+		fields := st.enrich(info, metadata)
+		if len(fields) == 0 {
+			continue
+		}
+		// Fields set by the client are kept, unless the enricher has a value for them.
+		target, ok := metadata[st.target].(map[string]interface{})
+		if !ok {
+			target = make(map[string]interface{}, len(fields))
+		}
+		for k, v := range fields {
+			target[k] = v
+		}
+		metadata[st.target] = target
+		enriched = true
+	}
+	if !enriched {
+		return nil
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode enriched metadata: %w", err)
+	}
+	event.Metadata = encoded
+	return nil
+}
+
+// Close closes the databases of the enrichers.
+func (p *Pipeline) Close() error {
+	var firstErr error
+	for _, c := range p.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// parseUserAgent returns the browser, operating system and device class of a user agent.
+func parseUserAgent(s string) map[string]interface{} {
+	if s == "" {
+		return nil
+	}
+	ua := useragent.New(s)
+	fields := map[string]interface{}{
+		"mobile": ua.Mobile(),
+		"bot":    ua.Bot(),
+	}
+	if name, version := ua.Browser(); name != "" {
+		fields["browser"] = name
+		if version != "" {
+			fields["browser_version"] = version
+		}
+	}
+	if osName := ua.OS(); osName != "" {
+		fields["os"] = osName
+	}
+	if platform := ua.Platform(); platform != "" {
+		fields["platform"] = platform
+	}
+	return fields
+}
+
+// kubernetesFields returns the values of the mapped headers, and the pod labels.
+func kubernetesFields(header http.Header, headers map[string]string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for name, field := range headers {
+		if v := header.Get(name); v != "" {
+			fields[field] = v
+		}
+	}
+	labels := make(map[string]interface{})
+	for name, values := range header {
+		if label, ok := strings.CutPrefix(name, KubernetesLabelHeaderPrefix); ok && label != "" && len(values) > 0 {
+			labels[strings.ToLower(label)] = values[0]
+		}
+	}
+	if len(labels) > 0 {
+		fields["labels"] = labels
+	}
+	return fields
+}
@@ -0,0 +1,136 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const chromeOnWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+func enrich(t *testing.T, ctx context.Context, specs []Spec, event domain.LogEvent) map[string]interface{} {
+	t.Helper()
+	p, err := New(specs)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer p.Close()
+	if err := p.Enrich(ctx, &event); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var metadata map[string]interface{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			t.Fatalf("expected JSON metadata, got %s", event.Metadata)
+		}
+	}
+	return metadata
+}
+
+func TestUserAgent(t *testing.T) {
+	ctx := domain.WithRequestInfo(context.Background(), domain.RequestInfo{UserAgent: chromeOnWindows})
+	metadata := enrich(t, ctx, []Spec{{Type: "user_agent"}}, domain.LogEvent{Metadata: []byte(`{"user_agent":{"raw":"kept"}}`)})
+
+	ua, _ := metadata["user_agent"].(map[string]interface{})
+	if ua["browser"] != "Chrome" || ua["browser_version"] != "120.0.0.0" || ua["os"] != "Windows 10" || ua["mobile"] != false || ua["bot"] != false {
+		t.Errorf("expected Chrome on Windows 10, got %v", ua)
+	}
+	if ua["raw"] != "kept" {
+		t.Errorf("expected the client's fields to be kept, got %v", ua)
+	}
+
+	// From a metadata field, e.g. of an access log line.
+	metadata = enrich(t, context.Background(), []Spec{{Type: "user_agent", Field: "agent", Target: "client"}},
+		domain.LogEvent{Metadata: []byte(`{"agent":"Googlebot/2.1 (+http://www.google.com/bot.html)"}`)})
+	if client, _ := metadata["client"].(map[string]interface{}); client["bot"] != true {
+		t.Errorf("expected a bot in the client field, got %v", metadata)
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Kubernetes-Namespace", "billing")
+	header.Set("X-Kubernetes-Pod", "api-7d9f")
+	header.Set("X-Kubernetes-Label-App", "api")
+	ctx := domain.WithRequestInfo(context.Background(), domain.RequestInfo{Header: header})
+
+	metadata := enrich(t, ctx, []Spec{{Type: "kubernetes"}}, domain.LogEvent{})
+	k8s, _ := metadata["kubernetes"].(map[string]interface{})
+	labels, _ := k8s["labels"].(map[string]interface{})
+	if k8s["namespace"] != "billing" || k8s["pod"] != "api-7d9f" || labels["app"] != "api" {
+		t.Errorf("expected the namespace, pod and labels, got %v", k8s)
+	}
+	if _, ok := k8s["node"]; ok {
+		t.Errorf("expected no node without its header, got %v", k8s)
+	}
+
+	metadata = enrich(t, ctx, []Spec{{Type: "kubernetes", Headers: map[string]string{"X-Pod": "pod"}}}, domain.LogEvent{})
+	if k8s, _ := metadata["kubernetes"].(map[string]interface{}); k8s["pod"] != nil || k8s["labels"] == nil {
+		t.Errorf("expected the configured headers to replace the defaults, got %v", k8s)
+	}
+}
+
+func TestSources(t *testing.T) {
+	ctx := domain.WithRequestInfo(context.Background(), domain.RequestInfo{UserAgent: chromeOnWindows})
+	specs := []Spec{{Type: "user_agent", Sources: []string{"web-*"}}}
+
+	if metadata := enrich(t, ctx, specs, domain.LogEvent{Source: "web-frontend"}); metadata["user_agent"] == nil {
+		t.Errorf("expected a matching source to be enriched, got %v", metadata)
+	}
+	event := domain.LogEvent{Source: "billing", Metadata: []byte(`{"a":1}`)}
+	if metadata := enrich(t, ctx, specs, event); metadata["user_agent"] != nil {
+		t.Errorf("expected other sources to be left alone, got %v", metadata)
+	}
+}
+
+func TestEnrichRejectsNonObjectMetadata(t *testing.T) {
+	p, err := New([]Spec{{Type: "user_agent"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	event := domain.LogEvent{Metadata: []byte(`[1,2]`)}
+	if err := p.Enrich(context.Background(), &event); err == nil || string(event.Metadata) != `[1,2]` {
+		t.Errorf("expected an error and the metadata left alone, got %v, %s", err, event.Metadata)
+	}
+}
+
+func TestGeoIPClientIP(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	info := domain.RequestInfo{ClientIP: "10.0.0.1", Header: header}
+
+	for _, tc := range []struct {
+		g    geoIP
+		want string
+	}{
+		{geoIP{}, "10.0.0.1"},
+		{geoIP{ipHeader: "X-Forwarded-For"}, "203.0.113.7"},
+		{geoIP{ipHeader: "X-Real-IP"}, "10.0.0.1"},
+		{geoIP{field: "remote_addr"}, "198.51.100.1"},
+	} {
+		if got := tc.g.clientIP(info, map[string]interface{}{"remote_addr": "198.51.100.1"}); got.String() != tc.want {
+			t.Errorf("%+v: expected %s, got %s", tc.g, tc.want, got)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, spec := range []Spec{
+		{Type: "geoip"},
+		{Type: "geoip", Database: "/nonexistent/GeoLite2-City.mmdb"},
+		{Type: "user_agent", Sources: []string{"["}},
+		{Type: "reverse_dns"},
+	} {
+		if _, err := New([]Spec{spec}); err == nil {
+			t.Errorf("%+v: expected an error", spec)
+		}
+	}
+
+	specs, err := ParseSpecs(`[{"type":"kubernetes","sources":["k8s-*"]}]`)
+	if err != nil || len(specs) != 1 || specs[0].Sources[0] != "k8s-*" {
+		t.Errorf("expected one spec, got %+v, %v", specs, err)
+	}
+}
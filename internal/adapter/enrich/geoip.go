@@ -0,0 +1,102 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoRecord holds the fields read from MaxMind City, Country and ASN databases; those a
+// database lacks stay empty.
+type geoRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// geoIP resolves client IPs to their location and autonomous system.
+type geoIP struct {
+	db       *maxminddb.Reader
+	field    string
+	ipHeader string
+}
+
+func openGeoIP(spec Spec) (*geoIP, error) {
+	if spec.Database == "" {
+		return nil, fmt.Errorf("geoip needs a database")
+	}
+	db, err := maxminddb.Open(spec.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &geoIP{db: db, field: spec.Field, ipHeader: spec.IPHeader}, nil
+}
+
+func (g *geoIP) Close() error {
+	return g.db.Close()
+}
+
+// clientIP returns the IP to resolve: the metadata field's, the first address of the
+// header's or the peer's.
+func (g *geoIP) clientIP(info domain.RequestInfo, metadata map[string]interface{}) net.IP {
+	addr := info.ClientIP
+	if g.ipHeader != "" {
+		if forwarded := info.Header.Get(g.ipHeader); forwarded != "" {
+			addr, _, _ = strings.Cut(forwarded, ",")
+		}
+	}
+	return net.ParseIP(strings.TrimSpace(value(g.field, addr, metadata)))
+}
+
+func (g *geoIP) enrich(info domain.RequestInfo, metadata map[string]interface{}) map[string]interface{} {
+	ip := g.clientIP(info, metadata)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() {
+		return nil
+	}
+	var rec geoRecord
+	if err := g.db.Lookup(ip, &rec); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	set := func(k, v string) {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	set("country_iso", rec.Country.ISOCode)
+	set("country", rec.Country.Names["en"])
+	if len(rec.Subdivisions) > 0 {
+		set("region_iso", rec.Subdivisions[0].ISOCode)
+		set("region", rec.Subdivisions[0].Names["en"])
+	}
+	set("city", rec.City.Names["en"])
+	set("timezone", rec.Location.TimeZone)
+	if rec.Location.Latitude != nil && rec.Location.Longitude != nil {
+		fields["lat"] = *rec.Location.Latitude
+		fields["lon"] = *rec.Location.Longitude
+	}
+	if rec.ASN != 0 {
+		fields["asn"] = rec.ASN
+	}
+	set("as_org", rec.ASOrg)
+	return fields
+}
@@ -0,0 +1,274 @@
+// Package fieldmap maps the payload keys of API keys onto the fields of events, for
+// senders whose payloads say e.g. msg, lvl, ts and svc, which would otherwise be stored
+// with an empty message and level.
+package fieldmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Targets of payload keys, besides metadata.<name>.
+const (
+	TargetMessage   = "message"
+	TargetLevel     = "level"
+	TargetEventTime = "event_time"
+	TargetSource    = "source"
+	TargetEventID   = "event_id"
+)
+
+// metadataPrefix starts the targets that rename a key into the metadata. The rest of the
+// target is the name of a top-level metadata field.
+const metadataPrefix = "metadata."
+
+// field is a payload key mapped onto its target.
+type field struct {
+	path   []string
+	target string
+}
+
+// compiledMapping is a field mapping ready to be applied.
+type compiledMapping struct {
+	updatedAt time.Time
+	fields    []field // Ordered by key.
+}
+
+// Mapper maps the payloads of API keys with a field mapping onto their events. Mappings
+// are loaded from a repository by Reload, which Run repeats so that changes made on
+// other replicas are picked up.
+type Mapper struct {
+	repo   domain.FieldMappingRepository
+	logger *slog.Logger
+
+	mappings atomic.Pointer[map[string]compiledMapping] // By key digest.
+}
+
+// NewMapper creates a Mapper. Until the first Reload, no event is mapped.
+func NewMapper(repo domain.FieldMappingRepository, logger *slog.Logger) *Mapper {
+	m := &Mapper{repo: repo, logger: logger.With("component", "field_mappings")}
+	m.mappings.Store(&map[string]compiledMapping{})
+	return m
+}
+
+// Map sets the fields of the event from the keys of its raw payload that the mapping of
+// the API key keyHash names, overwriting what they held. Events whose key has no mapping,
+// or whose payload is not a JSON object, are left as they are, as are fields whose key is
+// missing. Keys of the payload's metadata that are mapped are renamed rather than copied,
+// and times are parsed like textparser.ParseTime; those it does not recognize are skipped.
+func (m *Mapper) Map(keyHash string, event *domain.LogEvent) error {
+	if keyHash == "" || len(event.RawEvent) == 0 {
+		return nil
+	}
+	mapping, ok := (*m.mappings.Load())[keyHash]
+	if !ok {
+		return nil
+	}
+	payload, ok := decodeObject(event.RawEvent)
+	if !ok {
+		return nil
+	}
+
+	var metadata map[string]interface{}
+	if len(event.Metadata) > 0 {
+		if metadata, ok = decodeObject(event.Metadata); !ok {
+			return fmt.Errorf("failed to map fields: metadata is not a JSON object")
+		}
+	}
+	metadataChanged := false
+	for _, f := range mapping.fields {
+		v, ok := lookup(payload, f.path)
+		if !ok || v == nil {
+			continue
+		}
+		switch f.target {
+		case TargetMessage:
+			event.Message = stringValue(v)
+		case TargetLevel:
+			event.Level = stringValue(v)
+		case TargetSource:
+			event.Source = stringValue(v)
+		case TargetEventID:
+			event.ID = stringValue(v)
+		case TargetEventTime:
+			ts, ok := textparser.ParseTime(v, "")
+			if !ok {
+				continue
+			}
+			event.EventTime = ts
+		default:
+			if metadata == nil {
+				metadata = make(map[string]interface{})
+			}
+			metadata[strings.TrimPrefix(f.target, metadataPrefix)] = v
+			metadataChanged = true
+		}
+		if len(f.path) > 1 && f.path[0] == "metadata" && remove(metadata, f.path[1:]) {
+			metadataChanged = true
+		}
+	}
+
+	if metadataChanged {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode mapped metadata: %w", err)
+		}
+		event.Metadata = encoded
+	}
+	return nil
+}
+
+// decodeObject decodes a JSON object, keeping numbers as json.Number so that epochs and
+// IDs keep their precision.
+func decodeObject(data []byte) (map[string]interface{}, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+func lookup(obj map[string]interface{}, path []string) (interface{}, bool) {
+	var v interface{} = obj
+	for _, key := range path {
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = o[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// remove deletes the value at path, reporting whether there was one.
+func remove(obj map[string]interface{}, path []string) bool {
+	for _, key := range path[:len(path)-1] {
+		o, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		obj = o
+	}
+	last := path[len(path)-1]
+	if _, ok := obj[last]; !ok {
+		return false
+	}
+	delete(obj, last)
+	return true
+}
+
+// stringValue returns strings as they are and any other value as JSON, e.g. a level sent
+// as 30 or a message sent as an object.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+// Validate reports whether fields would compile as a mapping, wrapping
+// domain.ErrInvalidFieldMapping if not.
+func (m *Mapper) Validate(fields map[string]string) error {
+	if _, err := compile(fields); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidFieldMapping, err)
+	}
+	return nil
+}
+
+func compile(fields map[string]string) ([]field, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields to map")
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	compiled := make([]field, 0, len(fields))
+	mappedBy := make(map[string]string, len(fields)) // Key by target.
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+		if slices.Contains(path, "") {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		target := fields[key]
+		switch target {
+		case TargetMessage, TargetLevel, TargetEventTime, TargetSource, TargetEventID:
+		default:
+			if !strings.HasPrefix(target, metadataPrefix) || target == metadataPrefix {
+				return nil, fmt.Errorf("key %q has unknown target %q", key, target)
+			}
+		}
+		if other, ok := mappedBy[target]; ok {
+			return nil, fmt.Errorf("keys %q and %q are both mapped onto %s", other, key, target)
+		}
+		mappedBy[target] = key
+		compiled = append(compiled, field{path: path, target: target})
+	}
+	return compiled, nil
+}
+
+// Reload loads the mappings from the repository, compiling only those changed since the
+// last reload. A stored mapping that does not compile is logged and keeps its previous
+// version, or leaves its events unmapped if it has none.
+func (m *Mapper) Reload(ctx context.Context) error {
+	stored, err := m.repo.ListFieldMappings(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := *m.mappings.Load()
+	mappings := make(map[string]compiledMapping, len(stored))
+	for _, mapping := range stored {
+		if c, ok := current[mapping.KeyHash]; ok && c.updatedAt.Equal(mapping.UpdatedAt) {
+			mappings[mapping.KeyHash] = c
+			continue
+		}
+		fields, err := compile(mapping.Fields)
+		if err != nil {
+			m.logger.Error("failed to compile field mapping", "key_hash", mapping.KeyHash[:min(16, len(mapping.KeyHash))], "error", err)
+			if c, ok := current[mapping.KeyHash]; ok {
+				mappings[mapping.KeyHash] = c
+			}
+			continue
+		}
+		mappings[mapping.KeyHash] = compiledMapping{updatedAt: mapping.UpdatedAt, fields: fields}
+	}
+	m.mappings.Store(&mappings)
+	return nil
+}
+
+// Run reloads the mappings every interval until the context is cancelled.
+func (m *Mapper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(ctx); err != nil {
+				m.logger.Error("failed to reload field mappings", "error", err)
+			}
+		}
+	}
+}
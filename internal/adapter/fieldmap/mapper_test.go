@@ -0,0 +1,133 @@
+package fieldmap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeFieldMappingRepository struct {
+	mappings map[string]domain.FieldMapping
+}
+
+func (f *fakeFieldMappingRepository) ListFieldMappings(ctx context.Context) ([]domain.FieldMapping, error) {
+	var mappings []domain.FieldMapping
+	for _, m := range f.mappings {
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+func (f *fakeFieldMappingRepository) GetFieldMapping(ctx context.Context, keyHash string) (*domain.FieldMapping, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeFieldMappingRepository) PutFieldMapping(ctx context.Context, mapping domain.FieldMapping) (*domain.FieldMapping, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeFieldMappingRepository) DeleteFieldMapping(ctx context.Context, keyHash string) error {
+	return errors.New("not implemented")
+}
+
+// newEvent decodes a payload the way the ingest handler does.
+func newEvent(t *testing.T, payload string) *domain.LogEvent {
+	t.Helper()
+	var event domain.LogEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	event.RawEvent = json.RawMessage(payload)
+	return &event
+}
+
+func TestMapper(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	repo := &fakeFieldMappingRepository{mappings: map[string]domain.FieldMapping{
+		"tenant": {KeyHash: "tenant", UpdatedAt: time.Unix(1, 0), Fields: map[string]string{
+			"msg":            "message",
+			"lvl":            "level",
+			"ts":             "event_time",
+			"svc":            "source",
+			"ctx.user":       "metadata.user",
+			"metadata.reqid": "metadata.request_id",
+		}},
+	}}
+	m := NewMapper(repo, logger)
+	payload := `{"msg":"disk full","lvl":50,"ts":1700000000123,"svc":"billing","ctx":{"user":"u1"},"metadata":{"reqid":"r1","pod":"a"}}`
+
+	event := newEvent(t, payload)
+	if err := m.Map("tenant", event); err != nil || event.Message != "" {
+		t.Fatalf("expected no mapping before the first reload, got %+v, %v", event, err)
+	}
+	if err := m.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	event = newEvent(t, payload)
+	if err := m.Map("tenant", event); err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if event.Message != "disk full" || event.Level != "50" || event.Source != "billing" {
+		t.Errorf("expected message, level and source mapped, got %+v", event)
+	}
+	if want := time.UnixMilli(1700000000123).UTC(); !event.EventTime.Equal(want) {
+		t.Errorf("expected event time %s, got %s", want, event.EventTime)
+	}
+	if got, want := string(event.Metadata), `{"pod":"a","request_id":"r1","user":"u1"}`; got != want {
+		t.Errorf("expected metadata %s, got %s", want, got)
+	}
+
+	event = newEvent(t, payload)
+	if err := m.Map("other", event); err != nil || event.Message != "" {
+		t.Errorf("expected a key without a mapping left as is, got %+v, %v", event, err)
+	}
+
+	// A stored mapping that no longer compiles keeps its previous version.
+	repo.mappings["tenant"] = domain.FieldMapping{KeyHash: "tenant", UpdatedAt: time.Unix(2, 0), Fields: map[string]string{"msg": "body"}}
+	m.Reload(context.Background())
+	event = newEvent(t, payload)
+	if m.Map("tenant", event); event.Message != "disk full" {
+		t.Errorf("expected the previous mapping kept, got %+v", event)
+	}
+
+	delete(repo.mappings, "tenant")
+	m.Reload(context.Background())
+	event = newEvent(t, payload)
+	if m.Map("tenant", event); event.Message != "" {
+		t.Errorf("expected no mapping after it was deleted, got %+v", event)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := NewMapper(&fakeFieldMappingRepository{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		wantErr bool
+	}{
+		{"valid", map[string]string{"msg": "message", "a.b": "metadata.b"}, false},
+		{"empty", nil, true},
+		{"unknown target", map[string]string{"msg": "body"}, true},
+		{"empty metadata name", map[string]string{"msg": "metadata."}, true},
+		{"empty path segment", map[string]string{"a..b": "message"}, true},
+		{"same target twice", map[string]string{"msg": "message", "text": "message"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Validate(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, domain.ErrInvalidFieldMapping) {
+				t.Errorf("expected ErrInvalidFieldMapping, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	ingestv1 "github.com/V4T54L/watch-tower/api/ingest/v1"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// APIKeyMetadata is the gRPC metadata key carrying the API key, the equivalent of
+// the X-API-Key header on the HTTP endpoints.
+const APIKeyMetadata = "x-api-key"
+
+// IngestServer implements ingestv1.IngestServiceServer on top of the same IngestLogUseCase
+// used by the HTTP handlers, so PII redaction and buffering behave identically.
+type IngestServer struct {
+	ingestv1.UnimplementedIngestServiceServer
+
+	useCase usecase.IngestLogUseCase
+	logger  *slog.Logger
+	metrics *metrics.IngestMetrics
+}
+
+// NewIngestServer creates a new IngestServer.
+func NewIngestServer(uc usecase.IngestLogUseCase, logger *slog.Logger, m *metrics.IngestMetrics) *IngestServer {
+	return &IngestServer{
+		useCase: uc,
+		logger:  logger.With("component", "grpc_ingest"),
+		metrics: m,
+	}
+}
+
+// NewServer creates a gRPC server with the IngestService registered and API key
+// authentication enforced on every call. maxMessageSize bounds a single request message.
+func NewServer(uc usecase.IngestLogUseCase, apiKeyRepo domain.APIKeyRepository, maxMessageSize int, logger *slog.Logger, m *metrics.IngestMetrics) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.UnaryInterceptor(UnaryAuthInterceptor(apiKeyRepo, logger)),
+		grpc.StreamInterceptor(StreamAuthInterceptor(apiKeyRepo, logger)),
+	)
+	ingestv1.RegisterIngestServiceServer(srv, NewIngestServer(uc, logger, m))
+	return srv
+}
+
+// Ingest buffers every event in the request. Invalid events fail the whole request,
+// matching the HTTP endpoint's behaviour for malformed payloads.
+func (s *IngestServer) Ingest(ctx context.Context, req *ingestv1.IngestRequest) (*ingestv1.IngestResponse, error) {
+	resp := &ingestv1.IngestResponse{}
+	if err := s.ingestBatch(ctx, req, resp); err != nil {
+		return nil, err
+	}
+	if resp.Accepted == 0 && resp.Rejected > 0 {
+		return nil, status.Error(codes.Unavailable, "failed to buffer log events")
+	}
+	return resp, nil
+}
+
+// IngestStream buffers events from each message on the stream and returns the totals
+// once the client half-closes.
+func (s *IngestServer) IngestStream(stream ingestv1.IngestService_IngestStreamServer) error {
+	resp := &ingestv1.IngestResponse{}
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.ingestBatch(stream.Context(), req, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *IngestServer) ingestBatch(ctx context.Context, req *ingestv1.IngestRequest, resp *ingestv1.IngestResponse) error {
+	s.metrics.BytesTotal.Add(float64(proto.Size(req)))
+
+	events := make([]domain.LogEvent, len(req.GetEvents()))
+	for i, pb := range req.GetEvents() {
+		event, err := pb.ToDomain()
+		if err != nil {
+			s.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+			return status.Errorf(codes.InvalidArgument, "event %d: %v", i, err)
+		}
+		events[i] = event
+	}
+
+	var accepted int64
+	for i := range events {
+		if err := s.useCase.Ingest(ctx, &events[i]); err != nil {
+			s.logger.Error("Failed to ingest event from gRPC request", "error", err)
+			s.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			resp.Rejected++
+			continue
+		}
+		accepted++
+	}
+	if accepted > 0 {
+		s.metrics.EventsTotal.WithLabelValues("accepted").Add(float64(accepted))
+		resp.Accepted += accepted
+	}
+	return nil
+}
+
+// UnaryAuthInterceptor rejects unary calls that do not carry a valid API key.
+func UnaryAuthInterceptor(repo domain.APIKeyRepository, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authenticate(ctx, repo, logger); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor rejects streaming calls that do not carry a valid API key.
+func StreamAuthInterceptor(repo domain.APIKeyRepository, logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), repo, logger); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authenticate(ctx context.Context, repo domain.APIKeyRepository, logger *slog.Logger) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(APIKeyMetadata)
+	if len(keys) == 0 || keys[0] == "" {
+		logger.Warn("API key missing from gRPC call")
+		return status.Error(codes.Unauthenticated, "API key required")
+	}
+
+	isValid, err := repo.IsValid(ctx, keys[0])
+	if err != nil {
+		logger.Error("failed to validate API key", "error", err)
+		return status.Error(codes.Internal, "failed to validate API key")
+	}
+	if !isValid {
+		logger.Warn("invalid API key provided on gRPC call")
+		return status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	return nil
+}
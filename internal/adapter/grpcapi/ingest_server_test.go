@@ -0,0 +1,145 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ingestv1 "github.com/V4T54L/watch-tower/api/ingest/v1"
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeAPIKeyRepo struct{ valid string }
+
+func (f fakeAPIKeyRepo) IsValid(_ context.Context, key string) (bool, error) {
+	return key == f.valid, nil
+}
+
+type recordingUseCase struct {
+	events []domain.LogEvent
+	err    error
+}
+
+func (r *recordingUseCase) Ingest(_ context.Context, event *domain.LogEvent) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func newTestClient(t *testing.T, uc *recordingUseCase) ingestv1.IngestServiceClient {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(uc, fakeAPIKeyRepo{valid: "secret"}, 1<<20, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ingestv1.NewIngestServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), APIKeyMetadata, key)
+}
+
+func TestIngestServer_Ingest(t *testing.T) {
+	uc := &recordingUseCase{}
+	client := newTestClient(t, uc)
+	eventTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	resp, err := client.Ingest(withKey("secret"), &ingestv1.IngestRequest{Events: []*ingestv1.LogEvent{
+		{Message: "one", Level: "info", Source: "svc", EventTime: timestamppb.New(eventTime), Metadata: []byte(`{"user":"bob"}`)},
+		{Message: "two"},
+	}})
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if resp.GetAccepted() != 2 || resp.GetRejected() != 0 {
+		t.Errorf("got accepted=%d rejected=%d, want 2/0", resp.GetAccepted(), resp.GetRejected())
+	}
+	if len(uc.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(uc.events))
+	}
+	first := uc.events[0]
+	if !first.EventTime.Equal(eventTime) || first.Source != "svc" || string(first.Metadata) != `{"user":"bob"}` {
+		t.Errorf("unexpected event: %+v", first)
+	}
+	if len(first.RawEvent) == 0 {
+		t.Error("expected RawEvent to be set")
+	}
+}
+
+func TestIngestServer_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		ucErr    error
+		metadata []byte
+		wantCode codes.Code
+	}{
+		{name: "Missing API key", ctx: context.Background(), wantCode: codes.Unauthenticated},
+		{name: "Invalid API key", ctx: withKey("wrong"), wantCode: codes.Unauthenticated},
+		{name: "Metadata not an object", ctx: withKey("secret"), metadata: []byte(`[1,2]`), wantCode: codes.InvalidArgument},
+		{name: "Buffer failure", ctx: withKey("secret"), ucErr: errors.New("redis down"), wantCode: codes.Unavailable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, &recordingUseCase{err: tc.ucErr})
+			_, err := client.Ingest(tc.ctx, &ingestv1.IngestRequest{Events: []*ingestv1.LogEvent{
+				{Message: "hello", Metadata: tc.metadata},
+			}})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("expected code %v, got %v (%v)", tc.wantCode, got, err)
+			}
+		})
+	}
+}
+
+func TestIngestServer_IngestStream(t *testing.T) {
+	uc := &recordingUseCase{}
+	client := newTestClient(t, uc)
+
+	stream, err := client.IngestStream(withKey("secret"))
+	if err != nil {
+		t.Fatalf("IngestStream failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&ingestv1.IngestRequest{Events: []*ingestv1.LogEvent{{Message: "a"}, {Message: "b"}}}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv failed: %v", err)
+	}
+	if resp.GetAccepted() != 6 {
+		t.Errorf("expected 6 accepted, got %d", resp.GetAccepted())
+	}
+	if len(uc.events) != 6 {
+		t.Errorf("expected 6 buffered events, got %d", len(uc.events))
+	}
+}
@@ -0,0 +1,71 @@
+package journald
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxFieldSize bounds a single binary field so a corrupt length cannot exhaust memory.
+const maxFieldSize = 16 << 20
+
+// ReadEntry reads one entry in the Journal Export Format: "FIELD=value" lines, or for
+// values containing newlines or binary data "FIELD\n" followed by a little-endian uint64
+// length, the data and a newline. Entries are terminated by an empty line.
+// A field that occurs more than once keeps its first value. io.EOF is returned only
+// when no more entries are available.
+func ReadEntry(r *bufio.Reader) (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			switch {
+			case len(line) > 0:
+				return nil, io.ErrUnexpectedEOF
+			case len(fields) > 0:
+				return fields, nil
+			}
+			return nil, io.EOF
+		}
+		line = line[:len(line)-1]
+		if len(line) == 0 {
+			if len(fields) == 0 {
+				continue // Tolerate extra separators between entries.
+			}
+			return fields, nil
+		}
+
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			name := string(line[:eq])
+			if _, ok := fields[name]; !ok {
+				fields[name] = string(line[eq+1:])
+			}
+			continue
+		}
+
+		name := string(line)
+		var size uint64
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("failed to read length of field %s: %w", name, err)
+		}
+		if size > maxFieldSize {
+			return nil, fmt.Errorf("field %s of %d bytes exceeds %d bytes", name, size, maxFieldSize)
+		}
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("failed to read field %s: %w", name, err)
+		}
+		if data[size] != '\n' {
+			return nil, fmt.Errorf("field %s is not newline-terminated", name)
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = string(data[:size])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package journald
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/adapter/syslog"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const (
+	contentTypeJournalExport = "application/vnd.fdo.journal"
+	reconnectDelay           = 5 * time.Second
+)
+
+// ReaderConfig holds the journal source settings for the Reader.
+type ReaderConfig struct {
+	GatewayURL          string        // Base URL of systemd-journal-gatewayd, e.g. http://host:19531.
+	Units               []string      // Only read entries of these systemd units; empty reads all.
+	CursorFile          string        // Where the last ingested cursor is persisted; empty disables resume.
+	CursorFlushInterval time.Duration // How often the cursor is written while entries are flowing.
+}
+
+// Reader follows a systemd journal through systemd-journal-gatewayd's export format
+// endpoint and ingests every entry. The cursor of the last buffered entry is persisted
+// so that a restarted reader resumes where it stopped; entries ingested after the last
+// flush may be delivered again, so delivery is at-least-once.
+type Reader struct {
+	client  *http.Client
+	cfg     ReaderConfig
+	useCase usecase.IngestLogUseCase
+	logger  *slog.Logger
+	metrics *metrics.IngestMetrics
+
+	cursor      string
+	savedCursor string
+	lastSave    time.Time
+}
+
+// NewReader creates a new journald Reader.
+func NewReader(cfg ReaderConfig, uc usecase.IngestLogUseCase, logger *slog.Logger, m *metrics.IngestMetrics) *Reader {
+	if cfg.CursorFlushInterval <= 0 {
+		cfg.CursorFlushInterval = time.Second
+	}
+	return &Reader{
+		client:  &http.Client{},
+		cfg:     cfg,
+		useCase: uc,
+		logger:  logger.With("component", "journald_reader", "gateway_url", cfg.GatewayURL),
+		metrics: m,
+	}
+}
+
+// Run follows the journal until the context is cancelled, reconnecting after errors.
+func (r *Reader) Run(ctx context.Context) {
+	if err := r.loadCursor(); err != nil {
+		r.logger.Error("Failed to load journal cursor, reading from the start of the journal", "error", err)
+	}
+	r.logger.Info("Starting journald reader", "cursor", r.cursor)
+
+	for {
+		err := r.follow(ctx)
+		if err := r.saveCursor(); err != nil {
+			r.logger.Error("Failed to persist journal cursor", "error", err)
+		}
+		if ctx.Err() != nil {
+			r.logger.Info("Stopping journald reader")
+			return
+		}
+		r.logger.Error("Journal stream ended, reconnecting", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// follow streams entries after the current cursor and ingests them until the stream
+// fails or an entry cannot be buffered.
+func (r *Reader) follow(ctx context.Context) error {
+	query := url.Values{"follow": {""}}
+	for _, unit := range r.cfg.Units {
+		query.Add("_SYSTEMD_UNIT", unit)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(r.cfg.GatewayURL, "/")+"/entries?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentTypeJournalExport)
+	if r.cursor != "" {
+		// Skip the entry at the cursor itself, which was already ingested.
+		req.Header.Set("Range", "entries="+r.cursor+":1:")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to journal gateway: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("journal gateway returned %s", resp.Status)
+	}
+
+	br := bufio.NewReader(resp.Body)
+	for {
+		fields, err := ReadEntry(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("journal gateway closed the stream")
+			}
+			return err
+		}
+
+		event := ToLogEvent(fields)
+		r.metrics.BytesTotal.Add(float64(len(event.RawEvent)))
+		if err := r.useCase.Ingest(ctx, &event); err != nil {
+			r.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+			// Reconnect from the last buffered entry rather than skipping this one.
+			return fmt.Errorf("failed to ingest journal entry: %w", err)
+		}
+		r.metrics.EventsTotal.WithLabelValues("accepted").Inc()
+
+		if cursor := fields["__CURSOR"]; cursor != "" {
+			r.cursor = cursor
+		}
+		if time.Since(r.lastSave) >= r.cfg.CursorFlushInterval {
+			if err := r.saveCursor(); err != nil {
+				r.logger.Error("Failed to persist journal cursor", "error", err)
+			}
+		}
+	}
+}
+
+func (r *Reader) loadCursor() error {
+	if r.cfg.CursorFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.cfg.CursorFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.cursor = strings.TrimSpace(string(data))
+	r.savedCursor = r.cursor
+	return nil
+}
+
+// saveCursor atomically replaces the cursor file if the cursor moved since the last save.
+func (r *Reader) saveCursor() error {
+	r.lastSave = time.Now()
+	if r.cfg.CursorFile == "" || r.cursor == r.savedCursor {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.cfg.CursorFile), ".journald-cursor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(r.cursor + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.cfg.CursorFile); err != nil {
+		return err
+	}
+	r.savedCursor = r.cursor
+	return nil
+}
+
+// ToLogEvent converts journal fields into a LogEvent. The unit (or syslog identifier)
+// becomes the source and PRIORITY the level; user fields such as CODE_FILE are kept in
+// metadata with lower-cased names.
+func ToLogEvent(fields map[string]string) domain.LogEvent {
+	event := domain.LogEvent{
+		Message: fields["MESSAGE"],
+		Source:  firstNonEmpty(fields["_SYSTEMD_UNIT"], fields["SYSLOG_IDENTIFIER"], fields["_COMM"]),
+	}
+	if p, err := strconv.Atoi(fields["PRIORITY"]); err == nil {
+		event.Level = syslog.SeverityLevel(p)
+	}
+	if us, err := strconv.ParseInt(fields["__REALTIME_TIMESTAMP"], 10, 64); err == nil {
+		event.EventTime = time.UnixMicro(us).UTC()
+	}
+
+	metadata := make(map[string]interface{})
+	for name, value := range fields {
+		switch name {
+		case "MESSAGE", "PRIORITY":
+		case "__CURSOR":
+			metadata["journald_cursor"] = value
+		case "_HOSTNAME":
+			metadata["hostname"] = value
+		case "_SYSTEMD_UNIT":
+			metadata["systemd_unit"] = value
+		case "_PID":
+			metadata["pid"] = value
+		case "_BOOT_ID":
+			metadata["boot_id"] = value
+		default:
+			// Remaining trusted ("_") and address ("__") fields are dropped to keep events small.
+			if !strings.HasPrefix(name, "_") {
+				metadata[strings.ToLower(name)] = value
+			}
+		}
+	}
+	event.Metadata, _ = json.Marshal(metadata)
+	event.RawEvent, _ = json.Marshal(fields)
+	return event
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package journald
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type mockIngestUseCase struct {
+	mu     sync.Mutex
+	events []domain.LogEvent
+	err    error
+}
+
+func (m *mockIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func exportEntry(cursor, message string, extra ...string) string {
+	var b strings.Builder
+	b.WriteString("__CURSOR=" + cursor + "\n")
+	b.WriteString("__REALTIME_TIMESTAMP=1714564800123456\n")
+	b.WriteString("_SYSTEMD_UNIT=nginx.service\n_HOSTNAME=web-1\nPRIORITY=3\n")
+	for _, f := range extra {
+		b.WriteString(f + "\n")
+	}
+	if strings.Contains(message, "\n") {
+		b.WriteString("MESSAGE\n")
+		binary.Write(&b, binary.LittleEndian, uint64(len(message)))
+		b.WriteString(message + "\n")
+	} else {
+		b.WriteString("MESSAGE=" + message + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func TestReadEntry(t *testing.T) {
+	stream := exportEntry("s=1", "line one\nline two", "CODE_FILE=main.c") + exportEntry("s=2", "plain")
+	r := bufio.NewReader(strings.NewReader(stream))
+
+	first, err := ReadEntry(r)
+	if err != nil {
+		t.Fatalf("ReadEntry failed: %v", err)
+	}
+	if first["MESSAGE"] != "line one\nline two" || first["CODE_FILE"] != "main.c" {
+		t.Errorf("unexpected entry: %v", first)
+	}
+	second, err := ReadEntry(r)
+	if err != nil || second["__CURSOR"] != "s=2" {
+		t.Fatalf("unexpected second entry %v (err %v)", second, err)
+	}
+	if _, err := ReadEntry(r); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	if _, err := ReadEntry(bufio.NewReader(strings.NewReader("MESSAGE\n\x05\x00"))); err == nil {
+		t.Error("expected error for truncated binary field")
+	}
+}
+
+func TestToLogEvent(t *testing.T) {
+	fields, err := ReadEntry(bufio.NewReader(strings.NewReader(exportEntry("s=1", "upstream timed out", "CODE_FILE=main.c", "_UID=0"))))
+	if err != nil {
+		t.Fatalf("ReadEntry failed: %v", err)
+	}
+	event := ToLogEvent(fields)
+
+	if event.Source != "nginx.service" || event.Level != "error" || event.Message != "upstream timed out" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if !event.EventTime.Equal(time.UnixMicro(1714564800123456)) {
+		t.Errorf("unexpected event time %v", event.EventTime)
+	}
+	var md map[string]interface{}
+	json.Unmarshal(event.Metadata, &md)
+	if md["hostname"] != "web-1" || md["code_file"] != "main.c" || md["journald_cursor"] != "s=1" {
+		t.Errorf("unexpected metadata: %s", event.Metadata)
+	}
+	if _, ok := md["_uid"]; ok {
+		t.Errorf("expected trusted fields to be dropped: %s", event.Metadata)
+	}
+}
+
+func TestReader_ResumesFromCursor(t *testing.T) {
+	var ranges []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		if r.URL.Query().Get("_SYSTEMD_UNIT") != "nginx.service" || r.Header.Get("Accept") != contentTypeJournalExport {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", contentTypeJournalExport)
+		io.WriteString(w, exportEntry("s=2", "a")+exportEntry("s=3", "b"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	cursorFile := filepath.Join(t.TempDir(), "cursor")
+	os.WriteFile(cursorFile, []byte("s=1\n"), 0o600)
+
+	uc := &mockIngestUseCase{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reader := NewReader(ReaderConfig{GatewayURL: srv.URL, Units: []string{"nginx.service"}, CursorFile: cursorFile}, uc, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reader.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		uc.mu.Lock()
+		n := len(uc.events)
+		uc.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 events, got %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if ranges[0] != "entries=s=1:1:" {
+		t.Errorf("expected request to resume after saved cursor, got Range %q", ranges[0])
+	}
+	data, _ := os.ReadFile(cursorFile)
+	if !bytes.Equal(bytes.TrimSpace(data), []byte("s=3")) {
+		t.Errorf("expected cursor s=3 to be persisted, got %q", data)
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// InstrumentNotifier wraps a notifier to count the notifications delivered on channel.
+func InstrumentNotifier(n domain.Notifier, channel string, m *IngestMetrics) domain.Notifier {
+	return &instrumentedNotifier{next: n, channel: channel, metrics: m}
+}
+
+type instrumentedNotifier struct {
+	next    domain.Notifier
+	channel string
+	metrics *IngestMetrics
+}
+
+func (n *instrumentedNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	err := n.next.Notify(ctx, notification)
+	status := "sent"
+	if err != nil {
+		status = "failed"
+	}
+	n.metrics.NotificationsTotal.WithLabelValues(n.channel, status).Inc()
+	return err
+}
+
+// InstrumentAlertQuery wraps the repository an alert reads the stream length from to
+// record its evaluations.
+func InstrumentAlertQuery(repo domain.StreamAdminRepository, alert string, m *IngestMetrics) domain.StreamAdminRepository {
+	return &instrumentedAlertQuery{StreamAdminRepository: repo, alert: alert, metrics: m}
+}
+
+type instrumentedAlertQuery struct {
+	domain.StreamAdminRepository
+	alert   string
+	metrics *IngestMetrics
+}
+
+func (q *instrumentedAlertQuery) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	start := time.Now()
+	length, err := q.StreamAdminRepository.GetStreamLength(ctx, stream)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	q.metrics.AlertEvaluationDuration.WithLabelValues(q.alert, status).Observe(time.Since(start).Seconds())
+	return length, err
+}
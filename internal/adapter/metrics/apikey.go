@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// OtherAPIKeys is the api_key label of the keys that are not labeled individually.
+const OtherAPIKeys = "other"
+
+// InstrumentAPIKeyUsage wraps the ingest use case to count the events and bytes it
+// accepts per API key. Only the digests in labeled get a series of their own, which
+// keeps the cardinality bounded; every other key is counted as OtherAPIKeys and events
+// without a key are not counted.
+func InstrumentAPIKeyUsage(next usecase.IngestLogUseCase, labeled []string, m *IngestMetrics) usecase.IngestLogUseCase {
+	keys := make(map[string]bool, len(labeled))
+	for _, keyHash := range labeled {
+		keys[strings.ToLower(strings.TrimSpace(keyHash))] = true
+	}
+	return &instrumentedAPIKeyUsage{next: next, labeled: keys, metrics: m}
+}
+
+type instrumentedAPIKeyUsage struct {
+	next    usecase.IngestLogUseCase
+	labeled map[string]bool
+	metrics *IngestMetrics
+}
+
+func (u *instrumentedAPIKeyUsage) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	// Measured before passing the event on, which may stitch or redact it.
+	keyHash, size := domain.APIKeyHashFromContext(ctx), len(event.Message)+len(event.Metadata)
+	if err := u.next.Ingest(ctx, event); err != nil || keyHash == "" {
+		return err
+	}
+	label := OtherAPIKeys
+	if u.labeled[keyHash] {
+		label = keyHash
+	}
+	u.metrics.APIKeyEventsTotal.WithLabelValues(label).Inc()
+	u.metrics.APIKeyBytesTotal.WithLabelValues(label).Add(float64(size))
+	return nil
+}
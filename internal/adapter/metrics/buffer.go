@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// InstrumentIngestBuffer wraps the ingest service's buffer to record how long buffering
+// each event takes.
+func InstrumentIngestBuffer(repo domain.LogRepository, m *IngestMetrics) domain.LogRepository {
+	return &instrumentedIngestBuffer{LogRepository: repo, metrics: m}
+}
+
+type instrumentedIngestBuffer struct {
+	domain.LogRepository
+	metrics *IngestMetrics
+}
+
+func (b *instrumentedIngestBuffer) BufferLog(ctx context.Context, event domain.LogEvent) error {
+	start := time.Now()
+	err := b.LogRepository.BufferLog(ctx, event)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	b.metrics.BufferDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+	return err
+}
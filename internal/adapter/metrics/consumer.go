@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ConsumerMetrics holds all Prometheus metrics for the consumer service.
+type ConsumerMetrics struct {
+	StreamLength      *prometheus.GaugeVec
+	GroupLag          *prometheus.GaugeVec
+	PendingMessages   *prometheus.GaugeVec
+	OldestPendingAge  *prometheus.GaugeVec
+	BatchSize         prometheus.Histogram
+	SinkWriteDuration *prometheus.HistogramVec
+	EventAge          prometheus.Histogram
+	SLOEventsTotal    *prometheus.CounterVec
+	DLQEventsTotal    *prometheus.CounterVec
+}
+
+// NewConsumerMetrics initializes the consumer's Prometheus metrics and registers them with reg.
+func NewConsumerMetrics(reg prometheus.Registerer) *ConsumerMetrics {
+	factory := promauto.With(reg)
+	return &ConsumerMetrics{
+		StreamLength: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "stream_length",
+			Help:      "Number of entries in each buffer stream.",
+		}, []string{"stream"}),
+		GroupLag: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "group_lag",
+			Help:      "Number of entries in each buffer stream not yet delivered to the consumer group.",
+		}, []string{"stream", "group"}),
+		PendingMessages: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "pending_messages",
+			Help:      "Number of entries delivered to the consumer group but not yet acknowledged.",
+		}, []string{"stream", "group"}),
+		OldestPendingAge: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "oldest_pending_age_seconds",
+			Help:      "Time since the oldest unacknowledged entry was added to the stream, 0 if none are pending.",
+		}, []string{"stream", "group"}),
+		BatchSize: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "batch_size",
+			Help:      "Number of events in each batch read from the buffer.",
+			Buckets:   []float64{1, 10, 50, 100, 250, 500, 1000},
+		}),
+		SinkWriteDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "sink_write_duration_seconds",
+			Help:      "Time taken by each batch write to the sink, by status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"status"}), // status: success, error
+		EventAge: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "event_age_seconds",
+			Help:      "Time from receiving each event to committing it to the sink, end to end.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+		}),
+		SLOEventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "slo_events_total",
+			Help:      "Total number of events counted towards each service level indicator of the consumer, by SLO and result (good or bad).",
+		}, []string{"slo", "result"}), // slo: delivery_latency
+		DLQEventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "consumer",
+			Name:      "dlq_events_total",
+			Help:      "Total number of events moved to the dead-letter queue, by cause.",
+		}, []string{"cause"}), // cause: sink_failure, rejected, max_deliveries
+	}
+}
+
+// InstrumentBuffer wraps the consumer's buffer to record batch sizes and DLQ pushes.
+func InstrumentBuffer(repo domain.LogRepository, m *ConsumerMetrics) domain.LogRepository {
+	return &instrumentedBuffer{LogRepository: repo, metrics: m}
+}
+
+type instrumentedBuffer struct {
+	domain.LogRepository
+	metrics *ConsumerMetrics
+}
+
+func (b *instrumentedBuffer) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
+	events, err := b.LogRepository.ReadLogBatch(ctx, group, consumer, count)
+	if len(events) > 0 {
+		b.metrics.BatchSize.Observe(float64(len(events)))
+	}
+	return events, err
+}
+
+func (b *instrumentedBuffer) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
+	err := b.LogRepository.MoveToDLQ(ctx, events, failure)
+	if err == nil {
+		b.metrics.DLQEventsTotal.WithLabelValues(failure.Cause).Add(float64(len(events)))
+	}
+	return err
+}
+
+// InstrumentSink wraps the consumer's sink to record how long batch writes take and how
+// old the events they commit are. Events committed within deliveryTarget of being
+// received are good for the delivery_latency SLO.
+func InstrumentSink(repo domain.LogRepository, m *ConsumerMetrics, deliveryTarget time.Duration) domain.LogRepository {
+	return &instrumentedSink{LogRepository: repo, metrics: m, deliveryTarget: deliveryTarget}
+}
+
+type instrumentedSink struct {
+	domain.LogRepository
+	metrics        *ConsumerMetrics
+	deliveryTarget time.Duration
+}
+
+func (s *instrumentedSink) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
+	start := time.Now()
+	err := s.LogRepository.WriteLogBatch(ctx, events)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	now := time.Now()
+	s.metrics.SinkWriteDuration.WithLabelValues(status).Observe(now.Sub(start).Seconds())
+	if err == nil {
+		for _, event := range events {
+			if event.ReceivedAt.IsZero() {
+				continue
+			}
+			age := now.Sub(event.ReceivedAt)
+			s.metrics.EventAge.Observe(age.Seconds())
+			s.metrics.SLOEventsTotal.WithLabelValues("delivery_latency", sloResult(age <= s.deliveryTarget)).Inc()
+		}
+	}
+	return err
+}
@@ -7,45 +7,218 @@ import (
 
 // IngestMetrics holds all Prometheus metrics for the ingest service.
 type IngestMetrics struct {
-	EventsTotal       *prometheus.CounterVec
-	BytesTotal        prometheus.Counter
-	WALActive         prometheus.Gauge
-	APIKeyCacheHits   prometheus.Counter
-	APIKeyCacheMisses prometheus.Counter
+	EventsTotal              *prometheus.CounterVec
+	BytesTotal               prometheus.Counter
+	APIKeyEventsTotal        *prometheus.CounterVec
+	APIKeyBytesTotal         *prometheus.CounterVec
+	RequestDuration          *prometheus.HistogramVec
+	ErrorsTotal              *prometheus.CounterVec
+	PIIRedactionsTotal       *prometheus.CounterVec
+	PipelineEventsTotal      *prometheus.CounterVec
+	BufferDuration           *prometheus.HistogramVec
+	SLOEventsTotal           *prometheus.CounterVec
+	DroppedTotal             *prometheus.CounterVec
+	WALActive                prometheus.Gauge
+	WALQuarantinedTotal      prometheus.Counter
+	WALSizeBytes             prometheus.Gauge
+	WALDroppedSegmentsTotal  prometheus.Counter
+	WALExpiredSegmentsTotal  prometheus.Counter
+	WALReplayedEventsTotal   prometheus.Counter
+	WALReplayedBytesTotal    prometheus.Counter
+	WALReplayErrorsTotal     prometheus.Counter
+	WALSegments              prometheus.Gauge
+	WALOldestEventTimestamp  prometheus.Gauge
+	StreamEvictedUnreadTotal prometheus.Counter
+	DuplicatesDroppedTotal   prometheus.Counter
+	APIKeyCacheHits          prometheus.Counter
+	APIKeyCacheMisses        prometheus.Counter
+	RateLimitedTotal         *prometheus.CounterVec
+	QuotaExceededTotal       *prometheus.CounterVec
+	NotificationsTotal       *prometheus.CounterVec
+	AlertEvaluationDuration  *prometheus.HistogramVec
 }
 
-// NewIngestMetrics initializes and registers the Prometheus metrics.
-func NewIngestMetrics() *IngestMetrics {
+// NewIngestMetrics initializes the Prometheus metrics and registers them with reg.
+// Services pass prometheus.DefaultRegisterer; tests pass a fresh registry.
+func NewIngestMetrics(reg prometheus.Registerer) *IngestMetrics {
+	factory := promauto.With(reg)
 	return &IngestMetrics{
-		EventsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+		EventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "log_ingestor",
 			Subsystem: "ingest",
 			Name:      "events_total",
 			Help:      "Total number of ingested events by status.",
-		}, []string{"status"}), // status: accepted, error_parse, error_size, error_buffer, error_media_type
-		BytesTotal: promauto.NewCounter(prometheus.CounterOpts{
+		}, []string{"status"}), // status: accepted, error_parse, error_size, error_buffer, error_quota, error_media_type, error_encoding
+		BytesTotal: factory.NewCounter(prometheus.CounterOpts{
 			Namespace: "log_ingestor",
 			Subsystem: "ingest",
 			Name:      "bytes_total",
 			Help:      "Total number of bytes ingested.",
 		}),
-		WALActive: promauto.NewGauge(prometheus.GaugeOpts{
+		APIKeyEventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "api_key_events_total",
+			Help:      "Total number of events accepted by API key digest; keys not in METRICS_API_KEY_LABELS are counted as \"other\".",
+		}, []string{"api_key"}),
+		APIKeyBytesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "api_key_bytes_total",
+			Help:      "Total number of message and metadata bytes accepted by API key digest, labeled like api_key_events_total.",
+		}, []string{"api_key"}),
+		RequestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle each HTTP request, by route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		ErrorsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "api_errors_total",
+			Help:      "Total number of HTTP error responses, by error code.",
+		}, []string{"code"}),
+		PIIRedactionsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "pii_redactions_total",
+			Help:      "Total number of values redacted as PII, by detector, or by \"pattern\" and \"field\" for the other rules.",
+		}, []string{"detector"}),
+		PipelineEventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "pipeline_events_total",
+			Help:      "Total number of events run through the ingest pipeline, by decision (kept, sampled or dropped) and the rule that sampled or dropped them.",
+		}, []string{"decision", "rule"}),
+		BufferDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "buffer_duration_seconds",
+			Help:      "Time taken to buffer each event, including any WAL fallback, by status.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"status"}), // status: success, error
+		SLOEventsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "slo",
+			Name:      "events_total",
+			Help:      "Total number of events counted towards each service level indicator, by SLO and result (good or bad).",
+		}, []string{"slo", "result"}), // slo: availability, ingest_latency
+		DroppedTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "dropped_total",
+			Help:      "Total number of events dropped without being parsed, by listener and reason.",
+		}, []string{"listener", "reason"}), // reason: queue_full, too_large
+		WALActive: factory.NewGauge(prometheus.GaugeOpts{
 			Namespace: "log_ingestor",
 			Subsystem: "ingest",
 			Name:      "wal_active_gauge",
 			Help:      "Indicates if the Write-Ahead Log is currently active (1 for active, 0 for inactive).",
 		}),
-		APIKeyCacheHits: promauto.NewCounter(prometheus.CounterOpts{
+		WALQuarantinedTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_quarantined_segments_total",
+			Help:      "Total number of WAL segments moved to quarantine because of corrupt records.",
+		}),
+		WALSizeBytes: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_size_bytes",
+			Help:      "Total size of the Write-Ahead Log segments on disk.",
+		}),
+		WALDroppedSegmentsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_dropped_segments_total",
+			Help:      "Total number of WAL segments deleted by the drop_oldest backpressure policy.",
+		}),
+		WALExpiredSegmentsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_expired_segments_total",
+			Help:      "Total number of WAL segments deleted unreplayed because they exceeded WAL_RETENTION.",
+		}),
+		WALReplayedEventsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_replayed_events_total",
+			Help:      "Total number of events replayed from the WAL into the buffer.",
+		}),
+		WALReplayedBytesTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_replayed_bytes_total",
+			Help:      "Total number of payload bytes replayed from the WAL into the buffer.",
+		}),
+		WALReplayErrorsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_replay_errors_total",
+			Help:      "Total number of WAL replays that stopped because the buffer rejected events.",
+		}),
+		WALSegments: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_segments",
+			Help:      "Number of WAL segments on disk, excluding quarantined ones.",
+		}),
+		WALOldestEventTimestamp: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "wal_oldest_event_timestamp_seconds",
+			Help:      "Receive time of the oldest event waiting in the WAL as a Unix timestamp, 0 if the WAL is empty. Its age is time() minus this value.",
+		}),
+		StreamEvictedUnreadTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "stream_evicted_unread_total",
+			Help:      "Total number of Redis stream entries trimmed before the consumer group read them. Every replica reports the same total.",
+		}),
+		DuplicatesDroppedTotal: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "duplicates_dropped_total",
+			Help:      "Total number of events dropped because their event ID was buffered within REDIS_DEDUP_WINDOW.",
+		}),
+		APIKeyCacheHits: factory.NewCounter(prometheus.CounterOpts{
 			Namespace: "log_ingestor",
 			Subsystem: "auth",
 			Name:      "api_key_cache_hits_total",
 			Help:      "Total number of API key cache hits.",
 		}),
-		APIKeyCacheMisses: promauto.NewCounter(prometheus.CounterOpts{
+		APIKeyCacheMisses: factory.NewCounter(prometheus.CounterOpts{
 			Namespace: "log_ingestor",
 			Subsystem: "auth",
 			Name:      "api_key_cache_misses_total",
 			Help:      "Total number of API key cache misses.",
 		}),
+		RateLimitedTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "rate_limited_total",
+			Help:      "Total number of requests rejected with 429 by the rate limiter, by tier.",
+		}, []string{"tier"}),
+		QuotaExceededTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "ingest",
+			Name:      "quota_exceeded_total",
+			Help:      "Total number of requests from API keys over their daily quota, by level (soft: warned, hard: rejected with 429).",
+		}, []string{"level"}),
+		NotificationsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "notifier",
+			Name:      "notifications_total",
+			Help:      "Total number of operator notifications by channel and status (sent or failed, after retries).",
+		}, []string{"channel", "status"}),
+		AlertEvaluationDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "log_ingestor",
+			Subsystem: "alert",
+			Name:      "evaluation_duration_seconds",
+			Help:      "Time taken by the query of each alert evaluation, by alert and status; the count is the number of evaluations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"alert", "status"}), // status: success, error
 	}
 }
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+// InstrumentPipeline wraps the ingest pipeline to count the decisions it takes, by rule.
+func InstrumentPipeline(p usecase.EventPipeline, m *IngestMetrics) usecase.EventPipeline {
+	return &instrumentedPipeline{next: p, metrics: m}
+}
+
+type instrumentedPipeline struct {
+	next    usecase.EventPipeline
+	metrics *IngestMetrics
+}
+
+func (p *instrumentedPipeline) Process(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision) {
+	event, decision := p.next.Process(event)
+	p.metrics.PipelineEventsTotal.WithLabelValues(decision.Action, decision.Rule).Inc()
+	return event, decision
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+func sloResult(good bool) string {
+	if good {
+		return "good"
+	}
+	return "bad"
+}
+
+// InstrumentSLO wraps the ingest use case to count its events towards the availability
+// and ingest_latency SLOs. An event is available unless the service failed to accept
+// it; events rejected for the client's own reasons, over quota, while draining or after
+// the client went away do not count. Accepted events are fast if they were buffered
+// within latencyTarget.
+func InstrumentSLO(next usecase.IngestLogUseCase, latencyTarget time.Duration, m *IngestMetrics) usecase.IngestLogUseCase {
+	return &instrumentedSLO{next: next, latencyTarget: latencyTarget, metrics: m}
+}
+
+type instrumentedSLO struct {
+	next          usecase.IngestLogUseCase
+	latencyTarget time.Duration
+	metrics       *IngestMetrics
+}
+
+func (s *instrumentedSLO) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	start := time.Now()
+	err := s.next.Ingest(ctx, event)
+	switch {
+	case errors.Is(err, domain.ErrQuotaExceeded), errors.Is(err, usecase.ErrDraining), errors.Is(err, context.Canceled):
+		return err
+	case err != nil:
+		s.metrics.SLOEventsTotal.WithLabelValues("availability", "bad").Inc()
+		return err
+	}
+	s.metrics.SLOEventsTotal.WithLabelValues("availability", "good").Inc()
+	s.metrics.SLOEventsTotal.WithLabelValues("ingest_latency", sloResult(time.Since(start) <= s.latencyTarget)).Inc()
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package normalize gives events a time and a level in canonical form, whatever their
+// sources sent: the event time is parsed from the payload when the event has none and
+// kept within a skew of when it was received, and levels are mapped onto domain.Level*.
+package normalize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Policies for event times out of the accepted range.
+const (
+	SkewClamp  = "clamp"  // Moves the event time to the nearest bound of the range.
+	SkewReject = "reject" // Rejects the event with domain.ErrEventTimeOutOfRange.
+)
+
+// Config configures a Normalizer.
+type Config struct {
+	TimeFields []string      // Metadata fields the event time is parsed from, in order, when the event has none.
+	MaxPast    time.Duration // How long before it was received an event may have happened; 0 is unbounded.
+	MaxFuture  time.Duration // How long after, for senders with skewed clocks; 0 is unbounded.
+	SkewPolicy string        // SkewClamp or SkewReject.
+	Levels     bool          // Maps levels onto the canonical ones, see Level.
+}
+
+// Normalizer normalizes the time and level of events.
+type Normalizer struct {
+	cfg Config
+}
+
+// New creates a new Normalizer.
+func New(cfg Config) (*Normalizer, error) {
+	if cfg.SkewPolicy != SkewClamp && cfg.SkewPolicy != SkewReject {
+		return nil, fmt.Errorf("unknown event time skew policy %q", cfg.SkewPolicy)
+	}
+	if cfg.MaxPast < 0 || cfg.MaxFuture < 0 {
+		return nil, fmt.Errorf("event time skews must not be negative")
+	}
+	return &Normalizer{cfg: cfg}, nil
+}
+
+// Normalize normalizes an event received at receivedAt. Events without a time keep none
+// if no time field holds one; unrecognized levels are left as they are.
+func (n *Normalizer) Normalize(event *domain.LogEvent, receivedAt time.Time) error {
+	if n.cfg.Levels && event.Level != "" {
+		if level, ok := Level(event.Level); ok {
+			event.Level = level
+		}
+	}
+
+	if event.EventTime.IsZero() {
+		event.EventTime = n.timeFromMetadata(event.Metadata)
+		if event.EventTime.IsZero() {
+			return nil
+		}
+	}
+	if n.cfg.MaxPast > 0 {
+		if earliest := receivedAt.Add(-n.cfg.MaxPast); event.EventTime.Before(earliest) {
+			if n.cfg.SkewPolicy == SkewReject {
+				return fmt.Errorf("%w: %s is more than %s before it was received", domain.ErrEventTimeOutOfRange, event.EventTime.Format(time.RFC3339), n.cfg.MaxPast)
+			}
+			event.EventTime = earliest
+		}
+	}
+	if n.cfg.MaxFuture > 0 {
+		if latest := receivedAt.Add(n.cfg.MaxFuture); event.EventTime.After(latest) {
+			if n.cfg.SkewPolicy == SkewReject {
+				return fmt.Errorf("%w: %s is more than %s after it was received", domain.ErrEventTimeOutOfRange, event.EventTime.Format(time.RFC3339), n.cfg.MaxFuture)
+			}
+			event.EventTime = latest
+		}
+	}
+	return nil
+}
+
+// timeFromMetadata returns the time held by the first time field that has one, or the
+// zero time.
+func (n *Normalizer) timeFromMetadata(metadata json.RawMessage) time.Time {
+	if len(n.cfg.TimeFields) == 0 || len(metadata) == 0 {
+		return time.Time{}
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &fields); err != nil {
+		return time.Time{}
+	}
+	for _, name := range n.cfg.TimeFields {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		// Numbers are decoded as such, so that nanosecond epochs keep their precision.
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			continue
+		}
+		if ts, ok := textparser.ParseTime(v, ""); ok {
+			return ts
+		}
+	}
+	return time.Time{}
+}
+
+// levelNames maps the spellings of levels used by common logging libraries, lowercased,
+// onto the canonical levels.
+var levelNames = map[string]string{
+	"trace": domain.LevelTrace, "trc": domain.LevelTrace, "t": domain.LevelTrace, "verbose": domain.LevelTrace, "v": domain.LevelTrace, "finest": domain.LevelTrace, "finer": domain.LevelTrace,
+	"debug": domain.LevelDebug, "dbg": domain.LevelDebug, "d": domain.LevelDebug, "fine": domain.LevelDebug, "config": domain.LevelDebug,
+	"info": domain.LevelInfo, "inf": domain.LevelInfo, "i": domain.LevelInfo, "information": domain.LevelInfo, "informational": domain.LevelInfo, "notice": domain.LevelInfo, "log": domain.LevelInfo,
+	"warn": domain.LevelWarn, "warning": domain.LevelWarn, "wrn": domain.LevelWarn, "w": domain.LevelWarn,
+	"error": domain.LevelError, "err": domain.LevelError, "e": domain.LevelError, "severe": domain.LevelError,
+	"fatal": domain.LevelFatal, "ftl": domain.LevelFatal, "f": domain.LevelFatal, "critical": domain.LevelFatal, "crit": domain.LevelFatal,
+	"alert": domain.LevelFatal, "emerg": domain.LevelFatal, "emergency": domain.LevelFatal, "panic": domain.LevelFatal,
+}
+
+// syslogLevels maps syslog severities, 0 (emergency) to 7 (debug), onto the canonical levels.
+var syslogLevels = [8]string{domain.LevelFatal, domain.LevelFatal, domain.LevelFatal, domain.LevelError, domain.LevelWarn, domain.LevelInfo, domain.LevelInfo, domain.LevelDebug}
+
+// Level maps a level onto the canonical ones, reporting false if it does not recognize
+// it. Levels are names in any case, or numbers: syslog severities from 0 to 7, and the
+// levels of bunyan and pino from 10 (trace) to 60 (fatal).
+func Level(level string) (string, bool) {
+	s := strings.ToLower(strings.TrimSpace(level))
+	if l, ok := levelNames[s]; ok {
+		return l, true
+	}
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil || n < 0 || n == 8 || n == 9:
+		return level, false
+	case n < len(syslogLevels):
+		return syslogLevels[n], true
+	case n < 20:
+		return domain.LevelTrace, true
+	case n < 30:
+		return domain.LevelDebug, true
+	case n < 40:
+		return domain.LevelInfo, true
+	case n < 50:
+		return domain.LevelWarn, true
+	case n < 60:
+		return domain.LevelError, true
+	}
+	return domain.LevelFatal, true
+}
@@ -0,0 +1,76 @@
+package normalize
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestLevel(t *testing.T) {
+	for level, want := range map[string]string{
+		"WARN": "warn", "warning": "warn", "Information": "info", "CRIT": "fatal", "E": "error",
+		"30": "info", "50": "error", "60": "fatal", "10": "trace", "3": "error", "7": "debug",
+	} {
+		if got, ok := Level(level); !ok || got != want {
+			t.Errorf("%q: expected %q, got %q (ok=%v)", level, want, got, ok)
+		}
+	}
+	for _, level := range []string{"loud", "8", "-1"} {
+		if got, ok := Level(level); ok || got != level {
+			t.Errorf("%q: expected it unrecognized and kept, got %q (ok=%v)", level, got, ok)
+		}
+	}
+}
+
+func TestNormalizeTime(t *testing.T) {
+	received := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	n, err := New(Config{TimeFields: []string{"timestamp", "ts"}, MaxPast: 24 * time.Hour, MaxFuture: 5 * time.Minute, SkewPolicy: SkewClamp, Levels: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		metadata string
+		time     time.Time
+		want     time.Time
+	}{
+		{"RFC3339", `{"timestamp":"2024-05-01T11:30:00.5Z"}`, time.Time{}, time.Date(2024, 5, 1, 11, 30, 0, 5e8, time.UTC)},
+		{"Epoch millis", `{"ts":1714564200000}`, time.Time{}, time.Date(2024, 5, 1, 11, 50, 0, 0, time.UTC)},
+		{"Epoch nanos", `{"ts":1714564200000000123}`, time.Time{}, time.Date(2024, 5, 1, 11, 50, 0, 123, time.UTC)},
+		{"First field wins", `{"ts":"nonsense","timestamp":"2024-05-01 11:00:00"}`, time.Time{}, time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)},
+		{"Event time kept", `{"timestamp":"2024-05-01T11:30:00Z"}`, received.Add(-time.Hour), received.Add(-time.Hour)},
+		{"No time", `{"other":"2024-05-01T11:30:00Z"}`, time.Time{}, time.Time{}},
+		{"Future clamped", ``, received.Add(time.Hour), received.Add(5 * time.Minute)},
+		{"Past clamped", ``, received.AddDate(-1, 0, 0), received.Add(-24 * time.Hour)},
+	}
+	for _, tt := range tests {
+		event := domain.LogEvent{EventTime: tt.time, Metadata: []byte(tt.metadata)}
+		if err := n.Normalize(&event, received); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if !event.EventTime.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, event.EventTime)
+		}
+	}
+
+	n.cfg.SkewPolicy = SkewReject
+	event := domain.LogEvent{EventTime: received.Add(time.Hour), Level: "WARNING"}
+	if err := n.Normalize(&event, received); !errors.Is(err, domain.ErrEventTimeOutOfRange) {
+		t.Errorf("expected ErrEventTimeOutOfRange, got %v", err)
+	}
+	if event.Level != domain.LevelWarn {
+		t.Errorf("expected the level normalized, got %q", event.Level)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{SkewPolicy: "ignore"}); err == nil {
+		t.Error("expected an error for an unknown skew policy")
+	}
+	if _, err := New(Config{SkewPolicy: SkewClamp, MaxPast: -time.Hour}); err == nil {
+		t.Error("expected an error for a negative skew")
+	}
+}
@@ -0,0 +1,95 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// EmailNotifier implements domain.Notifier by sending a plain-text email through an SMTP
+// server. The connection is upgraded with STARTTLS when the server offers it, which it
+// must before credentials are sent.
+type EmailNotifier struct {
+	addr string // host:port of the SMTP server.
+	auth smtp.Auth
+	from string
+	to   []string
+	body *Template // Nil uses the message, link and labels.
+}
+
+// NewEmailNotifier creates a new EmailNotifier. An empty username sends mail without
+// authenticating.
+func NewEmailNotifier(addr, username, password, from string, to []string) *EmailNotifier {
+	n := &EmailNotifier{addr: addr, from: from, to: to}
+	if username != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// UseTemplate renders the body of the email with t.
+func (n *EmailNotifier) UseTemplate(t *Template) {
+	n.body = t
+}
+
+// Notify sends the notification to every recipient. smtp.SendMail has no context, so
+// ctx is only checked before sending.
+func (n *EmailNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := n.message(notification)
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(n.addr, n.auth, n.from, n.to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (n *EmailNotifier) message(notification domain.Notification) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&b, "Subject: [%s] %s\r\n", notification.Severity, notification.Title)
+	fmt.Fprintf(&b, "Date: %s\r\n", notification.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	if n.body != nil {
+		text, err := n.body.render(notification)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(text)
+		return b.Bytes(), nil
+	}
+	b.WriteString(notification.Message + "\r\n")
+	if notification.Link != "" {
+		b.WriteString("\r\n" + notification.Link + "\r\n")
+	}
+	if len(notification.Labels) > 0 {
+		keys := make([]string, 0, len(notification.Labels))
+		for k := range notification.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\r\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, notification.Labels[k])
+		}
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Notification channels.
+const (
+	ChannelWebhook   = "webhook"
+	ChannelSlack     = "slack"
+	ChannelEmail     = "email"
+	ChannelPagerDuty = "pagerduty"
+)
+
+// Config selects a channel and holds the settings it needs.
+type Config struct {
+	Channel       string // One of the Channel constants.
+	WebhookURL    string // webhook and slack.
+	WebhookSecret string // webhook: signs requests; empty leaves them unsigned.
+	SMTPAddr      string // email: host:port of the SMTP server.
+	SMTPUsername  string
+	SMTPPassword  string
+	EmailFrom     string
+	EmailTo       []string
+	PagerDutyURL  string // pagerduty: defaults to PagerDutyEventsURL.
+	PagerDutyKey  string // pagerduty: integration routing key.
+	Template      string // webhook, slack and email: text/template for the text, see Template; empty uses the built-in text.
+	Timeout       time.Duration
+}
+
+// New creates the notifier of the configured channel. It returns nil when the channel's
+// destination (webhook URL, SMTP server or routing key) is not set, which disables
+// notifications.
+func New(cfg Config) (domain.Notifier, error) {
+	var text *Template
+	if cfg.Template != "" {
+		var err error
+		if text, err = ParseTemplate(cfg.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.Channel {
+	case ChannelWebhook, ChannelSlack:
+		if cfg.WebhookURL == "" {
+			return nil, nil
+		}
+		secret := cfg.WebhookSecret
+		if cfg.Channel == ChannelSlack {
+			secret = "" // Slack authenticates by the secret URL and ignores signatures.
+		}
+		n := NewWebhookNotifier(cfg.WebhookURL, secret, cfg.Timeout)
+		n.UseTemplate(text)
+		return n, nil
+	case ChannelEmail:
+		if cfg.SMTPAddr == "" {
+			return nil, nil
+		}
+		if cfg.EmailFrom == "" || len(cfg.EmailTo) == 0 {
+			return nil, errors.New("email notifications need a sender and at least one recipient")
+		}
+		n := NewEmailNotifier(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom, cfg.EmailTo)
+		n.UseTemplate(text)
+		return n, nil
+	case ChannelPagerDuty:
+		if cfg.PagerDutyKey == "" {
+			return nil, nil
+		}
+		url := cfg.PagerDutyURL
+		if url == "" {
+			url = PagerDutyEventsURL
+		}
+		return NewPagerDutyNotifier(url, cfg.PagerDutyKey, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel %q", cfg.Channel)
+	}
+}
+
+// WithRetry wraps a notifier to retry failed deliveries up to retries more times,
+// doubling the wait from backoff after each attempt.
+func WithRetry(n domain.Notifier, retries int, backoff time.Duration) domain.Notifier {
+	return &retryingNotifier{next: n, retries: retries, backoff: backoff}
+}
+
+type retryingNotifier struct {
+	next    domain.Notifier
+	retries int
+	backoff time.Duration
+}
+
+func (r *retryingNotifier) Notify(ctx context.Context, n domain.Notification) error {
+	delay := r.backoff
+	for attempt := 0; ; attempt++ {
+		err := r.next.Notify(ctx, n)
+		if err == nil || attempt >= r.retries {
+			return err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+	}
+}
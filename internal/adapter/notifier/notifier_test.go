@@ -0,0 +1,118 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestWebhookNotifierSignsRequests(t *testing.T) {
+	var timestamp, signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamp = r.Header.Get("X-Watchtower-Timestamp")
+		signature = r.Header.Get("X-Watchtower-Signature")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(server.URL, "secret", time.Second)
+	if err := n.Notify(context.Background(), domain.Notification{Title: "DLQ", Severity: domain.SeverityCritical}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if timestamp == "" || signature != "sha256="+sign([]byte("secret"), timestamp, body) {
+		t.Errorf("expected a valid signature, got %q for timestamp %q", signature, timestamp)
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []pagerDutyEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	n := NewPagerDutyNotifier(server.URL, "key", time.Second)
+	for _, severity := range []string{domain.SeverityCritical, domain.SeverityResolved} {
+		if err := n.Notify(context.Background(), domain.Notification{Title: "DLQ", Message: "deep", Severity: severity}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if len(events) != 2 || events[0].EventAction != "trigger" || events[1].EventAction != "resolve" {
+		t.Fatalf("expected a trigger and a resolve, got %+v", events)
+	}
+	if events[0].DedupKey != events[1].DedupKey || events[0].Payload.Severity != "critical" {
+		t.Errorf("expected a critical incident resolved by the same key, got %+v", events)
+	}
+}
+
+type flakyNotifier struct {
+	failures int
+	calls    int
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	n.calls++
+	if n.calls <= n.failures {
+		return errors.New("channel down")
+	}
+	return nil
+}
+
+func TestWithRetry(t *testing.T) {
+	flaky := &flakyNotifier{failures: 2}
+	if err := WithRetry(flaky, 2, time.Millisecond).Notify(context.Background(), domain.Notification{}); err != nil || flaky.calls != 3 {
+		t.Errorf("expected delivery on the third attempt, got %d calls, err %v", flaky.calls, err)
+	}
+
+	flaky = &flakyNotifier{failures: 5}
+	if err := WithRetry(flaky, 2, time.Millisecond).Notify(context.Background(), domain.Notification{}); err == nil || flaky.calls != 3 {
+		t.Errorf("expected failure after 3 attempts, got %d calls, err %v", flaky.calls, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if n, err := New(Config{Channel: ChannelWebhook}); n != nil || err != nil {
+		t.Errorf("expected a webhook without URL to disable notifications, got %v, %v", n, err)
+	}
+	if _, err := New(Config{Channel: ChannelEmail, SMTPAddr: "smtp.example.com:587"}); err == nil {
+		t.Error("expected an error for email without recipients")
+	}
+	if _, err := New(Config{Channel: "sms"}); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	n, err := New(Config{Channel: ChannelSlack, WebhookURL: server.URL, Template: "{{.Severity}}: depth {{.Labels.depth}} see {{.Link}}", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	notification := domain.Notification{Severity: domain.SeverityCritical, Link: "https://runbook", Labels: map[string]string{"depth": "42"}}
+	if err := n.Notify(context.Background(), notification); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := "critical: depth 42 see https://runbook"; payload.Text != want {
+		t.Errorf("expected text %q, got %q", want, payload.Text)
+	}
+
+	if _, err := ParseTemplate("{{.Unknown}}"); err == nil {
+		t.Error("expected an error for a template referring to an unknown field")
+	}
+}
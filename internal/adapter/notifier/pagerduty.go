@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyNotifier implements domain.Notifier with the PagerDuty Events API v2. Warning
+// and critical notifications trigger an incident; resolved ones resolve it. Notifications
+// with the same title share an incident, so the resolution closes the incident its alert
+// opened.
+type PagerDutyNotifier struct {
+	url        string
+	routingKey string
+	client     *http.Client
+}
+
+// NewPagerDutyNotifier creates a new PagerDutyNotifier for the integration's routing key.
+func NewPagerDutyNotifier(url, routingKey string, timeout time.Duration) *PagerDutyNotifier {
+	return &PagerDutyNotifier{
+		url:        url,
+		routingKey: routingKey,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notify sends the notification as a trigger or resolve event.
+func (n *PagerDutyNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	event := pagerDutyEvent{
+		RoutingKey:  n.routingKey,
+		EventAction: "trigger",
+		DedupKey:    notification.Title,
+	}
+	if notification.Severity == domain.SeverityResolved {
+		event.EventAction = "resolve"
+	} else {
+		severity := "warning"
+		if notification.Severity == domain.SeverityCritical {
+			severity = "critical"
+		}
+		event.Payload = &pagerDutyPayload{
+			Summary:       notification.Title + ": " + notification.Message,
+			Source:        "watch-tower",
+			Severity:      severity,
+			Timestamp:     notification.Timestamp.Format(time.RFC3339),
+			CustomDetails: notification.Labels,
+		}
+		if notification.Link != "" {
+			event.Links = []pagerDutyLink{{Href: notification.Link, Text: notification.Title}}
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create pagerduty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("pagerduty returned unexpected status: %s: %s", resp.Status, msg)
+	}
+	return nil
+}
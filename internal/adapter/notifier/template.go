@@ -0,0 +1,47 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Template renders the text of a notification from a text/template, in place of the
+// built-in "[severity] title: message" format. The template is executed with the
+// domain.Notification, e.g.
+//
+//	{{.Severity}}: {{.Message}} (depth {{.Labels.depth}}) runbook: https://wiki/dlq {{.Link}}
+type Template struct {
+	tmpl *template.Template
+}
+
+// ParseTemplate parses a notification template and renders a sample notification with
+// it, so that templates referring to unknown fields fail at startup rather than when an
+// alert fires.
+func ParseTemplate(s string) (*Template, error) {
+	tmpl, err := template.New("notification").Option("missingkey=zero").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	t := &Template{tmpl: tmpl}
+	sample := domain.Notification{Title: "title", Message: "message", Severity: domain.SeverityWarning, Labels: map[string]string{}, Timestamp: time.Now()}
+	if _, err := t.render(sample); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// render returns the text of a notification. A nil Template uses the built-in format.
+func (t *Template) render(n domain.Notification) (string, error) {
+	if t == nil {
+		return formatText(n), nil
+	}
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, n); err != nil {
+		return "", fmt.Errorf("failed to render notification template: %w", err)
+	}
+	return b.String(), nil
+}
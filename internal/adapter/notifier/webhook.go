@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// WebhookNotifier implements domain.Notifier by POSTing the notification as JSON to a URL.
+// A "text" field is included so Slack-compatible incoming webhooks render it directly.
+//
+// With a secret, each request carries an X-Watchtower-Timestamp header and an
+// X-Watchtower-Signature header of the form "sha256=<hex>", the HMAC-SHA256 of the
+// timestamp, a ".", and the body. Receivers recompute it to verify the sender and reject
+// stale timestamps to prevent replays.
+type WebhookNotifier struct {
+	url    string
+	secret []byte
+	text   *Template // Nil uses the built-in text.
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a new WebhookNotifier. An empty secret sends unsigned requests.
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookPayload struct {
+	domain.Notification
+	Text string `json:"text"`
+}
+
+// UseTemplate renders the "text" field with t.
+func (n *WebhookNotifier) UseTemplate(t *Template) {
+	n.text = t
+}
+
+// Notify sends the notification to the configured webhook.
+func (n *WebhookNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	text, err := n.text.render(notification)
+	if err != nil {
+		return err
+	}
+	payload := webhookPayload{
+		Notification: notification,
+		Text:         text,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Watchtower-Timestamp", timestamp)
+		req.Header.Set("X-Watchtower-Signature", "sha256="+sign(n.secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of timestamp + "." + body.
+func sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func formatText(n domain.Notification) string {
+	text := fmt.Sprintf("[%s] %s: %s", n.Severity, n.Title, n.Message)
+	if n.Link != "" {
+		text += "\n" + n.Link
+	}
+	return text
+}
@@ -0,0 +1,105 @@
+package pii
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// compiledPolicy is a PII policy ready to be applied.
+type compiledPolicy struct {
+	updatedAt time.Time
+	redactor  *Redactor
+}
+
+// PolicyRedactor redacts the events of API keys with a PII policy of their own by that
+// policy, and every other event with the global Redactor. Policies are loaded from a
+// repository by Reload, which Run repeats so that changes made on other replicas are
+// picked up.
+type PolicyRedactor struct {
+	global  *Redactor
+	repo    domain.PIIPolicyRepository
+	hashKey []byte
+	logger  *slog.Logger
+
+	policies atomic.Pointer[map[string]compiledPolicy] // By key digest.
+}
+
+// NewPolicyRedactor creates a PolicyRedactor falling back to global. Policies hash with
+// the HMAC key hashKey. Until the first Reload, every event is redacted by global.
+func NewPolicyRedactor(global *Redactor, repo domain.PIIPolicyRepository, hashKey []byte, logger *slog.Logger) *PolicyRedactor {
+	p := &PolicyRedactor{global: global, repo: repo, hashKey: hashKey, logger: logger.With("component", "pii_policies")}
+	p.policies.Store(&map[string]compiledPolicy{})
+	return p
+}
+
+// Redact redacts the event by the policy of its API key (see domain.LogEvent.APIKeyHash)
+// or, if it has none, by the global rules.
+func (p *PolicyRedactor) Redact(event *domain.LogEvent) error {
+	if event.APIKeyHash != "" {
+		if policy, ok := (*p.policies.Load())[event.APIKeyHash]; ok {
+			return policy.redactor.Redact(event)
+		}
+	}
+	return p.global.Redact(event)
+}
+
+// Validate reports whether rules would compile as a policy, wrapping
+// domain.ErrInvalidPIIPolicy if not.
+func (p *PolicyRedactor) Validate(rules []domain.PIIRule) error {
+	if _, err := p.global.withRules(rules, p.hashKey); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidPIIPolicy, err)
+	}
+	return nil
+}
+
+// Reload loads the policies from the repository, compiling only those changed since the
+// last reload. A stored policy that does not compile is logged and keeps its previous
+// version, or the global rules if it has none, rather than leaving its events unredacted.
+func (p *PolicyRedactor) Reload(ctx context.Context) error {
+	stored, err := p.repo.ListPIIPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := *p.policies.Load()
+	policies := make(map[string]compiledPolicy, len(stored))
+	for _, policy := range stored {
+		if c, ok := current[policy.KeyHash]; ok && c.updatedAt.Equal(policy.UpdatedAt) {
+			policies[policy.KeyHash] = c
+			continue
+		}
+		redactor, err := p.global.withRules(policy.Rules, p.hashKey)
+		if err != nil {
+			p.logger.Error("failed to compile PII policy", "key_hash", policy.KeyHash[:min(16, len(policy.KeyHash))], "error", err)
+			if c, ok := current[policy.KeyHash]; ok {
+				policies[policy.KeyHash] = c
+			}
+			continue
+		}
+		policies[policy.KeyHash] = compiledPolicy{updatedAt: policy.UpdatedAt, redactor: redactor}
+	}
+	p.policies.Store(&policies)
+	return nil
+}
+
+// Run reloads the policies every interval until the context is cancelled.
+func (p *PolicyRedactor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Reload(ctx); err != nil {
+				p.logger.Error("failed to reload PII policies", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pii
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakePIIPolicyRepository struct {
+	policies map[string]domain.PIIPolicy
+}
+
+func (f *fakePIIPolicyRepository) ListPIIPolicies(ctx context.Context) ([]domain.PIIPolicy, error) {
+	var policies []domain.PIIPolicy
+	for _, p := range f.policies {
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+func (f *fakePIIPolicyRepository) GetPIIPolicy(ctx context.Context, keyHash string) (*domain.PIIPolicy, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakePIIPolicyRepository) PutPIIPolicy(ctx context.Context, policy domain.PIIPolicy) (*domain.PIIPolicy, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakePIIPolicyRepository) DeletePIIPolicy(ctx context.Context, keyHash string) error {
+	return errors.New("not implemented")
+}
+
+func TestPolicyRedactor(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	repo := &fakePIIPolicyRepository{policies: map[string]domain.PIIPolicy{
+		"strict": {KeyHash: "strict", Rules: []domain.PIIRule{{Name: "ip", Fields: []string{"ip"}}}, UpdatedAt: time.Unix(1, 0)},
+	}}
+	p := NewPolicyRedactor(NewRedactor([]string{"email"}, logger), repo, nil, logger)
+
+	redact := func(keyHash string) string {
+		event := &domain.LogEvent{APIKeyHash: keyHash, Metadata: json.RawMessage(`{"email":"a@example.com","ip":"10.0.0.1"}`)}
+		if err := p.Redact(event); err != nil {
+			t.Fatalf("Redact() error = %v", err)
+		}
+		return string(event.Metadata)
+	}
+
+	global := `{"email":"[REDACTED]","ip":"10.0.0.1"}`
+	if got := redact("strict"); got != global {
+		t.Errorf("expected the global rules before the first reload, got %s", got)
+	}
+	if err := p.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got, want := redact("strict"), `{"email":"a@example.com","ip":"[REDACTED]"}`; got != want {
+		t.Errorf("expected the key's policy to replace the global rules, got %s, want %s", got, want)
+	}
+	if got := redact("other"); got != global {
+		t.Errorf("expected the global rules for a key without a policy, got %s", got)
+	}
+
+	// A stored policy that no longer compiles keeps its previous version.
+	repo.policies["strict"] = domain.PIIPolicy{KeyHash: "strict", Rules: []domain.PIIRule{{Name: "bad", Pattern: "("}}, UpdatedAt: time.Unix(2, 0)}
+	p.Reload(context.Background())
+	if got := redact("strict"); got != `{"email":"a@example.com","ip":"[REDACTED]"}` {
+		t.Errorf("expected the previous policy kept, got %s", got)
+	}
+
+	delete(repo.policies, "strict")
+	p.Reload(context.Background())
+	if got := redact("strict"); got != global {
+		t.Errorf("expected the global rules after the policy was deleted, got %s", got)
+	}
+}
+
+func TestPolicyRedactor_Validate(t *testing.T) {
+	p := NewPolicyRedactor(NewRedactor(nil, slog.Default()), &fakePIIPolicyRepository{}, nil, slog.Default())
+	if err := p.Validate([]domain.PIIRule{{Name: "bad", Pattern: "("}}); !errors.Is(err, domain.ErrInvalidPIIPolicy) {
+		t.Errorf("expected ErrInvalidPIIPolicy, got %v", err)
+	}
+	if err := p.Validate([]domain.PIIRule{{Name: "email", Detector: DetectorEmail}}); err != nil {
+		t.Errorf("expected a valid policy, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"log/slog"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/V4T54L/watch-tower/internal/domain"
 )
 
@@ -11,54 +13,194 @@ const RedactedPlaceholder = "[REDACTED]"
 
 // Redactor is responsible for redacting sensitive information from log events.
 type Redactor struct {
-	fieldsToRedact map[string]struct{} // Use a map for O(1) lookups
-	logger         *slog.Logger
+	fieldRules   []*compiledRule // Without a pattern.
+	patternRules []*compiledRule
+	dropRawEvent bool
+	hits         *prometheus.CounterVec // By rule kind; nil disables.
+	logger       *slog.Logger
 }
 
 // NewRedactor creates a new Redactor instance with a given set of fields to redact.
 func NewRedactor(fields []string, logger *slog.Logger) *Redactor {
-	fieldSet := make(map[string]struct{}, len(fields))
-	for _, field := range fields {
-		fieldSet[field] = struct{}{}
+	r := &Redactor{logger: logger}
+	// A field rule with the default action cannot fail to compile.
+	if rule, err := compileRule(Rule{Name: "fields", Fields: fields}, nil); err == nil {
+		r.fieldRules = append(r.fieldRules, rule)
+	}
+	return r
+}
+
+// NewRuleRedactor creates a Redactor applying rules, see Rule, hashing with the HMAC
+// key hashKey. Field rules are applied before pattern rules, so a redacted field is not
+// matched again.
+func NewRuleRedactor(rules []Rule, hashKey []byte, logger *slog.Logger) (*Redactor, error) {
+	r := &Redactor{logger: logger}
+	for _, rule := range rules {
+		c, err := compileRule(rule, hashKey)
+		if err != nil {
+			return nil, err
+		}
+		if c.re == nil {
+			r.fieldRules = append(r.fieldRules, c)
+		} else {
+			r.patternRules = append(r.patternRules, c)
+		}
 	}
-	return &Redactor{
-		fieldsToRedact: fieldSet,
-		logger:         logger,
+	return r, nil
+}
+
+// withRules returns a Redactor applying rules instead of r's, configured like r
+// otherwise.
+func (r *Redactor) withRules(rules []Rule, hashKey []byte) (*Redactor, error) {
+	derived, err := NewRuleRedactor(rules, hashKey, r.logger)
+	if err != nil {
+		return nil, err
 	}
+	derived.dropRawEvent, derived.hits = r.dropRawEvent, r.hits
+	return derived, nil
 }
 
-// Redact modifies the LogEvent in place to remove PII from its metadata.
+// Redact modifies the LogEvent in place to remove PII from its message, its metadata
+// and its raw event, or drops the raw event if so configured. Single-key fields are
+// matched at any depth, so payloads that adapters nest under a single metadata key are
+// covered as well.
 // It returns an error if JSON processing fails.
 func (r *Redactor) Redact(event *domain.LogEvent) error {
-	if len(r.fieldsToRedact) == 0 || len(event.Metadata) == 0 {
+	if r.dropRawEvent {
+		event.RawEvent = nil
+	}
+	if len(r.fieldRules) == 0 && len(r.patternRules) == 0 {
 		return nil
 	}
 
-	var metadata map[string]interface{}
-	if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
-		r.logger.Warn("failed to unmarshal metadata for PII redaction", "error", err, "event_id", event.ID)
-		// We can't process it, so we leave it as is.
-		return err
+	// Pattern rules limited to fields do not apply to the message.
+	var global []*compiledRule
+	for _, rule := range r.patternRules {
+		if rule.paths == nil {
+			global = append(global, rule)
+		}
+	}
+	if message, ok := r.replaceMatches(event.Message, global); ok {
+		event.Message = message
+		event.PIIRedacted = true
 	}
 
-	redacted := false
-	for field := range r.fieldsToRedact {
-		if _, ok := metadata[field]; ok {
-			metadata[field] = RedactedPlaceholder
-			redacted = true
+	if len(event.Metadata) > 0 {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			r.logger.Warn("failed to unmarshal metadata for PII redaction", "error", err, "event_id", event.ID)
+			// We can't process it, so we leave it as is.
+			return err
+		}
+
+		if _, ok := r.redactValue(metadata, nil, global); ok {
+			event.PIIRedacted = true
+			modifiedMetadata, err := json.Marshal(metadata)
+			if err != nil {
+				r.logger.Error("failed to marshal modified metadata after PII redaction", "error", err, "event_id", event.ID)
+				// This is a more serious internal error.
+				return err
+			}
+			event.Metadata = modifiedMetadata
 		}
 	}
 
-	if redacted {
-		event.PIIRedacted = true
-		modifiedMetadata, err := json.Marshal(metadata)
-		if err != nil {
-			r.logger.Error("failed to marshal modified metadata after PII redaction", "error", err, "event_id", event.ID)
-			// This is a more serious internal error.
-			return err
+	// Raw events that are JSON strings, such as syslog lines, hold no fields but may match
+	// pattern rules.
+	var raw interface{}
+	if len(event.RawEvent) > 0 && json.Unmarshal(event.RawEvent, &raw) == nil {
+		if modified, ok := r.redactValue(raw, nil, global); ok {
+			modifiedRaw, err := json.Marshal(modified)
+			if err != nil {
+				r.logger.Error("failed to marshal raw event after PII redaction", "error", err, "event_id", event.ID)
+				return err
+			}
+			event.RawEvent = modifiedRaw
+			event.PIIRedacted = true
 		}
-		event.Metadata = modifiedMetadata
 	}
 
 	return nil
 }
+
+// redactValue applies the rules to the value at path in nested objects and arrays, with
+// the pattern rules in scope there, and returns the value with whether anything was
+// redacted. Objects and arrays are modified in place.
+func (r *Redactor) redactValue(v interface{}, path []string, scope []*compiledRule) (interface{}, bool) {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			keyPath := append(path[:len(path):len(path)], key)
+			if rule := r.matchFieldRule(keyPath); rule != nil {
+				if rule.action == ActionRemove {
+					delete(v, key)
+				} else {
+					v[key] = rule.apply(stringify(value))
+				}
+				r.hit(rule, 1)
+				redacted = true
+				continue
+			}
+			keyScope := scope
+			for _, rule := range r.patternRules {
+				if rule.paths != nil && rule.matchesField(keyPath) {
+					keyScope = append(keyScope[:len(keyScope):len(keyScope)], rule)
+				}
+			}
+			if modified, ok := r.redactValue(value, keyPath, keyScope); ok {
+				v[key] = modified
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if modified, ok := r.redactValue(value, path, scope); ok {
+				v[i] = modified
+				redacted = true
+			}
+		}
+	case string:
+		if modified, ok := r.replaceMatches(v, scope); ok {
+			return modified, true
+		}
+	}
+	return v, redacted
+}
+
+func (r *Redactor) matchFieldRule(path []string) *compiledRule {
+	for _, rule := range r.fieldRules {
+		if rule.matchesField(path) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) replaceMatches(s string, rules []*compiledRule) (string, bool) {
+	redacted := false
+	for _, rule := range rules {
+		if modified, n := rule.replaceMatches(s); n > 0 {
+			s = modified
+			r.hit(rule, n)
+			redacted = true
+		}
+	}
+	return s, redacted
+}
+
+func (r *Redactor) hit(rule *compiledRule, n int) {
+	if r.hits != nil {
+		r.hits.WithLabelValues(rule.kind).Add(float64(n))
+	}
+}
+
+// stringify returns a string as is and any other JSON value encoded, for actions that
+// work on text.
+func stringify(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
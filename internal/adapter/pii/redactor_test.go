@@ -2,6 +2,7 @@ package pii
 
 import (
 	"encoding/json"
+	"io"
 	"log/slog"
 	"testing"
 
@@ -9,7 +10,7 @@ import (
 )
 
 func TestRedactor(t *testing.T) {
-	logger := slog.New(slog.NewJSONHandler(nil, nil))
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	redactor := NewRedactor([]string{"email", "ssn"}, logger)
 
 	tests := []struct {
@@ -40,6 +41,13 @@ func TestRedactor(t *testing.T) {
 			expectRedacted:   false,
 			expectErr:        false,
 		},
+		{
+			name:             "Redact nested fields",
+			inputMetadata:    `{"payload": {"pusher": {"email": "test@example.com"}, "commits": [{"ssn": "000-00-0000"}]}}`,
+			expectedMetadata: `{"payload": {"pusher": {"email": "[REDACTED]"}, "commits": [{"ssn": "[REDACTED]"}]}}`,
+			expectRedacted:   true,
+			expectErr:        false,
+		},
 		{
 			name:             "Empty metadata",
 			inputMetadata:    `{}`,
@@ -89,10 +97,30 @@ func TestRedactor(t *testing.T) {
 				t.Errorf("metadata map length mismatch: got %d, want %d", len(actualMap), len(expectedMap))
 			}
 			for k, v := range expectedMap {
-				if actualMap[k] != v {
-					t.Errorf("metadata mismatch for key %s: got %v, want %v", k, actualMap[k], v)
+				want, _ := json.Marshal(v)
+				got, _ := json.Marshal(actualMap[k])
+				if string(got) != string(want) {
+					t.Errorf("metadata mismatch for key %s: got %s, want %s", k, got, want)
 				}
 			}
 		})
 	}
 }
+
+func TestRedactor_RawEvent(t *testing.T) {
+	redactor := NewRedactor([]string{"email"}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	event := &domain.LogEvent{RawEvent: json.RawMessage(`{"message":"signup","user":{"email":"test@example.com"}}`)}
+	if err := redactor.Redact(event); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if !event.PIIRedacted || string(event.RawEvent) != `{"message":"signup","user":{"email":"[REDACTED]"}}` {
+		t.Errorf("unexpected raw event: %s (redacted %v)", event.RawEvent, event.PIIRedacted)
+	}
+
+	line := json.RawMessage(`"email=test@example.com"`)
+	event = &domain.LogEvent{RawEvent: line}
+	if err := redactor.Redact(event); err != nil || string(event.RawEvent) != string(line) || event.PIIRedacted {
+		t.Errorf("expected a raw text line to be left alone, got %s, %v", event.RawEvent, err)
+	}
+}
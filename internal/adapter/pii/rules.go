@@ -0,0 +1,325 @@
+package pii
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Actions a rule takes on the values it matches.
+const (
+	ActionRedact = "redact" // Replaces the value with RedactedPlaceholder, the default.
+	ActionMask   = "mask"   // Replaces all but the last 4 letters and digits with '*', keeping separators.
+	ActionHash   = "hash"   // Replaces the value with its keyed HMAC, so equal values still correlate.
+	ActionRemove = "remove" // Deletes the field; only for rules without a pattern.
+)
+
+// Built-in detectors, for the values that a regular expression alone matches too often.
+const (
+	DetectorEmail      = "email"
+	DetectorCreditCard = "credit_card" // 13 to 19 digits passing the Luhn check.
+	DetectorSSN        = "ssn"         // US social security numbers in a valid range.
+)
+
+// Rule is a PII redaction rule.
+//
+// A rule without a pattern or detector matches the fields in Fields and applies its
+// action to their whole value. Fields are dotted paths into the metadata or raw event,
+// where "*" matches any one key; arrays are transparent, so "users.email" matches the
+// emails of an array of users. A path of a single key matches that key at any depth.
+//
+// A rule with a pattern or detector applies its action to every match in the message and
+// in the string values of the metadata and raw event, or, if it has Fields, only in the
+// values of those fields and the values nested under them.
+//
+// Detector is one of the Detector constants and Action one of the Action constants.
+type Rule = domain.PIIRule
+
+// rulesFile is the YAML document LoadRules reads.
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules reads the rules of a YAML file with a top-level "rules" list.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII rules file: %w", err)
+	}
+	var file rulesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse PII rules file %s: %w", path, err)
+	}
+	return file.Rules, nil
+}
+
+type detector struct {
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+var detectors = map[string]detector{
+	DetectorEmail:      {re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	DetectorCreditCard: {re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhnValid},
+	DetectorSSN:        {re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), valid: ssnValid},
+}
+
+// luhnValid reports whether the digits of s, ignoring separators, pass the Luhn check.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// ssnValid reports whether an SSN of the form AAA-GG-SSSS was ever issuable: area 000,
+// 666 and 900-999, group 00 and serial 0000 are not.
+func ssnValid(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// compiledRule is a Rule ready to be applied.
+type compiledRule struct {
+	name   string
+	kind   string     // The detector, "pattern" or "field", for metrics.
+	paths  [][]string // nil matches every value, for pattern rules.
+	re     *regexp.Regexp
+	valid  func(match string) bool
+	action string
+	key    []byte // Of ActionHash.
+}
+
+func compileRule(rule Rule, hashKey []byte) (*compiledRule, error) {
+	c := &compiledRule{name: rule.Name, kind: "field", action: rule.Action, key: hashKey}
+	if c.action == "" {
+		c.action = ActionRedact
+	}
+	switch c.action {
+	case ActionRedact, ActionMask, ActionHash, ActionRemove:
+	default:
+		return nil, fmt.Errorf("PII rule %q: unknown action %q", rule.Name, rule.Action)
+	}
+
+	for _, field := range rule.Fields {
+		if field = strings.TrimSpace(field); field != "" {
+			c.paths = append(c.paths, strings.Split(field, "."))
+		}
+	}
+
+	switch {
+	case rule.Pattern != "" && rule.Detector != "":
+		return nil, fmt.Errorf("PII rule %q: pattern and detector are exclusive", rule.Name)
+	case rule.Pattern != "":
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("PII rule %q: %w", rule.Name, err)
+		}
+		c.re, c.kind = re, "pattern"
+	case rule.Detector != "":
+		d, ok := detectors[rule.Detector]
+		if !ok {
+			return nil, fmt.Errorf("PII rule %q: unknown detector %q", rule.Name, rule.Detector)
+		}
+		c.re, c.valid, c.kind = d.re, d.valid, rule.Detector
+	case len(c.paths) == 0:
+		return nil, fmt.Errorf("PII rule %q: fields, pattern or detector is required", rule.Name)
+	}
+	if c.action == ActionHash && len(hashKey) == 0 {
+		return nil, fmt.Errorf("PII rule %q: action hash requires a hash key", rule.Name)
+	}
+	if c.re != nil && c.action == ActionRemove {
+		return nil, fmt.Errorf("PII rule %q: action remove only applies to fields", rule.Name)
+	}
+	return c, nil
+}
+
+// matchesField reports whether the rule's fields include the value at path.
+func (c *compiledRule) matchesField(path []string) bool {
+	for _, p := range c.paths {
+		if len(p) == 1 {
+			if p[0] == "*" || p[0] == path[len(path)-1] {
+				return true
+			}
+			continue
+		}
+		if len(p) != len(path) {
+			continue
+		}
+		matched := true
+		for i, seg := range p {
+			if seg != "*" && seg != path[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceMatches applies the action to every match of the rule's pattern in s and
+// returns the number of matches.
+func (c *compiledRule) replaceMatches(s string) (string, int) {
+	replaced := 0
+	out := c.re.ReplaceAllStringFunc(s, func(match string) string {
+		if c.valid != nil && !c.valid(match) {
+			return match
+		}
+		replaced++
+		return c.apply(match)
+	})
+	return out, replaced
+}
+
+// apply returns what the rule's action replaces s with.
+func (c *compiledRule) apply(s string) string {
+	switch c.action {
+	case ActionMask:
+		return mask(s)
+	case ActionHash:
+		// 128 bits are plenty to tell values apart.
+		mac := hmac.New(sha256.New, c.key)
+		mac.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+	return RedactedPlaceholder
+}
+
+// maskKeep is the number of trailing letters and digits mask keeps.
+const maskKeep = 4
+
+// mask replaces the letters and digits of s with '*', except the last maskKeep of them
+// if there are more than twice as many, and keeps everything else, so "4111-1111-1111-1234"
+// becomes "****-****-****-1234" and a short value is masked entirely.
+func mask(s string) string {
+	runes := []rune(s)
+	total := 0
+	for _, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			total++
+		}
+	}
+	keep := 0
+	if total > 2*maskKeep {
+		keep = maskKeep
+	}
+	seen := 0
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if seen++; seen <= total-keep {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
+
+// Options configure the Redactor created by LoadRedactor.
+type Options struct {
+	// Fields are keys redacted at any depth, each optionally followed by ":" and the action
+	// to take instead, e.g. "email:hash".
+	Fields []string
+	// Detectors are applied to the message and every string value, after Fields.
+	Detectors []string
+	RulesFile string // YAML rules applied after Detectors, see LoadRules; empty for none.
+	HashKey   []byte // HMAC key of ActionHash.
+	// DropRawEvent removes the raw event of every event instead of redacting it, since
+	// the message and metadata parsed from it are kept.
+	DropRawEvent bool
+	// Hits counts redacted values by detector, see metrics.IngestMetrics.PIIRedactionsTotal;
+	// nil disables.
+	Hits *prometheus.CounterVec
+}
+
+// LoadRedactor creates a Redactor for the fields and detectors, followed by the rules of
+// the rules file.
+func LoadRedactor(opts Options, logger *slog.Logger) (*Redactor, error) {
+	rules := FieldRules(opts.Fields)
+	for _, d := range opts.Detectors {
+		if d = strings.TrimSpace(d); d != "" {
+			rules = append(rules, Rule{Name: "detector:" + d, Detector: d})
+		}
+	}
+	if opts.RulesFile != "" {
+		fileRules, err := LoadRules(opts.RulesFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+		logger.Info("Loaded PII redaction rules", "file", opts.RulesFile, "rules", len(fileRules))
+	}
+	r, err := NewRuleRedactor(rules, opts.HashKey, logger)
+	if err != nil {
+		return nil, err
+	}
+	r.dropRawEvent, r.hits = opts.DropRawEvent, opts.Hits
+	return r, nil
+}
+
+// FieldRules returns a rule per action for fields of the form "key" or "key:action".
+func FieldRules(fields []string) []Rule {
+	var rules []Rule
+	byAction := make(map[string]int)
+	for _, field := range fields {
+		field, action, _ := strings.Cut(strings.TrimSpace(field), ":")
+		if field == "" {
+			continue
+		}
+		if action == "" {
+			action = ActionRedact
+		}
+		i, ok := byAction[action]
+		if !ok {
+			i = len(rules)
+			byAction[action] = i
+			rules = append(rules, Rule{Name: "fields:" + action, Action: action})
+		}
+		rules[i].Fields = append(rules[i].Fields, field)
+	}
+	return rules
+}
+
+// LoadHashKey returns the HMAC key of ActionHash, read from keyFile if key is empty.
+// Surrounding whitespace of the file is ignored.
+func LoadHashKey(key, keyFile string) ([]byte, error) {
+	if key != "" || keyFile == "" {
+		return []byte(key), nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII hash key file: %w", err)
+	}
+	return bytes.TrimSpace(data), nil
+}
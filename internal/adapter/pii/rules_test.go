@@ -0,0 +1,209 @@
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestRuleRedactor(t *testing.T) {
+	rules := []Rule{
+		{Name: "address", Fields: []string{"*.address", "orders.card"}, Action: ActionRemove},
+		{Name: "phone", Fields: []string{"phone"}, Action: ActionMask},
+		{Name: "email", Detector: DetectorEmail, Action: ActionHash},
+		{Name: "card", Detector: DetectorCreditCard},
+		{Name: "ssn", Detector: DetectorSSN},
+		{Name: "token", Fields: []string{"headers"}, Pattern: `tok_[a-z0-9]+`},
+	}
+	redactor, err := NewRuleRedactor(rules, []byte("secret"), slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRuleRedactor() error = %v", err)
+	}
+
+	event := &domain.LogEvent{
+		Message: "paid with 4111 1111 1111 1111, not 4111 1111 1111 1112; ssn 123-45-6789, not 000-12-3456; token tok_abc",
+		Metadata: json.RawMessage(`{
+			"user": {"address": "1 Main St", "phone": "555-123-4567", "email": "a@example.com"},
+			"orders": [{"card": "4111111111111111", "note": "address kept"}],
+			"headers": {"authorization": "Bearer tok_abc123"}
+		}`),
+	}
+	if err := redactor.Redact(event); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+
+	wantMessage := "paid with [REDACTED], not 4111 1111 1111 1112; ssn [REDACTED], not 000-12-3456; token tok_abc"
+	if event.Message != wantMessage || !event.PIIRedacted {
+		t.Errorf("expected message %q, got %q (redacted %v)", wantMessage, event.Message, event.PIIRedacted)
+	}
+	want := `{
+		"user": {"phone": "***-***-4567", "email": "` + hashOf("secret", "a@example.com") + `"},
+		"orders": [{"note": "address kept"}],
+		"headers": {"authorization": "Bearer [REDACTED]"}
+	}`
+	var got, expected interface{}
+	json.Unmarshal(event.Metadata, &got)
+	json.Unmarshal([]byte(want), &expected)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(expected)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("expected metadata %s, got %s", wantJSON, gotJSON)
+	}
+}
+
+func TestRuleRedactor_RawTextLine(t *testing.T) {
+	redactor, err := NewRuleRedactor([]Rule{{Name: "email", Detector: DetectorEmail}}, nil, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRuleRedactor() error = %v", err)
+	}
+	event := &domain.LogEvent{RawEvent: json.RawMessage(`"login by test@example.com"`)}
+	if err := redactor.Redact(event); err != nil || string(event.RawEvent) != `"login by [REDACTED]"` {
+		t.Errorf("expected the email in the raw line redacted, got %s, %v", event.RawEvent, err)
+	}
+}
+
+func TestNewRuleRedactor_InvalidRules(t *testing.T) {
+	for _, rule := range []Rule{
+		{Name: "empty"},
+		{Name: "bad regex", Pattern: "("},
+		{Name: "unknown detector", Detector: "passport"},
+		{Name: "both", Pattern: "x", Detector: DetectorEmail},
+		{Name: "unknown action", Fields: []string{"a"}, Action: "encrypt"},
+		{Name: "remove match", Detector: DetectorEmail, Action: ActionRemove},
+		{Name: "hash without key", Fields: []string{"a"}, Action: ActionHash},
+	} {
+		if _, err := NewRuleRedactor([]Rule{rule}, nil, slog.New(slog.NewJSONHandler(io.Discard, nil))); err == nil {
+			t.Errorf("expected an error for rule %q", rule.Name)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yml")
+	os.WriteFile(path, []byte(`
+rules:
+  - name: cards
+    detector: credit_card
+    action: mask
+  - name: address
+    fields: [user.address]
+    action: remove
+`), 0o600)
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if len(rules) != 2 || rules[0].Detector != DetectorCreditCard || rules[1].Fields[0] != "user.address" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	os.WriteFile(path, []byte("rules:\n  - name: typo\n    feilds: [a]\n"), 0o600)
+	if _, err := LoadRules(path); err == nil || !strings.Contains(err.Error(), "feilds") {
+		t.Errorf("expected an error for an unknown key, got %v", err)
+	}
+}
+
+func TestLoadRedactor_ExampleRules(t *testing.T) {
+	opts := Options{Fields: []string{"password"}, RulesFile: "../../../deploy/pii/rules.yml", HashKey: []byte("secret")}
+	if _, err := LoadRedactor(opts, slog.New(slog.NewJSONHandler(io.Discard, nil))); err != nil {
+		t.Errorf("expected the example rules to load, got %v", err)
+	}
+}
+
+func hashOf(key, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func TestHashIsDeterministicPerKey(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	redact := func(key string) string {
+		redactor, err := LoadRedactor(Options{Fields: []string{"user_id:hash"}, HashKey: []byte(key)}, logger)
+		if err != nil {
+			t.Fatalf("LoadRedactor() error = %v", err)
+		}
+		event := &domain.LogEvent{Metadata: json.RawMessage(`{"user_id":"u-42"}`)}
+		if err := redactor.Redact(event); err != nil {
+			t.Fatalf("Redact() error = %v", err)
+		}
+		return string(event.Metadata)
+	}
+	if a, b := redact("k1"), redact("k1"); a != b || a == `{"user_id":"u-42"}` {
+		t.Errorf("expected equal values hashed alike, got %s and %s", a, b)
+	}
+	if a, b := redact("k1"), redact("k2"); a == b {
+		t.Errorf("expected different keys to hash differently, got %s", a)
+	}
+}
+
+func TestMask(t *testing.T) {
+	for in, want := range map[string]string{
+		"4111-1111-1111-1234": "****-****-****-1234",
+		"4111111111111234":    "************1234",
+		"123-45-6789":         "***-**-6789",
+		"1234":                "****",
+		"":                    "",
+	} {
+		if got := mask(in); got != want {
+			t.Errorf("mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFieldRules(t *testing.T) {
+	rules := FieldRules([]string{"email:hash", " password", "", "card:mask", "ssn"})
+	if len(rules) != 3 {
+		t.Fatalf("expected a rule per action, got %+v", rules)
+	}
+	if rules[0].Action != ActionHash || rules[1].Action != ActionRedact || len(rules[1].Fields) != 2 || rules[2].Fields[0] != "card" {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+}
+
+func TestLoadRedactor_DetectorsAndRawEvent(t *testing.T) {
+	m := metrics.NewIngestMetrics(prometheus.NewRegistry())
+	redactor, err := LoadRedactor(Options{Detectors: []string{"credit_card", " ssn"}, DropRawEvent: true, Hits: m.PIIRedactionsTotal}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("LoadRedactor() error = %v", err)
+	}
+
+	event := &domain.LogEvent{
+		Message:  "charge 4111111111111111 for 123-45-6789 and 234-56-7890",
+		Metadata: json.RawMessage(`{"order":{"card":"5500 0000 0000 0004"}}`),
+		RawEvent: json.RawMessage(`{"message":"charge 4111111111111111"}`),
+	}
+	if err := redactor.Redact(event); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if want := "charge [REDACTED] for [REDACTED] and [REDACTED]"; event.Message != want {
+		t.Errorf("expected message %q, got %q", want, event.Message)
+	}
+	if string(event.Metadata) != `{"order":{"card":"[REDACTED]"}}` || event.RawEvent != nil {
+		t.Errorf("expected the card redacted and the raw event dropped, got %s and %s", event.Metadata, event.RawEvent)
+	}
+	if got := testutil.ToFloat64(m.PIIRedactionsTotal.WithLabelValues(DetectorCreditCard)); got != 2 {
+		t.Errorf("expected 2 credit card redactions counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.PIIRedactionsTotal.WithLabelValues(DetectorSSN)); got != 2 {
+		t.Errorf("expected 2 SSN redactions counted, got %v", got)
+	}
+
+	if _, err := LoadRedactor(Options{Detectors: []string{"passport"}}, slog.Default()); err == nil {
+		t.Error("expected an error for an unknown detector")
+	}
+}
@@ -0,0 +1,294 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/normalize"
+	"github.com/V4T54L/watch-tower/internal/adapter/textparser"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Spec describes one processor of a pipeline, e.g.
+//
+//	{"type":"drop","levels":["debug","trace"]}
+//	{"type":"drop","name":"health_checks","pattern":"GET /healthz?\\b"}
+//	{"type":"sample","name":"billing_debug","rate":0.1,"levels":["debug"],"sources":["billing"]}
+//	{"type":"rename","from":"usr","to":"user"}
+//	{"type":"parse_message","format":"logfmt"}
+//	{"type":"parse_message","format":"grok","pattern":"%{NGINXACCESS}","sources":["nginx-*"]}
+//	{"type":"add_fields","fields":{"env":"prod"}}
+type Spec struct {
+	Type    string            `json:"type"`              // "drop", "sample", "rename", "parse_message" or "add_fields"
+	Name    string            `json:"name,omitempty"`    // Names the rule in metrics and decisions; defaults to its type and position, e.g. "drop_0".
+	Sources []string          `json:"sources,omitempty"` // path.Match patterns of the event sources it applies to; empty applies to all.
+	Levels  []string          `json:"levels,omitempty"`  // drop, sample: levels of the events concerned, matched in canonical form (see normalize.Level), so "debug" matches DBG and 20.
+	Rate    float64           `json:"rate,omitempty"`    // sample: share of the events concerned that are kept, above 0 and at most 1.
+	From    string            `json:"from,omitempty"`    // rename: metadata field to rename.
+	To      string            `json:"to,omitempty"`      // rename: its new name; an existing field of that name is replaced.
+	Format  string            `json:"format,omitempty"`  // parse_message: "json" (also embedded in text), "logfmt", "regex", "grok" or "dissect".
+	Pattern string            `json:"pattern,omitempty"` // parse_message: required for regex, grok (see textparser.NewGrokParser for its library) and dissect. drop, sample: regex the message of the events concerned matches.
+	Fields  map[string]string `json:"fields,omitempty"`  // add_fields: static fields to set in the metadata.
+}
+
+// Config is the pipeline file, e.g. {"processors":[{"type":"drop","levels":["debug"]}]}.
+type Config struct {
+	Processors []Spec `json:"processors"`
+}
+
+// processor transforms an event and its decoded metadata in place. It reports false to
+// drop the event.
+type processor func(event *domain.LogEvent, metadata map[string]interface{}) bool
+
+// stage is a processor and what dropping an event by it means.
+type stage struct {
+	process processor
+	action  string // domain.DecisionSampled or domain.DecisionDropped.
+	rule    string
+}
+
+// random returns the number sampling rules keep an event below, in [0, 1).
+var random = rand.Float64
+
+// Pipeline applies its processors, in order, to every event of a batch.
+type Pipeline struct {
+	processors []stage
+	logger     *slog.Logger
+}
+
+// ParseConfig decodes a pipeline file.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid pipeline config: %w", err)
+	}
+	return cfg, nil
+}
+
+// New builds a Pipeline from its config.
+func New(cfg Config, logger *slog.Logger) (*Pipeline, error) {
+	p := &Pipeline{logger: logger}
+	for i, spec := range cfg.Processors {
+		proc, err := newProcessor(spec)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline processor %d: %w", i, err)
+		}
+		if len(spec.Sources) > 0 {
+			if proc, err = forSources(spec.Sources, proc); err != nil {
+				return nil, fmt.Errorf("pipeline processor %d: %w", i, err)
+			}
+		}
+		st := stage{process: proc, action: domain.DecisionDropped, rule: spec.Name}
+		if strings.EqualFold(spec.Type, "sample") {
+			st.action = domain.DecisionSampled
+		}
+		if st.rule == "" {
+			st.rule = fmt.Sprintf("%s_%d", strings.ToLower(spec.Type), i)
+		}
+		p.processors = append(p.processors, st)
+	}
+	return p, nil
+}
+
+// forSources limits a processor to the events whose source matches one of the patterns.
+func forSources(sources []string, proc processor) (processor, error) {
+	for _, pattern := range sources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid source pattern %q", pattern)
+		}
+	}
+	return func(event *domain.LogEvent, metadata map[string]interface{}) bool {
+		for _, pattern := range sources {
+			if ok, _ := path.Match(pattern, event.Source); ok {
+				return proc(event, metadata)
+			}
+		}
+		return true
+	}, nil
+}
+
+func newProcessor(spec Spec) (processor, error) {
+	switch strings.ToLower(spec.Type) {
+	case "drop":
+		matches, err := newMatcher(spec)
+		if err != nil {
+			return nil, fmt.Errorf("drop %w", err)
+		}
+		return func(event *domain.LogEvent, _ map[string]interface{}) bool {
+			return !matches(event)
+		}, nil
+	case "sample":
+		if spec.Rate <= 0 || spec.Rate > 1 {
+			return nil, fmt.Errorf("sample rate must be above 0 and at most 1, got %v", spec.Rate)
+		}
+		matches, err := newMatcher(spec)
+		if err != nil {
+			return nil, fmt.Errorf("sample %w", err)
+		}
+		return func(event *domain.LogEvent, _ map[string]interface{}) bool {
+			return !matches(event) || random() < spec.Rate
+		}, nil
+	case "rename":
+		if spec.From == "" || spec.To == "" {
+			return nil, fmt.Errorf("rename needs from and to")
+		}
+		return func(_ *domain.LogEvent, metadata map[string]interface{}) bool {
+			if v, ok := metadata[spec.From]; ok {
+				delete(metadata, spec.From)
+				metadata[spec.To] = v
+			}
+			return true
+		}, nil
+	case "parse_message":
+		parse, err := newMessageParser(spec)
+		if err != nil {
+			return nil, err
+		}
+		return func(event *domain.LogEvent, metadata map[string]interface{}) bool {
+			fields, ok := parse(event.Message)
+			if !ok {
+				return true
+			}
+			for k, v := range fields {
+				metadata[k] = v
+			}
+			return true
+		}, nil
+	case "add_fields":
+		if len(spec.Fields) == 0 {
+			return nil, fmt.Errorf("add_fields needs at least one field")
+		}
+		return func(_ *domain.LogEvent, metadata map[string]interface{}) bool {
+			for k, v := range spec.Fields {
+				metadata[k] = v
+			}
+			return true
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown processor type %q", spec.Type)
+	}
+}
+
+// newMatcher returns a function reporting whether an event has one of the levels and a
+// message matching the pattern of a drop or sample rule, whichever of them are set.
+func newMatcher(spec Spec) (func(*domain.LogEvent) bool, error) {
+	if len(spec.Levels) == 0 && spec.Pattern == "" && !strings.EqualFold(spec.Type, "sample") {
+		return nil, fmt.Errorf("needs levels or a pattern")
+	}
+	var levels map[string]struct{}
+	if len(spec.Levels) > 0 {
+		levels = make(map[string]struct{}, len(spec.Levels))
+		for _, level := range spec.Levels {
+			levels[levelKey(level)] = struct{}{}
+		}
+	}
+	var re *regexp.Regexp
+	if spec.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(spec.Pattern); err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+	}
+	return func(event *domain.LogEvent) bool {
+		if levels != nil {
+			if _, ok := levels[levelKey(event.Level)]; !ok {
+				return false
+			}
+		}
+		return re == nil || re.MatchString(event.Message)
+	}, nil
+}
+
+// levelKey returns the canonical form of a level, which the ingest pipeline sees before
+// the normalizer, or the level in lower case if it is not recognized.
+func levelKey(level string) string {
+	if canonical, ok := normalize.Level(level); ok {
+		return canonical
+	}
+	return strings.ToLower(level)
+}
+
+// newMessageParser returns a function extracting fields from a message. Messages it does
+// not match are left alone.
+func newMessageParser(spec Spec) (func(string) (map[string]interface{}, bool), error) {
+	switch strings.ToLower(spec.Format) {
+	case "json":
+		return textparser.JSONParser{}.Parse, nil
+	case "logfmt":
+		return textparser.LogfmtParser{}.Parse, nil
+	case "regex":
+		p, err := textparser.NewRegexParser(spec.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse, nil
+	case "grok":
+		p, err := textparser.NewGrokParser(spec.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse, nil
+	case "dissect":
+		p, err := textparser.NewDissectParser(spec.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return p.Parse, nil
+	default:
+		return nil, fmt.Errorf("unknown parse_message format %q", spec.Format)
+	}
+}
+
+// Transform runs the processors over a batch and returns the events that were not
+// dropped. The events are copies; the batch itself is left as it was, so its messages
+// can still be acknowledged. Events whose metadata is not a JSON object pass through
+// untouched.
+func (p *Pipeline) Transform(events []domain.LogEvent) []domain.LogEvent {
+	if len(p.processors) == 0 {
+		return events
+	}
+	kept := make([]domain.LogEvent, 0, len(events))
+	for _, event := range events {
+		if event, decision := p.Process(event); decision.Action == domain.DecisionKept {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// Process runs the processors over one event and returns it, transformed, with the
+// decision taken on it, like Transform.
+func (p *Pipeline) Process(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision) {
+	kept := domain.PipelineDecision{Action: domain.DecisionKept}
+	if len(p.processors) == 0 {
+		return event, kept
+	}
+	metadata := map[string]interface{}{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil || metadata == nil {
+			p.logger.Warn("Skipping pipeline for event whose metadata is not a JSON object", "event_id", event.ID, "error", err)
+			return event, kept
+		}
+	}
+
+	for _, st := range p.processors {
+		if !st.process(&event, metadata) {
+			return event, domain.PipelineDecision{Action: st.action, Rule: st.rule}
+		}
+	}
+
+	if len(metadata) > 0 || len(event.Metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			p.logger.Error("Failed to encode metadata after pipeline, keeping the original", "event_id", event.ID, "error", err)
+		} else {
+			event.Metadata = encoded
+		}
+	}
+	return event, kept
+}
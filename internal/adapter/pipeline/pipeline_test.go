@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+func TestPipelineTransform(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg, err := ParseConfig([]byte(`{"processors":[
+		{"type":"drop","levels":["DEBUG"]},
+		{"type":"parse_message","format":"logfmt"},
+		{"type":"rename","from":"usr","to":"user"},
+		{"type":"add_fields","fields":{"env":"prod"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	p, err := New(cfg, logger)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	events := []domain.LogEvent{
+		{ID: "1", Level: "debug", Message: "noise"},
+		{ID: "2", Level: "info", Message: "usr=bob action=login", Metadata: json.RawMessage(`{"region":"eu"}`)},
+		{ID: "3", Level: "warn", Message: "plain text"},
+		// Levels are matched in canonical form, as the normalizer has not run yet.
+		{ID: "4", Level: "DBG", Message: "noise"},
+		{ID: "5", Level: "20", Message: "noise"},
+	}
+	kept := p.Transform(events)
+	if len(kept) != 2 || kept[0].ID != "2" || kept[1].ID != "3" {
+		t.Fatalf("expected events 2 and 3 kept, got %v", kept)
+	}
+	if got, want := string(kept[0].Metadata), `{"action":"login","env":"prod","region":"eu","user":"bob"}`; got != want {
+		t.Errorf("expected metadata %s, got %s", want, got)
+	}
+	if got, want := string(kept[1].Metadata), `{"env":"prod"}`; got != want {
+		t.Errorf("expected metadata %s, got %s", want, got)
+	}
+	if string(events[1].Metadata) != `{"region":"eu"}` {
+		t.Errorf("expected the read batch left unchanged, got %s", events[1].Metadata)
+	}
+}
+
+func TestPipelineSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := New(Config{Processors: []Spec{
+		{Type: "parse_message", Format: "grok", Pattern: "%{NGINXERROR}", Sources: []string{"nginx-*"}},
+		{Type: "parse_message", Format: "dissect", Pattern: "%{service} %{status}", Sources: []string{"billing"}},
+	}}, logger)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	kept := p.Transform([]domain.LogEvent{
+		{ID: "1", Source: "nginx-edge", Message: `2024/05/01 12:00:00 [warn] 31#31: upstream response is buffered`},
+		{ID: "2", Source: "billing", Message: "invoices ok"},
+		{ID: "3", Source: "auth", Message: "sessions ok"},
+	})
+	var nginx map[string]interface{}
+	json.Unmarshal(kept[0].Metadata, &nginx)
+	if nginx["level"] != "warn" || nginx["pid"] != float64(31) {
+		t.Errorf("expected the nginx error log parsed, got %s", kept[0].Metadata)
+	}
+	if got, want := string(kept[1].Metadata), `{"service":"invoices","status":"ok"}`; got != want {
+		t.Errorf("expected metadata %s, got %s", want, got)
+	}
+	if len(kept[2].Metadata) != 0 {
+		t.Errorf("expected other sources left alone, got %s", kept[2].Metadata)
+	}
+}
+
+func TestPipelineSamplingAndDropRules(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p, err := New(Config{Processors: []Spec{
+		{Type: "drop", Name: "health_checks", Pattern: `GET /healthz?\b`},
+		{Type: "sample", Name: "billing_debug", Rate: 0.1, Levels: []string{"debug"}, Sources: []string{"billing"}},
+		{Type: "drop", Levels: []string{"trace"}},
+	}}, logger)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer func(r func() float64) { random = r }(random)
+
+	tests := []struct {
+		name   string
+		event  domain.LogEvent
+		random float64
+		want   domain.PipelineDecision
+	}{
+		{"Health check", domain.LogEvent{Message: "GET /health 200"}, 0, domain.PipelineDecision{Action: domain.DecisionDropped, Rule: "health_checks"}},
+		{"Sampled out", domain.LogEvent{Source: "billing", Level: "DEBUG"}, 0.5, domain.PipelineDecision{Action: domain.DecisionSampled, Rule: "billing_debug"}},
+		{"Sampled in", domain.LogEvent{Source: "billing", Level: "debug"}, 0.05, domain.PipelineDecision{Action: domain.DecisionKept}},
+		{"Other source", domain.LogEvent{Source: "auth", Level: "debug"}, 0.5, domain.PipelineDecision{Action: domain.DecisionKept}},
+		{"Unnamed rule", domain.LogEvent{Level: "trace"}, 0, domain.PipelineDecision{Action: domain.DecisionDropped, Rule: "drop_2"}},
+		{"Kept", domain.LogEvent{Message: "GET /healthcheck-report 200"}, 0, domain.PipelineDecision{Action: domain.DecisionKept}},
+	}
+	for _, tt := range tests {
+		random = func() float64 { return tt.random }
+		if _, got := p.Process(tt.event); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestNewRejectsInvalidProcessors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, spec := range []Spec{
+		{Type: "drop"},
+		{Type: "rename", From: "a"},
+		{Type: "parse_message", Format: "xml"},
+		{Type: "parse_message", Format: "regex", Pattern: "no groups"},
+		{Type: "parse_message", Format: "dissect", Pattern: "%{a}%{b}"},
+		{Type: "drop", Pattern: "("},
+		{Type: "sample", Levels: []string{"debug"}},
+		{Type: "sample", Rate: 1.5},
+		{Type: "add_fields", Fields: map[string]string{"env": "prod"}, Sources: []string{"["}},
+		{Type: "add_fields"},
+		{Type: "uppercase"},
+	} {
+		if _, err := New(Config{Processors: []Spec{spec}}, logger); err == nil {
+			t.Errorf("expected an error for %+v", spec)
+		}
+	}
+}
+
+func TestReloader(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "pipeline.json")
+	if err := os.WriteFile(path, []byte(`{"processors":[{"type":"drop","levels":["debug"]}]}`), 0600); err != nil {
+		t.Fatalf("failed to write pipeline file: %v", err)
+	}
+	r, err := NewReloader(path, logger)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	events := []domain.LogEvent{{ID: "1", Level: "debug"}, {ID: "2", Level: "info"}}
+	if kept := r.Transform(events); len(kept) != 1 {
+		t.Fatalf("expected the debug event dropped, got %v", kept)
+	}
+
+	t.Run("Invalid file keeps the current pipeline", func(t *testing.T) {
+		os.WriteFile(path, []byte(`{"processors":[{"type":"uppercase"}]}`), 0600)
+		if err := r.load(); err == nil {
+			t.Fatal("expected an error for an unknown processor")
+		}
+		if kept := r.Transform(events); len(kept) != 1 {
+			t.Errorf("expected the previous pipeline still applied, got %v", kept)
+		}
+	})
+
+	t.Run("Changed file replaces the pipeline", func(t *testing.T) {
+		os.WriteFile(path, []byte(`{"processors":[{"type":"drop","levels":["info"]}]}`), 0600)
+		if err := r.load(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if kept := r.Transform(events); len(kept) != 1 || kept[0].ID != "1" {
+			t.Errorf("expected the info event dropped, got %v", kept)
+		}
+	})
+}
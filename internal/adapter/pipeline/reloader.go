@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Reloader serves the pipeline defined in a file and swaps it for a new one when the
+// file changes, without restarting the consumer. A file that fails to load leaves the
+// current pipeline in place.
+type Reloader struct {
+	path    string
+	logger  *slog.Logger
+	current atomic.Pointer[Pipeline]
+	modTime time.Time // Of the loaded file; only Run touches it after NewReloader.
+}
+
+// NewReloader loads the pipeline file at path.
+func NewReloader(path string, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{path: path, logger: logger.With("component", "pipeline")}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Transform runs the current pipeline over a batch.
+func (r *Reloader) Transform(events []domain.LogEvent) []domain.LogEvent {
+	return r.current.Load().Transform(events)
+}
+
+// Process runs the current pipeline over one event.
+func (r *Reloader) Process(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision) {
+	return r.current.Load().Process(event)
+}
+
+// Run checks the file for changes every interval until ctx is done.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			if err != nil {
+				r.logger.Warn("Failed to check pipeline file, keeping the current pipeline", "path", r.path, "error", err)
+				continue
+			}
+			if info.ModTime().Equal(r.modTime) {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Error("Failed to reload pipeline, keeping the current one", "path", r.path, "error", err)
+				// Not retried until the file changes again.
+				r.modTime = info.ModTime()
+				continue
+			}
+			r.logger.Info("Reloaded pipeline", "path", r.path, "processors", len(r.current.Load().processors))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reloader) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read pipeline file: %w", err)
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read pipeline file: %w", err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	p, err := New(cfg, r.logger)
+	if err != nil {
+		return err
+	}
+	r.current.Store(p)
+	r.modTime = info.ModTime()
+	return nil
+}
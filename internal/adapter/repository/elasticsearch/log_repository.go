@@ -0,0 +1,219 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const (
+	defaultIndexPrefix  = "logs"
+	defaultMaxRetries   = 5
+	defaultRetryBackoff = 500 * time.Millisecond
+	requestTimeout      = 30 * time.Second
+)
+
+var errNotImplemented = errors.New("method not implemented for this repository type")
+
+// Options configures the connection to the cluster. Username and Password, or APIKey,
+// authenticate requests; an empty one is not sent.
+type Options struct {
+	URL          string // Base URL of the cluster, e.g. "https://es.example.com:9200".
+	Username     string
+	Password     string
+	APIKey       string        // Base64 encoded "id:api_key", sent as "Authorization: ApiKey ...".
+	IndexPrefix  string        // Events go to daily indices named "<prefix>-YYYY.MM.DD"; default "logs".
+	MaxRetries   int           // Bulk requests retried for throttled or failed documents; default 5.
+	RetryBackoff time.Duration // Wait before the first retry, doubled for every following one; default 500ms.
+}
+
+// LogRepository implements the sink part of the domain.LogRepository interface for
+// Elasticsearch and OpenSearch, using the bulk API. Documents are indexed by event ID,
+// so writing an event twice leaves a single document.
+type LogRepository struct {
+	opts   Options
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewLogRepository creates a new Elasticsearch log repository.
+func NewLogRepository(opts Options, logger *slog.Logger) *LogRepository {
+	opts.URL = strings.TrimRight(opts.URL, "/")
+	if opts.IndexPrefix == "" {
+		opts.IndexPrefix = defaultIndexPrefix
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultRetryBackoff
+	}
+	return &LogRepository{
+		opts:   opts,
+		client: &http.Client{Timeout: requestTimeout},
+		logger: logger.With("component", "elasticsearch_repository"),
+	}
+}
+
+// bulkResponse is the part of a bulk API response needed to find failed documents.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// WriteLogBatch indexes a batch of events with the bulk API. Documents the cluster
+// throttles (429) or fails to index (5xx) are sent again with exponential backoff, as is
+// the whole request when the cluster throttles it. Documents it refuses, e.g. for a
+// mapping conflict, fail the batch with domain.ErrEventRejected once the others are
+// indexed.
+func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	pending := events
+	backoff := r.opts.RetryBackoff
+	var rejected []error
+	for attempt := 0; ; attempt++ {
+		retry, refused, err := r.bulk(ctx, pending)
+		if err != nil && !errors.Is(err, errThrottled) {
+			return err
+		}
+		rejected = append(rejected, refused...)
+		if len(retry) == 0 {
+			break
+		}
+		if attempt == r.opts.MaxRetries {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("elasticsearch failed to index %d documents after %d retries", len(retry), r.opts.MaxRetries)
+		}
+
+		r.logger.Warn("Elasticsearch throttled or failed documents, retrying", "attempt", attempt+1, "count", len(retry), "delay", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		pending = retry
+	}
+
+	if len(rejected) > 0 {
+		return fmt.Errorf("%w: %d of %d documents: %w", domain.ErrEventRejected, len(rejected), len(events), rejected[0])
+	}
+	return nil
+}
+
+// errThrottled is returned by bulk when the cluster refuses the whole request with 429.
+var errThrottled = errors.New("elasticsearch is throttling bulk requests")
+
+// bulk sends one bulk request. It returns the events to send again and the errors of the
+// documents that were refused for good.
+func (r *LogRepository) bulk(ctx context.Context, events []domain.LogEvent) ([]domain.LogEvent, []error, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, event := range events {
+		action := map[string]map[string]string{"index": {"_index": r.indexName(event), "_id": event.ID}}
+		if err := enc.Encode(action); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := enc.Encode(event); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.opts.URL+"/_bulk", &body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if r.opts.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+r.opts.APIKey)
+	} else if r.opts.Username != "" {
+		req.SetBasicAuth(r.opts.Username, r.opts.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send bulk request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return events, nil, errThrottled
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("elasticsearch bulk request returned %s: %s", resp.Status, msg)
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	if len(result.Items) != len(events) {
+		return nil, nil, fmt.Errorf("bulk response has %d items for %d documents", len(result.Items), len(events))
+	}
+
+	var retry []domain.LogEvent
+	var rejected []error
+	for i, item := range result.Items {
+		for _, res := range item { // A single "index" entry.
+			switch {
+			case res.Status == http.StatusTooManyRequests || res.Status >= 500:
+				retry = append(retry, events[i])
+			case res.Status >= 300:
+				rejected = append(rejected, fmt.Errorf("event %s: status %d: %s", events[i].ID, res.Status, res.Error))
+			}
+		}
+	}
+	return retry, rejected, nil
+}
+
+// indexName returns the daily index of an event, by event time or, if it has none, the
+// time it was received.
+func (r *LogRepository) indexName(event domain.LogEvent) string {
+	ts := event.EventTime
+	if ts.IsZero() {
+		ts = event.ReceivedAt
+	}
+	return r.opts.IndexPrefix + "-" + ts.UTC().Format("2006.01.02")
+}
+
+// BufferLog is not implemented for this repository.
+func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) error {
+	return errNotImplemented
+}
+
+// ReadLogBatch is not implemented for this repository.
+func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
+	return nil, errNotImplemented
+}
+
+// AcknowledgeLogs is not implemented for this repository.
+func (r *LogRepository) AcknowledgeLogs(ctx context.Context, group string, messageIDs ...string) error {
+	return errNotImplemented
+}
+
+// MoveToDLQ is not implemented for this repository.
+func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
+	return errNotImplemented
+}
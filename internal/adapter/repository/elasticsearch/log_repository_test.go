@@ -0,0 +1,128 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// fakeCluster answers bulk requests with the status decided for each document ID, and
+// counts the requests and the documents indexed.
+type fakeCluster struct {
+	mu       sync.Mutex
+	requests int
+	throttle int            // Whole requests answered with 429 before any is accepted.
+	statuses map[string]int // Per document ID, default 201; a 429 is returned only once.
+	indexed  map[string]string
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	if c.throttle > 0 {
+		c.throttle--
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var items []string
+	hasErrors := false
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+				ID    string `json:"_id"`
+			} `json:"index"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan() // The document.
+
+		status := http.StatusCreated
+		if s, ok := c.statuses[action.Index.ID]; ok {
+			status = s
+			if s == http.StatusTooManyRequests {
+				delete(c.statuses, action.Index.ID)
+			}
+		}
+		if status >= 300 {
+			hasErrors = true
+		} else {
+			c.indexed[action.Index.ID] = action.Index.Index
+		}
+		items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":%d}}`, action.Index.ID, status))
+	}
+	fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+}
+
+func newTestRepository(t *testing.T, cluster *fakeCluster) *LogRepository {
+	t.Helper()
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewLogRepository(Options{URL: server.URL, RetryBackoff: time.Millisecond, MaxRetries: 3}, logger)
+}
+
+func TestWriteLogBatch(t *testing.T) {
+	day := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	events := []domain.LogEvent{
+		{ID: "1", EventTime: day, Message: "a"},
+		{ID: "2", ReceivedAt: day.Add(2 * time.Hour), Message: "b"},
+		{ID: "3", EventTime: day, Message: "c"},
+	}
+
+	t.Run("Daily indices", func(t *testing.T) {
+		cluster := &fakeCluster{indexed: map[string]string{}}
+		if err := newTestRepository(t, cluster).WriteLogBatch(context.Background(), events); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cluster.indexed["1"] != "logs-2024.05.01" || cluster.indexed["2"] != "logs-2024.05.02" {
+			t.Errorf("expected events indexed by day, got %v", cluster.indexed)
+		}
+	})
+
+	t.Run("Retries throttled requests and documents", func(t *testing.T) {
+		cluster := &fakeCluster{throttle: 1, statuses: map[string]int{"2": http.StatusTooManyRequests}, indexed: map[string]string{}}
+		if err := newTestRepository(t, cluster).WriteLogBatch(context.Background(), events); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(cluster.indexed) != 3 || cluster.requests != 3 {
+			t.Errorf("expected all 3 events indexed in 3 requests, got %d in %d", len(cluster.indexed), cluster.requests)
+		}
+	})
+
+	t.Run("Rejected documents", func(t *testing.T) {
+		cluster := &fakeCluster{statuses: map[string]int{"3": http.StatusBadRequest}, indexed: map[string]string{}}
+		err := newTestRepository(t, cluster).WriteLogBatch(context.Background(), events)
+		if !errors.Is(err, domain.ErrEventRejected) {
+			t.Fatalf("expected a rejection, got %v", err)
+		}
+		if len(cluster.indexed) != 2 {
+			t.Errorf("expected the 2 other events indexed, got %d", len(cluster.indexed))
+		}
+	})
+
+	t.Run("Gives up after the retries", func(t *testing.T) {
+		cluster := &fakeCluster{throttle: 10, indexed: map[string]string{}}
+		err := newTestRepository(t, cluster).WriteLogBatch(context.Background(), events)
+		if err == nil || errors.Is(err, domain.ErrEventRejected) {
+			t.Fatalf("expected a plain error, got %v", err)
+		}
+		if cluster.requests != 4 {
+			t.Errorf("expected 1 request and 3 retries, got %d", cluster.requests)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const (
+	// readWait bounds how long ReadLogBatch waits for a batch to fill, mirroring the
+	// blocking read used by the Redis Streams repository.
+	readWait     = 2 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+var (
+	errNotImplemented = errors.New("method not implemented for this repository type")
+	errProducerOnly   = errors.New("kafka repository was created without a consumer group reader")
+)
+
+// LogRepository implements domain.LogRepository on a Kafka topic read through a consumer group.
+// Message IDs are encoded as "partition-offset" in LogEvent.StreamMessageID so that
+// AcknowledgeLogs can commit the offsets of a processed batch.
+type LogRepository struct {
+	reader    *kafka.Reader // Nil for producers, see NewProducer.
+	writer    *kafka.Writer
+	dlqWriter *kafka.Writer
+	topic     string
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	skipped map[int]int64 // Highest undecodable offset per partition, committed with the next ack.
+}
+
+// NewProducer creates a Kafka LogRepository that only publishes events. It never joins a
+// consumer group, which would assign it partitions that it does not read.
+func NewProducer(brokers []string, topic string, logger *slog.Logger) *LogRepository {
+	return &LogRepository{
+		writer: newWriter(brokers, topic),
+		topic:  topic,
+		logger: logger.With("component", "kafka_repository"),
+	}
+}
+
+// NewLogRepository creates a new Kafka LogRepository that consumes the topic as part of the
+// consumer group. The group is fixed for the lifetime of the reader, so the group argument
+// of ReadLogBatch and AcknowledgeLogs is ignored.
+func NewLogRepository(brokers []string, topic, dlqTopic, group string, logger *slog.Logger) *LogRepository {
+	return &LogRepository{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			GroupID:  group,
+			Topic:    topic,
+			MaxWait:  readWait,
+			MinBytes: 1,
+			MaxBytes: 10e6,
+			// Offsets are committed explicitly once a batch is sinked or moved to the DLQ.
+			CommitInterval: 0,
+		}),
+		writer:    newWriter(brokers, topic),
+		dlqWriter: newWriter(brokers, dlqTopic),
+		topic:     topic,
+		logger:    logger.With("component", "kafka_repository"),
+		skipped:   make(map[int]int64),
+	}
+}
+
+func newWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: writeTimeout,
+	}
+}
+
+// BufferLog publishes a log event to the topic, keyed by source so that events from one
+// source stay ordered within a partition.
+func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log event: %w", err)
+	}
+	msg := kafka.Message{Value: payload}
+	if event.Source != "" {
+		msg.Key = []byte(event.Source) // A nil key is balanced round-robin.
+	}
+	if err := r.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to write to kafka topic %s: %w", r.topic, err)
+	}
+	return nil
+}
+
+// ReadLogBatch fetches up to count messages, waiting at most readWait for the batch to fill.
+func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
+	if r.reader == nil {
+		return nil, errProducerOnly
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, readWait)
+	defer cancel()
+
+	var events []domain.LogEvent
+	for len(events) < count {
+		msg, err := r.reader.FetchMessage(fetchCtx)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			return nil, fmt.Errorf("failed to fetch from kafka: %w", err)
+		}
+
+		var event domain.LogEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			// Committing the offset now would also commit the unprocessed messages before it,
+			// so it is held back until the batch is acknowledged.
+			r.logger.Warn("Failed to unmarshal log event from topic, skipping", "partition", msg.Partition, "offset", msg.Offset, "error", err)
+			r.mu.Lock()
+			r.skipped[msg.Partition] = max(r.skipped[msg.Partition], msg.Offset)
+			r.mu.Unlock()
+			continue
+		}
+		event.StreamMessageID = formatMessageID(msg.Partition, msg.Offset)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// AcknowledgeLogs commits, for each partition, the highest offset among the processed
+// messages and the undecodable ones skipped while reading them.
+func (r *LogRepository) AcknowledgeLogs(ctx context.Context, group string, messageIDs ...string) error {
+	if r.reader == nil {
+		return errProducerOnly
+	}
+	offsets := make(map[int]int64)
+	for _, id := range messageIDs {
+		partition, offset, err := parseMessageID(id)
+		if err != nil {
+			return err
+		}
+		if prev, ok := offsets[partition]; !ok || offset > prev {
+			offsets[partition] = offset
+		}
+	}
+
+	r.mu.Lock()
+	skipped := r.skipped
+	r.skipped = make(map[int]int64)
+	r.mu.Unlock()
+	for partition, offset := range skipped {
+		if prev, ok := offsets[partition]; !ok || offset > prev {
+			offsets[partition] = offset
+		}
+	}
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	msgs := make([]kafka.Message, 0, len(offsets))
+	for partition, offset := range offsets {
+		msgs = append(msgs, kafka.Message{Topic: r.topic, Partition: partition, Offset: offset})
+	}
+	if err := r.reader.CommitMessages(ctx, msgs...); err != nil {
+		// Keep the skipped offsets so that the next acknowledgement retries them.
+		r.mu.Lock()
+		for partition, offset := range skipped {
+			r.skipped[partition] = max(r.skipped[partition], offset)
+		}
+		r.mu.Unlock()
+		return fmt.Errorf("failed to commit kafka offsets: %w", err)
+	}
+	return nil
+}
+
+// MoveToDLQ publishes a batch of events to the dead-letter topic.
+func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if r.dlqWriter == nil {
+		return errProducerOnly
+	}
+
+	msgs := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			r.logger.Error("Failed to marshal event for DLQ", "event_id", event.ID, "error", err)
+			continue
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(event.ID),
+			Value: payload,
+			Headers: []kafka.Header{
+				{Key: "original_topic", Value: []byte(r.topic)},
+				{Key: "original_event_id", Value: []byte(event.ID)},
+				{Key: "cause", Value: []byte(failure.Cause)},
+				{Key: "reason", Value: []byte(failure.Reason)},
+				{Key: "attempts", Value: []byte(strconv.Itoa(failure.Attempts))},
+				{Key: "consumer", Value: []byte(failure.Consumer)},
+				{Key: "failed_at", Value: []byte(failure.FailedAt.Format(time.RFC3339Nano))},
+			},
+		})
+	}
+
+	if err := r.dlqWriter.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to write to kafka DLQ topic: %w", err)
+	}
+	r.logger.Warn("Moved events to DLQ", "count", len(msgs))
+	return nil
+}
+
+// WriteLogBatch is not implemented for this repository.
+func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
+	return errNotImplemented
+}
+
+// Close closes the reader and writers.
+func (r *LogRepository) Close() error {
+	if r.reader == nil {
+		return r.writer.Close()
+	}
+	return errors.Join(r.reader.Close(), r.writer.Close(), r.dlqWriter.Close())
+}
+
+func formatMessageID(partition int, offset int64) string {
+	return strconv.Itoa(partition) + "-" + strconv.FormatInt(offset, 10)
+}
+
+func parseMessageID(id string) (int, int64, error) {
+	p, o, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid kafka message id %q", id)
+	}
+	partition, err := strconv.Atoi(p)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid kafka message id %q: %w", id, err)
+	}
+	offset, err := strconv.ParseInt(o, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid kafka message id %q: %w", id, err)
+	}
+	return partition, offset, nil
+}
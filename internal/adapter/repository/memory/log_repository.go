@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// readWait bounds how long ReadLogBatch waits for events, mirroring the blocking read used
+// by the Redis Streams repository.
+const readWait = 2 * time.Second
+
+var (
+	errNotImplemented = errors.New("method not implemented for this repository type")
+	errQueueFull      = errors.New("memory buffer is full")
+)
+
+// LogRepository implements domain.LogRepository on a bounded in-process queue, for running
+// ingest and consumer in one process without Redis. Events beyond the capacity spill to
+// the WAL and are read back, in order, once the queue has room again. Events still
+// queued are lost if the process stops, so drains wait for them, see Flushed.
+type LogRepository struct {
+	capacity int
+	wal      domain.WALRepository // Overflow; nil rejects events with domain.ErrBufferFull.
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	queue    []domain.LogEvent
+	pending  map[string]struct{} // Read but not yet acknowledged; they count towards capacity.
+	nextID   uint64
+	spilled  bool          // Whether the WAL may hold events; new events go there too, to keep their order.
+	spilling int           // WAL writes in progress; the WAL is not drained while there are any.
+	ready    chan struct{} // Closed and replaced whenever events are queued.
+}
+
+// NewLogRepository creates an in-memory LogRepository holding up to capacity events.
+func NewLogRepository(capacity int, wal domain.WALRepository, logger *slog.Logger) *LogRepository {
+	return &LogRepository{
+		capacity: capacity,
+		wal:      wal,
+		logger:   logger.With("component", "memory_repository"),
+		pending:  make(map[string]struct{}),
+		spilled:  wal != nil, // It may hold events from before a restart.
+		ready:    make(chan struct{}),
+	}
+}
+
+// BufferLog queues an event, spilling it to the WAL if the queue is full.
+func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) error {
+	r.mu.Lock()
+	if !r.spilled && r.enqueue(event) == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.wal == nil {
+		r.mu.Unlock()
+		return domain.ErrBufferFull
+	}
+	r.spilled = true
+	r.spilling++
+	r.mu.Unlock()
+
+	// The WAL may wait for space under its backpressure policy, which refill frees, so
+	// the lock is not held.
+	err := r.wal.Write(ctx, event)
+	r.mu.Lock()
+	r.spilling--
+	r.mu.Unlock()
+	return err
+}
+
+// enqueue queues an event if there is room. The caller must hold r.mu.
+func (r *LogRepository) enqueue(event domain.LogEvent) error {
+	if len(r.queue)+len(r.pending) >= r.capacity {
+		return errQueueFull
+	}
+	r.queue = append(r.queue, event)
+	close(r.ready)
+	r.ready = make(chan struct{})
+	return nil
+}
+
+// ReadLogBatch takes up to count queued events, waiting at most readWait for some to
+// arrive. The group and consumer are ignored; there is only one reader.
+func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
+	timer := time.NewTimer(readWait)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		if err := r.refill(ctx); err != nil {
+			r.logger.Error("Failed to read spilled events from WAL", "error", err)
+		}
+		if len(r.queue) > 0 {
+			n := min(count, len(r.queue))
+			events := make([]domain.LogEvent, n)
+			copy(events, r.queue)
+			r.queue = r.queue[n:]
+			for i := range events {
+				r.nextID++
+				events[i].StreamMessageID = strconv.FormatUint(r.nextID, 10)
+				r.pending[events[i].StreamMessageID] = struct{}{}
+			}
+			r.mu.Unlock()
+			return events, nil
+		}
+		ready := r.ready
+		r.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// refill moves spilled events from the WAL into the queue while there is room. The
+// caller must hold r.mu.
+func (r *LogRepository) refill(ctx context.Context) error {
+	if !r.spilled || len(r.queue)+len(r.pending) >= r.capacity {
+		return nil
+	}
+	err := r.wal.Replay(ctx, r.enqueue)
+	if errors.Is(err, errQueueFull) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.spilled = r.spilling > 0
+	return nil
+}
+
+// ReplayWAL moves spilled events from the WAL into the queue while there is room. The
+// queue is also refilled on every read, so this only drains it sooner.
+func (r *LogRepository) ReplayWAL(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refill(ctx)
+}
+
+// Flushed fails while events are queued, read but not yet acknowledged, or spilled to
+// the WAL and not read back, so that a drain, which retries it, only reports the process
+// safe to stop once the consumer has processed every buffered event.
+func (r *LogRepository) Flushed(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n := len(r.queue) + len(r.pending); n > 0 {
+		return fmt.Errorf("%d events still buffered in memory", n)
+	}
+	if r.spilled {
+		return errors.New("spilled events still in the WAL")
+	}
+	return nil
+}
+
+// AcknowledgeLogs releases the capacity held by processed events.
+func (r *LogRepository) AcknowledgeLogs(ctx context.Context, group string, messageIDs ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range messageIDs {
+		delete(r.pending, id)
+	}
+	return nil
+}
+
+// MoveToDLQ logs the events that could not be sinked. There is no dead-letter store in
+// memory, so they are not kept.
+func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
+	for _, event := range events {
+		r.logger.Error("Dropping event that could not be sinked", "event_id", event.ID, "source", event.Source, "reason", failure.Reason, "attempts", failure.Attempts)
+	}
+	return nil
+}
+
+// WriteLogBatch is not implemented for this repository.
+func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
+	return errNotImplemented
+}
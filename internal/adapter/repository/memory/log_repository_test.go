@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+)
+
+func TestLogRepository(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	t.Run("Full buffer without WAL", func(t *testing.T) {
+		repo := NewLogRepository(1, nil, logger)
+		if err := repo.BufferLog(ctx, domain.LogEvent{ID: "1"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := repo.BufferLog(ctx, domain.LogEvent{ID: "2"}); !errors.Is(err, domain.ErrBufferFull) {
+			t.Fatalf("expected ErrBufferFull, got %v", err)
+		}
+	})
+
+	t.Run("Overflow spills to WAL in order", func(t *testing.T) {
+		spill := &mocks.MockWALRepository{}
+		repo := NewLogRepository(2, spill, logger)
+		for _, id := range []string{"1", "2", "3", "4", "5"} {
+			if err := repo.BufferLog(ctx, domain.LogEvent{ID: id}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		// A new repository may find events in the WAL, so it starts out spilling.
+		if len(spill.Events) != 5 {
+			t.Fatalf("expected all events spilled, got %d", len(spill.Events))
+		}
+
+		var got []string
+		for len(got) < 5 {
+			events, err := repo.ReadLogBatch(ctx, "group", "consumer", 10)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(events) > 2 {
+				t.Fatalf("expected at most the capacity of 2 events, got %d", len(events))
+			}
+			for _, event := range events {
+				got = append(got, event.ID)
+				repo.AcknowledgeLogs(ctx, "group", event.StreamMessageID)
+			}
+		}
+		if want := "[1 2 3 4 5]"; fmt.Sprint(got) != want {
+			t.Errorf("expected events %s, got %v", want, got)
+		}
+
+		// Once the WAL is drained, events are queued in memory again.
+		repo.BufferLog(ctx, domain.LogEvent{ID: "6"})
+		if len(spill.Events) != 0 {
+			t.Errorf("expected event 6 queued in memory, got %d in the WAL", len(spill.Events))
+		}
+	})
+
+	t.Run("Flushed once every event is processed", func(t *testing.T) {
+		spill := &mocks.MockWALRepository{}
+		repo := NewLogRepository(1, spill, logger)
+		repo.BufferLog(ctx, domain.LogEvent{ID: "1"})
+		if err := repo.Flushed(ctx); err == nil {
+			t.Fatal("expected an error while events are spilled")
+		}
+		events, _ := repo.ReadLogBatch(ctx, "group", "consumer", 10)
+		if err := repo.Flushed(ctx); err == nil {
+			t.Fatal("expected an error while events are unacknowledged")
+		}
+		repo.AcknowledgeLogs(ctx, "group", events[0].StreamMessageID)
+		if err := repo.Flushed(ctx); err != nil {
+			t.Errorf("expected no error once every event is acknowledged, got %v", err)
+		}
+	})
+
+	t.Run("Unacknowledged events hold capacity", func(t *testing.T) {
+		repo := NewLogRepository(1, nil, logger)
+		repo.BufferLog(ctx, domain.LogEvent{ID: "1"})
+		events, _ := repo.ReadLogBatch(ctx, "group", "consumer", 10)
+		if err := repo.BufferLog(ctx, domain.LogEvent{ID: "2"}); !errors.Is(err, domain.ErrBufferFull) {
+			t.Fatalf("expected ErrBufferFull before the ack, got %v", err)
+		}
+		repo.AcknowledgeLogs(ctx, "group", events[0].StreamMessageID)
+		if err := repo.BufferLog(ctx, domain.LogEvent{ID: "2"}); err != nil {
+			t.Errorf("expected room after the ack, got %v", err)
+		}
+	})
+}
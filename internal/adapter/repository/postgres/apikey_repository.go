@@ -1,87 +1,266 @@
 package postgres
 
 import (
+	"container/list"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
 )
 
+// APIKeyCacheOptions bounds the cache of API key validation results.
+type APIKeyCacheOptions struct {
+	TTL time.Duration // Of valid keys.
+	// NegativeTTL is how long invalid keys are cached, shorter than TTL so that a newly
+	// created key is accepted soon after a client first tried it.
+	NegativeTTL time.Duration
+	Size        int // Keys cached at most; the least recently used one is evicted. 0 is unbounded.
+}
+
 type cacheEntry struct {
+	hash      string
 	isValid   bool
 	expiresAt time.Time
 }
 
 // APIKeyRepository implements the domain.APIKeyRepository interface using PostgreSQL
-// as the source of truth and an in-memory, time-based cache.
+// as the source of truth and an in-memory, time-based LRU cache. Keys are stored and
+// cached by their digest (see domain.HashAPIKey), never in plain text.
 type APIKeyRepository struct {
-	db       *sql.DB
-	logger   *slog.Logger
-	cache    map[string]cacheEntry
-	mu       sync.RWMutex
-	cacheTTL time.Duration
-	metrics  *metrics.IngestMetrics
+	db      *sql.DB
+	logger  *slog.Logger
+	opts    APIKeyCacheOptions
+	metrics *metrics.IngestMetrics
+	lookup  func(ctx context.Context, hash string) (bool, error) // Queries the database; replaced in tests.
+
+	mu    sync.Mutex
+	cache map[string]*list.Element // Of cacheEntry, by digest.
+	lru   *list.List               // Most recently used first.
+	// generation counts invalidations, so that a lookup that started before one does not
+	// cache the result it read before the key was changed.
+	generation uint64
 }
 
 // NewAPIKeyRepository creates a new instance of the PostgreSQL API key repository.
-func NewAPIKeyRepository(db *sql.DB, logger *slog.Logger, cacheTTL time.Duration, m *metrics.IngestMetrics) *APIKeyRepository {
-	return &APIKeyRepository{
-		db:       db,
-		logger:   logger,
-		cache:    make(map[string]cacheEntry),
-		cacheTTL: cacheTTL,
-		metrics:  m,
+func NewAPIKeyRepository(db *sql.DB, logger *slog.Logger, opts APIKeyCacheOptions, m *metrics.IngestMetrics) *APIKeyRepository {
+	r := &APIKeyRepository{
+		db:      db,
+		logger:  logger,
+		opts:    opts,
+		metrics: m,
+		cache:   make(map[string]*list.Element),
+		lru:     list.New(),
 	}
+	r.lookup = r.queryIsValid
+	return r
 }
 
 // IsValid checks if an API key is valid. It first checks a local cache and falls
 // back to the database if the key is not found or the cache entry has expired.
 func (r *APIKeyRepository) IsValid(ctx context.Context, key string) (bool, error) {
-	// 1. Check cache with a read lock
-	r.mu.RLock()
-	entry, found := r.cache[key]
-	r.mu.RUnlock()
+	return r.IsValidHash(ctx, domain.HashAPIKey(key))
+}
 
-	if found && time.Now().Before(entry.expiresAt) {
+// IsValidHash checks if the API key with the given digest is valid, like IsValid.
+func (r *APIKeyRepository) IsValidHash(ctx context.Context, hash string) (bool, error) {
+	// 1. Check cache
+	if isValid, found := r.cached(hash); found {
 		if r.metrics != nil {
 			r.metrics.APIKeyCacheHits.Inc()
 		}
-		return entry.isValid, nil
+		return isValid, nil
 	}
 
-	// 2. Cache miss or expired, query DB and update cache with a write lock
+	// 2. Cache miss or expired, query DB and update cache
 	if r.metrics != nil {
 		r.metrics.APIKeyCacheMisses.Inc()
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Double-check cache in case another goroutine populated it while waiting for the lock
-	entry, found = r.cache[key]
-	if found && time.Now().Before(entry.expiresAt) {
-		return entry.isValid, nil
-	}
-
 	// 3. Query the database
-	var isValid bool
-	// A key is valid if it exists, is active, and has not expired.
-	query := `SELECT EXISTS(SELECT 1 FROM api_keys WHERE key = $1 AND is_active = true AND (expires_at IS NULL OR expires_at > NOW()))`
-	err := r.db.QueryRowContext(ctx, query, key).Scan(&isValid)
+	generation := r.currentGeneration()
+	isValid, err := r.lookup(ctx, hash)
 	if err != nil {
 		r.logger.Error("failed to validate API key in database", "error", err)
 		// Don't cache errors, let the next request retry from the DB
 		return false, err
 	}
 
-	// 4. Update cache
-	r.cache[key] = cacheEntry{
-		isValid:   isValid,
-		expiresAt: time.Now().Add(r.cacheTTL),
+	// 4. Update cache, unless the key was invalidated while it was looked up
+	ttl := r.opts.TTL
+	if !isValid {
+		ttl = r.opts.NegativeTTL
 	}
+	r.store(hash, isValid, ttl, generation)
 
 	return isValid, nil
 }
+
+// queryIsValid reports whether the key with the given digest exists, is active, and has
+// not expired.
+func (r *APIKeyRepository) queryIsValid(ctx context.Context, hash string) (bool, error) {
+	var isValid bool
+	query := `SELECT EXISTS(SELECT 1 FROM api_keys WHERE key_hash = $1 AND is_active = true AND (expires_at IS NULL OR expires_at > NOW()))`
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(&isValid)
+	return isValid, err
+}
+
+func (r *APIKeyRepository) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// cached returns the unexpired validation result of a digest, marking it recently used.
+func (r *APIKeyRepository) cached(hash string) (isValid, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elem, ok := r.cache[hash]
+	if !ok {
+		return false, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		r.lru.Remove(elem)
+		delete(r.cache, hash)
+		return false, false
+	}
+	r.lru.MoveToFront(elem)
+	return entry.isValid, true
+}
+
+// store caches the validation result of a digest read at the given generation, unless
+// an invalidation happened since.
+func (r *APIKeyRepository) store(hash string, isValid bool, ttl time.Duration, generation uint64) {
+	if ttl <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return
+	}
+	entry := &cacheEntry{hash: hash, isValid: isValid, expiresAt: time.Now().Add(ttl)}
+	if elem, ok := r.cache[hash]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.cache[hash] = r.lru.PushFront(entry)
+	if r.opts.Size > 0 && r.lru.Len() > r.opts.Size {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(*cacheEntry).hash)
+	}
+}
+
+// Invalidate drops the cached result of a key, by digest, so that the next request with
+// it is validated against the database. An empty digest drops every cached result.
+// Lookups in flight do not cache what they read before it.
+func (r *APIKeyRepository) Invalidate(hash string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	if hash == "" {
+		r.cache = make(map[string]*list.Element)
+		r.lru.Init()
+		return
+	}
+	if elem, ok := r.cache[hash]; ok {
+		r.lru.Remove(elem)
+		delete(r.cache, hash)
+	}
+}
+
+const apiKeyColumns = `key_hash, COALESCE(description, ''), is_active, created_at, expires_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*domain.APIKey, error) {
+	var key domain.APIKey
+	var expiresAt sql.NullTime
+	if err := row.Scan(&key.KeyHash, &key.Description, &key.IsActive, &key.CreatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	return &key, nil
+}
+
+// CreateAPIKey stores a new API key by its digest.
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO api_keys (key_hash, description, expires_at) VALUES ($1, $2, $3) RETURNING `+apiKeyColumns,
+		key.KeyHash, key.Description, key.ExpiresAt)
+	created, err := scanAPIKey(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	// A client may have tried the key before it existed.
+	r.Invalidate(key.KeyHash)
+	return created, nil
+}
+
+// GetAPIKey returns an API key by its digest.
+func (r *APIKeyRepository) GetAPIKey(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns every API key, newest first.
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// UpdateAPIKey changes the description and expiry of an API key.
+func (r *APIKeyRepository) UpdateAPIKey(ctx context.Context, keyHash string, description *string, expiresAt *time.Time) (*domain.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET description = COALESCE($2, description), expires_at = COALESCE($3, expires_at)
+		WHERE key_hash = $1 RETURNING `+apiKeyColumns, keyHash, description, expiresAt)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	r.Invalidate(keyHash)
+	return key, nil
+}
+
+// DeactivateAPIKey stops an API key from being accepted. The row is kept, so that its
+// usage can still be attributed.
+func (r *APIKeyRepository) DeactivateAPIKey(ctx context.Context, keyHash string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_keys SET is_active = false WHERE key_hash = $1`, keyHash)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate API key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	r.Invalidate(keyHash)
+	return nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := NewAPIKeyRepository(nil, logger, APIKeyCacheOptions{TTL: time.Minute, NegativeTTL: time.Minute, Size: 2}, nil)
+
+	r.store("a", true, time.Minute, 0)
+	r.store("b", false, time.Minute, 0)
+	r.cached("a") // a is now more recently used than b.
+	r.store("c", true, time.Minute, 0)
+
+	if _, found := r.cached("b"); found {
+		t.Error("expected the least recently used key to be evicted")
+	}
+	if isValid, found := r.cached("a"); !found || !isValid {
+		t.Errorf("expected a cached as valid, got %t, %t", isValid, found)
+	}
+
+	r.Invalidate("a")
+	if _, found := r.cached("a"); found {
+		t.Error("expected an invalidated key to be dropped")
+	}
+	r.Invalidate("")
+	if _, found := r.cached("c"); found || r.lru.Len() != 0 {
+		t.Error("expected every key dropped")
+	}
+
+	r.store("expired", true, time.Nanosecond, r.generation)
+	time.Sleep(time.Millisecond)
+	if _, found := r.cached("expired"); found || r.lru.Len() != 0 {
+		t.Error("expected an expired entry to be dropped")
+	}
+}
+
+func TestAPIKeyCacheInvalidationDuringLookup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := NewAPIKeyRepository(nil, logger, APIKeyCacheOptions{TTL: time.Minute, NegativeTTL: time.Minute}, nil)
+	// The lookup reads the key as valid before its revocation commits, whose invalidation
+	// arrives before the lookup returns.
+	r.lookup = func(ctx context.Context, hash string) (bool, error) {
+		r.Invalidate(hash)
+		return true, nil
+	}
+
+	if isValid, err := r.IsValidHash(context.Background(), "a"); err != nil || !isValid {
+		t.Fatalf("expected the lookup's result, got %t, %v", isValid, err)
+	}
+	if _, found := r.cached("a"); found {
+		t.Error("expected the result read before the invalidation not to be cached")
+	}
+
+	r.lookup = func(ctx context.Context, hash string) (bool, error) { return false, nil }
+	r.IsValidHash(context.Background(), "a")
+	if isValid, found := r.cached("a"); !found || isValid {
+		t.Errorf("expected the next lookup cached, got %t, %t", isValid, found)
+	}
+}
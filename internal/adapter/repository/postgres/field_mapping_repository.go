@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// FieldMappingRepository implements the domain.FieldMappingRepository interface for
+// PostgreSQL.
+type FieldMappingRepository struct {
+	db *sql.DB
+}
+
+// NewFieldMappingRepository creates a new PostgreSQL field mapping repository.
+func NewFieldMappingRepository(db *sql.DB) *FieldMappingRepository {
+	return &FieldMappingRepository{db: db}
+}
+
+func scanFieldMapping(row interface{ Scan(...any) error }) (*domain.FieldMapping, error) {
+	var mapping domain.FieldMapping
+	var fields []byte
+	if err := row.Scan(&mapping.KeyHash, &fields, &mapping.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(fields, &mapping.Fields); err != nil {
+		return nil, fmt.Errorf("failed to decode field mapping: %w", err)
+	}
+	return &mapping, nil
+}
+
+// ListFieldMappings returns every field mapping, ordered by key.
+func (r *FieldMappingRepository) ListFieldMappings(ctx context.Context) ([]domain.FieldMapping, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT key_hash, fields, updated_at FROM field_mappings ORDER BY key_hash`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list field mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []domain.FieldMapping{}
+	for rows.Next() {
+		mapping, err := scanFieldMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan field mapping: %w", err)
+		}
+		mappings = append(mappings, *mapping)
+	}
+	return mappings, rows.Err()
+}
+
+// GetFieldMapping returns the field mapping of a key.
+func (r *FieldMappingRepository) GetFieldMapping(ctx context.Context, keyHash string) (*domain.FieldMapping, error) {
+	mapping, err := scanFieldMapping(r.db.QueryRowContext(ctx, `SELECT key_hash, fields, updated_at FROM field_mappings WHERE key_hash = $1`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrFieldMappingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// PutFieldMapping creates or replaces the field mapping of a key.
+func (r *FieldMappingRepository) PutFieldMapping(ctx context.Context, mapping domain.FieldMapping) (*domain.FieldMapping, error) {
+	fields, err := json.Marshal(mapping.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode field mapping: %w", err)
+	}
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO field_mappings (key_hash, fields) VALUES ($1, $2)
+		ON CONFLICT (key_hash) DO UPDATE SET fields = EXCLUDED.fields, updated_at = NOW()
+		RETURNING key_hash, fields, updated_at`, mapping.KeyHash, fields)
+	stored, err := scanFieldMapping(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put field mapping: %w", err)
+	}
+	return stored, nil
+}
+
+// DeleteFieldMapping deletes the field mapping of a key, whose payloads are then taken
+// as they are.
+func (r *FieldMappingRepository) DeleteFieldMapping(ctx context.Context, keyHash string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM field_mappings WHERE key_hash = $1`, keyHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete field mapping: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrFieldMappingNotFound
+	}
+	return nil
+}
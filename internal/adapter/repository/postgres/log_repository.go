@@ -3,7 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/lib/pq"
@@ -13,13 +16,23 @@ import (
 type LogRepository struct {
 	db     *sql.DB
 	logger *slog.Logger
+	group  string // Consumer group whose written messages are tracked; empty tracks none.
 }
 
 // NewLogRepository creates a new PostgreSQL log repository.
-func NewLogRepository(db *sql.DB, logger *slog.Logger) domain.LogRepository {
+func NewLogRepository(db *sql.DB, logger *slog.Logger) *LogRepository {
 	return &LogRepository{db: db, logger: logger}
 }
 
+// EnableExactlyOnce makes WriteLogBatch record the buffer message IDs of the events it
+// writes for group, in the same transaction, and skip events whose message was already
+// written. A message that is redelivered because its acknowledgement failed after the
+// write is then not written twice. Message IDs must be unique within the group, as Redis
+// stream IDs are.
+func (r *LogRepository) EnableExactlyOnce(group string) {
+	r.group = group
+}
+
 // WriteLogBatch writes a batch of log events to PostgreSQL using the COPY protocol for high performance.
 // It uses an ON CONFLICT clause to perform an upsert, ensuring idempotency based on event_id.
 func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
@@ -33,6 +46,15 @@ func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEv
 	}
 	defer txn.Rollback() // Rollback is a no-op if Commit() is called
 
+	if r.group != "" {
+		if events, err = r.claimUnwritten(ctx, txn, events); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return txn.Commit()
+		}
+	}
+
 	// Use a temporary table to stage the data, then merge into the main table.
 	// This is a common pattern for high-performance, idempotent bulk inserts.
 	tempTableName := "logs_temp_import"
@@ -41,52 +63,145 @@ func (r *LogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEv
 		return err
 	}
 
-	stmt, err := txn.Prepare(pq.CopyIn(tempTableName, "event_id", "received_at", "event_time", "source", "level", "message", "metadata"))
+	stmt, err := txn.Prepare(pq.CopyIn(tempTableName, "event_id", "received_at", "event_time", "source", "level", "message", "metadata", "api_key_hash"))
 	if err != nil {
 		return err
 	}
 
 	for _, event := range events {
-		_, err = stmt.ExecContext(ctx, event.ID, event.ReceivedAt, event.EventTime, event.Source, event.Level, event.Message, event.Metadata)
+		_, err = stmt.ExecContext(ctx, event.ID, event.ReceivedAt, event.EventTime, event.Source, event.Level, event.Message, event.Metadata, nullIfEmpty(event.APIKeyHash))
 		if err != nil {
 			// Close the statement to avoid connection issues
 			_ = stmt.Close()
-			return err
+			return classifyError(err)
 		}
 	}
 
+	// COPY reports bad rows when the statement is closed.
 	if err := stmt.Close(); err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	// Upsert from the temp table into the main table
 	upsertQuery := `
-		INSERT INTO logs (event_id, received_at, event_time, source, level, message, metadata)
-		SELECT event_id, received_at, event_time, source, level, message, metadata FROM ` + tempTableName + `
+		INSERT INTO logs (event_id, received_at, event_time, source, level, message, metadata, api_key_hash)
+		SELECT event_id, received_at, event_time, source, level, message, metadata, api_key_hash FROM ` + tempTableName + `
 		ON CONFLICT (event_id) DO UPDATE SET
 			received_at = EXCLUDED.received_at,
 			event_time = EXCLUDED.event_time,
 			source = EXCLUDED.source,
 			level = EXCLUDED.level,
 			message = EXCLUDED.message,
-			metadata = EXCLUDED.metadata;
+			metadata = EXCLUDED.metadata,
+			api_key_hash = EXCLUDED.api_key_hash;
 	`
 	_, err = txn.ExecContext(ctx, upsertQuery)
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	return txn.Commit()
 }
 
+// claimUnwritten records the message IDs of events as written and returns the events
+// whose message was not written before. A message another transaction is writing blocks
+// the insert until that transaction ends. Events without a message ID, such as replayed
+// spool events, are always written.
+func (r *LogRepository) claimUnwritten(ctx context.Context, txn *sql.Tx, events []domain.LogEvent) ([]domain.LogEvent, error) {
+	var ids []string
+	for _, event := range events {
+		if event.StreamMessageID != "" {
+			ids = append(ids, event.StreamMessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return events, nil
+	}
+
+	rows, err := txn.QueryContext(ctx, `
+		INSERT INTO sink_written_messages (consumer_group, message_id)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (consumer_group, message_id) DO NOTHING
+		RETURNING message_id
+	`, r.group, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record written messages: %w", err)
+	}
+	defer rows.Close()
+	claimed := make(map[string]struct{}, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		claimed[id] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unwritten := make([]domain.LogEvent, 0, len(events))
+	for _, event := range events {
+		if _, ok := claimed[event.StreamMessageID]; ok || event.StreamMessageID == "" {
+			unwritten = append(unwritten, event)
+		}
+	}
+	if skipped := len(events) - len(unwritten); skipped > 0 {
+		r.logger.Info("Skipping events whose messages were already written", "count", skipped, "consumer_group", r.group)
+	}
+	return unwritten, nil
+}
+
+// PruneWrittenMessages deletes the written message IDs recorded longer than retention
+// ago every interval, until ctx is done. Retention must exceed the time a message can
+// stay pending before it is redelivered or moved to the DLQ.
+func (r *LogRepository) PruneWrittenMessages(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := r.db.ExecContext(ctx, `DELETE FROM sink_written_messages WHERE consumer_group = $1 AND written_at < $2`, r.group, time.Now().Add(-retention))
+			if err != nil {
+				r.logger.Warn("Failed to prune written message IDs", "error", err)
+				continue
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				r.logger.Debug("Pruned written message IDs", "count", n)
+			}
+		}
+	}
+}
+
+// nullIfEmpty stores an empty string as NULL.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// classifyError marks errors caused by the data of an event, which no retry can fix, as
+// domain.ErrEventRejected.
+func classifyError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23": // data_exception, integrity_constraint_violation
+		return fmt.Errorf("%w: %w", domain.ErrEventRejected, err)
+	}
+	return err
+}
+
 func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) error {
 	query := `
-		INSERT INTO log_buffer (id, received_at, event_time, source, level, message, metadata, consumer_group, acknowledged, retry_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NULL, false, 0)
+		INSERT INTO log_buffer (id, received_at, event_time, source, level, message, metadata, api_key_hash, consumer_group, acknowledged, retry_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULL, false, 0)
 		ON CONFLICT (id) DO NOTHING;
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		event.ID, event.ReceivedAt, event.EventTime, event.Source, event.Level, event.Message, event.Metadata,
+		event.ID, event.ReceivedAt, event.EventTime, event.Source, event.Level, event.Message, event.Metadata, nullIfEmpty(event.APIKeyHash),
 	)
 	if err != nil {
 		r.logger.Error("failed to buffer log", "error", err)
@@ -96,7 +211,7 @@ func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) er
 
 func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
 	query := `
-		SELECT id, received_at, event_time, source, level, message, metadata
+		SELECT id, received_at, event_time, source, level, message, metadata, COALESCE(api_key_hash, '')
 		FROM log_buffer
 		WHERE acknowledged = false AND (consumer_group IS NULL OR consumer_group = $1)
 		ORDER BY received_at ASC
@@ -114,7 +229,7 @@ func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string
 	var events []domain.LogEvent
 	for rows.Next() {
 		var e domain.LogEvent
-		if err := rows.Scan(&e.ID, &e.ReceivedAt, &e.EventTime, &e.Source, &e.Level, &e.Message, &e.Metadata); err != nil {
+		if err := rows.Scan(&e.ID, &e.ReceivedAt, &e.EventTime, &e.Source, &e.Level, &e.Message, &e.Metadata, &e.APIKeyHash); err != nil {
 			return nil, err
 		}
 		events = append(events, e)
@@ -164,7 +279,7 @@ func (r *LogRepository) AcknowledgeLogs(ctx context.Context, group string, messa
 	return err
 }
 
-func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent) error {
+func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
 	if len(events) == 0 {
 		return nil
 	}
@@ -176,10 +291,10 @@ func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent)
 
 	for _, e := range events {
 		_, err := tx.ExecContext(ctx, `
-			INSERT INTO log_dlq (id, failed_at, reason, event_time, source, level, message, metadata)
-			VALUES ($1, now(), $2, $3, $4, $5, $6, $7)
+			INSERT INTO log_dlq (id, failed_at, reason, attempts, consumer, event_time, source, level, message, metadata, api_key_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 			ON CONFLICT (id) DO NOTHING
-		`, e.ID, "manual DLQ move", e.EventTime, e.Source, e.Level, e.Message, e.Metadata)
+		`, e.ID, failure.FailedAt, failure.Reason, failure.Attempts, failure.Consumer, e.EventTime, e.Source, e.Level, e.Message, e.Metadata, nullIfEmpty(e.APIKeyHash))
 		if err != nil {
 			tx.Rollback()
 			r.logger.Error("failed to move log to DLQ", "error", err)
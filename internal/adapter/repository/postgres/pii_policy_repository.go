@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// PIIPolicyRepository implements the domain.PIIPolicyRepository interface for PostgreSQL.
+type PIIPolicyRepository struct {
+	db *sql.DB
+}
+
+// NewPIIPolicyRepository creates a new PostgreSQL PII policy repository.
+func NewPIIPolicyRepository(db *sql.DB) *PIIPolicyRepository {
+	return &PIIPolicyRepository{db: db}
+}
+
+func scanPIIPolicy(row interface{ Scan(...any) error }) (*domain.PIIPolicy, error) {
+	var policy domain.PIIPolicy
+	var rules []byte
+	if err := row.Scan(&policy.KeyHash, &rules, &policy.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rules, &policy.Rules); err != nil {
+		return nil, fmt.Errorf("failed to decode PII policy rules: %w", err)
+	}
+	return &policy, nil
+}
+
+// ListPIIPolicies returns every PII policy, ordered by key.
+func (r *PIIPolicyRepository) ListPIIPolicies(ctx context.Context) ([]domain.PIIPolicy, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT key_hash, rules, updated_at FROM pii_policies ORDER BY key_hash`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PII policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []domain.PIIPolicy{}
+	for rows.Next() {
+		policy, err := scanPIIPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan PII policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetPIIPolicy returns the PII policy of a key.
+func (r *PIIPolicyRepository) GetPIIPolicy(ctx context.Context, keyHash string) (*domain.PIIPolicy, error) {
+	policy, err := scanPIIPolicy(r.db.QueryRowContext(ctx, `SELECT key_hash, rules, updated_at FROM pii_policies WHERE key_hash = $1`, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrPIIPolicyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PII policy: %w", err)
+	}
+	return policy, nil
+}
+
+// PutPIIPolicy creates or replaces the PII policy of a key.
+func (r *PIIPolicyRepository) PutPIIPolicy(ctx context.Context, policy domain.PIIPolicy) (*domain.PIIPolicy, error) {
+	rules, err := json.Marshal(policy.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PII policy rules: %w", err)
+	}
+	row := r.db.QueryRowContext(ctx, `
+		INSERT INTO pii_policies (key_hash, rules) VALUES ($1, $2)
+		ON CONFLICT (key_hash) DO UPDATE SET rules = EXCLUDED.rules, updated_at = NOW()
+		RETURNING key_hash, rules, updated_at`, policy.KeyHash, rules)
+	stored, err := scanPIIPolicy(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put PII policy: %w", err)
+	}
+	return stored, nil
+}
+
+// DeletePIIPolicy deletes the PII policy of a key, which then falls back to the global
+// rules.
+func (r *PIIPolicyRepository) DeletePIIPolicy(ctx context.Context, keyHash string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM pii_policies WHERE key_hash = $1`, keyHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete PII policy: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrPIIPolicyNotFound
+	}
+	return nil
+}
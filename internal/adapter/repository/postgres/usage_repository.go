@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// UsageRepository implements the domain.UsageRepository interface for PostgreSQL.
+type UsageRepository struct {
+	db *sql.DB
+}
+
+// NewUsageRepository creates a new PostgreSQL usage repository.
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// AddUsage adds usage to the daily totals in one transaction, so replicas flushing
+// concurrently never lose each other's counts.
+func (r *UsageRepository) AddUsage(ctx context.Context, usage []domain.Usage) ([]domain.Usage, error) {
+	txn, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback() // Rollback is a no-op if Commit() is called
+
+	stmt, err := txn.PrepareContext(ctx, `
+		INSERT INTO api_key_usage (key_hash, day, events, bytes) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key_hash, day) DO UPDATE
+		SET events = api_key_usage.events + EXCLUDED.events, bytes = api_key_usage.bytes + EXCLUDED.bytes
+		RETURNING events, bytes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare usage upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	totals := make([]domain.Usage, len(usage))
+	for i, u := range usage {
+		totals[i] = domain.Usage{KeyHash: u.KeyHash, Day: u.Day}
+		if err := stmt.QueryRowContext(ctx, u.KeyHash, u.Day, u.Events, u.Bytes).Scan(&totals[i].Events, &totals[i].Bytes); err != nil {
+			return nil, fmt.Errorf("failed to add usage: %w", err)
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// ListUsage returns the usage ordered by day and key.
+func (r *UsageRepository) ListUsage(ctx context.Context, from, to, keyHash string) ([]domain.Usage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key_hash, to_char(day, 'YYYY-MM-DD'), events, bytes FROM api_key_usage
+		WHERE day BETWEEN $1 AND $2 AND ($3 = '' OR key_hash = $3)
+		ORDER BY day, key_hash`, from, to, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []domain.Usage{}
+	for rows.Next() {
+		var u domain.Usage
+		if err := rows.Scan(&u.KeyHash, &u.Day, &u.Events, &u.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
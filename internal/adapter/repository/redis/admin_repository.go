@@ -150,3 +150,12 @@ func (r *AdminRepository) AcknowledgeMessages(ctx context.Context, stream, group
 func (r *AdminRepository) TrimStream(ctx context.Context, stream string, maxLen int64) (int64, error) {
 	return r.client.XTrimMaxLen(ctx, stream, maxLen).Result()
 }
+
+// GetStreamLength returns the number of entries in a stream.
+func (r *AdminRepository) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	length, err := r.client.XLen(ctx, stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get length of stream %s: %w", stream, err)
+	}
+	return length, nil
+}
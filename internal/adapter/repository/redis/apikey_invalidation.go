@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// InvalidateAllAPIKeys is the message that drops every cached API key validation result.
+const InvalidateAllAPIKeys = "*"
+
+// APIKeyInvalidationPublisher publishes API key invalidations to the replicas subscribed
+// with SubscribeAPIKeyInvalidations.
+type APIKeyInvalidationPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewAPIKeyInvalidationPublisher creates a new APIKeyInvalidationPublisher.
+func NewAPIKeyInvalidationPublisher(client *redis.Client, channel string) *APIKeyInvalidationPublisher {
+	return &APIKeyInvalidationPublisher{client: client, channel: channel}
+}
+
+// InvalidateAPIKey publishes the digest of a key.
+func (p *APIKeyInvalidationPublisher) InvalidateAPIKey(ctx context.Context, keyHash string) error {
+	if err := p.client.Publish(ctx, p.channel, keyHash).Err(); err != nil {
+		return fmt.Errorf("failed to publish API key invalidation: %w", err)
+	}
+	return nil
+}
+
+// SubscribeAPIKeyInvalidations calls invalidate with the digest of every key published
+// on channel (see domain.HashAPIKey), or with "" for InvalidateAllAPIKeys, until the
+// context is cancelled. The admin API publishes the keys it changes; after changing one
+// in the database, operators run redis-cli PUBLISH <channel> <key_hash>.
+// The subscription is restored after connection failures; invalidations published
+// meanwhile are lost and the key only expires with the cache TTL.
+func SubscribeAPIKeyInvalidations(ctx context.Context, client *redis.Client, channel string, invalidate func(keyHash string), logger *slog.Logger) {
+	sub := client.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	logger.Info("Subscribed to API key invalidations", "channel", channel)
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			keyHash := msg.Payload
+			if keyHash == InvalidateAllAPIKeys {
+				keyHash = ""
+			}
+			logger.Info("Invalidating cached API key", "key_hash", msg.Payload)
+			invalidate(keyHash)
+		}
+	}
+}
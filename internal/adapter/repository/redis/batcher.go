@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// xaddFlushTimeout bounds the final flush of the XADD batcher on shutdown.
+const xaddFlushTimeout = 5 * time.Second
+
+// XAddBatching configures the micro-batcher that pipelines the XADDs issued by BufferLog.
+// A batch is sent when it holds Size events or MaxDelay after its first event, whichever
+// comes first.
+type XAddBatching struct {
+	Size     int
+	MaxDelay time.Duration
+}
+
+// xaddBatcher hands events from BufferLog to the goroutine running RunXAddBatcher.
+type xaddBatcher struct {
+	requests chan xaddRequest
+	done     chan struct{} // Closed when the batcher stops accepting requests.
+}
+
+type xaddRequest struct {
+	event  domain.LogEvent
+	result chan error
+}
+
+// RunXAddBatcher pipelines the XADDs of concurrent BufferLog calls until ctx is done.
+// Each call still waits for its own XADD and falls back to the WAL on its own error. The
+// pending batch is flushed when ctx is done; later calls send their XADD directly.
+func (r *LogRepository) RunXAddBatcher(ctx context.Context, batching XAddBatching) {
+	b := &xaddBatcher{requests: make(chan xaddRequest), done: make(chan struct{})}
+	r.batcher.Store(b)
+	r.logger.Info("Starting XADD batcher", "batch_size", batching.Size, "max_delay", batching.MaxDelay)
+
+	timer := time.NewTimer(batching.MaxDelay)
+	timer.Stop()
+	var pending []xaddRequest
+	flush := func(ctx context.Context) {
+		timer.Stop()
+		r.flushXAdds(ctx, pending)
+		pending = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(b.done)
+			r.batcher.Store(nil)
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), xaddFlushTimeout)
+			flush(flushCtx)
+			cancel()
+			r.logger.Info("Stopped XADD batcher")
+			return
+		case req := <-b.requests:
+			pending = append(pending, req)
+			if len(pending) == 1 {
+				timer.Reset(batching.MaxDelay)
+			}
+			if len(pending) >= batching.Size {
+				flush(context.WithoutCancel(ctx))
+			}
+		case <-timer.C:
+			flush(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// flushXAdds sends the XADDs of a batch in one pipeline and reports each command's result
+// to its caller.
+func (r *LogRepository) flushXAdds(ctx context.Context, reqs []xaddRequest) {
+	if len(reqs) == 0 {
+		return
+	}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(reqs))
+	for i, req := range reqs {
+		args, _, err := r.xaddArgs(req.event)
+		if err != nil {
+			req.result <- err
+			continue
+		}
+		cmds[i] = pipe.XAdd(ctx, args)
+	}
+	if pipe.Len() > 0 {
+		// Exec returns the first failed command's error; every command is checked below.
+		_, _ = pipe.Exec(ctx)
+	}
+	for i, req := range reqs {
+		if cmds[i] != nil {
+			req.result <- cmds[i].Err()
+		}
+	}
+}
+
+// batchXAdd queues an event for the running batcher and waits for its XADD. It reports
+// false if no batcher is running, in which case the caller sends the XADD itself.
+func (r *LogRepository) batchXAdd(ctx context.Context, event domain.LogEvent) (bool, error) {
+	b := r.batcher.Load()
+	if b == nil {
+		return false, nil
+	}
+	req := xaddRequest{event: event, result: make(chan error, 1)}
+	select {
+	case b.requests <- req:
+	case <-b.done:
+		return false, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+	return true, <-req.result
+}
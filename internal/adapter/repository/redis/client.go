@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClientOptions holds the connection settings managed Redis providers require beyond the
+// address: ACL credentials and TLS material. Credentials may also be given in the URL;
+// the ones here take precedence.
+type ClientOptions struct {
+	Username string
+	Password string
+	CAFile   string // PEM bundle the server certificate is verified against instead of the system pool.
+	CertFile string // PEM client certificate for servers that require mutual TLS.
+	KeyFile  string
+}
+
+// NewClient creates a client for addr, which is a redis:// URL, a rediss:// URL for TLS,
+// or a bare host:port.
+func NewClient(addr string, opts ClientOptions) (*redis.Client, error) {
+	redisOpts, err := clientOptions(addr, opts)
+	if err != nil {
+		return nil, err
+	}
+	return redis.NewClient(redisOpts), nil
+}
+
+func clientOptions(addr string, opts ClientOptions) (*redis.Options, error) {
+	redisOpts := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		var err error
+		if redisOpts, err = redis.ParseURL(addr); err != nil {
+			return nil, fmt.Errorf("failed to parse redis url: %w", err)
+		}
+	}
+	if opts.Username != "" {
+		redisOpts.Username = opts.Username
+	}
+	if opts.Password != "" {
+		redisOpts.Password = opts.Password
+	}
+
+	if opts.CAFile == "" && opts.CertFile == "" && opts.KeyFile == "" {
+		return redisOpts, nil
+	}
+	if redisOpts.TLSConfig == nil {
+		// TLS material implies TLS, even with a redis:// URL or a bare address.
+		host, _, err := net.SplitHostPort(redisOpts.Addr)
+		if err != nil {
+			host = redisOpts.Addr
+		}
+		redisOpts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("redis CA bundle contains no certificates")
+		}
+		redisOpts.TLSConfig.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		redisOpts.TLSConfig.Certificates = []tls.Certificate{cert}
+	}
+	return redisOpts, nil
+}
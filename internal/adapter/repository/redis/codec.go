@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Payload codecs for StreamOptions.Codec. Each entry names the codec it was written with
+// in its codec field, and entries without one are plain JSON, so a stream can hold
+// entries of every codec. Consumers must be upgraded before producers switch to a codec
+// they do not know.
+const (
+	CodecNone   = "none"
+	CodecSnappy = "snappy"
+	CodecZstd   = "zstd"
+)
+
+// The zstd encoder and decoder are safe for concurrent EncodeAll and DecodeAll calls.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// encodePayload compresses a payload with codec.
+func encodePayload(codec string, payload []byte) []byte {
+	switch codec {
+	case CodecSnappy:
+		return s2.EncodeSnappy(nil, payload)
+	case CodecZstd:
+		return zstdEncoder.EncodeAll(payload, nil)
+	default:
+		return payload
+	}
+}
+
+// decodePayload decompresses a payload written with codec.
+func decodePayload(codec string, payload []byte) ([]byte, error) {
+	switch codec {
+	case "", CodecNone:
+		return payload, nil
+	case CodecSnappy:
+		return s2.Decode(nil, payload)
+	case CodecZstd:
+		return zstdDecoder.DecodeAll(payload, nil)
+	default:
+		return nil, fmt.Errorf("unknown payload codec %q", codec)
+	}
+}
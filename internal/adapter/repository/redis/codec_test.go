@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPayloadCodecs(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"level":"info","message":"request served","source":"api"}`), 20)
+
+	for _, codec := range []string{CodecNone, CodecSnappy, CodecZstd} {
+		t.Run(codec, func(t *testing.T) {
+			encoded := encodePayload(codec, payload)
+			if codec != CodecNone && len(encoded) >= len(payload) {
+				t.Errorf("expected %s to shrink a repetitive payload, got %d of %d bytes", codec, len(encoded), len(payload))
+			}
+			decoded, err := decodePayload(codec, encoded)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !bytes.Equal(decoded, payload) {
+				t.Errorf("expected the payload back unchanged")
+			}
+		})
+	}
+
+	t.Run("Unknown codec", func(t *testing.T) {
+		if _, err := decodePayload("lz4", payload); err == nil {
+			t.Error("expected an error for an unknown codec")
+		}
+	})
+}
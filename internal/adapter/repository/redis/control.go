@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// pauseKeyPrefix prefixes the keys that pause the consumers of a group. A key holds the
+// time the group was paused.
+const pauseKeyPrefix = "consumer_paused:"
+
+// SetGroupPaused pauses or resumes the consumers of a group.
+func (r *AdminRepository) SetGroupPaused(ctx context.Context, group string, paused bool) error {
+	var err error
+	if paused {
+		// An existing pause keeps its original time.
+		err = r.client.SetNX(ctx, pauseKeyPrefix+group, time.Now().UTC().Format(time.RFC3339Nano), 0).Err()
+	} else {
+		err = r.client.Del(ctx, pauseKeyPrefix+group).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update pause of group %s: %w", group, err)
+	}
+	return nil
+}
+
+// GetGroupPause reports whether the consumers of a group are paused.
+func (r *AdminRepository) GetGroupPause(ctx context.Context, group string) (*domain.GroupPause, error) {
+	pause := &domain.GroupPause{Group: group}
+	pausedAt, err := r.client.Get(ctx, pauseKeyPrefix+group).Result()
+	if errors.Is(err, redis.Nil) {
+		return pause, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pause of group %s: %w", group, err)
+	}
+	pause.Paused = true
+	pause.PausedAt, _ = time.Parse(time.RFC3339Nano, pausedAt)
+	return pause, nil
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// dedupKeyPrefix prefixes the keys that mark event IDs as recently buffered.
+const dedupKeyPrefix = "log_dedup:"
+
+// EnableDedup drops events whose ID was already buffered within window, so a client
+// retrying after a timeout does not add the event to the stream twice. Each event costs
+// an extra SET NX round-trip. It must be called before the repository is used.
+func (r *LogRepository) EnableDedup(window time.Duration) {
+	r.dedupWindow = window
+}
+
+// claimEventID marks an event ID as buffered, reporting false if it already was within
+// the dedup window.
+func (r *LogRepository) claimEventID(ctx context.Context, id string) (bool, error) {
+	claimed, err := r.client.SetNX(ctx, dedupKeyPrefix+id, 1, r.dedupWindow).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check event for duplicates: %w", err)
+	}
+	return claimed, nil
+}
+
+// releaseEventID removes the mark of an event that could not be buffered, so its retry
+// is not dropped.
+func (r *LogRepository) releaseEventID(ctx context.Context, id string) {
+	if err := r.client.Del(context.WithoutCancel(ctx), dedupKeyPrefix+id).Err(); err != nil {
+		r.logger.Warn("Failed to clear dedup mark of unbuffered event", "event_id", id, "error", err)
+	}
+}
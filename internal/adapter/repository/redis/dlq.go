@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// dlqChunkSize is how many DLQ entries are re-driven per round-trip.
+const dlqChunkSize = 100
+
+// ListDLQ returns up to count DLQ entries with IDs after the given one, or from the start
+// of the DLQ stream if after is empty.
+func (r *LogRepository) ListDLQ(ctx context.Context, after string, count int64) ([]domain.DLQEntry, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	messages, err := r.client.XRangeN(ctx, r.dlqStreamKey, start, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DLQ stream %s: %w", r.dlqStreamKey, err)
+	}
+	entries := make([]domain.DLQEntry, len(messages))
+	for i, msg := range messages {
+		entries[i] = dlqEntry(msg)
+	}
+	return entries, nil
+}
+
+// GetDLQEntry returns a single DLQ entry.
+func (r *LogRepository) GetDLQEntry(ctx context.Context, id string) (*domain.DLQEntry, error) {
+	messages, err := r.client.XRange(ctx, r.dlqStreamKey, id, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DLQ entry %s: %w", id, err)
+	}
+	if len(messages) == 0 {
+		return nil, domain.ErrDLQEntryNotFound
+	}
+	entry := dlqEntry(messages[0])
+	return &entry, nil
+}
+
+// RedriveDLQ appends the events of the given DLQ entries to the log stream again and
+// removes the entries. Missing entries are ignored.
+func (r *LogRepository) RedriveDLQ(ctx context.Context, ids ...string) (int64, error) {
+	var redriven int64
+	for i := 0; i < len(ids); i += dlqChunkSize {
+		pipe := r.client.Pipeline()
+		var cmds []*redis.XMessageSliceCmd
+		for _, id := range ids[i:min(i+dlqChunkSize, len(ids))] {
+			cmds = append(cmds, pipe.XRange(ctx, r.dlqStreamKey, id, id))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return redriven, fmt.Errorf("failed to read DLQ entries: %w", err)
+		}
+		var messages []redis.XMessage
+		for _, cmd := range cmds {
+			messages = append(messages, cmd.Val()...)
+		}
+		n, err := r.redrive(ctx, messages)
+		redriven += n
+		if err != nil {
+			return redriven, err
+		}
+	}
+	return redriven, nil
+}
+
+// RedriveAllDLQ re-drives every entry in the DLQ when it is called. Events that fail
+// again while it runs are left for a later re-drive.
+func (r *LogRepository) RedriveAllDLQ(ctx context.Context) (int64, error) {
+	last, err := r.client.XRevRangeN(ctx, r.dlqStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read DLQ stream %s: %w", r.dlqStreamKey, err)
+	}
+	if len(last) == 0 {
+		return 0, nil
+	}
+
+	var redriven int64
+	start := "-"
+	for {
+		messages, err := r.client.XRangeN(ctx, r.dlqStreamKey, start, last[0].ID, dlqChunkSize).Result()
+		if err != nil {
+			return redriven, fmt.Errorf("failed to read DLQ stream %s: %w", r.dlqStreamKey, err)
+		}
+		if len(messages) == 0 {
+			return redriven, nil
+		}
+		n, err := r.redrive(ctx, messages)
+		redriven += n
+		if err != nil {
+			return redriven, err
+		}
+		// Entries that could not be decoded stay behind, so the scan moves past them.
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// redrive appends the events of DLQ messages to the log stream and deletes the messages
+// in one transaction, so an event is never in both or neither.
+func (r *LogRepository) redrive(ctx context.Context, messages []redis.XMessage) (int64, error) {
+	pipe := r.client.TxPipeline()
+	var ids []string
+	for _, msg := range messages {
+		var event domain.LogEvent
+		payload, _ := msg.Values["payload"].(string)
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			r.logger.Warn("Failed to unmarshal DLQ entry, leaving it in the DLQ", "message_id", msg.ID, "error", err)
+			continue
+		}
+		args, _, err := r.xaddArgs(event)
+		if err != nil {
+			return 0, err
+		}
+		pipe.XAdd(ctx, args)
+		ids = append(ids, msg.ID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	pipe.XDel(ctx, r.dlqStreamKey, ids...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to re-drive DLQ entries: %w", err)
+	}
+	r.logger.Info("Re-drove DLQ entries", "count", len(ids))
+	return int64(len(ids)), nil
+}
+
+// PurgeDLQ deletes the given DLQ entries.
+func (r *LogRepository) PurgeDLQ(ctx context.Context, ids ...string) (int64, error) {
+	n, err := r.client.XDel(ctx, r.dlqStreamKey, ids...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete DLQ entries: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeAllDLQ empties the DLQ.
+func (r *LogRepository) PurgeAllDLQ(ctx context.Context) (int64, error) {
+	n, err := r.client.XTrimMaxLen(ctx, r.dlqStreamKey, 0).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge DLQ stream %s: %w", r.dlqStreamKey, err)
+	}
+	return n, nil
+}
+
+func dlqEntry(msg redis.XMessage) domain.DLQEntry {
+	entry := domain.DLQEntry{ID: msg.ID}
+	entry.OriginalEventID, _ = msg.Values["original_event_id"].(string)
+	entry.OriginalStream, _ = msg.Values["original_stream"].(string)
+	entry.Cause, _ = msg.Values["cause"].(string)
+	entry.Reason, _ = msg.Values["reason"].(string)
+	entry.Consumer, _ = msg.Values["consumer"].(string)
+	if attempts, ok := msg.Values["attempts"].(string); ok {
+		entry.Attempts, _ = strconv.Atoi(attempts)
+	}
+	if failedAt, ok := msg.Values["failed_at"].(string); ok {
+		entry.FailedAt, _ = time.Parse(time.RFC3339Nano, failedAt)
+	}
+	if payload, ok := msg.Values["payload"].(string); ok && json.Valid([]byte(payload)) {
+		entry.Payload = json.RawMessage(payload)
+	}
+	return entry
+}
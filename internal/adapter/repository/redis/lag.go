@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+)
+
+// MonitorLag periodically reports the length of each shard and how far group is behind
+// on it, until ctx is done.
+func (r *LogRepository) MonitorLag(ctx context.Context, group string, interval time.Duration, m *metrics.ConsumerMetrics) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for shard := 0; shard < r.shards(); shard++ {
+			if err := r.reportLag(ctx, streamKey(shard), group, m); err != nil && ctx.Err() == nil {
+				r.logger.Warn("Failed to check consumer lag", "stream", streamKey(shard), "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *LogRepository) reportLag(ctx context.Context, stream, group string, m *metrics.ConsumerMetrics) error {
+	length, err := r.client.XLen(ctx, stream).Result()
+	if err != nil {
+		return err
+	}
+	m.StreamLength.WithLabelValues(stream).Set(float64(length))
+
+	groups, err := r.client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.Name == group {
+			m.GroupLag.WithLabelValues(stream, group).Set(float64(g.Lag))
+		}
+	}
+
+	pending, err := r.client.XPending(ctx, stream, group).Result()
+	if err != nil {
+		return err
+	}
+	m.PendingMessages.WithLabelValues(stream, group).Set(float64(pending.Count))
+	var age float64
+	if pending.Count > 0 {
+		// Stream IDs start with the time in milliseconds the entry was added.
+		ms, _ := strconv.ParseInt(strings.SplitN(pending.Lower, "-", 2)[0], 10, 64)
+		age = time.Since(time.UnixMilli(ms)).Seconds()
+	}
+	m.OldestPendingAge.WithLabelValues(stream, group).Set(age)
+	return nil
+}
@@ -26,18 +26,32 @@ type LogRepository struct {
 	client       *redis.Client
 	logger       *slog.Logger
 	wal          domain.WALRepository
+	replayPacing ReplayPacing
+	streams      StreamOptions
+	nextShard    atomic.Uint64 // Round-robin position.
 	dlqStreamKey string
 	isAvailable  atomic.Bool
+	batcher      atomic.Pointer[xaddBatcher] // Set while RunXAddBatcher is running.
+	claimStart   []string                    // Per shard, where ClaimStaleLogs resumes its scan; only the reclaim loop uses it.
+	dedupWindow  time.Duration               // 0 disables duplicate detection.
 	metrics      *metrics.IngestMetrics
 }
 
 // NewLogRepository creates a new Redis LogRepository.
-// The WAL is optional; pass nil if not needed (e.g., for consumers).
-func NewLogRepository(client *redis.Client, logger *slog.Logger, group, consumer, dlqStreamKey string, wal domain.WALRepository, m *metrics.IngestMetrics) (*LogRepository, error) {
+// The WAL is optional; pass nil if not needed (e.g., for consumers). replayPacing applies
+// when the WAL is replayed. Producers and consumers must use the same shard count, apart
+// from the rollout of a higher one.
+func NewLogRepository(client *redis.Client, logger *slog.Logger, group, consumer, dlqStreamKey string, wal domain.WALRepository, replayPacing ReplayPacing, streams StreamOptions, m *metrics.IngestMetrics) (*LogRepository, error) {
+	if err := streams.validate(); err != nil {
+		return nil, err
+	}
 	repo := &LogRepository{
 		client:       client,
 		logger:       logger.With("component", "redis_repository"),
 		wal:          wal,
+		replayPacing: replayPacing,
+		streams:      streams,
+		claimStart:   make([]string, max(streams.Shards, 1)),
 		dlqStreamKey: dlqStreamKey,
 		metrics:      m,
 	}
@@ -83,6 +97,10 @@ func (r *LogRepository) StartHealthCheck(ctx context.Context, interval time.Dura
 					r.logger.Error("Failed to replay WAL after Redis recovery", "error", err)
 				} else {
 					r.isAvailable.Store(true)
+					// Drain events written to a new WAL segment during the first pass.
+					if err := r.ReplayWAL(ctx); err != nil {
+						r.logger.Error("Failed to replay WAL after Redis recovery", "error", err)
+					}
 				}
 			} else if !isCurrentlyAvailable && wasAvailable {
 				r.logger.Warn("Redis connection lost. Activating WAL.", "error", err)
@@ -95,38 +113,12 @@ func (r *LogRepository) StartHealthCheck(ctx context.Context, interval time.Dura
 	}
 }
 
-// ReplayWAL replays events from the WAL to Redis and truncates the WAL on success.
-func (r *LogRepository) ReplayWAL(ctx context.Context) error {
-	r.logger.Info("Starting WAL replay to Redis")
-	var replayedCount int
-	replayHandler := func(event domain.LogEvent) error {
-		if err := r.bufferLogToRedis(ctx, event); err != nil {
-			// If we can't write to Redis during replay, something is very wrong.
-			r.logger.Error("Failed to buffer event from WAL to Redis", "event_id", event.ID, "error", err)
-			return err
-		}
-		replayedCount++
-		return nil
-	}
-
-	if err := r.wal.Replay(ctx, replayHandler); err != nil {
-		return fmt.Errorf("WAL replay failed: %w", err)
-	}
-
-	r.logger.Info("WAL replay finished", "replayed_count", replayedCount)
-	if err := r.wal.Truncate(ctx); err != nil {
-		r.logger.Error("Failed to truncate WAL after successful replay", "error", err)
-		return fmt.Errorf("failed to truncate WAL after successful replay: %w", err)
-	}
-
-	r.logger.Info("WAL truncated successfully")
-	return nil
-}
-
 func (r *LogRepository) setupConsumerGroup(ctx context.Context, group string) error {
-	err := r.client.XGroupCreateMkStream(ctx, logStreamKey, group, "0").Err()
-	if err != nil && !isRedisBusyGroupError(err) {
-		return fmt.Errorf("failed to create consumer group: %w", err)
+	for _, stream := range r.streamKeys() {
+		err := r.client.XGroupCreateMkStream(ctx, stream, group, "0").Err()
+		if err != nil && !isRedisBusyGroupError(err) {
+			return fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+		}
 	}
 	return nil
 }
@@ -165,33 +157,74 @@ func (r *LogRepository) BufferLog(ctx context.Context, event domain.LogEvent) er
 }
 
 func (r *LogRepository) bufferLogToRedis(ctx context.Context, event domain.LogEvent) error {
-	payload, err := json.Marshal(event)
-	if err != nil {
-		return fmt.Errorf("failed to marshal log event: %w", err)
+	if r.dedupWindow > 0 && event.ID != "" {
+		claimed, err := r.claimEventID(ctx, event.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			r.logger.Debug("Dropping duplicate event", "event_id", event.ID)
+			if r.metrics != nil {
+				r.metrics.DuplicatesDroppedTotal.Inc()
+			}
+			return nil
+		}
 	}
 
-	args := &redis.XAddArgs{
-		Stream: logStreamKey,
-		Values: map[string]interface{}{"payload": payload},
+	batched, err := r.batchXAdd(ctx, event)
+	if !batched {
+		var args *redis.XAddArgs
+		if args, _, err = r.xaddArgs(event); err != nil {
+			return err
+		}
+		err = r.client.XAdd(ctx, args).Err()
 	}
-
-	if err := r.client.XAdd(ctx, args).Err(); err != nil {
+	if err != nil {
+		if r.dedupWindow > 0 && event.ID != "" {
+			r.releaseEventID(ctx, event.ID)
+		}
 		return fmt.Errorf("failed to XADD to redis stream: %w", err)
 	}
 	return nil
 }
 
-// ReadLogBatch reads a batch of log events from the Redis Stream for a consumer group.
+// xaddArgs builds the XADD that appends an event to its shard of the log stream, along
+// with the size of the payload as stored.
+func (r *LogRepository) xaddArgs(event domain.LogEvent) (*redis.XAddArgs, int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal log event: %w", err)
+	}
+	values := map[string]interface{}{}
+	if codec := r.streams.Codec; codec != "" && codec != CodecNone {
+		payload = encodePayload(codec, payload)
+		values["codec"] = codec
+	}
+	values["payload"] = payload
+	args := &redis.XAddArgs{
+		Stream: streamKey(r.shardFor(event.Source)),
+		Values: values,
+	}
+	r.streams.trim(args)
+	return args, len(payload), nil
+}
+
+// ReadLogBatch reads a batch of log events from all shards of the Redis Stream for a
+// consumer group.
 func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]domain.LogEvent, error) {
+	streams := r.streamKeys()
+	for range r.shards() {
+		streams = append(streams, ">")
+	}
 	args := &redis.XReadGroupArgs{
 		Group:    group,
 		Consumer: consumer,
-		Streams:  []string{logStreamKey, ">"},
-		Count:    int64(count),
+		Streams:  streams,
+		Count:    int64(max(count/r.shards(), 1)),
 		Block:    2 * time.Second,
 	}
 
-	streams, err := r.client.XReadGroup(ctx, args).Result()
+	results, err := r.client.XReadGroup(ctx, args).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, nil
@@ -199,11 +232,15 @@ func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string
 		return nil, fmt.Errorf("failed to XREADGROUP from redis: %w", err)
 	}
 
-	if len(streams) == 0 || len(streams[0].Messages) == 0 {
-		return nil, nil
+	var events []domain.LogEvent
+	for _, result := range results {
+		events = append(events, r.decodeMessages(shardOf(result.Stream), result.Messages)...)
 	}
+	return events, nil
+}
 
-	messages := streams[0].Messages
+// decodeMessages decodes the log events in messages of a shard, skipping malformed ones.
+func (r *LogRepository) decodeMessages(shard int, messages []redis.XMessage) []domain.LogEvent {
 	events := make([]domain.LogEvent, 0, len(messages))
 	for _, msg := range messages {
 		payload, ok := msg.Values["payload"].(string)
@@ -212,16 +249,22 @@ func (r *LogRepository) ReadLogBatch(ctx context.Context, group, consumer string
 			continue
 		}
 
+		codec, _ := msg.Values["codec"].(string)
+		data, err := decodePayload(codec, []byte(payload))
+		if err != nil {
+			r.logger.Warn("Failed to decode log event payload from stream, skipping", "message_id", msg.ID, "codec", codec, "error", err)
+			continue
+		}
+
 		var event domain.LogEvent
-		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		if err := json.Unmarshal(data, &event); err != nil {
 			r.logger.Warn("Failed to unmarshal log event from stream, skipping", "message_id", msg.ID, "error", err)
 			continue
 		}
-		event.StreamMessageID = msg.ID
+		event.StreamMessageID = formatMessageID(shard, msg.ID)
 		events = append(events, event)
 	}
-
-	return events, nil
+	return events
 }
 
 // AcknowledgeLogs acknowledges processed messages in the Redis Stream.
@@ -229,14 +272,23 @@ func (r *LogRepository) AcknowledgeLogs(ctx context.Context, group string, messa
 	if len(messageIDs) == 0 {
 		return nil
 	}
-	if err := r.client.XAck(ctx, logStreamKey, group, messageIDs...).Err(); err != nil {
+	byShard := make(map[int][]string)
+	for _, messageID := range messageIDs {
+		shard, id := parseMessageID(messageID)
+		byShard[shard] = append(byShard[shard], id)
+	}
+	pipe := r.client.Pipeline()
+	for shard, ids := range byShard {
+		pipe.XAck(ctx, streamKey(shard), group, ids...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to XACK messages in redis: %w", err)
 	}
 	return nil
 }
 
 // MoveToDLQ moves a batch of events to the Dead-Letter Queue stream.
-func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent) error {
+func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
 	if len(events) == 0 {
 		return nil
 	}
@@ -248,13 +300,18 @@ func (r *LogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent)
 			r.logger.Error("Failed to marshal event for DLQ", "event_id", event.ID, "error", err)
 			continue
 		}
+		shard, _ := parseMessageID(event.StreamMessageID)
 		args := &redis.XAddArgs{
 			Stream: r.dlqStreamKey,
 			Values: map[string]interface{}{
 				"payload":           payload,
 				"original_event_id": event.ID,
-				"original_stream":   logStreamKey,
-				// "failed_at":       time.Now().UTC().Format(time.RFC3339), // Removed as per attempted content
+				"original_stream":   streamKey(shard),
+				"cause":             failure.Cause,
+				"reason":            failure.Reason,
+				"attempts":          failure.Attempts,
+				"consumer":          failure.Consumer,
+				"failed_at":         failure.FailedAt.Format(time.RFC3339Nano),
 			},
 		}
 		pipe.XAdd(ctx, args)
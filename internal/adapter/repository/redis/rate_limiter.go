@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// tokenBucketScript atomically refills and consumes a token bucket stored as a hash.
+// It uses the Redis server clock so that all ingest replicas agree on elapsed time.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry_after = (cost - tokens) / rate
+end
+
+redis.call('HSET', key, 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens), tostring(retry_after), tostring((burst - tokens) / rate)}
+`)
+
+// RateLimiter implements domain.RateLimiter using a token bucket stored in Redis.
+type RateLimiter struct {
+	client *redis.Client
+}
+
+// NewRateLimiter creates a new Redis-backed RateLimiter.
+func NewRateLimiter(client *redis.Client) *RateLimiter {
+	return &RateLimiter{client: client}
+}
+
+// Allow consumes cost tokens from the bucket identified by key.
+func (l *RateLimiter) Allow(ctx context.Context, key string, ratePerSecond float64, burst int64, cost int64) (*domain.RateLimitResult, error) {
+	if ratePerSecond <= 0 || burst <= 0 {
+		return nil, fmt.Errorf("invalid rate limit parameters: rate=%v burst=%d", ratePerSecond, burst)
+	}
+
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{rateLimitKeyPrefix + key}, ratePerSecond, burst, cost).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(res) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result length: %d", len(res))
+	}
+
+	allowed, _ := res[0].(int64)
+	tokens, err := parseScriptFloat(res[1])
+	if err != nil {
+		return nil, err
+	}
+	retryAfter, err := parseScriptFloat(res[2])
+	if err != nil {
+		return nil, err
+	}
+	resetAfter, err := parseScriptFloat(res[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.RateLimitResult{
+		Allowed:    allowed == 1,
+		Limit:      burst,
+		Remaining:  int64(math.Floor(tokens)),
+		ResetAfter: secondsToDuration(resetAfter),
+		RetryAfter: secondsToDuration(retryAfter),
+	}, nil
+}
+
+func parseScriptFloat(v interface{}) (float64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected rate limit script value type %T", v)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse rate limit script value %q: %w", s, err)
+	}
+	return f, nil
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
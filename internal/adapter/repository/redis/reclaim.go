@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// ClaimStaleLogs takes over up to count messages that have been pending in group for at
+// least minIdle, typically because the consumer that read them died, and sets each
+// event's DeliveryCount. Successive calls scan the pending entries list of each shard
+// from where the previous one stopped and wrap around at its end.
+func (r *LogRepository) ClaimStaleLogs(ctx context.Context, group, consumer string, minIdle time.Duration, count int) ([]domain.LogEvent, error) {
+	var events []domain.LogEvent
+	for shard := 0; shard < r.shards() && len(events) < count; shard++ {
+		claimed, err := r.claimStaleShard(ctx, shard, group, consumer, minIdle, count-len(events))
+		if err != nil {
+			return events, err
+		}
+		events = append(events, claimed...)
+	}
+	return events, nil
+}
+
+func (r *LogRepository) claimStaleShard(ctx context.Context, shard int, group, consumer string, minIdle time.Duration, count int) ([]domain.LogEvent, error) {
+	start := r.claimStart[shard]
+	if start == "" {
+		start = "0-0"
+	}
+	stream := streamKey(shard)
+	messages, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    start,
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to XAUTOCLAIM from %s: %w", stream, err)
+	}
+	r.claimStart[shard] = next
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	// XAUTOCLAIM does not report delivery counts, so they are read back from the now
+	// claimed entries.
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   stream,
+		Group:    group,
+		Start:    messages[0].ID,
+		End:      messages[len(messages)-1].ID,
+		Count:    int64(len(messages)),
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery counts of claimed messages: %w", err)
+	}
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		deliveries[formatMessageID(shard, p.ID)] = p.RetryCount
+	}
+
+	events := r.decodeMessages(shard, messages)
+	for i := range events {
+		events[i].DeliveryCount = deliveries[events[i].StreamMessageID]
+	}
+	return events, nil
+}
@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// replayChunkSize is how many events one replay worker sends to Redis per pipeline.
+	replayChunkSize = 100
+	// replayProgressInterval is how often a running replay logs its progress.
+	replayProgressInterval = 10 * time.Second
+)
+
+// ReplayPacing limits how fast the WAL is replayed into Redis, so that a Redis that has
+// just recovered is not knocked over again by the whole backlog at once. Zero rates are
+// unlimited.
+type ReplayPacing struct {
+	EventsPerSecond float64
+	BytesPerSecond  float64
+	Concurrency     int // Pipelines in flight at once; values below 1 mean 1.
+}
+
+// ReplayWAL replays events from the WAL to Redis, paced by the repository's
+// ReplayPacing. The WAL deletes segments as they are replayed and resumes from its
+// cursor if a previous replay was interrupted.
+func (r *LogRepository) ReplayWAL(ctx context.Context) error {
+	concurrency := max(r.replayPacing.Concurrency, 1)
+	eventLimiter := newReplayLimiter(r.replayPacing.EventsPerSecond)
+	byteLimiter := newReplayLimiter(r.replayPacing.BytesPerSecond)
+
+	r.logger.Info("Starting WAL replay to Redis", "events_per_second", r.replayPacing.EventsPerSecond, "bytes_per_second", r.replayPacing.BytesPerSecond, "concurrency", concurrency)
+	start := time.Now()
+	lastProgress := start
+	var replayedCount, replayedBytes int64
+
+	handler := func(events []domain.LogEvent) error {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var errs []error
+		for i := 0; i < len(events); i += replayChunkSize {
+			chunk := events[i:min(i+replayChunkSize, len(events))]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				n, err := r.replayChunk(ctx, chunk, eventLimiter, byteLimiter)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+				replayedCount += int64(len(chunk))
+				replayedBytes += int64(n)
+				if r.metrics != nil {
+					r.metrics.WALReplayedEventsTotal.Add(float64(len(chunk)))
+					r.metrics.WALReplayedBytesTotal.Add(float64(n))
+				}
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			// The whole batch is replayed again, so events that did reach Redis are
+			// duplicated; the sink's upsert on event_id absorbs that.
+			r.logger.Error("Failed to buffer events from WAL to Redis", "batch_size", len(events), "error", err)
+			return err
+		}
+
+		if time.Since(lastProgress) >= replayProgressInterval {
+			lastProgress = time.Now()
+			r.logger.Info("WAL replay progress", "replayed_count", replayedCount, "replayed_bytes", replayedBytes,
+				"events_per_second", float64(replayedCount)/time.Since(start).Seconds())
+		}
+		return nil
+	}
+
+	if err := r.wal.ReplayBatch(ctx, concurrency*replayChunkSize, handler); err != nil {
+		if r.metrics != nil {
+			r.metrics.WALReplayErrorsTotal.Inc()
+		}
+		r.logger.Error("WAL replay stopped", "replayed_count", replayedCount)
+		return fmt.Errorf("WAL replay failed: %w", err)
+	}
+
+	r.logger.Info("WAL replay finished", "replayed_count", replayedCount, "replayed_bytes", replayedBytes, "duration", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// replayChunk sends events to Redis in one pipeline once the limiters allow it and
+// returns the number of payload bytes sent.
+func (r *LogRepository) replayChunk(ctx context.Context, events []domain.LogEvent, eventLimiter, byteLimiter *rate.Limiter) (int, error) {
+	pipe := r.client.Pipeline()
+	var size int
+	for _, event := range events {
+		args, n, err := r.xaddArgs(event)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+		pipe.XAdd(ctx, args)
+	}
+
+	if err := waitReplayLimiter(ctx, eventLimiter, len(events)); err != nil {
+		return 0, err
+	}
+	if err := waitReplayLimiter(ctx, byteLimiter, size); err != nil {
+		return 0, err
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to XADD replayed events to redis stream: %w", err)
+	}
+	return size, nil
+}
+
+// newReplayLimiter returns a limiter for perSecond with a burst of one second, or nil if
+// perSecond is not positive.
+func newReplayLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), max(int(perSecond), 1))
+}
+
+// waitReplayLimiter waits until the limiter allows n more units, in steps no larger than
+// its burst. A nil limiter never waits.
+func waitReplayLimiter(ctx context.Context, l *rate.Limiter, n int) error {
+	if l == nil {
+		return nil
+	}
+	for n > 0 {
+		step := min(n, l.Burst())
+		if err := l.WaitN(ctx, step); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Shard policies for StreamOptions.ShardPolicy.
+const (
+	ShardRoundRobin = "round_robin" // Spread events evenly over the shards.
+	ShardBySource   = "source"      // Keep the events of a source in one shard, in order.
+)
+
+// StreamOptions describes how the log stream is laid out in Redis.
+//
+// Shards spreads the stream over several streams so that no single key takes all writes.
+// Shard 0 is the original log_events stream and shard i > 0 is log_events:i, so an
+// unsharded deployment can be expanded online: a repository started with the higher
+// count creates the consumer group on the new shards from their first entry, so what
+// producers write there waits until the consumers are restarted with the new count.
+// Shrinking strands the entries of the removed shards.
+//
+// MaxLen or MaxAge bound each shard, trimming the oldest entries on XADD, consumed or
+// not, so Redis memory cannot grow without limit while consumers lag.
+//
+// Codec compresses the payload of new entries; see CodecNone.
+type StreamOptions struct {
+	Shards      int // Values below 1 mean 1.
+	ShardPolicy string
+	MaxLen      int64         // Approximate entries kept per shard, 0 is unbounded.
+	MaxAge      time.Duration // Approximate age of the oldest entry kept, 0 is unbounded.
+	Codec       string
+}
+
+func (o StreamOptions) validate() error {
+	switch o.ShardPolicy {
+	case "", ShardRoundRobin, ShardBySource:
+	default:
+		return fmt.Errorf("unknown stream shard policy %q", o.ShardPolicy)
+	}
+	switch o.Codec {
+	case "", CodecNone, CodecSnappy, CodecZstd:
+	default:
+		return fmt.Errorf("unknown stream payload codec %q", o.Codec)
+	}
+	if o.MaxLen > 0 && o.MaxAge > 0 {
+		return errors.New("stream max length and max age cannot both be set")
+	}
+	return nil
+}
+
+// trim sets the approximate trimming of an XADD.
+func (o StreamOptions) trim(args *redis.XAddArgs) {
+	switch {
+	case o.MaxLen > 0:
+		args.MaxLen = o.MaxLen
+		args.Approx = true
+	case o.MaxAge > 0:
+		args.MinID = strconv.FormatInt(time.Now().Add(-o.MaxAge).UnixMilli(), 10)
+		args.Approx = true
+	}
+}
+
+// streamKey returns the stream of a shard.
+func streamKey(shard int) string {
+	if shard == 0 {
+		return logStreamKey
+	}
+	return logStreamKey + ":" + strconv.Itoa(shard)
+}
+
+// shardOf returns the shard of a stream key.
+func shardOf(stream string) int {
+	_, suffix, ok := strings.Cut(stream, ":")
+	if !ok {
+		return 0
+	}
+	shard, _ := strconv.Atoi(suffix)
+	return shard
+}
+
+// streamKeys returns the streams of all shards.
+func (r *LogRepository) streamKeys() []string {
+	keys := make([]string, r.shards())
+	for i := range keys {
+		keys[i] = streamKey(i)
+	}
+	return keys
+}
+
+// shardFor picks the shard an event is appended to.
+func (r *LogRepository) shardFor(source string) int {
+	shards := r.shards()
+	if shards == 1 {
+		return 0
+	}
+	if r.streams.ShardPolicy == ShardBySource {
+		h := fnv.New32a()
+		h.Write([]byte(source))
+		return int(h.Sum32() % uint32(shards))
+	}
+	return int(r.nextShard.Add(1) % uint64(shards))
+}
+
+func (r *LogRepository) shards() int {
+	return max(r.streams.Shards, 1)
+}
+
+// Message IDs of sharded streams are encoded as "shard/id" in LogEvent.StreamMessageID so
+// that AcknowledgeLogs knows which stream to acknowledge them in. IDs of shard 0 are left
+// as they are.
+func formatMessageID(shard int, id string) string {
+	if shard == 0 {
+		return id
+	}
+	return strconv.Itoa(shard) + "/" + id
+}
+
+func parseMessageID(id string) (int, string) {
+	s, streamID, ok := strings.Cut(id, "/")
+	if !ok {
+		return 0, id
+	}
+	shard, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, id
+	}
+	return shard, streamID
+}
+
+// MonitorEvictions periodically counts the entries that trimming removed from the shards
+// before group read them, until ctx is done. Every replica observes the same total, so
+// the counter should be aggregated with max rather than sum.
+func (r *LogRepository) MonitorEvictions(ctx context.Context, group string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	observed := make([]int64, r.shards())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for shard := range observed {
+				evicted, err := r.evictedUnread(ctx, streamKey(shard), group)
+				if err != nil {
+					r.logger.Warn("Failed to check stream for evicted entries", "stream", streamKey(shard), "error", err)
+					continue
+				}
+				if evicted > observed[shard] {
+					r.logger.Warn("Stream entries were trimmed before they were consumed", "stream", streamKey(shard), "count", evicted-observed[shard])
+					if r.metrics != nil {
+						r.metrics.StreamEvictedUnreadTotal.Add(float64(evicted - observed[shard]))
+					}
+				}
+				observed[shard] = max(observed[shard], evicted)
+			}
+		}
+	}
+}
+
+// evictedUnread returns how many entries have been removed from a stream without group
+// reading them. Trimming removes the oldest entries, so those are the removed entries
+// beyond the ones the group has read.
+func (r *LogRepository) evictedUnread(ctx context.Context, stream, group string) (int64, error) {
+	info, err := r.client.XInfoStream(ctx, stream).Result()
+	if err != nil {
+		return 0, err
+	}
+	groups, err := r.client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, g := range groups {
+		if g.Name == group {
+			return max(info.EntriesAdded-info.Length-g.EntriesRead, 0), nil
+		}
+	}
+	return 0, fmt.Errorf("consumer group %s not found", group)
+}
@@ -0,0 +1,77 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AgeLimits configures time-based rotation and retention. Zero values disable them.
+type AgeLimits struct {
+	// SegmentMaxAge starts a new segment once the current one is this old, so retention
+	// can drop old events even when little is written.
+	SegmentMaxAge time.Duration
+	// Retention deletes segments whose newest event was written longer ago than this.
+	// Replaying them would only duplicate events that clients have long since resent or
+	// that downstream deduplication no longer recognizes.
+	Retention time.Duration
+}
+
+// segmentCreatedAt returns the creation time encoded in a segment's name, or the zero
+// time if the name does not carry one.
+func segmentCreatedAt(segmentPath string) time.Time {
+	name := strings.TrimPrefix(filepath.Base(segmentPath), segmentPrefix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, segmentExt), legacySegmentExt)
+	nanos, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// segmentExpired reports whether the current segment has reached SegmentMaxAge and
+// holds at least one record.
+func (w *WALRepository) segmentExpired() bool {
+	return w.ageLimits.SegmentMaxAge > 0 && w.currentSegment != nil && w.currentSize > 0 &&
+		time.Since(w.currentCreatedAt) >= w.ageLimits.SegmentMaxAge
+}
+
+// expireSegments deletes segments older than the retention period. A segment's age is
+// that of its last write, so no event it holds is younger than the retention period.
+// It is called with w.mu held.
+func (w *WALRepository) expireSegments() error {
+	if w.ageLimits.Retention <= 0 {
+		return nil
+	}
+	segments, err := w.getSortedSegments()
+	if err != nil {
+		return err
+	}
+	for _, segmentPath := range segments {
+		info, err := os.Stat(segmentPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat WAL segment %s: %w", segmentPath, err)
+		}
+		age := time.Since(info.ModTime())
+		if age < w.ageLimits.Retention {
+			continue
+		}
+		if segmentPath == w.currentPath && w.currentSegment != nil {
+			if w.currentSize == 0 {
+				continue
+			}
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+		if err := w.removeSegment(segmentPath); err != nil {
+			return fmt.Errorf("failed to delete expired WAL segment %s: %w", segmentPath, err)
+		}
+		w.metrics.WALExpiredSegmentsTotal.Inc()
+		w.logger.Warn("Deleted expired WAL segment without replaying it", "path", segmentPath, "size", info.Size(), "age", age.Round(time.Second))
+	}
+	return nil
+}
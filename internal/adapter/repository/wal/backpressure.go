@@ -0,0 +1,119 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Backpressure policies applied when a write would exceed the WAL's maximum total size.
+const (
+	// PolicyBlock waits for a replay to free space, up to Backpressure.BlockTimeout.
+	PolicyBlock = "block"
+	// PolicyDropOldest deletes the oldest segments, losing their events, to make room.
+	PolicyDropOldest = "drop_oldest"
+	// PolicyReject fails the write at once so that clients are told to retry.
+	PolicyReject = "reject"
+)
+
+// defaultBlockTimeout applies when PolicyBlock is configured without a timeout.
+const defaultBlockTimeout = 5 * time.Second
+
+// Backpressure configures how the WAL behaves once it is full. Writes that cannot be
+// accepted fail with domain.ErrBufferFull.
+type Backpressure struct {
+	Policy       string
+	BlockTimeout time.Duration
+}
+
+// ParsePolicy validates a BACKPRESSURE_POLICY value. "drop" is accepted as an alias of
+// drop_oldest and an empty value selects block.
+func ParsePolicy(s string) (string, error) {
+	switch s {
+	case "", PolicyBlock:
+		return PolicyBlock, nil
+	case PolicyDropOldest, "drop":
+		return PolicyDropOldest, nil
+	case PolicyReject:
+		return PolicyReject, nil
+	}
+	return "", fmt.Errorf("unknown backpressure policy %q", s)
+}
+
+// reserve makes room for size more bytes according to the backpressure policy. It is
+// called with w.mu held, which PolicyBlock releases while it waits.
+func (w *WALRepository) reserve(ctx context.Context, size int64) error {
+	if w.totalSize+size <= w.maxTotalSize {
+		return nil
+	}
+	if size > w.maxTotalSize {
+		return fmt.Errorf("%w: record of %d bytes exceeds the WAL max total size of %d", domain.ErrBufferFull, size, w.maxTotalSize)
+	}
+
+	switch w.backpressure.Policy {
+	case PolicyDropOldest:
+		for w.totalSize+size > w.maxTotalSize {
+			if err := w.dropOldestSegment(); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case PolicyBlock:
+		timeout := w.backpressure.BlockTimeout
+		if timeout <= 0 {
+			timeout = defaultBlockTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for w.totalSize+size > w.maxTotalSize {
+			freed := w.spaceFreed
+			w.mu.Unlock()
+			select {
+			case <-freed:
+				w.mu.Lock()
+			case <-timer.C:
+				w.mu.Lock()
+				return fmt.Errorf("%w: WAL still full after waiting %s", domain.ErrBufferFull, timeout)
+			case <-ctx.Done():
+				w.mu.Lock()
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: WAL max total size exceeded (%d > %d)", domain.ErrBufferFull, w.totalSize, w.maxTotalSize)
+}
+
+// dropOldestSegment deletes the oldest segment. If that is the segment being written, a
+// new one is started first.
+func (w *WALRepository) dropOldestSegment() error {
+	segments, err := w.getSortedSegments()
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 || (len(segments) == 1 && segments[0] == w.currentPath && w.currentSize == 0) {
+		return fmt.Errorf("%w: no WAL segment left to drop", domain.ErrBufferFull)
+	}
+
+	oldest := segments[0]
+	if oldest == w.currentPath {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	info, err := os.Stat(oldest)
+	if err != nil {
+		return fmt.Errorf("failed to stat WAL segment %s: %w", oldest, err)
+	}
+	if err := w.removeSegment(oldest); err != nil {
+		return fmt.Errorf("failed to drop WAL segment %s: %w", oldest, err)
+	}
+	w.metrics.WALDroppedSegmentsTotal.Inc()
+	w.logger.Error("WAL full, dropped oldest segment", "path", oldest, "size", info.Size())
+	return nil
+}
@@ -0,0 +1,90 @@
+package wal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// encryptedRecordVersion prefixes the payload of an encrypted record, followed by the
+// nonce and the AES-GCM ciphertext. Plain payloads are JSON objects and start with '{',
+// so both kinds can be told apart within one segment.
+const encryptedRecordVersion = 0x01
+
+// LoadEncryptionKey returns the AES key configured for the WAL, base64-encoded either in
+// key or in the file keyFile. keyFile suits keys provisioned by a secret manager or
+// decrypted from KMS at deploy time. A nil key means encryption is disabled.
+func LoadEncryptionKey(key, keyFile string) ([]byte, error) {
+	if key == "" && keyFile == "" {
+		return nil, nil
+	}
+	if key == "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WAL encryption key file: %w", err)
+		}
+		key = string(bytes.TrimSpace(data))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("WAL encryption key is not valid base64: %w", err)
+	}
+	if n := len(decoded); n != 16 && n != 24 && n != 32 {
+		return nil, fmt.Errorf("WAL encryption key must be 16, 24 or 32 bytes, got %d", n)
+	}
+	return decoded, nil
+}
+
+// EnableEncryption makes the WAL encrypt the records it writes with AES-GCM under key.
+// Records written before encryption was enabled are still replayed. It must be called
+// before the WAL is used.
+func (w *WALRepository) EnableEncryption(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL cipher: %w", err)
+	}
+	w.aead = aead
+	return nil
+}
+
+// seal encrypts a payload if encryption is enabled.
+func (w *WALRepository) seal(payload []byte) ([]byte, error) {
+	if w.aead == nil {
+		return payload, nil
+	}
+	out := make([]byte, 1+w.aead.NonceSize(), 1+w.aead.NonceSize()+len(payload)+w.aead.Overhead())
+	out[0] = encryptedRecordVersion
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, fmt.Errorf("failed to generate WAL record nonce: %w", err)
+	}
+	return w.aead.Seal(out, out[1:], payload, nil), nil
+}
+
+// open decrypts a payload written by seal and passes plain payloads through. A record
+// that cannot be decrypted is reported as corrupt, so its segment is quarantined rather
+// than deleted.
+func (w *WALRepository) open(payload []byte) ([]byte, error) {
+	if len(payload) == 0 || payload[0] != encryptedRecordVersion {
+		return payload, nil
+	}
+	if w.aead == nil {
+		return nil, fmt.Errorf("%w: record is encrypted but no WAL encryption key is configured", errCorruptRecord)
+	}
+	nonceSize := w.aead.NonceSize()
+	if len(payload) < 1+nonceSize {
+		return nil, fmt.Errorf("%w: encrypted record too short", errCorruptRecord)
+	}
+	plain, err := w.aead.Open(nil, payload[1:1+nonceSize], payload[1+nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decrypt record: %v", errCorruptRecord, err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,150 @@
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Status reports the WAL's segments, quarantined segments, replay cursor and the age of
+// the oldest event still waiting to be replayed.
+func (w *WALRepository) Status(ctx context.Context) (*domain.WALStatus, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segments, err := w.getSortedSegments()
+	if err != nil {
+		return nil, err
+	}
+	status := &domain.WALStatus{Segments: []domain.WALSegmentInfo{}}
+	for _, segmentPath := range segments {
+		info, err := segmentInfo(segmentPath)
+		if err != nil {
+			return nil, err
+		}
+		status.Segments = append(status.Segments, info)
+		status.TotalSizeBytes += info.SizeBytes
+	}
+
+	entries, err := os.ReadDir(filepath.Join(w.dir, quarantineDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read WAL quarantine directory: %w", err)
+	}
+	for _, entry := range entries {
+		info, err := segmentInfo(filepath.Join(w.dir, quarantineDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		status.Quarantined = append(status.Quarantined, info)
+	}
+
+	cursor := w.loadCursor()
+	status.CursorSegment, status.CursorOffset = cursor.Segment, cursor.Offset
+
+	oldest, err := w.peek(ctx, segments, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(oldest) > 0 && !oldest[0].ReceivedAt.IsZero() {
+		status.OldestEventTime = oldest[0].ReceivedAt
+		status.OldestEventAgeSeconds = time.Since(oldest[0].ReceivedAt).Seconds()
+	}
+	return status, nil
+}
+
+// Peek returns up to count of the oldest unreplayed events, starting at the replay
+// cursor, without removing them. Reading stops early at a corrupt record.
+func (w *WALRepository) Peek(ctx context.Context, count int) ([]domain.LogEvent, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segments, err := w.getSortedSegments()
+	if err != nil {
+		return nil, err
+	}
+	return w.peek(ctx, segments, count)
+}
+
+func (w *WALRepository) peek(ctx context.Context, segments []string, count int) ([]domain.LogEvent, error) {
+	events := []domain.LogEvent{}
+	cursor := w.loadCursor()
+	for _, segmentPath := range segments {
+		if len(events) >= count {
+			break
+		}
+		var start int64
+		if filepath.Base(segmentPath) == cursor.Segment {
+			start = cursor.Offset
+		}
+		segmentEvents, err := w.readSegment(ctx, segmentPath, start, count-len(events))
+		events = append(events, segmentEvents...)
+		if err != nil {
+			if errors.Is(err, errCorruptRecord) {
+				break
+			}
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// readSegment reads up to count events from a segment, starting at offset start.
+func (w *WALRepository) readSegment(ctx context.Context, segmentPath string, start int64, count int) ([]domain.LogEvent, error) {
+	file, err := os.Open(segmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment %s: %w", segmentPath, err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek segment %s to %d: %w", segmentPath, start, err)
+	}
+
+	var events []domain.LogEvent
+	if strings.HasSuffix(segmentPath, legacySegmentExt) {
+		scanner := bufio.NewScanner(file)
+		for len(events) < count && scanner.Scan() {
+			var event domain.LogEvent
+			if json.Unmarshal(scanner.Bytes(), &event) == nil {
+				events = append(events, event)
+			}
+		}
+		return events, scanner.Err()
+	}
+
+	r := bufio.NewReader(file)
+	for len(events) < count {
+		if ctx.Err() != nil {
+			return events, ctx.Err()
+		}
+		payload, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		event, err := w.decodeEvent(payload)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func segmentInfo(path string) (domain.WALSegmentInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return domain.WALSegmentInfo{}, fmt.Errorf("failed to stat WAL segment %s: %w", path, err)
+	}
+	return domain.WALSegmentInfo{Name: info.Name(), SizeBytes: info.Size(), ModifiedAt: info.ModTime()}, nil
+}
@@ -0,0 +1,63 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Records are framed as a little-endian uint32 payload length and the CRC-32C of the
+// payload, followed by the payload itself, a JSON-encoded LogEvent, encrypted if the WAL
+// has an encryption key.
+const recordHeaderSize = 8
+
+// maxRecordSize bounds the length field so a corrupt header cannot exhaust memory.
+const maxRecordSize = 64 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorruptRecord is returned for torn writes, checksum mismatches and implausible lengths.
+var errCorruptRecord = errors.New("corrupt WAL record")
+
+// errTornRecord additionally marks a record cut short by the end of the segment, as left
+// behind by a crash during a write.
+var errTornRecord = errors.New("torn WAL record")
+
+func encodeRecord(payload []byte) []byte {
+	buf := make([]byte, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	copy(buf[recordHeaderSize:], payload)
+	return buf
+}
+
+// readRecord returns the next payload. io.EOF is returned only at a record boundary; a
+// segment ending inside a record is reported as corrupt.
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w: truncated header", errCorruptRecord, errTornRecord)
+		}
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("%w: length %d exceeds %d bytes", errCorruptRecord, size, maxRecordSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %w: truncated payload", errCorruptRecord, errTornRecord)
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", errCorruptRecord)
+	}
+	return payload, nil
+}
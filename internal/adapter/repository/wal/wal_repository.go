@@ -3,8 +3,11 @@ package wal
 import (
 	"bufio"
 	"context"
+	"crypto/cipher"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -13,12 +16,24 @@ import (
 	"sync"
 	"time"
 
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
 	"github.com/V4T54L/watch-tower/internal/domain"
 )
 
 const (
 	segmentPrefix = "segment-"
-	filePerm      = 0644
+	segmentExt    = ".wal"
+	// legacySegmentExt marks segments written as bare JSON lines before records were
+	// checksummed. They are still replayed but never appended to.
+	legacySegmentExt = ".log"
+	quarantineDir    = "quarantine"
+	cursorFile       = "replay.cursor"
+	// cursorSaveEvery is how many replayed events may pass between cursor writes.
+	cursorSaveEvery = 100
+	// sizeReconcileInterval is how often the cached total size is checked against the
+	// directory, correcting drift from segments removed or added by hand.
+	sizeReconcileInterval = time.Minute
+	filePerm              = 0644
 )
 
 // WALRepository implements a file-based Write-Ahead Log.
@@ -26,15 +41,29 @@ type WALRepository struct {
 	dir            string
 	maxSegmentSize int64
 	maxTotalSize   int64
+	backpressure   Backpressure
+	ageLimits      AgeLimits
 	logger         *slog.Logger
-
-	mu             sync.Mutex
-	currentSegment *os.File
-	currentSize    int64
+	metrics        *metrics.IngestMetrics
+	aead           cipher.AEAD // Encrypts record payloads; nil leaves them in plain text.
+
+	// replayMu serializes replays and truncation. A replay holds it, but not mu, while it
+	// reads segments and runs the handler, so writes go on to a new segment meanwhile.
+	replayMu sync.Mutex
+
+	mu               sync.Mutex
+	currentSegment   *os.File
+	currentPath      string
+	currentSize      int64
+	currentCreatedAt time.Time
+	totalSize        int64 // Bytes in all segments, maintained by every operation that changes them.
+	lastReconcile    time.Time
+	oldestEvent      time.Time     // Receive time of the oldest unreplayed event, zero if the WAL is empty.
+	spaceFreed       chan struct{} // Closed and replaced whenever totalSize shrinks.
 }
 
 // NewWALRepository creates a new WALRepository.
-func NewWALRepository(dir string, maxSegmentSize, maxTotalSize int64, logger *slog.Logger) (*WALRepository, error) {
+func NewWALRepository(dir string, maxSegmentSize, maxTotalSize int64, backpressure Backpressure, ageLimits AgeLimits, logger *slog.Logger, m *metrics.IngestMetrics) (*WALRepository, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create WAL directory %s: %w", dir, err)
 	}
@@ -43,48 +72,71 @@ func NewWALRepository(dir string, maxSegmentSize, maxTotalSize int64, logger *sl
 		dir:            dir,
 		maxSegmentSize: maxSegmentSize,
 		maxTotalSize:   maxTotalSize,
+		backpressure:   backpressure,
+		ageLimits:      ageLimits,
 		logger:         logger.With("component", "wal_repository"),
+		metrics:        m,
+		spaceFreed:     make(chan struct{}),
 	}
 
 	if err := w.openLatestSegment(); err != nil {
 		return nil, err
 	}
+	if err := w.reconcileSize(); err != nil {
+		return nil, fmt.Errorf("failed to calculate WAL size: %w", err)
+	}
+	w.refreshGauges()
 
 	return w, nil
 }
 
 // Write appends an event to the current WAL segment.
 func (w *WALRepository) Write(ctx context.Context, event domain.LogEvent) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	data, err := json.Marshal(event)
+	payload, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal log event for WAL: %w", err)
 	}
-	data = append(data, '\n')
+	if payload, err = w.seal(payload); err != nil {
+		return err
+	}
+	data := encodeRecord(payload)
 
-	if w.currentSegment == nil {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Since(w.lastReconcile) >= sizeReconcileInterval {
+		if err := w.reconcileSize(); err != nil {
+			w.logger.Error("Failed to reconcile WAL size", "error", err)
+		}
+		if err := w.expireSegments(); err != nil {
+			w.logger.Error("Failed to delete expired WAL segments", "error", err)
+		}
+	}
+	if w.segmentExpired() {
 		if err := w.rotate(); err != nil {
 			return err
 		}
 	}
-
-	// Check total size before writing
-	totalSize, err := w.calculateTotalSize()
-	if err != nil {
-		w.logger.Error("Failed to calculate total WAL size", "error", err)
-		return fmt.Errorf("could not verify WAL disk space: %w", err)
+	// Make room before writing; this may wait for a replay, which closes the segment.
+	if err := w.reserve(ctx, int64(len(data))); err != nil {
+		return err
 	}
-	if totalSize+int64(len(data)) > w.maxTotalSize {
-		return fmt.Errorf("WAL max total size exceeded (%d > %d)", totalSize, w.maxTotalSize)
+
+	if w.currentSegment == nil {
+		if err := w.rotate(); err != nil {
+			return err
+		}
 	}
 
 	n, err := w.currentSegment.Write(data)
+	w.addSize(int64(n))
 	if err != nil {
 		return fmt.Errorf("failed to write to WAL segment: %w", err)
 	}
 	w.currentSize += int64(n)
+	if w.oldestEvent.IsZero() {
+		w.setOldestEvent(event.ReceivedAt)
+	}
 
 	if w.currentSize >= w.maxSegmentSize {
 		if err := w.rotate(); err != nil {
@@ -95,17 +147,27 @@ func (w *WALRepository) Write(ctx context.Context, event domain.LogEvent) error
 	return nil
 }
 
-// Replay reads all WAL segments and calls the handler for each event.
+// Replay calls the handler for each event in the WAL, oldest first. See ReplayBatch.
 func (w *WALRepository) Replay(ctx context.Context, handler func(event domain.LogEvent) error) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if w.currentSegment != nil {
-		w.currentSegment.Close()
-		w.currentSegment = nil
-	}
+	return w.ReplayBatch(ctx, 1, func(events []domain.LogEvent) error {
+		return handler(events[0])
+	})
+}
 
-	segments, err := w.getSortedSegments()
+// ReplayBatch calls the handler with batches of up to batchSize events in the WAL, oldest
+// first, resuming from the replay cursor left by an earlier, interrupted replay. Each
+// segment is deleted once it has been replayed, and the cursor is persisted periodically
+// and whenever the handler fails, so a failed replay neither repeats finished segments
+// nor loses its progress. The cursor only moves past whole batches: a batch the handler
+// fails is replayed again in full. After a crash, at most cursorSaveEvery events plus one
+// batch are replayed again.
+// Only the segments that exist when the replay starts are replayed; the current one is
+// sealed, and events written during the replay go to a new segment, left for the next.
+func (w *WALRepository) ReplayBatch(ctx context.Context, batchSize int, handler func(events []domain.LogEvent) error) error {
+	w.replayMu.Lock()
+	defer w.replayMu.Unlock()
+
+	segments, err := w.sealSegments()
 	if err != nil {
 		return err
 	}
@@ -114,44 +176,298 @@ func (w *WALRepository) Replay(ctx context.Context, handler func(event domain.Lo
 		w.logger.Info("WAL is empty, nothing to replay")
 		return nil
 	}
-	w.logger.Info("Starting WAL replay", "segment_count", len(segments))
+	cursor := w.loadCursor()
+	w.logger.Info("Starting WAL replay", "segment_count", len(segments), "cursor_segment", cursor.Segment, "cursor_offset", cursor.Offset)
 
 	for _, segmentPath := range segments {
-		file, err := os.Open(segmentPath)
-		if err != nil {
-			return fmt.Errorf("failed to open segment %s for replay: %w", segmentPath, err)
+		name := filepath.Base(segmentPath)
+		var start int64
+		if name == cursor.Segment {
+			start = cursor.Offset
+		}
+		b := &replayBatcher{
+			size:    max(batchSize, 1),
+			handler: handler,
+			logger:  w.logger,
+			saveCursor: func(offset int64) {
+				if err := w.saveCursor(replayCursor{Segment: name, Offset: offset}); err != nil {
+					w.logger.Warn("Failed to persist WAL replay cursor", "error", err)
+				}
+			},
 		}
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			if ctx.Err() != nil {
-				file.Close()
-				return ctx.Err()
+		replaySegment := w.replaySegment
+		if strings.HasSuffix(segmentPath, legacySegmentExt) {
+			replaySegment = w.replayLegacySegment
+		}
+		err := replaySegment(ctx, segmentPath, start, b)
+		if errors.Is(err, os.ErrNotExist) {
+			// Deleted by retention or backpressure since the replay started.
+			continue
+		}
+		if errors.Is(err, errCorruptRecord) {
+			// Events before the corruption have been replayed; the segment is kept for
+			// inspection instead of being deleted.
+			w.mu.Lock()
+			err = w.quarantine(segmentPath, err)
+			w.mu.Unlock()
+			if err != nil {
+				return err
 			}
-			var event domain.LogEvent
-			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-				w.logger.Warn("Failed to unmarshal event from WAL, skipping", "error", err, "line", scanner.Text())
-				continue
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		w.mu.Lock()
+		err = w.removeSegment(segmentPath)
+		w.mu.Unlock()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove replayed WAL segment %s: %w", segmentPath, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := os.Remove(filepath.Join(w.dir, cursorFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Warn("Failed to remove WAL replay cursor", "error", err)
+	}
+	w.refreshGauges()
+	w.logger.Info("WAL replay completed")
+	return nil
+}
+
+// sealSegments deletes expired segments, closes the current one so that the next write
+// starts a new one, and returns the segments left to replay.
+func (w *WALRepository) sealSegments() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.expireSegments(); err != nil {
+		w.logger.Error("Failed to delete expired WAL segments", "error", err)
+	}
+	if w.currentSegment != nil {
+		w.currentSegment.Close()
+		w.currentSegment = nil
+	}
+	return w.getSortedSegments()
+}
+
+// replayBatcher collects the events of one segment into batches for the replay handler
+// and moves the cursor only past batches the handler has accepted.
+type replayBatcher struct {
+	size       int
+	handler    func(events []domain.LogEvent) error
+	saveCursor func(offset int64)
+	logger     *slog.Logger
+
+	batch     []domain.LogEvent
+	start     int64 // Offset of the first event in batch.
+	next      int64 // Offset after the last event in batch.
+	sinceSave int
+}
+
+// add queues the event read between offset and next, handing a full batch to the handler.
+func (b *replayBatcher) add(event domain.LogEvent, offset, next int64) error {
+	if len(b.batch) == 0 {
+		b.start = offset
+	}
+	b.batch = append(b.batch, event)
+	b.next = next
+	if len(b.batch) >= b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush hands the queued events to the handler. On failure the cursor is left at the
+// start of the batch.
+func (b *replayBatcher) flush() error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	if err := b.handler(b.batch); err != nil {
+		b.saveCursor(b.start)
+		b.logger.Error("WAL replay handler failed, stopping replay", "error", err)
+		return fmt.Errorf("replay handler failed: %w", err)
+	}
+	b.sinceSave += len(b.batch)
+	b.batch = nil
+	if b.sinceSave >= cursorSaveEvery {
+		b.saveCursor(b.next)
+		b.sinceSave = 0
+	}
+	return nil
+}
+
+// stop persists the cursor at the first event not yet accepted, given the offset reading
+// stopped at.
+func (b *replayBatcher) stop(offset int64) {
+	if len(b.batch) > 0 {
+		offset = b.start
+	}
+	b.saveCursor(offset)
+}
+
+// replaySegment replays a segment of checksummed records from offset start, stopping at
+// the first corrupt one after handing over the events before it.
+func (w *WALRepository) replaySegment(ctx context.Context, segmentPath string, start int64, b *replayBatcher) error {
+	file, err := os.Open(segmentPath)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s for replay: %w", segmentPath, err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek segment %s to %d: %w", segmentPath, start, err)
+	}
+
+	r := bufio.NewReader(file)
+	for offset := start; ; {
+		if ctx.Err() != nil {
+			b.stop(offset)
+			return ctx.Err()
+		}
+		payload, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			if err := b.flush(); err != nil {
+				return err
 			}
-			if err := handler(event); err != nil {
-				file.Close()
-				w.logger.Error("WAL replay handler failed, stopping replay", "error", err)
-				return fmt.Errorf("replay handler failed: %w", err)
+			b.saveCursor(offset)
+			return nil
+		}
+		var event domain.LogEvent
+		if err == nil {
+			event, err = w.decodeEvent(payload)
+		}
+		if err != nil {
+			if err := b.flush(); err != nil {
+				return err
 			}
+			return fmt.Errorf("segment %s at offset %d: %w", segmentPath, offset, err)
 		}
-		if err := scanner.Err(); err != nil {
-			file.Close()
-			return fmt.Errorf("error scanning segment %s: %w", segmentPath, err)
+		next := offset + int64(recordHeaderSize+len(payload))
+		if err := b.add(event, offset, next); err != nil {
+			return err
 		}
-		file.Close()
+		offset = next
 	}
+}
 
-	w.logger.Info("WAL replay completed")
+// decodeEvent decrypts a record payload if needed and decodes the event in it.
+func (w *WALRepository) decodeEvent(payload []byte) (domain.LogEvent, error) {
+	var event domain.LogEvent
+	plain, err := w.open(payload)
+	if err != nil {
+		return event, err
+	}
+	if err := json.Unmarshal(plain, &event); err != nil {
+		return event, fmt.Errorf("%w: %v", errCorruptRecord, err)
+	}
+	return event, nil
+}
+
+// replayLegacySegment replays a segment of JSON lines from offset start. Lacking
+// checksums, undecodable lines can only be skipped.
+func (w *WALRepository) replayLegacySegment(ctx context.Context, segmentPath string, start int64, b *replayBatcher) error {
+	file, err := os.Open(segmentPath)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s for replay: %w", segmentPath, err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek segment %s to %d: %w", segmentPath, start, err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	offset := start
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			b.stop(offset)
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		next := offset + int64(len(line)) + 1
+		var event domain.LogEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			w.logger.Warn("Failed to unmarshal event from legacy WAL segment, skipping", "error", err, "line", scanner.Text())
+		} else if err := b.add(event, offset, next); err != nil {
+			return err
+		}
+		offset = next
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error scanning segment %s: %w", segmentPath, err)
+	}
+	if err := b.flush(); err != nil {
+		return err
+	}
+	b.saveCursor(offset)
+	return nil
+}
+
+// replayCursor is the position of the next event to replay.
+type replayCursor struct {
+	Segment string `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// loadCursor returns the persisted cursor, or the zero cursor if there is none.
+func (w *WALRepository) loadCursor() replayCursor {
+	var cursor replayCursor
+	data, err := os.ReadFile(filepath.Join(w.dir, cursorFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			w.logger.Warn("Failed to read WAL replay cursor, replaying from the start", "error", err)
+		}
+		return cursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		w.logger.Warn("Invalid WAL replay cursor, replaying from the start", "error", err)
+		return replayCursor{}
+	}
+	return cursor
+}
+
+// saveCursor writes the cursor atomically so a crash never leaves a partial cursor.
+func (w *WALRepository) saveCursor(cursor replayCursor) error {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, cursorFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, filePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// quarantine moves a corrupt segment into the quarantine directory, where it no longer
+// counts towards the WAL size.
+func (w *WALRepository) quarantine(segmentPath string, cause error) error {
+	dir := filepath.Join(w.dir, quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create WAL quarantine directory: %w", err)
+	}
+	dest := filepath.Join(dir, filepath.Base(segmentPath))
+	info, err := os.Stat(segmentPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat WAL segment %s: %w", segmentPath, err)
+	}
+	if err := os.Rename(segmentPath, dest); err != nil {
+		return fmt.Errorf("failed to quarantine WAL segment %s: %w", segmentPath, err)
+	}
+	w.addSize(-info.Size())
+	w.refreshGauges()
+	w.metrics.WALQuarantinedTotal.Inc()
+	w.logger.Error("Quarantined corrupt WAL segment", "error", cause, "path", dest)
 	return nil
 }
 
 // Truncate removes all WAL segment files.
 func (w *WALRepository) Truncate(ctx context.Context) error {
+	w.replayMu.Lock()
+	defer w.replayMu.Unlock()
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -166,11 +482,15 @@ func (w *WALRepository) Truncate(ctx context.Context) error {
 	}
 
 	for _, segmentPath := range segments {
-		if err := os.Remove(segmentPath); err != nil {
+		if err := w.removeSegment(segmentPath); err != nil {
 			w.logger.Error("Failed to remove WAL segment", "path", segmentPath, "error", err)
 		}
 	}
 
+	if err := os.Remove(filepath.Join(w.dir, cursorFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Error("Failed to remove WAL replay cursor", "error", err)
+	}
+
 	w.logger.Info("WAL truncated")
 	return w.openLatestSegment()
 }
@@ -186,7 +506,8 @@ func (w *WALRepository) rotate() error {
 		w.currentSegment = nil
 	}
 
-	segmentName := fmt.Sprintf("%s%d.log", segmentPrefix, time.Now().UnixNano())
+	now := time.Now()
+	segmentName := fmt.Sprintf("%s%d%s", segmentPrefix, now.UnixNano(), segmentExt)
 	path := filepath.Join(w.dir, segmentName)
 
 	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, filePerm)
@@ -195,7 +516,10 @@ func (w *WALRepository) rotate() error {
 	}
 
 	w.currentSegment = f
+	w.currentPath = path
 	w.currentSize = 0
+	w.currentCreatedAt = now
+	w.refreshGauges()
 	w.logger.Info("Rotated to new WAL segment", "path", path)
 	return nil
 }
@@ -211,9 +535,23 @@ func (w *WALRepository) openLatestSegment() error {
 	}
 
 	latestSegmentPath := segments[len(segments)-1]
-	stat, err := os.Stat(latestSegmentPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat latest segment %s: %w", latestSegmentPath, err)
+	if strings.HasSuffix(latestSegmentPath, legacySegmentExt) {
+		return w.rotate()
+	}
+	// Appending after a torn or corrupt record would get the new records quarantined with
+	// it on replay, so the segment is repaired or left alone first.
+	size, err := validSize(latestSegmentPath)
+	switch {
+	case errors.Is(err, errTornRecord):
+		w.logger.Warn("Truncating torn record at the end of WAL segment", "path", latestSegmentPath, "valid_size", size, "error", err)
+		if err := os.Truncate(latestSegmentPath, size); err != nil {
+			return fmt.Errorf("failed to truncate torn WAL segment %s: %w", latestSegmentPath, err)
+		}
+	case errors.Is(err, errCorruptRecord):
+		w.logger.Warn("Latest WAL segment is corrupt, starting a new one", "path", latestSegmentPath, "error", err)
+		return w.rotate()
+	case err != nil:
+		return err
 	}
 
 	f, err := os.OpenFile(latestSegmentPath, os.O_APPEND|os.O_WRONLY, filePerm)
@@ -222,16 +560,41 @@ func (w *WALRepository) openLatestSegment() error {
 	}
 
 	w.currentSegment = f
-	w.currentSize = stat.Size()
+	w.currentPath = latestSegmentPath
+	w.currentSize = size
+	w.currentCreatedAt = segmentCreatedAt(latestSegmentPath)
 	w.logger.Info("Opened existing WAL segment", "path", latestSegmentPath, "size", w.currentSize)
 
-	if w.currentSize >= w.maxSegmentSize {
+	if w.currentSize >= w.maxSegmentSize || w.segmentExpired() {
 		return w.rotate()
 	}
 
 	return nil
 }
 
+// validSize returns the length of the segment up to the end of its last intact record,
+// along with the error that stopped the scan, if any.
+func validSize(segmentPath string) (int64, error) {
+	file, err := os.Open(segmentPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open latest segment %s: %w", segmentPath, err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var size int64
+	for {
+		payload, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return size, nil
+		}
+		if err != nil {
+			return size, fmt.Errorf("segment %s at offset %d: %w", segmentPath, size, err)
+		}
+		size += int64(recordHeaderSize + len(payload))
+	}
+}
+
 func (w *WALRepository) getSortedSegments() ([]string, error) {
 	entries, err := os.ReadDir(w.dir)
 	if err != nil {
@@ -266,6 +629,69 @@ func (w *WALRepository) calculateTotalSize() (int64, error) {
 	return totalSize, nil
 }
 
+// reconcileSize replaces the cached total size with the size on disk.
+func (w *WALRepository) reconcileSize() error {
+	w.lastReconcile = time.Now()
+	totalSize, err := w.calculateTotalSize()
+	if err != nil {
+		return err
+	}
+	if totalSize != w.totalSize {
+		w.logger.Debug("Reconciled WAL size", "cached", w.totalSize, "actual", totalSize)
+	}
+	w.addSize(totalSize - w.totalSize)
+	return nil
+}
+
+func (w *WALRepository) addSize(delta int64) {
+	w.totalSize += delta
+	w.metrics.WALSizeBytes.Set(float64(w.totalSize))
+	if delta < 0 {
+		close(w.spaceFreed)
+		w.spaceFreed = make(chan struct{})
+	}
+}
+
+// removeSegment deletes a segment and subtracts its size from the total.
+func (w *WALRepository) removeSegment(segmentPath string) error {
+	info, err := os.Stat(segmentPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(segmentPath); err != nil {
+		return err
+	}
+	w.addSize(-info.Size())
+	w.refreshGauges()
+	return nil
+}
+
+// refreshGauges sets the segment count and oldest event gauges from the segments on disk.
+// It runs whenever segments are created or removed, which is rare enough to afford a
+// directory listing and a read of the first unreplayed record.
+func (w *WALRepository) refreshGauges() {
+	segments, err := w.getSortedSegments()
+	if err != nil {
+		w.logger.Warn("Failed to refresh WAL gauges", "error", err)
+		return
+	}
+	w.metrics.WALSegments.Set(float64(len(segments)))
+	var oldest time.Time
+	if events, err := w.peek(context.Background(), segments, 1); err == nil && len(events) > 0 {
+		oldest = events[0].ReceivedAt
+	}
+	w.setOldestEvent(oldest)
+}
+
+func (w *WALRepository) setOldestEvent(t time.Time) {
+	w.oldestEvent = t
+	if t.IsZero() {
+		w.metrics.WALOldestEventTimestamp.Set(0)
+		return
+	}
+	w.metrics.WALOldestEventTimestamp.Set(float64(t.UnixNano()) / 1e9)
+}
+
 // Close ensures the current segment is closed gracefully.
 func (w *WALRepository) Close() error {
 	w.mu.Lock()
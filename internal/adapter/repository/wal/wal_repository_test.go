@@ -1,12 +1,22 @@
 package wal
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/google/uuid"
 )
@@ -19,7 +29,7 @@ func setupTestWAL(t *testing.T, maxSegmentSize, maxTotalSize int64) (*WALReposit
 	}
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-	wal, err := NewWALRepository(dir, maxSegmentSize, maxTotalSize, logger)
+	wal, err := NewWALRepository(dir, maxSegmentSize, maxTotalSize, Backpressure{Policy: PolicyReject}, AgeLimits{}, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
 	if err != nil {
 		t.Fatalf("failed to create WALRepository: %v", err)
 	}
@@ -51,7 +61,7 @@ func TestWAL_WriteAndReplay(t *testing.T) {
 
 	// Re-open the WAL to simulate a restart
 	var err error
-	wal, err = NewWALRepository(wal.dir, 1024, 10*1024, wal.logger)
+	wal, err = NewWALRepository(wal.dir, 1024, 10*1024, wal.backpressure, wal.ageLimits, wal.logger, wal.metrics)
 	if err != nil {
 		t.Fatalf("failed to re-open WAL: %v", err)
 	}
@@ -145,7 +155,593 @@ func TestWAL_MaxTotalSize(t *testing.T) {
 		}
 	}
 
+	if !errors.Is(err, domain.ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull when writing beyond max total size, got %v", err)
+	}
+}
+
+func TestWAL_ReplayQuarantinesCorruptSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		corrupt  func(data []byte) []byte
+		replayed int
+	}{
+		{name: "Torn write", corrupt: func(data []byte) []byte { return append(data, encodeRecord([]byte(`{"message":"torn"}`))[:12]...) }, replayed: 3},
+		{name: "Checksum mismatch", corrupt: func(data []byte) []byte { data[len(data)-3] ^= 0xff; return data }, replayed: 2},
+		{name: "Implausible length", corrupt: func(data []byte) []byte { return append(data, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0) }, replayed: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+			defer cleanup()
+
+			for i := 0; i < 3; i++ {
+				if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "event"}); err != nil {
+					t.Fatalf("failed to write event: %v", err)
+				}
+			}
+			wal.Close()
+
+			segments, _ := wal.getSortedSegments()
+			data, _ := os.ReadFile(segments[0])
+			os.WriteFile(segments[0], tt.corrupt(data), filePerm)
+
+			// A later, intact segment must still be replayed.
+			wal.rotate()
+			wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "after"})
+			wal.Close()
+
+			before := testutil.ToFloat64(wal.metrics.WALQuarantinedTotal)
+			var replayed []domain.LogEvent
+			err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+				replayed = append(replayed, event)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			if len(replayed) != tt.replayed+1 || replayed[len(replayed)-1].Message != "after" {
+				t.Errorf("expected %d events ending with the intact segment, got %+v", tt.replayed+1, replayed)
+			}
+			if _, err := os.Stat(filepath.Join(wal.dir, quarantineDir, filepath.Base(segments[0]))); err != nil {
+				t.Errorf("expected segment in quarantine: %v", err)
+			}
+			if got := testutil.ToFloat64(wal.metrics.WALQuarantinedTotal) - before; got != 1 {
+				t.Errorf("expected 1 quarantined segment, got %v", got)
+			}
+
+			if err := wal.Truncate(context.Background()); err != nil {
+				t.Fatalf("failed to truncate WAL: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(wal.dir, quarantineDir, filepath.Base(segments[0]))); err != nil {
+				t.Errorf("truncate removed the quarantined segment: %v", err)
+			}
+		})
+	}
+}
+
+func TestWAL_ReopenTruncatesTornTail(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "before crash"}); err != nil {
+			t.Fatalf("failed to write event: %v", err)
+		}
+	}
+	wal.Close()
+
+	// Simulate a crash in the middle of a write.
+	segments, _ := wal.getSortedSegments()
+	f, _ := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, filePerm)
+	f.Write(encodeRecord([]byte(`{"message":"torn"}`))[:12])
+	f.Close()
+
+	var err error
+	wal, err = NewWALRepository(wal.dir, 10*1024, 100*1024, wal.backpressure, wal.ageLimits, wal.logger, wal.metrics)
+	if err != nil {
+		t.Fatalf("failed to re-open WAL: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "after restart"}); err != nil {
+			t.Fatalf("failed to write event: %v", err)
+		}
+	}
+
+	before := testutil.ToFloat64(wal.metrics.WALQuarantinedTotal)
+	var replayed []string
+	err = wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		replayed = append(replayed, event.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	want := []string{"before crash", "before crash", "after restart", "after restart"}
+	if fmt.Sprint(replayed) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, replayed)
+	}
+	if got := testutil.ToFloat64(wal.metrics.WALQuarantinedTotal) - before; got != 0 {
+		t.Errorf("expected no quarantined segments, got %v", got)
+	}
+}
+
+func TestWAL_Encryption(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+	defer cleanup()
+
+	// Records written before encryption is enabled must still replay.
+	wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "plain"})
+	key, err := LoadEncryptionKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)), "")
+	if err != nil {
+		t.Fatalf("failed to load key: %v", err)
+	}
+	if err := wal.EnableEncryption(key); err != nil {
+		t.Fatalf("failed to enable encryption: %v", err)
+	}
+	wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "secret card 4111"})
+	wal.Close()
+
+	segments, _ := wal.getSortedSegments()
+	data, _ := os.ReadFile(segments[0])
+	if bytes.Contains(data, []byte("secret card")) {
+		t.Error("encrypted record is readable on disk")
+	}
+
+	var replayed []string
+	err = wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		replayed = append(replayed, event.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if fmt.Sprint(replayed) != "[plain secret card 4111]" {
+		t.Errorf("unexpected replayed events: %v", replayed)
+	}
+
+	t.Run("Wrong key quarantines the segment", func(t *testing.T) {
+		wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "secret"})
+		wal.Close()
+		wal.EnableEncryption(bytes.Repeat([]byte{8}, 32))
+		if err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+			t.Errorf("replayed undecryptable event %+v", event)
+			return nil
+		}); err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(wal.dir, quarantineDir)); len(entries) != 1 {
+			t.Errorf("expected the segment in quarantine, got %d entries", len(entries))
+		}
+	})
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{1}, 16)
+	keyFile := filepath.Join(t.TempDir(), "wal.key")
+	os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(raw)+"\n"), filePerm)
+
+	if key, err := LoadEncryptionKey("", keyFile); err != nil || !bytes.Equal(key, raw) {
+		t.Errorf("LoadEncryptionKey from file = %v, %v", key, err)
+	}
+	if key, err := LoadEncryptionKey("", ""); key != nil || err != nil {
+		t.Errorf("expected no key, got %v, %v", key, err)
+	}
+	if _, err := LoadEncryptionKey(base64.StdEncoding.EncodeToString([]byte("short")), ""); err == nil {
+		t.Error("expected an error for a key of the wrong size")
+	}
+}
+
+func TestWAL_StatusAndPeek(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 300, 100*1024)
+	defer cleanup()
+
+	status, err := wal.Status(context.Background())
+	if err != nil || status.TotalSizeBytes != 0 || !status.OldestEventTime.IsZero() {
+		t.Fatalf("unexpected status of an empty WAL: %+v, %v", status, err)
+	}
+
+	oldest := time.Now().Add(-time.Hour)
+	for i := 0; i < 10; i++ {
+		wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), ReceivedAt: oldest.Add(time.Duration(i) * time.Minute), Message: fmt.Sprint(i)})
+	}
+
+	status, err = wal.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(status.Segments) < 2 || status.TotalSizeBytes != wal.totalSize {
+		t.Errorf("expected several segments totalling %d bytes, got %+v", wal.totalSize, status)
+	}
+	if !status.OldestEventTime.Equal(oldest) || status.OldestEventAgeSeconds < 3600 {
+		t.Errorf("expected the oldest event from an hour ago, got %v (%vs)", status.OldestEventTime, status.OldestEventAgeSeconds)
+	}
+
+	events, err := wal.Peek(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("peek failed: %v", err)
+	}
+	var messages []string
+	for _, event := range events {
+		messages = append(messages, event.Message)
+	}
+	if fmt.Sprint(messages) != "[0 1 2 3]" {
+		t.Errorf("expected the four oldest events, got %v", messages)
+	}
+
+	// Peeking does not consume events.
+	var replayed int
+	wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		replayed++
+		return nil
+	})
+	if replayed != 10 {
+		t.Errorf("expected 10 replayed events after peeking, got %d", replayed)
+	}
+}
+
+func TestWAL_AgeLimits(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+	defer cleanup()
+	wal.ageLimits = AgeLimits{SegmentMaxAge: time.Minute, Retention: time.Hour}
+
+	wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "stale"})
+	first := wal.currentPath
+	wal.currentCreatedAt = time.Now().Add(-2 * time.Minute)
+	wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "fresh"})
+	if wal.currentPath == first {
+		t.Fatal("expected an old segment to be rotated")
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(first, old, old)
+	before := testutil.ToFloat64(wal.metrics.WALExpiredSegmentsTotal)
+	var replayed []string
+	if err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		replayed = append(replayed, event.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if fmt.Sprint(replayed) != "[fresh]" {
+		t.Errorf("expected only the fresh event, got %v", replayed)
+	}
+	if got := testutil.ToFloat64(wal.metrics.WALExpiredSegmentsTotal) - before; got != 1 {
+		t.Errorf("expected 1 expired segment, got %v", got)
+	}
+}
+
+func TestWAL_ReplayBatch(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+	defer cleanup()
+	for i := 0; i < 7; i++ {
+		wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: fmt.Sprint(i)})
+	}
+
+	// The batch that fails is replayed again in full.
+	var batches []string
+	failOn := "3"
+	handler := func(events []domain.LogEvent) error {
+		var messages []string
+		for _, event := range events {
+			messages = append(messages, event.Message)
+		}
+		if messages[0] == failOn {
+			failOn = ""
+			return errors.New("buffer down")
+		}
+		batches = append(batches, fmt.Sprint(messages))
+		return nil
+	}
+	if err := wal.ReplayBatch(context.Background(), 3, handler); err == nil {
+		t.Fatal("expected the failing batch to stop the replay")
+	}
+	if err := wal.ReplayBatch(context.Background(), 3, handler); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if got := fmt.Sprint(batches); got != "[[0 1 2] [3 4 5] [6]]" {
+		t.Errorf("unexpected batches: %s", got)
+	}
+}
+
+func TestWAL_WriteDuringReplay(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 10*1024, 100*1024)
+	defer cleanup()
+	wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "old"})
+
+	// A paced replay must not hold up writes, nor the admin status.
+	replaying, release := make(chan struct{}), make(chan struct{})
+	var replayed []string
+	done := make(chan error)
+	go func() {
+		done <- wal.Replay(context.Background(), func(event domain.LogEvent) error {
+			close(replaying)
+			<-release
+			replayed = append(replayed, event.Message)
+			return nil
+		})
+	}()
+	<-replaying
+
+	written := make(chan error)
+	go func() {
+		written <- wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "new"})
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("failed to write event during replay: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the write to complete while the handler is replaying")
+	}
+	if _, err := wal.Status(context.Background()); err != nil {
+		t.Fatalf("failed to get status during replay: %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if fmt.Sprint(replayed) != "[old]" {
+		t.Errorf("expected only the event written before the replay, got %v", replayed)
+	}
+	if err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		replayed = append(replayed, event.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if fmt.Sprint(replayed) != "[old new]" {
+		t.Errorf("expected the event written during the replay to be replayed next, got %v", replayed)
+	}
+}
+
+func TestWAL_ReplayLegacySegment(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"event_id":"a","message":"legacy 1"}` + "\n" + `not json` + "\n" + `{"event_id":"b","message":"legacy 2"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, segmentPrefix+"1"+legacySegmentExt), []byte(legacy), filePerm); err != nil {
+		t.Fatalf("failed to write legacy segment: %v", err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	wal, err := NewWALRepository(dir, 1024, 10*1024, Backpressure{}, AgeLimits{}, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+	if err != nil {
+		t.Fatalf("failed to create WALRepository: %v", err)
+	}
+	defer wal.Close()
+	if err := wal.Write(context.Background(), domain.LogEvent{ID: "c", Message: "framed"}); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+
+	var messages []string
+	if err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		messages = append(messages, event.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(messages) != 3 || messages[0] != "legacy 1" || messages[1] != "legacy 2" || messages[2] != "framed" {
+		t.Errorf("unexpected replay: %v", messages)
+	}
+}
+
+func TestWAL_ReplayResumesFromCursor(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 4*1024, 1024*1024)
+	defer cleanup()
+
+	const total = 250
+	for i := 0; i < total; i++ {
+		if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("failed to write event: %v", err)
+		}
+	}
+	segments, _ := wal.getSortedSegments()
+	if len(segments) < 3 {
+		t.Fatalf("expected several segments, got %d", len(segments))
+	}
+
+	// The first replay fails part-way, as if Redis went away again.
+	var seen []string
+	err := wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		if len(seen) == 150 {
+			return errors.New("redis unavailable")
+		}
+		seen = append(seen, event.Message)
+		return nil
+	})
 	if err == nil {
-		t.Fatal("expected an error when writing beyond max total size, but got nil")
+		t.Fatal("expected replay to fail")
+	}
+	remaining, _ := wal.getSortedSegments()
+	if len(remaining) >= len(segments) {
+		t.Errorf("expected finished segments to be deleted, %d of %d remain", len(remaining), len(segments))
+	}
+
+	err = wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		seen = append(seen, event.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("resumed replay failed: %v", err)
+	}
+	if len(seen) != total {
+		t.Fatalf("expected %d events without duplicates, got %d", total, len(seen))
+	}
+	for i, msg := range seen {
+		if msg != fmt.Sprint(i) {
+			t.Fatalf("event %d out of order or duplicated: %s", i, msg)
+		}
+	}
+
+	remaining, _ = wal.getSortedSegments()
+	if len(remaining) != 0 {
+		t.Errorf("expected no segments after a complete replay, got %d", len(remaining))
+	}
+	if _, err := os.Stat(filepath.Join(wal.dir, cursorFile)); !os.IsNotExist(err) {
+		t.Errorf("expected the cursor to be removed, got %v", err)
+	}
+
+	// The WAL keeps working after a complete replay.
+	if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "next"}); err != nil {
+		t.Fatalf("failed to write after replay: %v", err)
+	}
+}
+
+func TestWAL_CachedTotalSize(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 512, 1024*1024)
+	defer cleanup()
+
+	assertSize := func(stage string) {
+		t.Helper()
+		actual, err := wal.calculateTotalSize()
+		if err != nil {
+			t.Fatalf("failed to calculate size: %v", err)
+		}
+		if wal.totalSize != actual {
+			t.Errorf("%s: cached size %d, on disk %d", stage, wal.totalSize, actual)
+		}
+		if gauge := testutil.ToFloat64(wal.metrics.WALSizeBytes); gauge != float64(actual) {
+			t.Errorf("%s: gauge reports %v, on disk %d", stage, gauge, actual)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: "sized event"})
+	}
+	if wal.totalSize == 0 {
+		t.Fatal("expected a non-zero size after writes")
+	}
+	assertSize("after writes")
+
+	// Reopening starts from the size on disk.
+	wal.Close()
+	reopened, err := NewWALRepository(wal.dir, 512, 1024*1024, wal.backpressure, wal.ageLimits, wal.logger, wal.metrics)
+	if err != nil {
+		t.Fatalf("failed to re-open WAL: %v", err)
+	}
+	wal = reopened
+	assertSize("after reopen")
+
+	calls := 0
+	wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		if calls++; calls > 10 {
+			return errors.New("stop")
+		}
+		return nil
+	})
+	assertSize("after partial replay")
+
+	wal.Truncate(context.Background())
+	assertSize("after truncate")
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]string{"": PolicyBlock, "block": PolicyBlock, "drop": PolicyDropOldest, "drop_oldest": PolicyDropOldest, "reject": PolicyReject} {
+		if got, err := ParsePolicy(in); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePolicy("spill"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestWAL_BackpressureDropOldest(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 300, 1000)
+	defer cleanup()
+	wal.backpressure = Backpressure{Policy: PolicyDropOldest}
+
+	before := testutil.ToFloat64(wal.metrics.WALDroppedSegmentsTotal)
+	for i := 0; i < 40; i++ {
+		if err := wal.Write(context.Background(), domain.LogEvent{ID: uuid.NewString(), Message: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+	if wal.totalSize > wal.maxTotalSize {
+		t.Errorf("WAL holds %d bytes, above the %d byte limit", wal.totalSize, wal.maxTotalSize)
+	}
+	if testutil.ToFloat64(wal.metrics.WALDroppedSegmentsTotal) == before {
+		t.Error("expected dropped segments to be counted")
+	}
+
+	var messages []string
+	wal.Replay(context.Background(), func(event domain.LogEvent) error {
+		messages = append(messages, event.Message)
+		return nil
+	})
+	if len(messages) == 0 || len(messages) == 40 || messages[len(messages)-1] != "39" {
+		t.Errorf("expected only the newest events to survive, got %v", messages)
+	}
+}
+
+func TestWAL_BackpressureBlock(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 300, 600)
+	defer cleanup()
+	wal.backpressure = Backpressure{Policy: PolicyBlock, BlockTimeout: 50 * time.Millisecond}
+
+	event := domain.LogEvent{ID: uuid.NewString(), Message: "blocking"}
+	var err error
+	for i := 0; i < 20 && err == nil; i++ {
+		err = wal.Write(context.Background(), event)
+	}
+	if !errors.Is(err, domain.ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull after the block timeout, got %v", err)
+	}
+
+	// A replay frees space and releases a blocked writer.
+	wal.backpressure.BlockTimeout = 5 * time.Second
+	done := make(chan error, 1)
+	go func() { done <- wal.Write(context.Background(), event) }()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("write returned before space was freed: %v", err)
+	default:
+	}
+	if err := wal.Replay(context.Background(), func(domain.LogEvent) error { return nil }); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("blocked write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked write was not released by the replay")
+	}
+}
+
+func TestWAL_Gauges(t *testing.T) {
+	wal, cleanup := setupTestWAL(t, 100, 100*1024)
+	defer cleanup()
+	if got := testutil.ToFloat64(wal.metrics.WALOldestEventTimestamp); got != 0 {
+		t.Errorf("expected no oldest event in an empty WAL, got %v", got)
+	}
+
+	first := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		event := domain.LogEvent{ID: uuid.NewString(), ReceivedAt: first.Add(time.Duration(i) * time.Second), Message: "gauge test event"}
+		if err := wal.Write(context.Background(), event); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	segments, _ := wal.getSortedSegments()
+	if got := testutil.ToFloat64(wal.metrics.WALSegments); got != float64(len(segments)) || got < 2 {
+		t.Errorf("expected the segment gauge to track %d segments, got %v", len(segments), got)
+	}
+	if got := testutil.ToFloat64(wal.metrics.WALOldestEventTimestamp); got != float64(first.Unix()) {
+		t.Errorf("expected oldest event timestamp %d, got %v", first.Unix(), got)
+	}
+
+	if err := wal.Replay(context.Background(), func(domain.LogEvent) error { return nil }); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if got := testutil.ToFloat64(wal.metrics.WALOldestEventTimestamp); got != 0 {
+		t.Errorf("expected no oldest event after replay, got %v", got)
+	}
+	if got := testutil.ToFloat64(wal.metrics.WALSegments); got != 0 {
+		t.Errorf("expected no segments after replay, got %v", got)
 	}
 }
@@ -0,0 +1,75 @@
+package s3import
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint records how far an object has been imported.
+type Checkpoint struct {
+	ETag  string `json:"etag"`  // The object version the progress refers to.
+	Lines int64  `json:"lines"` // Lines consumed so far, including skipped ones.
+	Done  bool   `json:"done"`
+}
+
+// FileCheckpoints keeps per-object checkpoints in a JSON file, rewritten atomically on
+// every save so an interrupted import can be resumed.
+type FileCheckpoints struct {
+	path string
+
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewFileCheckpoints loads the checkpoints stored at path, if any.
+func NewFileCheckpoints(path string) (*FileCheckpoints, error) {
+	c := &FileCheckpoints{path: path, checkpoints: make(map[string]Checkpoint)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Get returns the checkpoint of an object key.
+func (c *FileCheckpoints) Get(key string) (Checkpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.checkpoints[key]
+	return cp, ok
+}
+
+// Save records the checkpoint of an object key and persists all checkpoints.
+func (c *FileCheckpoints) Save(key string, cp Checkpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints[key] = cp
+
+	data, err := json.MarshalIndent(c.checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".checkpoints-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
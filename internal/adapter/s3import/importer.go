@@ -0,0 +1,203 @@
+package s3import
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const defaultCheckpointEvery = 1000
+
+// S3API is the subset of the S3 client used by the Importer.
+type S3API interface {
+	awss3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error)
+}
+
+// ImporterConfig holds the source and progress settings for the Importer.
+type ImporterConfig struct {
+	Bucket          string
+	Prefix          string
+	MaxLineSize     int
+	CheckpointEvery int64 // Lines between checkpoints within an object.
+}
+
+// Result summarises an import run.
+type Result struct {
+	Objects  int   // Objects imported to completion during this run.
+	Skipped  int   // Objects already completed by an earlier run.
+	Events   int64 // Events buffered.
+	BadLines int64 // Lines that were not valid JSON log events.
+}
+
+// Importer backfills historical logs from NDJSON objects (optionally gzip or zstd
+// compressed) under an S3 prefix. Every event goes through the IngestLogUseCase, so PII
+// redaction and buffering behave as for live traffic. Progress is checkpointed per object,
+// so a failed or interrupted run can be restarted and resumes where it stopped; events
+// after the last checkpoint are imported again, so delivery is at-least-once.
+type Importer struct {
+	s3          S3API
+	cfg         ImporterConfig
+	checkpoints *FileCheckpoints
+	useCase     usecase.IngestLogUseCase
+	logger      *slog.Logger
+}
+
+// NewImporter creates a new Importer.
+func NewImporter(s3Client S3API, cfg ImporterConfig, checkpoints *FileCheckpoints, uc usecase.IngestLogUseCase, logger *slog.Logger) *Importer {
+	if cfg.MaxLineSize <= 0 {
+		cfg.MaxLineSize = bufio.MaxScanTokenSize
+	}
+	if cfg.CheckpointEvery <= 0 {
+		cfg.CheckpointEvery = defaultCheckpointEvery
+	}
+	return &Importer{
+		s3:          s3Client,
+		cfg:         cfg,
+		checkpoints: checkpoints,
+		useCase:     uc,
+		logger:      logger.With("component", "s3_importer", "bucket", cfg.Bucket, "prefix", cfg.Prefix),
+	}
+}
+
+// Run imports every object under the prefix in key order, stopping at the first error.
+func (im *Importer) Run(ctx context.Context) (Result, error) {
+	var res Result
+	paginator := awss3.NewListObjectsV2Paginator(im.s3, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(im.cfg.Bucket),
+		Prefix: aws.String(im.cfg.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return res, fmt.Errorf("failed to list s3://%s/%s: %w", im.cfg.Bucket, im.cfg.Prefix, err)
+		}
+		for _, obj := range page.Contents {
+			key, etag := aws.ToString(obj.Key), aws.ToString(obj.ETag)
+			cp, ok := im.checkpoints.Get(key)
+			if ok && cp.ETag != etag {
+				im.logger.Warn("Object changed since it was checkpointed, importing it again", "key", key)
+				cp = Checkpoint{}
+			}
+			if cp.Done {
+				res.Skipped++
+				continue
+			}
+			if err := im.importObject(ctx, key, etag, cp.Lines, &res); err != nil {
+				return res, err
+			}
+			res.Objects++
+		}
+	}
+	return res, nil
+}
+
+func (im *Importer) importObject(ctx context.Context, key, etag string, skip int64, res *Result) error {
+	im.logger.Info("Importing object", "key", key, "resume_at_line", skip)
+	obj, err := im.s3.GetObject(ctx, &awss3.GetObjectInput{Bucket: aws.String(im.cfg.Bucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", im.cfg.Bucket, key, err)
+	}
+	defer obj.Body.Close()
+
+	r, err := decompress(obj.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decompress s3://%s/%s: %w", im.cfg.Bucket, key, err)
+	}
+	defer r.Close()
+
+	origin := map[string]interface{}{"s3_bucket": im.cfg.Bucket, "s3_key": key}
+	save := func(lines int64, done bool) error {
+		if err := im.checkpoints.Save(key, Checkpoint{ETag: etag, Lines: lines, Done: done}); err != nil {
+			return fmt.Errorf("failed to save checkpoint for %s: %w", key, err)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), im.cfg.MaxLineSize)
+	var lines int64
+	for scanner.Scan() {
+		lines++
+		if lines <= skip {
+			continue
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) > 0 {
+			event, err := toLogEvent(line, origin)
+			if err != nil {
+				im.logger.Warn("Skipping invalid line", "key", key, "line", lines, "error", err)
+				res.BadLines++
+			} else {
+				if err := im.useCase.Ingest(ctx, &event); err != nil {
+					// Checkpoint the lines before this one so a rerun retries it.
+					if saveErr := save(lines-1, false); saveErr != nil {
+						im.logger.Error("Failed to save checkpoint", "error", saveErr)
+					}
+					return fmt.Errorf("failed to ingest line %d of %s: %w", lines, key, err)
+				}
+				res.Events++
+			}
+		}
+		if lines%im.cfg.CheckpointEvery == 0 {
+			if err := save(lines, false); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if saveErr := save(lines, false); saveErr != nil {
+			im.logger.Error("Failed to save checkpoint", "error", saveErr)
+		}
+		return fmt.Errorf("failed to read s3://%s/%s: %w", im.cfg.Bucket, key, err)
+	}
+	return save(lines, true)
+}
+
+// toLogEvent decodes an NDJSON line and records the source object under the "s3_import"
+// metadata key.
+func toLogEvent(line []byte, origin map[string]interface{}) (domain.LogEvent, error) {
+	var event domain.LogEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return domain.LogEvent{}, err
+	}
+	metadata := map[string]interface{}{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			return domain.LogEvent{}, fmt.Errorf("metadata must be a JSON object: %w", err)
+		}
+	}
+	metadata["s3_import"] = origin
+	event.Metadata, _ = json.Marshal(metadata)
+	event.RawEvent = append([]byte(nil), line...)
+	return event, nil
+}
+
+// decompress detects gzip and zstd streams by their magic bytes; anything else is read as is.
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(br)
+	case len(magic) == 4 && bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return io.NopCloser(br), nil
+}
@@ -0,0 +1,125 @@
+package s3import
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeS3 struct {
+	objects map[string][]byte
+	gets    []string
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *awss3.ListObjectsV2Input, optFns ...func(*awss3.Options)) (*awss3.ListObjectsV2Output, error) {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, aws.ToString(params.Prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	out := &awss3.ListObjectsV2Output{}
+	for _, k := range keys {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(k), ETag: aws.String(`"` + k + `-v1"`)})
+	}
+	return out, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	f.gets = append(f.gets, key)
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+type recordingUseCase struct {
+	events []domain.LogEvent
+	failOn string
+}
+
+func (r *recordingUseCase) Ingest(_ context.Context, event *domain.LogEvent) error {
+	if r.failOn != "" && event.Message == r.failOn {
+		return errors.New("buffer unavailable")
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func TestImporter_ResumesFromCheckpoint(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("{\"message\":\"a1\"}\n{\"message\":\"a2\"}\n"))
+	zw.Close()
+	zenc, _ := zstd.NewWriter(nil)
+	zst := zenc.EncodeAll([]byte("{\"message\":\"b1\"}\nnot json\n{\"message\":\"b2\",\"metadata\":{\"user\":\"bob\"}}\n{\"message\":\"b3\"}\n"), nil)
+
+	s3 := &fakeS3{objects: map[string][]byte{
+		"logs/2024/a.ndjson.gz":  gz.Bytes(),
+		"logs/2024/b.ndjson.zst": zst,
+		"other/c.ndjson":         []byte(`{"message":"c1"}`),
+	}}
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoints.json")
+	cfg := ImporterConfig{Bucket: "archive", Prefix: "logs/", CheckpointEvery: 1}
+
+	// The first run fails on b2, after a1, a2 and b1 were buffered.
+	checkpoints, err := NewFileCheckpoints(checkpointPath)
+	if err != nil {
+		t.Fatalf("NewFileCheckpoints failed: %v", err)
+	}
+	uc := &recordingUseCase{failOn: "b2"}
+	res, err := NewImporter(s3, cfg, checkpoints, uc, logger).Run(context.Background())
+	if err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if res.Objects != 1 || res.Events != 3 || res.BadLines != 1 {
+		t.Errorf("unexpected first run result: %+v", res)
+	}
+
+	// The second run skips the completed object and resumes at b2.
+	checkpoints, err = NewFileCheckpoints(checkpointPath)
+	if err != nil {
+		t.Fatalf("NewFileCheckpoints failed: %v", err)
+	}
+	uc = &recordingUseCase{}
+	res, err = NewImporter(s3, cfg, checkpoints, uc, logger).Run(context.Background())
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if res.Skipped != 1 || res.Objects != 1 || res.Events != 2 {
+		t.Errorf("unexpected second run result: %+v", res)
+	}
+	if len(uc.events) != 2 || uc.events[0].Message != "b2" || uc.events[1].Message != "b3" {
+		t.Fatalf("unexpected events: %+v", uc.events)
+	}
+
+	var md map[string]interface{}
+	json.Unmarshal(uc.events[0].Metadata, &md)
+	origin, _ := md["s3_import"].(map[string]interface{})
+	if md["user"] != "bob" || origin["s3_key"] != "logs/2024/b.ndjson.zst" {
+		t.Errorf("unexpected metadata: %s", uc.events[0].Metadata)
+	}
+	if cp, _ := checkpoints.Get("logs/2024/b.ndjson.zst"); !cp.Done || cp.Lines != 4 {
+		t.Errorf("unexpected checkpoint: %+v", cp)
+	}
+}
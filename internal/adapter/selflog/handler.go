@@ -0,0 +1,214 @@
+// Package selflog ships the service's own logs into its ingest pipeline, so operators can
+// search them alongside everything else.
+package selflog
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+	"github.com/google/uuid"
+)
+
+// SourcePrefix starts the source of every shipped event. Events from clients may use it
+// too; the internal ones are those without an API key.
+const SourcePrefix = "watch-tower/"
+
+// Options configures which records are shipped.
+type Options struct {
+	Service string     // Events are shipped with source SourcePrefix + Service.
+	Level   slog.Level // Records below this level are not shipped.
+	// SampleRate is the fraction of records below warning level that are shipped; warnings
+	// and errors always are.
+	SampleRate   float64
+	QueueSize    int           // Records waiting to be shipped; more are dropped.
+	ErrorBackoff time.Duration // Shipping pauses this long after an event fails to be ingested.
+}
+
+// shippingKey marks the context of the events the shipper ingests.
+type shippingKey struct{}
+
+// shipper holds the queue every Handler derived from the same NewHandler shares.
+type shipper struct {
+	opts     Options
+	queue    chan domain.LogEvent
+	shipping atomic.Pointer[string] // ID of the event being ingested, if any.
+}
+
+// Handler passes records on to the next handler and queues them to be shipped by Run.
+// Records that shipping an event logs would ship themselves in a loop, so they are not
+// shipped: those logged with the shipping context or with the event's event_id are
+// dropped, and after a failed ingest shipping pauses, dropping what overflows the queue
+// meanwhile.
+type Handler struct {
+	next   slog.Handler
+	ship   *shipper
+	attrs  []slog.Attr
+	groups []string
+}
+
+// NewHandler creates a Handler in front of next. Records are queued from the start, and
+// shipped once Run is called.
+func NewHandler(next slog.Handler, opts Options) *Handler {
+	return &Handler{next: next, ship: &shipper{opts: opts, queue: make(chan domain.LogEvent, opts.QueueSize)}}
+}
+
+// Enabled reports whether the next handler handles the level; records it drops are not
+// shipped either.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes the record on and queues it to be shipped.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.shouldShip(ctx, r) {
+		select {
+		case h.ship.queue <- h.event(r):
+		default: // Full: logging never blocks on the pipeline.
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) shouldShip(ctx context.Context, r slog.Record) bool {
+	opts := h.ship.opts
+	switch {
+	case ctx != nil && ctx.Value(shippingKey{}) != nil:
+		return false
+	case r.Level < opts.Level:
+		return false
+	case h.loggedByShipping(r):
+		return false
+	case r.Level >= slog.LevelWarn:
+		return true
+	}
+	return opts.SampleRate >= 1 || rand.Float64() < opts.SampleRate
+}
+
+// loggedByShipping reports whether the record is about the event being shipped.
+func (h *Handler) loggedByShipping(r slog.Record) bool {
+	id := h.ship.shipping.Load()
+	if id == nil {
+		return false
+	}
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == "event_id" && a.Value.String() == *id
+		return !found
+	})
+	return found
+}
+
+// event converts a record into the event shipped for it, with its attributes as
+// metadata. Attributes in groups are keyed by their dotted path.
+func (h *Handler) event(r slog.Record) domain.LogEvent {
+	metadata := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(metadata, "", a)
+	}
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, ".") + "."
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(metadata, prefix, a)
+		return true
+	})
+
+	event := domain.LogEvent{
+		EventTime: r.Time.UTC(),
+		Source:    SourcePrefix + h.ship.opts.Service,
+		Level:     strings.ToLower(r.Level.String()),
+		Message:   r.Message,
+	}
+	if len(metadata) > 0 {
+		// Values that cannot be encoded, such as channels, leave the event without metadata.
+		event.Metadata, _ = json.Marshal(metadata)
+	}
+	return event
+}
+
+func addAttr(metadata map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(metadata, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	switch val := v.Any().(type) {
+	case error:
+		metadata[prefix+a.Key] = val.Error()
+	case time.Duration:
+		metadata[prefix+a.Key] = val.String()
+	default:
+		metadata[prefix+a.Key] = val
+	}
+}
+
+// WithAttrs returns a handler whose records carry attrs, like the next handler's.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, ".")
+	}
+	h2 := *h
+	h2.next = h.next.WithAttrs(attrs)
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if prefix != "" {
+			a = slog.Group(prefix, a)
+		}
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler whose record attributes are in the named group.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.next = h.next.WithGroup(name)
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	return &h2
+}
+
+// Run ships the queued records into uc until ctx is done. Shipped events carry no API
+// key, so they are neither metered nor attributed to a client.
+func (h *Handler) Run(ctx context.Context, uc usecase.IngestLogUseCase) {
+	shipCtx := context.WithValue(ctx, shippingKey{}, true)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-h.ship.queue:
+			event.ID = uuid.NewString()
+			h.ship.shipping.Store(&event.ID)
+			err := uc.Ingest(shipCtx, &event)
+			h.ship.shipping.Store(nil)
+			if err == nil || h.ship.opts.ErrorBackoff <= 0 {
+				continue
+			}
+			// Not logged: the log would be shipped and fail again.
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(h.ship.opts.ErrorBackoff):
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package selflog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// loggingIngestUseCase records the events it ingests and, like the buffer when Redis is
+// down, logs about each of them.
+type loggingIngestUseCase struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+	events []domain.LogEvent
+	err    error
+}
+
+func (u *loggingIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	u.logger.Warn("Redis is unavailable, writing to WAL", "event_id", event.ID)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.events = append(u.events, *event)
+	return u.err
+}
+
+func (u *loggingIngestUseCase) snapshot() []domain.LogEvent {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]domain.LogEvent(nil), u.events...)
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(slog.NewTextHandler(io.Discard, nil), Options{Service: "ingest", Level: slog.LevelInfo, SampleRate: 1, QueueSize: 10})
+	logger := slog.New(h)
+	uc := &loggingIngestUseCase{logger: logger}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger.With("component", "wal").WithGroup("segment").Info("rotated", "size", 42)
+	logger.Debug("below the level")
+	go h.Run(ctx, uc)
+
+	deadline := time.Now().Add(time.Second)
+	for len(uc.snapshot()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the record to be shipped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Give a loop the chance to ship the warning logged while shipping.
+	time.Sleep(50 * time.Millisecond)
+
+	events := uc.snapshot()
+	if len(events) != 1 {
+		t.Fatalf("expected only the info record shipped, got %+v", events)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(events[0].Metadata, &metadata); err != nil {
+		t.Fatalf("expected JSON metadata, got %v", err)
+	}
+	if events[0].Source != "watch-tower/ingest" || events[0].Level != "info" || metadata["component"] != "wal" || metadata["segment.size"] != 42.0 {
+		t.Errorf("unexpected event %+v with metadata %v", events[0], metadata)
+	}
+}
+
+func TestHandlerBacksOffAfterFailures(t *testing.T) {
+	h := NewHandler(slog.NewTextHandler(io.Discard, nil), Options{Service: "ingest", QueueSize: 10, ErrorBackoff: time.Hour})
+	logger := slog.New(h)
+	uc := &loggingIngestUseCase{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), err: errors.New("buffer full")}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger.Error("first")
+	logger.Error("second")
+	go h.Run(ctx, uc)
+
+	time.Sleep(50 * time.Millisecond)
+	if got := uc.snapshot(); len(got) != 1 {
+		t.Errorf("expected shipping to pause after the first failure, got %d events", len(got))
+	}
+}
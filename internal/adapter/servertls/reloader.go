@@ -0,0 +1,138 @@
+// Package servertls serves TLS from certificate files that are reloaded when they
+// change, so certificates can be renewed without restarting the service.
+package servertls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Files names the PEM files a server's TLS is configured from.
+type Files struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs client certificates are verified against. Set, clients
+	// may present a certificate, which is then verified; clients without one can still
+	// connect and authenticate otherwise. Empty asks for no client certificates.
+	ClientCAFile string
+}
+
+type material struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// Reloader serves the certificate and client CAs in its files, and swaps them for new
+// ones when a file changes. Files that fail to load leave the current ones in place.
+type Reloader struct {
+	files   Files
+	logger  *slog.Logger
+	current atomic.Pointer[material]
+	modTime time.Time // Latest of the loaded files; only Run touches it after NewReloader.
+}
+
+// NewReloader loads the files.
+func NewReloader(files Files, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{files: files, logger: logger.With("component", "server_tls")}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns a server configuration that uses the current material for every
+// new connection.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m := r.current.Load()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*m.cert},
+			}
+			if m.clientCAs != nil {
+				cfg.ClientCAs = m.clientCAs
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// Run checks the files for changes every interval until ctx is done.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			modTime, err := r.latestModTime()
+			if err != nil {
+				r.logger.Warn("Failed to check TLS files, keeping the current certificates", "error", err)
+				continue
+			}
+			if modTime.Equal(r.modTime) {
+				continue
+			}
+			if err := r.load(); err != nil {
+				r.logger.Error("Failed to reload TLS files, keeping the current certificates", "error", err)
+				// Not retried until a file changes again.
+				r.modTime = modTime
+				continue
+			}
+			r.logger.Info("Reloaded TLS certificates", "cert", r.files.CertFile, "client_ca", r.files.ClientCAFile)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *Reloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return fmt.Errorf("failed to read TLS files: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	m := &material{cert: &cert}
+	if r.files.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.files.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		m.clientCAs = x509.NewCertPool()
+		if !m.clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("client CA bundle contains no certificates")
+		}
+	}
+	r.current.Store(m)
+	r.modTime = modTime
+	return nil
+}
@@ -0,0 +1,404 @@
+package sqs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const (
+	maxReceiveMessages = 10               // SQS upper bound for ReceiveMessage.
+	maxWaitTime        = 20 * time.Second // SQS upper bound for long polling.
+	receiveErrorDelay  = 5 * time.Second
+)
+
+// SQSAPI is the subset of the SQS client used by the Worker.
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, params *awssqs.ReceiveMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *awssqs.DeleteMessageBatchInput, optFns ...func(*awssqs.Options)) (*awssqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibilityBatch(ctx context.Context, params *awssqs.ChangeMessageVisibilityBatchInput, optFns ...func(*awssqs.Options)) (*awssqs.ChangeMessageVisibilityBatchOutput, error)
+}
+
+// S3API is the subset of the S3 client used to fetch objects referenced by S3 event notifications.
+type S3API interface {
+	GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error)
+}
+
+// WorkerConfig holds the queue settings for the Worker.
+type WorkerConfig struct {
+	QueueURL          string
+	MaxMessages       int32         // Messages per receive, capped at 10.
+	WaitTime          time.Duration // Long-polling wait, capped at 20s.
+	VisibilityTimeout time.Duration // Extended for the whole batch until it has been processed and deleted.
+	MaxLineSize       int           // Max size of a single line in an S3 object.
+}
+
+// Worker drains an SQS queue and ingests its messages. Message bodies may be log events,
+// SNS notifications wrapping log events, or S3 event notifications, in which case the
+// referenced objects are downloaded and ingested line by line.
+//
+// Messages are deleted only after every event they carry has been buffered; otherwise they
+// become visible again once the visibility timeout expires and are retried, so delivery is
+// at-least-once.
+type Worker struct {
+	sqs     SQSAPI
+	s3      S3API
+	cfg     WorkerConfig
+	useCase usecase.IngestLogUseCase
+	logger  *slog.Logger
+	metrics *metrics.IngestMetrics
+}
+
+// NewWorker creates a new SQS Worker. s3Client may be nil if the queue never carries S3 notifications.
+func NewWorker(sqsClient SQSAPI, s3Client S3API, cfg WorkerConfig, uc usecase.IngestLogUseCase, logger *slog.Logger, m *metrics.IngestMetrics) *Worker {
+	if cfg.MaxMessages <= 0 || cfg.MaxMessages > maxReceiveMessages {
+		cfg.MaxMessages = maxReceiveMessages
+	}
+	if cfg.WaitTime < 0 || cfg.WaitTime > maxWaitTime {
+		cfg.WaitTime = maxWaitTime
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = time.Minute
+	}
+	if cfg.MaxLineSize <= 0 {
+		cfg.MaxLineSize = bufio.MaxScanTokenSize
+	}
+	return &Worker{
+		sqs:     sqsClient,
+		s3:      s3Client,
+		cfg:     cfg,
+		useCase: uc,
+		logger:  logger.With("component", "sqs_worker", "queue_url", cfg.QueueURL),
+		metrics: m,
+	}
+}
+
+// Run polls the queue until the context is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Info("Starting SQS worker")
+	for {
+		if ctx.Err() != nil {
+			w.logger.Info("Stopping SQS worker")
+			return
+		}
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("SQS poll failed", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(receiveErrorDelay):
+			}
+		}
+	}
+}
+
+// Poll receives one batch of messages, ingests them and deletes the ones that succeeded.
+func (w *Worker) Poll(ctx context.Context) error {
+	out, err := w.sqs.ReceiveMessage(ctx, &awssqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(w.cfg.QueueURL),
+		MaxNumberOfMessages: w.cfg.MaxMessages,
+		WaitTimeSeconds:     int32(w.cfg.WaitTime / time.Second),
+		VisibilityTimeout:   int32(w.cfg.VisibilityTimeout / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to receive SQS messages: %w", err)
+	}
+	if len(out.Messages) == 0 {
+		return nil
+	}
+
+	hb := w.startHeartbeat(ctx, out.Messages)
+	defer hb.stop()
+
+	var done []types.DeleteMessageBatchRequestEntry
+	for i, msg := range out.Messages {
+		if err := w.processMessage(ctx, msg); err != nil {
+			w.logger.Warn("Failed to process SQS message, leaving it for redelivery", "message_id", aws.ToString(msg.MessageId), "error", err)
+			hb.release(i)
+			continue
+		}
+		done = append(done, types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+	}
+	if len(done) == 0 {
+		return nil
+	}
+
+	res, err := w.sqs.DeleteMessageBatch(ctx, &awssqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(w.cfg.QueueURL),
+		Entries:  done,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete SQS messages: %w", err)
+	}
+	for _, f := range res.Failed {
+		w.logger.Warn("Failed to delete SQS message", "entry_id", aws.ToString(f.Id), "code", aws.ToString(f.Code), "error", aws.ToString(f.Message))
+	}
+	return nil
+}
+
+// visibilityHeartbeat periodically extends the visibility timeout of a received batch.
+// Messages are processed one after another, so without it the messages waiting behind a
+// slow S3 download would be redelivered to another worker mid-flight.
+type visibilityHeartbeat struct {
+	mu       sync.Mutex
+	messages map[int]types.Message // Messages still held, by their index in the batch.
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// startHeartbeat extends the visibility of every message in the batch until it is
+// released or the heartbeat is stopped.
+func (w *Worker) startHeartbeat(ctx context.Context, msgs []types.Message) *visibilityHeartbeat {
+	hb := &visibilityHeartbeat{messages: make(map[int]types.Message, len(msgs)), done: make(chan struct{})}
+	for i, msg := range msgs {
+		hb.messages[i] = msg
+	}
+
+	hb.wg.Add(1)
+	go func() {
+		defer hb.wg.Done()
+		ticker := time.NewTicker(w.cfg.VisibilityTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hb.done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.extendVisibility(ctx, hb.held())
+			}
+		}
+	}()
+	return hb
+}
+
+// release stops extending a message, so that it becomes visible again for redelivery.
+func (hb *visibilityHeartbeat) release(i int) {
+	hb.mu.Lock()
+	delete(hb.messages, i)
+	hb.mu.Unlock()
+}
+
+func (hb *visibilityHeartbeat) held() []types.ChangeMessageVisibilityBatchRequestEntry {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, 0, len(hb.messages))
+	for i, msg := range hb.messages {
+		entries = append(entries, types.ChangeMessageVisibilityBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+	}
+	return entries
+}
+
+func (hb *visibilityHeartbeat) stop() {
+	close(hb.done)
+	hb.wg.Wait()
+}
+
+func (w *Worker) extendVisibility(ctx context.Context, entries []types.ChangeMessageVisibilityBatchRequestEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	for i := range entries {
+		entries[i].VisibilityTimeout = int32(w.cfg.VisibilityTimeout / time.Second)
+	}
+	res, err := w.sqs.ChangeMessageVisibilityBatch(ctx, &awssqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(w.cfg.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		w.logger.Warn("Failed to extend SQS message visibility", "count", len(entries), "error", err)
+		return
+	}
+	for _, f := range res.Failed {
+		w.logger.Warn("Failed to extend SQS message visibility", "entry_id", aws.ToString(f.Id), "code", aws.ToString(f.Code), "error", aws.ToString(f.Message))
+	}
+}
+
+// snsEnvelope is the JSON body SNS delivers to SQS when raw message delivery is disabled.
+type snsEnvelope struct {
+	Type      string `json:"Type"`
+	MessageID string `json:"MessageId"`
+	TopicArn  string `json:"TopicArn"`
+	Message   string `json:"Message"`
+}
+
+// s3Notification covers both S3 event notifications and the test event S3 sends when
+// notifications are first configured.
+type s3Notification struct {
+	Event   string `json:"Event"`
+	Records []struct {
+		EventSource string `json:"eventSource"`
+		EventName   string `json:"eventName"`
+		S3          struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+func (w *Worker) processMessage(ctx context.Context, msg types.Message) error {
+	body := []byte(aws.ToString(msg.Body))
+	w.metrics.BytesTotal.Add(float64(len(body)))
+
+	origin := map[string]interface{}{"sqs_message_id": aws.ToString(msg.MessageId)}
+
+	var env snsEnvelope
+	if json.Unmarshal(body, &env) == nil && env.Type == "Notification" && env.TopicArn != "" {
+		body = []byte(env.Message)
+		origin["sns_message_id"] = env.MessageID
+		origin["sns_topic_arn"] = env.TopicArn
+	}
+
+	var notif s3Notification
+	if json.Unmarshal(body, &notif) == nil {
+		if notif.Event == "s3:TestEvent" {
+			return nil
+		}
+		if len(notif.Records) > 0 && notif.Records[0].EventSource == "aws:s3" {
+			for _, rec := range notif.Records {
+				if !strings.HasPrefix(rec.EventName, "ObjectCreated:") {
+					continue
+				}
+				if err := w.ingestObject(ctx, rec.S3.Bucket.Name, rec.S3.Object.Key, origin); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return w.ingestPayload(ctx, body, origin)
+}
+
+// ingestObject downloads an S3 object and ingests every non-empty line. Objects whose key
+// ends in .gz or that are stored with gzip Content-Encoding are decompressed first.
+func (w *Worker) ingestObject(ctx context.Context, bucket, escapedKey string, origin map[string]interface{}) error {
+	if w.s3 == nil {
+		return errors.New("received S3 notification but no S3 client is configured")
+	}
+	// Object keys in S3 notifications are URL-encoded with '+' for spaces.
+	key, err := url.QueryUnescape(escapedKey)
+	if err != nil {
+		return fmt.Errorf("invalid S3 object key %q: %w", escapedKey, err)
+	}
+
+	obj, err := w.s3.GetObject(ctx, &awss3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer obj.Body.Close()
+
+	var r io.Reader = obj.Body
+	if strings.HasSuffix(key, ".gz") || aws.ToString(obj.ContentEncoding) == "gzip" {
+		gz, err := gzip.NewReader(obj.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress s3://%s/%s: %w", bucket, key, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	objOrigin := make(map[string]interface{}, len(origin)+2)
+	for k, v := range origin {
+		objOrigin[k] = v
+	}
+	objOrigin["s3_bucket"] = bucket
+	objOrigin["s3_key"] = key
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), w.cfg.MaxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		w.metrics.BytesTotal.Add(float64(len(line)))
+		if err := w.ingestPayload(ctx, append([]byte(nil), line...), objOrigin); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// ingestPayload ingests a JSON log event, a JSON array of events, or a plain-text line.
+// Where the message came from is recorded under the "sqs" metadata key.
+func (w *Worker) ingestPayload(ctx context.Context, payload []byte, origin map[string]interface{}) error {
+	trimmed := bytes.TrimSpace(payload)
+
+	var raws []json.RawMessage
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[' && json.Unmarshal(trimmed, &raws) == nil:
+	case len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed):
+		raws = []json.RawMessage{trimmed}
+	default:
+		return w.ingest(ctx, domain.LogEvent{Message: string(trimmed)}, trimmed, origin)
+	}
+
+	for _, raw := range raws {
+		var event domain.LogEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			// Valid JSON that does not fit the LogEvent shape is kept verbatim.
+			event = domain.LogEvent{Message: string(raw)}
+		}
+		if err := w.ingest(ctx, event, raw, origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) ingest(ctx context.Context, event domain.LogEvent, raw []byte, origin map[string]interface{}) error {
+	metadata := map[string]interface{}{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil || metadata == nil {
+			metadata = map[string]interface{}{"value": event.Metadata}
+		}
+	}
+	metadata["sqs"] = origin
+	event.Metadata, _ = json.Marshal(metadata)
+	if json.Valid(raw) {
+		event.RawEvent = raw
+	} else {
+		event.RawEvent, _ = json.Marshal(string(raw))
+	}
+
+	if err := w.useCase.Ingest(ctx, &event); err != nil {
+		w.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+		return fmt.Errorf("failed to ingest event: %w", err)
+	}
+	w.metrics.EventsTotal.WithLabelValues("accepted").Inc()
+	return nil
+}
@@ -0,0 +1,207 @@
+package sqs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	awssqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeSQS struct {
+	messages []types.Message
+	deleted  []string
+
+	mu       sync.Mutex
+	extended map[string]int // Visibility extensions per receipt handle.
+}
+
+func (f *fakeSQS) ReceiveMessage(ctx context.Context, params *awssqs.ReceiveMessageInput, optFns ...func(*awssqs.Options)) (*awssqs.ReceiveMessageOutput, error) {
+	msgs := f.messages
+	f.messages = nil
+	return &awssqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (f *fakeSQS) DeleteMessageBatch(ctx context.Context, params *awssqs.DeleteMessageBatchInput, optFns ...func(*awssqs.Options)) (*awssqs.DeleteMessageBatchOutput, error) {
+	for _, e := range params.Entries {
+		f.deleted = append(f.deleted, aws.ToString(e.ReceiptHandle))
+	}
+	return &awssqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibilityBatch(ctx context.Context, params *awssqs.ChangeMessageVisibilityBatchInput, optFns ...func(*awssqs.Options)) (*awssqs.ChangeMessageVisibilityBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.extended == nil {
+		f.extended = make(map[string]int)
+	}
+	for _, e := range params.Entries {
+		f.extended[aws.ToString(e.ReceiptHandle)]++
+	}
+	return &awssqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	data, ok := f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+type recordingUseCase struct {
+	events []domain.LogEvent
+	failOn string
+}
+
+func (r *recordingUseCase) Ingest(_ context.Context, event *domain.LogEvent) error {
+	if r.failOn != "" && event.Message == r.failOn {
+		return errors.New("buffer unavailable")
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func message(handle, body string) types.Message {
+	return types.Message{MessageId: aws.String("id-" + handle), ReceiptHandle: aws.String(handle), Body: aws.String(body)}
+}
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestWorker_Poll(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	snsBody, _ := json.Marshal(map[string]string{
+		"Type":      "Notification",
+		"MessageId": "sns-1",
+		"TopicArn":  "arn:aws:sns:us-east-1:123:logs",
+		"Message":   `{"message":"from sns","level":"warn"}`,
+	})
+	s3Body := `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"logs"},"object":{"key":"app/2024+05.ndjson.gz"}}}]}`
+
+	sqsClient := &fakeSQS{messages: []types.Message{
+		message("direct", `{"message":"direct","metadata":{"user":"bob"}}`),
+		message("text", "plain text line"),
+		message("sns", string(snsBody)),
+		message("s3", s3Body),
+		message("test", `{"Service":"Amazon S3","Event":"s3:TestEvent"}`),
+		message("fail", `{"message":"fail me"}`),
+	}}
+	s3Client := &fakeS3{objects: map[string][]byte{
+		"logs/app/2024 05.ndjson.gz": gzipped(`{"message":"s3 line 1"}` + "\n\n" + `{"message":"s3 line 2"}` + "\n"),
+	}}
+	uc := &recordingUseCase{failOn: "fail me"}
+
+	w := NewWorker(sqsClient, s3Client, WorkerConfig{QueueURL: "https://sqs/queue"}, uc, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	wantDeleted := []string{"direct", "text", "sns", "s3", "test"}
+	if len(sqsClient.deleted) != len(wantDeleted) {
+		t.Fatalf("expected deleted %v, got %v", wantDeleted, sqsClient.deleted)
+	}
+	for i, h := range wantDeleted {
+		if sqsClient.deleted[i] != h {
+			t.Errorf("expected deleted %v, got %v", wantDeleted, sqsClient.deleted)
+			break
+		}
+	}
+
+	wantMessages := []string{"direct", "plain text line", "from sns", "s3 line 1", "s3 line 2"}
+	if len(uc.events) != len(wantMessages) {
+		t.Fatalf("expected %d events, got %d", len(wantMessages), len(uc.events))
+	}
+	for i, msg := range wantMessages {
+		if uc.events[i].Message != msg {
+			t.Errorf("event %d: expected message %q, got %q", i, msg, uc.events[i].Message)
+		}
+	}
+
+	var md map[string]interface{}
+	json.Unmarshal(uc.events[0].Metadata, &md)
+	if md["user"] != "bob" || md["sqs"] == nil {
+		t.Errorf("expected original metadata plus sqs origin, got %s", uc.events[0].Metadata)
+	}
+	json.Unmarshal(uc.events[2].Metadata, &md)
+	if origin, _ := md["sqs"].(map[string]interface{}); origin["sns_topic_arn"] != "arn:aws:sns:us-east-1:123:logs" {
+		t.Errorf("expected SNS topic in metadata, got %s", uc.events[2].Metadata)
+	}
+	json.Unmarshal(uc.events[3].Metadata, &md)
+	if origin, _ := md["sqs"].(map[string]interface{}); origin["s3_key"] != "app/2024 05.ndjson.gz" {
+		t.Errorf("expected decoded S3 key in metadata, got %s", uc.events[3].Metadata)
+	}
+}
+
+func TestWorker_S3FetchFailureKeepsMessage(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sqsClient := &fakeSQS{messages: []types.Message{
+		message("missing", `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"logs"},"object":{"key":"nope"}}}]}`),
+	}}
+
+	w := NewWorker(sqsClient, &fakeS3{}, WorkerConfig{QueueURL: "https://sqs/queue"}, &recordingUseCase{}, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if len(sqsClient.deleted) != 0 {
+		t.Errorf("expected message to be left for redelivery, deleted %v", sqsClient.deleted)
+	}
+}
+
+// slowUseCase blocks on one message for a while to simulate a slow S3 download.
+type slowUseCase struct {
+	slowOn string
+	delay  time.Duration
+}
+
+func (s *slowUseCase) Ingest(_ context.Context, event *domain.LogEvent) error {
+	if event.Message == s.slowOn {
+		time.Sleep(s.delay)
+	}
+	return nil
+}
+
+func TestWorker_HeartbeatExtendsWholeBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fake := &fakeSQS{messages: []types.Message{
+		message("slow", `{"message":"slow"}`),
+		message("waiting", `{"message":"waiting"}`),
+	}}
+	w := NewWorker(fake, nil, WorkerConfig{QueueURL: "q", VisibilityTimeout: time.Second}, &slowUseCase{slowOn: "slow", delay: 700 * time.Millisecond}, logger, metrics.NewIngestMetrics(prometheus.NewRegistry()))
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.extended["waiting"] == 0 {
+		t.Errorf("expected the message waiting behind a slow one to be extended, got %v", fake.extended)
+	}
+	if len(fake.deleted) != 2 {
+		t.Errorf("expected both messages to be deleted, got %v", fake.deleted)
+	}
+}
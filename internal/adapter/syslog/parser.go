@@ -0,0 +1,249 @@
+package syslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const nilValue = "-"
+
+var (
+	ErrInvalidPriority = errors.New("invalid syslog priority")
+	ErrInvalidHeader   = errors.New("invalid syslog header")
+)
+
+var severityNames = [8]string{"emergency", "alert", "critical", "error", "warning", "notice", "informational", "debug"}
+
+// severityLevels maps syslog severities onto the levels used by the rest of the pipeline.
+var severityLevels = [8]string{"fatal", "fatal", "fatal", "error", "warn", "info", "info", "debug"}
+
+// Message is a parsed syslog message in either RFC3164 or RFC5424 format.
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int // 0 for RFC3164
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Message        string
+}
+
+// Parse parses a single syslog message, detecting RFC5424 by its version field
+// and falling back to RFC3164 otherwise.
+func Parse(data []byte, now time.Time) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	if len(data) < 3 || data[0] != '<' {
+		return nil, ErrInvalidPriority
+	}
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return nil, ErrInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, ErrInvalidPriority
+	}
+
+	msg := &Message{Facility: pri / 8, Severity: pri % 8}
+	rest := string(data[end+1:])
+
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		msg.Version = 1
+		if err := parse5424(msg, rest[2:]); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	parse3164(msg, rest, now)
+	return msg, nil
+}
+
+func parse5424(msg *Message, rest string) error {
+	fields := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		sp := strings.IndexByte(rest, ' ')
+		if sp < 0 {
+			return fmt.Errorf("%w: expected 5 header fields", ErrInvalidHeader)
+		}
+		fields = append(fields, rest[:sp])
+		rest = rest[sp+1:]
+	}
+
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: bad timestamp: %v", ErrInvalidHeader, err)
+		}
+		msg.Timestamp = ts.UTC()
+	}
+	msg.Hostname = nilToEmpty(fields[1])
+	msg.AppName = nilToEmpty(fields[2])
+	msg.ProcID = nilToEmpty(fields[3])
+	msg.MsgID = nilToEmpty(fields[4])
+
+	if strings.HasPrefix(rest, nilValue) {
+		rest = strings.TrimPrefix(rest[1:], " ")
+	} else if strings.HasPrefix(rest, "[") {
+		sd, remaining, err := parseStructuredData(rest)
+		if err != nil {
+			return err
+		}
+		msg.StructuredData = sd
+		rest = strings.TrimPrefix(remaining, " ")
+	}
+
+	// Strip the UTF-8 BOM that RFC5424 allows before the message.
+	msg.Message = strings.TrimPrefix(rest, "\ufeff")
+	return nil
+}
+
+// parseStructuredData parses one or more SD-ELEMENTs: [id key="value" ...][id2 ...]
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	sd := make(map[string]map[string]string)
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		idEnd := strings.IndexAny(s, " ]")
+		if idEnd < 0 {
+			return nil, "", fmt.Errorf("%w: unterminated structured data", ErrInvalidHeader)
+		}
+		id := s[:idEnd]
+		params := make(map[string]string)
+		s = s[idEnd:]
+
+		for {
+			s = strings.TrimLeft(s, " ")
+			if strings.HasPrefix(s, "]") {
+				s = s[1:]
+				break
+			}
+			eq := strings.Index(s, "=\"")
+			if eq < 0 {
+				return nil, "", fmt.Errorf("%w: malformed structured data param", ErrInvalidHeader)
+			}
+			name := s[:eq]
+			s = s[eq+2:]
+
+			var val strings.Builder
+			closed := false
+			for i := 0; i < len(s); i++ {
+				c := s[i]
+				if c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+					val.WriteByte(s[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					s = s[i+1:]
+					closed = true
+					break
+				}
+				val.WriteByte(c)
+			}
+			if !closed {
+				return nil, "", fmt.Errorf("%w: unterminated structured data value", ErrInvalidHeader)
+			}
+			params[name] = val.String()
+		}
+		sd[id] = params
+	}
+	return sd, s, nil
+}
+
+// parse3164 is lenient by design: BSD syslog senders vary widely, so anything that does
+// not match the expected header is kept as the message body.
+func parse3164(msg *Message, rest string, now time.Time) {
+	const stampLen = len(time.Stamp)
+	if len(rest) >= stampLen+1 {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:stampLen], now.Location()); err == nil {
+			// RFC3164 timestamps carry no year; assume the current one unless that
+			// would put the message noticeably in the future (e.g. around New Year).
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts.UTC()
+			rest = strings.TrimPrefix(rest[stampLen:], " ")
+
+			if sp := strings.IndexByte(rest, ' '); sp > 0 {
+				msg.Hostname = rest[:sp]
+				rest = rest[sp+1:]
+			}
+		}
+	}
+
+	// TAG is up to 32 alphanumeric characters, optionally followed by [pid], then ':'.
+	if colon := strings.Index(rest, ": "); colon > 0 && colon <= 48 && !strings.ContainsAny(rest[:colon], " ") {
+		tag := rest[:colon]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.AppName = tag
+		rest = rest[colon+2:]
+	}
+	msg.Message = rest
+}
+
+// ToLogEvent converts a parsed syslog message into a LogEvent.
+func (m *Message) ToLogEvent() domain.LogEvent {
+	metadata := map[string]interface{}{
+		"syslog_facility": m.Facility,
+		"syslog_severity": severityNames[m.Severity],
+	}
+	if m.Hostname != "" {
+		metadata["hostname"] = m.Hostname
+	}
+	if m.AppName != "" {
+		metadata["app_name"] = m.AppName
+	}
+	if m.ProcID != "" {
+		metadata["proc_id"] = m.ProcID
+	}
+	if m.MsgID != "" {
+		metadata["msg_id"] = m.MsgID
+	}
+	if len(m.StructuredData) > 0 {
+		metadata["structured_data"] = m.StructuredData
+	}
+	metadataBytes, _ := json.Marshal(metadata)
+
+	source := m.AppName
+	if source == "" {
+		source = m.Hostname
+	}
+
+	return domain.LogEvent{
+		EventTime: m.Timestamp,
+		Source:    source,
+		Level:     severityLevels[m.Severity],
+		Message:   m.Message,
+		Metadata:  metadataBytes,
+	}
+}
+
+// SeverityLevel returns the pipeline level for a syslog severity (0-7), or "" if it is out of range.
+// Journald PRIORITY values use the same scale.
+func SeverityLevel(severity int) string {
+	if severity < 0 || severity >= len(severityLevels) {
+		return ""
+	}
+	return severityLevels[severity]
+}
+
+func nilToEmpty(s string) string {
+	if s == nilValue {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package syslog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		input     string
+		expectErr bool
+		check     func(t *testing.T, m *Message)
+	}{
+		{
+			name:  "RFC5424 with structured data",
+			input: `<165>1 2024-03-10T11:59:58.123Z host01 api 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][meta seq="1"] An application event`,
+			check: func(t *testing.T, m *Message) {
+				if m.Version != 1 || m.Facility != 20 || m.Severity != 5 {
+					t.Errorf("unexpected priority fields: %+v", m)
+				}
+				if m.Hostname != "host01" || m.AppName != "api" || m.ProcID != "1234" || m.MsgID != "ID47" {
+					t.Errorf("unexpected header fields: %+v", m)
+				}
+				if m.StructuredData["exampleSDID@32473"]["eventSource"] != `App"lication` || m.StructuredData["meta"]["seq"] != "1" {
+					t.Errorf("unexpected structured data: %v", m.StructuredData)
+				}
+				if m.Message != "An application event" {
+					t.Errorf("unexpected message: %q", m.Message)
+				}
+				if !m.Timestamp.Equal(time.Date(2024, time.March, 10, 11, 59, 58, 123000000, time.UTC)) {
+					t.Errorf("unexpected timestamp: %v", m.Timestamp)
+				}
+			},
+		},
+		{
+			name:  "RFC5424 with nil values",
+			input: `<14>1 - - - - - - hello`,
+			check: func(t *testing.T, m *Message) {
+				if !m.Timestamp.IsZero() || m.Hostname != "" || m.StructuredData != nil || m.Message != "hello" {
+					t.Errorf("unexpected message: %+v", m)
+				}
+			},
+		},
+		{
+			name:  "RFC3164",
+			input: `<34>Mar  9 22:14:15 mymachine su[231]: 'su root' failed for lonvick on /dev/pts/8`,
+			check: func(t *testing.T, m *Message) {
+				if m.Version != 0 || m.Severity != 2 || m.Hostname != "mymachine" || m.AppName != "su" || m.ProcID != "231" {
+					t.Errorf("unexpected header fields: %+v", m)
+				}
+				if m.Message != "'su root' failed for lonvick on /dev/pts/8" {
+					t.Errorf("unexpected message: %q", m.Message)
+				}
+				if m.Timestamp.Year() != 2024 || m.Timestamp.Day() != 9 {
+					t.Errorf("unexpected timestamp: %v", m.Timestamp)
+				}
+			},
+		},
+		{
+			name:  "RFC3164 from last year",
+			input: `<13>Dec 31 23:59:59 host app: bye`,
+			check: func(t *testing.T, m *Message) {
+				if m.Timestamp.Year() != 2023 {
+					t.Errorf("expected timestamp in previous year, got %v", m.Timestamp)
+				}
+			},
+		},
+		{name: "Missing priority", input: `hello`, expectErr: true},
+		{name: "Priority out of range", input: `<200>hello`, expectErr: true},
+		{name: "Truncated RFC5424 header", input: `<14>1 2024-03-10T11:59:58Z host`, expectErr: true},
+		{name: "Unterminated structured data", input: `<14>1 - - - - - [id k="v`, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.input), now)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.expectErr)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, m)
+			}
+		})
+	}
+}
+
+func TestMessage_ToLogEvent(t *testing.T) {
+	m, err := Parse([]byte(`<11>1 2024-03-10T11:59:58Z host01 billing - - [req id="7"] charge failed`), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	event := m.ToLogEvent()
+
+	if event.Source != "billing" || event.Level != "error" || event.Message != "charge failed" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+		t.Fatalf("failed to unmarshal metadata: %v", err)
+	}
+	if metadata["hostname"] != "host01" || metadata["syslog_severity"] != "error" {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+}
@@ -0,0 +1,254 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const (
+	defaultMaxMessageSize = 64 * 1024
+	defaultTCPIdleTimeout = 5 * time.Minute
+	defaultMaxTCPConns    = 1024
+	// maxOctetCountLen bounds the length prefix of an octet-counted frame; ten digits
+	// exceed any sensible message size.
+	maxOctetCountLen = 10
+)
+
+// ServerConfig holds the listener settings for the Server. An empty address disables
+// that transport.
+type ServerConfig struct {
+	UDPAddr        string
+	TCPAddr        string
+	MaxMessageSize int
+	TCPIdleTimeout time.Duration // A TCP connection that sends nothing for this long is closed.
+	MaxTCPConns    int           // Further TCP connections are closed as soon as they are accepted.
+}
+
+// Server receives syslog messages over UDP and/or TCP and ingests them.
+type Server struct {
+	cfg     ServerConfig
+	useCase usecase.IngestLogUseCase
+	logger  *slog.Logger
+	metrics *metrics.IngestMetrics
+
+	conns chan struct{} // Semaphore of open TCP connections.
+	wg    sync.WaitGroup
+}
+
+// NewServer creates a new syslog Server.
+func NewServer(cfg ServerConfig, uc usecase.IngestLogUseCase, logger *slog.Logger, m *metrics.IngestMetrics) *Server {
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+	if cfg.TCPIdleTimeout <= 0 {
+		cfg.TCPIdleTimeout = defaultTCPIdleTimeout
+	}
+	if cfg.MaxTCPConns <= 0 {
+		cfg.MaxTCPConns = defaultMaxTCPConns
+	}
+	return &Server{
+		cfg:     cfg,
+		useCase: uc,
+		logger:  logger.With("component", "syslog_server"),
+		metrics: m,
+		conns:   make(chan struct{}, cfg.MaxTCPConns),
+	}
+}
+
+// Start binds the configured listeners and serves them until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	if s.cfg.UDPAddr != "" {
+		conn, err := net.ListenPacket("udp", s.cfg.UDPAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on syslog UDP %s: %w", s.cfg.UDPAddr, err)
+		}
+		s.logger.Info("starting syslog UDP listener", "addr", conn.LocalAddr().String())
+		s.wg.Add(1)
+		go s.serveUDP(ctx, conn)
+	}
+
+	if s.cfg.TCPAddr != "" {
+		ln, err := net.Listen("tcp", s.cfg.TCPAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on syslog TCP %s: %w", s.cfg.TCPAddr, err)
+		}
+		s.logger.Info("starting syslog TCP listener", "addr", ln.Addr().String())
+		s.wg.Add(1)
+		go s.serveTCP(ctx, ln)
+	}
+
+	return nil
+}
+
+// Wait blocks until all listeners and connections have stopped.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) serveUDP(ctx context.Context, conn net.PacketConn) {
+	defer s.wg.Done()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, s.cfg.MaxMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Warn("syslog UDP read failed", "error", err)
+			continue
+		}
+		s.handleMessage(ctx, buf[:n])
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener) {
+	defer s.wg.Done()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Warn("syslog TCP accept failed", "error", err)
+			continue
+		}
+		select {
+		case s.conns <- struct{}{}:
+		default:
+			s.logger.Warn("too many syslog TCP connections, closing new connection", "max_conns", s.cfg.MaxTCPConns, "remote_addr", conn.RemoteAddr().String())
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go s.handleConn(ctx, conn)
+	}
+}
+
+// handleConn reads messages from a TCP connection. Both RFC6587 framings are supported:
+// octet counting ("LEN SP MSG") and non-transparent framing (newline-terminated).
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
+	defer s.wg.Done()
+	defer func() { <-s.conns }()
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReaderSize(conn, s.cfg.MaxMessageSize)
+	for {
+		// The deadline is renewed per message, so it only closes idle or stalled peers.
+		conn.SetReadDeadline(time.Now().Add(s.cfg.TCPIdleTimeout))
+		first, err := r.Peek(1)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				s.logger.Debug("closing idle syslog TCP connection", "remote_addr", conn.RemoteAddr().String())
+			} else if !errors.Is(err, io.EOF) && ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				s.logger.Warn("syslog TCP read failed", "error", err, "remote_addr", conn.RemoteAddr().String())
+			}
+			return
+		}
+
+		var frame []byte
+		if first[0] >= '0' && first[0] <= '9' {
+			frame, err = ReadOctetCounted(r, s.cfg.MaxMessageSize)
+		} else {
+			frame, err = r.ReadSlice('\n')
+			if errors.Is(err, bufio.ErrBufferFull) {
+				err = fmt.Errorf("syslog message exceeds %d bytes", s.cfg.MaxMessageSize)
+			} else if errors.Is(err, io.EOF) && len(frame) > 0 {
+				err = nil
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logger.Warn("dropping syslog TCP connection", "error", err, "remote_addr", conn.RemoteAddr().String())
+			}
+			return
+		}
+		s.handleMessage(ctx, frame)
+	}
+}
+
+// ReadOctetCounted reads one RFC6587 octet-counted frame ("LEN SP MSG") from r,
+// rejecting frames larger than maxSize and length prefixes longer than maxOctetCountLen.
+func ReadOctetCounted(r *bufio.Reader, maxSize int) ([]byte, error) {
+	var prefix []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' {
+			break
+		}
+		if len(prefix) == maxOctetCountLen {
+			return nil, fmt.Errorf("octet count prefix exceeds %d bytes", maxOctetCountLen)
+		}
+		prefix = append(prefix, b)
+	}
+	n, err := strconv.Atoi(string(prefix))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid octet count %q", prefix)
+	}
+	if n > maxSize {
+		return nil, fmt.Errorf("syslog message of %d bytes exceeds %d bytes", n, maxSize)
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (s *Server) handleMessage(ctx context.Context, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	s.metrics.BytesTotal.Add(float64(len(data)))
+
+	msg, err := Parse(data, time.Now())
+	if err != nil {
+		s.logger.Warn("failed to parse syslog message", "error", err)
+		s.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return
+	}
+
+	event := msg.ToLogEvent()
+	event.RawEvent, _ = json.Marshal(string(data))
+	if err := s.useCase.Ingest(ctx, &event); err != nil {
+		s.logger.Error("failed to ingest syslog message", "error", err)
+		s.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+		return
+	}
+	s.metrics.EventsTotal.WithLabelValues("accepted").Inc()
+}
@@ -0,0 +1,79 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadOctetCounted(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "Frame", input: "5 hello<34>", expected: "hello"},
+		{name: "Frame too large", input: "100 hello", expectErr: true},
+		{name: "Invalid count", input: "0x5 hello", expectErr: true},
+		{name: "Unbounded prefix", input: strings.Repeat("9", 64), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := ReadOctetCounted(bufio.NewReader(strings.NewReader(tt.input)), 16)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ReadOctetCounted() error = %v, wantErr %v", err, tt.expectErr)
+			}
+			if string(frame) != tt.expected {
+				t.Errorf("expected frame %q, got %q", tt.expected, frame)
+			}
+		})
+	}
+}
+
+func TestServer_TCPConnectionLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewServer(ServerConfig{TCPIdleTimeout: 100 * time.Millisecond, MaxTCPConns: 1}, nil, logger, nil)
+	s.wg.Add(1)
+	go s.serveTCP(ctx, ln)
+	defer func() {
+		cancel()
+		s.Wait()
+	}()
+
+	idle, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer idle.Close()
+	// Wait until the first connection holds the only slot.
+	for len(s.conns) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	extra, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer extra.Close()
+	extra.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := extra.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected the connection over the limit to be closed, got %v", err)
+	}
+
+	idle.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("expected the idle connection to be closed, got %v", err)
+	}
+}
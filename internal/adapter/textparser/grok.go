@@ -0,0 +1,118 @@
+package textparser
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// grokPatterns is a subset of the Logstash core patterns, covering common application,
+// syslog and web server logs, and a library of whole-line patterns for common software:
+// NGINXACCESS and NGINXERROR, COMMONAPACHELOG, COMBINEDAPACHELOG and APACHEERROR,
+// POSTGRESQL (for the default log_line_prefix '%m [%p] ') and JSONOBJECT, an embedded
+// JSON object to capture with the :json type.
+var grokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?[0-9]+)`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `(?:%{BASE10NUM})`,
+	"POSINT":            `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":         `\b(?:[0-9]+)\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"MONTH":             `\b(?:Jan(?:uary)?|Feb(?:ruary)?|Mar(?:ch)?|Apr(?:il)?|May|Jun(?:e)?|Jul(?:y)?|Aug(?:ust)?|Sep(?:tember)?|Oct(?:ober)?|Nov(?:ember)?|Dec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0[1-9]|[12][0-9]|3[01]|[1-9])`,
+	"DAY":               `\b(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)\b`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"LOGLEVEL":          `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo(?:rmation)?|INFO(?:RMATION)?|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?)`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response:int} (?:%{NUMBER:bytes:int}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+	"APACHEERRORTIME":   `%{DAY} %{MONTH} %{MONTHDAY} %{TIME} %{YEAR}`,
+	"APACHEERROR":       `\[%{APACHEERRORTIME:timestamp}\] \[(?:%{WORD:module})?:%{LOGLEVEL:level}\] \[pid %{POSINT:pid:int}(?::tid %{NONNEGINT:tid:int})?\](?: \[client %{IPORHOST:clientip}(?::%{POSINT:clientport:int})?\])? (?:(?P<errorcode>AH\d+): )?%{GREEDYDATA:message}`,
+	"NGINXACCESS":       `%{IPORHOST:clientip} - %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response:int} (?:%{NUMBER:bytes:int}|-) %{QS:referrer} %{QS:agent}(?: %{QS:forwarded_for})?`,
+	"NGINXERRORTIME":    `%{YEAR}/%{MONTHNUM}/%{MONTHDAY} %{TIME}`,
+	"NGINXERROR":        `%{NGINXERRORTIME:timestamp} \[%{LOGLEVEL:level}\] %{POSINT:pid:int}#%{NONNEGINT:tid:int}: (?:\*%{NONNEGINT:connection:int} )?%{GREEDYDATA:message}`,
+	"POSTGRESQLTIME":    `%{YEAR}-%{MONTHNUM}-%{MONTHDAY} %{TIME}(?: [A-Z]{2,5})?`,
+	"POSTGRESQLLEVEL":   `(?:DEBUG[1-5]?|LOG|INFO|NOTICE|WARNING|ERROR|FATAL|PANIC|STATEMENT|DETAIL|HINT|CONTEXT)`,
+	"POSTGRESQL":        `%{POSTGRESQLTIME:timestamp} \[%{POSINT:pid:int}\] %{POSTGRESQLLEVEL:level}:  ?%{GREEDYDATA:message}`,
+	"JSONOBJECT":        `\{.*\}`,
+}
+
+// grokRef matches %{PATTERN}, %{PATTERN:field} and %{PATTERN:field:int|float|json}.
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::(\w+)(?::(int|float|json))?)?\}`)
+
+const maxGrokDepth = 16
+
+// NewGrokParser compiles a grok expression into a RegexParser. Fields named in the
+// expression become named groups; a ":int" or ":float" suffix converts the captured value,
+// and ":json" decodes it, keeping the text if it is not valid JSON.
+func NewGrokParser(pattern string) (*RegexParser, error) {
+	types := make(map[string]string)
+	expanded, err := expandGrok(pattern, types, 0)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewRegexParser(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("grok %q: %w", pattern, err)
+	}
+	p.types = types
+	return p, nil
+}
+
+func expandGrok(pattern string, types map[string]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok pattern nesting exceeds %d levels", maxGrokDepth)
+	}
+	var expandErr error
+	out := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if expandErr != nil {
+			return ""
+		}
+		m := grokRef.FindStringSubmatch(ref)
+		name, field, typ := m[1], m[2], m[3]
+		def, ok := grokPatterns[name]
+		if !ok {
+			expandErr = fmt.Errorf("unknown grok pattern %q", name)
+			return ""
+		}
+		inner, err := expandGrok(def, types, depth+1)
+		if err != nil {
+			expandErr = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		if typ != "" {
+			types[field] = typ
+		}
+		return "(?P<" + field + ">" + inner + ")"
+	})
+	return out, expandErr
+}
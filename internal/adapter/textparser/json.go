@@ -0,0 +1,29 @@
+package textparser
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSONParser extracts the fields of a JSON object embedded in a line, e.g. the payload of
+// `2024-05-01T12:00:00Z INFO {"user":"bob"}`. Text before the object becomes the message
+// field, unless the object has one.
+type JSONParser struct{}
+
+// Parse implements Parser. The object runs from the first "{" to the last "}" of the line.
+func (JSONParser) Parse(line string) (map[string]interface{}, bool) {
+	start, end := strings.IndexByte(line, '{'), strings.LastIndexByte(line, '}')
+	if start < 0 || end < start {
+		return nil, false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line[start:end+1]), &fields); err != nil || fields == nil {
+		return nil, false
+	}
+	if prefix := strings.TrimSpace(line[:start]); prefix != "" {
+		if _, ok := fields["message"]; !ok {
+			fields["message"] = prefix
+		}
+	}
+	return fields, true
+}
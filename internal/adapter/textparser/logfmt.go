@@ -0,0 +1,72 @@
+package textparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LogfmtParser parses key=value lines as produced by logfmt loggers, e.g.
+// `time=2024-05-01T12:00:00Z level=info msg="user logged in" user=bob`.
+// Values may be double-quoted with Go-style escapes; a bare key is recorded as true.
+// A line matches only if every token is a key or key=value pair and at least one has a value.
+type LogfmtParser struct{}
+
+// Parse implements Parser.
+func (LogfmtParser) Parse(line string) (map[string]interface{}, bool) {
+	fields := make(map[string]interface{})
+	hasValue := false
+	s := strings.TrimSpace(line)
+
+	for len(s) > 0 {
+		end := strings.IndexAny(s, "= ")
+		if end == 0 {
+			return nil, false
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		key := s[:end]
+		if strings.ContainsAny(key, `"`) {
+			return nil, false
+		}
+		s = s[end:]
+
+		if !strings.HasPrefix(s, "=") {
+			fields[key] = true
+			s = strings.TrimLeft(s, " ")
+			continue
+		}
+		s = s[1:]
+		hasValue = true
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(quoted)
+			s = s[len(quoted):]
+			if len(s) > 0 && s[0] != ' ' {
+				return nil, false
+			}
+		} else {
+			sp := strings.IndexByte(s, ' ')
+			if sp < 0 {
+				sp = len(s)
+			}
+			value = s[:sp]
+			if strings.ContainsAny(value, `"=`) {
+				return nil, false
+			}
+			s = s[sp:]
+		}
+		fields[key] = value
+		s = strings.TrimLeft(s, " ")
+	}
+
+	if !hasValue {
+		return nil, false
+	}
+	return fields, true
+}
@@ -0,0 +1,194 @@
+package textparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// Parser extracts fields from a single line of text. It reports false when the line does
+// not match, so that the next parser in the chain can try.
+type Parser interface {
+	Parse(line string) (map[string]interface{}, bool)
+}
+
+// Spec describes one parser in a chain. It is the element type of the TEXT_PARSERS JSON array.
+type Spec struct {
+	Type       string `json:"type"`                  // "regex", "grok", "dissect", "json" or "logfmt"
+	Pattern    string `json:"pattern,omitempty"`     // Required for regex, grok and dissect.
+	TimeFormat string `json:"time_format,omitempty"` // Go layout for the timestamp field; common formats are tried when empty.
+}
+
+// Chain runs parsers in order and maps the fields of the first match onto a LogEvent.
+type Chain struct {
+	parsers     []Parser
+	timeFormats []string
+}
+
+// NewChain builds a Chain from its specs.
+func NewChain(specs []Spec) (*Chain, error) {
+	c := &Chain{}
+	for i, spec := range specs {
+		var p Parser
+		var err error
+		switch strings.ToLower(spec.Type) {
+		case "regex":
+			p, err = NewRegexParser(spec.Pattern)
+		case "grok":
+			p, err = NewGrokParser(spec.Pattern)
+		case "dissect":
+			p, err = NewDissectParser(spec.Pattern)
+		case "json":
+			p = JSONParser{}
+		case "logfmt":
+			p = LogfmtParser{}
+		default:
+			err = fmt.Errorf("unknown parser type %q", spec.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("text parser %d: %w", i, err)
+		}
+		c.parsers = append(c.parsers, p)
+		c.timeFormats = append(c.timeFormats, spec.TimeFormat)
+	}
+	return c, nil
+}
+
+// ParseSpecs decodes a JSON array of parser specs, e.g.
+// [{"type":"grok","pattern":"%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} %{GREEDYDATA:message}"},{"type":"logfmt"}].
+// An empty string yields no specs.
+func ParseSpecs(s string) ([]Spec, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var specs []Spec
+	if err := json.Unmarshal([]byte(s), &specs); err != nil {
+		return nil, fmt.Errorf("invalid text parser specs: %w", err)
+	}
+	return specs, nil
+}
+
+// Parse converts a line into a LogEvent. Well-known field names populate the event's Level,
+// Source, EventTime and Message; every other field goes into Metadata. A line no parser
+// matches becomes the Message as-is.
+func (c *Chain) Parse(line string) domain.LogEvent {
+	event := domain.LogEvent{Message: line}
+	if c == nil {
+		return event
+	}
+
+	for i, p := range c.parsers {
+		fields, ok := p.Parse(line)
+		if !ok {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			s, isString := v.(string)
+			switch strings.ToLower(k) {
+			case "level", "lvl", "severity":
+				if isString {
+					event.Level = strings.ToLower(s)
+					continue
+				}
+			case "source", "service", "app":
+				if isString {
+					event.Source = s
+					continue
+				}
+			case "message", "msg":
+				if isString {
+					event.Message = s
+					continue
+				}
+			case "timestamp", "time", "ts":
+				if ts, ok := ParseTime(v, c.timeFormats[i]); ok {
+					event.EventTime = ts
+					continue
+				}
+			}
+			metadata[k] = v
+		}
+		if len(metadata) > 0 {
+			event.Metadata, _ = json.Marshal(metadata)
+		}
+		return event
+	}
+	return event
+}
+
+// defaultTimeFormats are tried in order when a parser has no explicit time_format.
+var defaultTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",
+	"02/Jan/2006:15:04:05 -0700",      // Apache/nginx access logs
+	"2006/01/02 15:04:05",             // nginx error logs
+	"Mon Jan 02 15:04:05.999999 2006", // Apache error logs
+	"2006-01-02 15:04:05.999 MST",     // PostgreSQL
+	time.RubyDate,
+	time.UnixDate,
+	time.Stamp,
+}
+
+// ParseTime parses a timestamp field with format, a Go layout, or if it is empty with the
+// common formats of defaultTimeFormats. Numbers and numeric strings are Unix epochs in
+// seconds, milliseconds, microseconds or nanoseconds, told apart by their magnitude.
+func ParseTime(v interface{}, format string) (time.Time, bool) {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case int64:
+		s = strconv.FormatInt(val, 10)
+	case float64:
+		s = strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		s = val.String()
+	default:
+		return time.Time{}, false
+	}
+
+	formats := defaultTimeFormats
+	if format != "" {
+		formats = []string{format}
+	}
+	for _, f := range formats {
+		ts, err := time.Parse(f, s)
+		if err != nil {
+			continue
+		}
+		if ts.Year() == 0 { // Formats without a year, e.g. syslog timestamps.
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+		}
+		return ts.UTC(), true
+	}
+
+	// Unix epoch in seconds, milliseconds, microseconds or nanoseconds.
+	if f, err := strconv.ParseFloat(s, 64); err == nil && f > 0 {
+		switch {
+		case f > 1e17:
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return time.Unix(0, n).UTC(), true
+			}
+			return time.Unix(0, int64(f)).UTC(), true
+		case f > 1e14:
+			return time.UnixMicro(int64(f)).UTC(), true
+		case f > 1e12:
+			return time.UnixMilli(int64(f)).UTC(), true
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
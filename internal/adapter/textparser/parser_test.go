@@ -0,0 +1,219 @@
+package textparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLogfmtParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  map[string]interface{}
+		match bool
+	}{
+		{
+			name:  "Simple",
+			line:  `level=info msg="user logged in" user=bob`,
+			want:  map[string]interface{}{"level": "info", "msg": "user logged in", "user": "bob"},
+			match: true,
+		},
+		{
+			name:  "Escaped quotes and bare key",
+			line:  `msg="say \"hi\"" debug`,
+			want:  map[string]interface{}{"msg": `say "hi"`, "debug": true},
+			match: true,
+		},
+		{name: "Plain text", line: "something went wrong", match: false},
+		{name: "Unterminated quote", line: `msg="oops`, match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LogfmtParser{}.Parse(tt.line)
+			if ok != tt.match {
+				t.Fatalf("expected match=%v, got %v (%v)", tt.match, ok, got)
+			}
+			if tt.match && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGrokParser(t *testing.T) {
+	p, err := NewGrokParser(`%{COMMONAPACHELOG}`)
+	if err != nil {
+		t.Fatalf("NewGrokParser failed: %v", err)
+	}
+	fields, ok := p.Parse(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`)
+	if !ok {
+		t.Fatal("expected access log line to match")
+	}
+	want := map[string]interface{}{
+		"clientip":    "127.0.0.1",
+		"ident":       "-",
+		"auth":        "frank",
+		"timestamp":   "10/Oct/2000:13:55:36 -0700",
+		"verb":        "GET",
+		"request":     "/apache_pb.gif",
+		"httpversion": "1.0",
+		"response":    int64(200),
+		"bytes":       int64(2326),
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v", want, fields)
+	}
+
+	if _, err := NewGrokParser(`%{NOPE:x}`); err == nil {
+		t.Error("expected error for unknown grok pattern")
+	}
+}
+
+func TestGrokLibrary(t *testing.T) {
+	tests := []struct {
+		pattern string
+		line    string
+		want    map[string]interface{}
+	}{
+		{
+			pattern: "%{NGINXACCESS}",
+			line:    `203.0.113.7 - - [01/May/2024:12:00:00 +0000] "GET /health HTTP/1.1" 200 2 "-" "curl/8.5.0"`,
+			want:    map[string]interface{}{"clientip": "203.0.113.7", "verb": "GET", "request": "/health", "response": int64(200), "agent": `"curl/8.5.0"`},
+		},
+		{
+			pattern: "%{NGINXERROR}",
+			line:    `2024/05/01 12:00:00 [error] 31#31: *7 open() "/srv/favicon.ico" failed (2: No such file or directory)`,
+			want:    map[string]interface{}{"timestamp": "2024/05/01 12:00:00", "level": "error", "pid": int64(31), "connection": int64(7)},
+		},
+		{
+			pattern: "%{APACHEERROR}",
+			line:    `[Wed May 01 12:00:00.123456 2024] [core:error] [pid 1234:tid 5678] [client 203.0.113.7:51234] AH00037: Symbolic link not allowed`,
+			want:    map[string]interface{}{"module": "core", "level": "error", "pid": int64(1234), "clientip": "203.0.113.7", "errorcode": "AH00037", "message": "Symbolic link not allowed"},
+		},
+		{
+			pattern: "%{POSTGRESQL}",
+			line:    `2024-05-01 12:00:00.123 UTC [4321] ERROR:  relation "users" does not exist`,
+			want:    map[string]interface{}{"timestamp": "2024-05-01 12:00:00.123 UTC", "pid": int64(4321), "level": "ERROR", "message": `relation "users" does not exist`},
+		},
+		{
+			pattern: "%{LOGLEVEL:level} %{JSONOBJECT:payload:json}",
+			line:    `INFO {"user":"bob","attempt":2}`,
+			want:    map[string]interface{}{"level": "INFO", "payload": map[string]interface{}{"user": "bob", "attempt": float64(2)}},
+		},
+	}
+	for _, tt := range tests {
+		p, err := NewGrokParser("^" + tt.pattern + "$")
+		if err != nil {
+			t.Fatalf("%s: NewGrokParser failed: %v", tt.pattern, err)
+		}
+		fields, ok := p.Parse(tt.line)
+		if !ok {
+			t.Errorf("%s: expected %q to match", tt.pattern, tt.line)
+			continue
+		}
+		for k, v := range tt.want {
+			if !reflect.DeepEqual(fields[k], v) {
+				t.Errorf("%s: expected %s=%v, got %v", tt.pattern, k, v, fields[k])
+			}
+		}
+	}
+}
+
+func TestDissectParser(t *testing.T) {
+	p, err := NewDissectParser(`%{date} %{+date} %{level->} %{?thread} %{message}`)
+	if err != nil {
+		t.Fatalf("NewDissectParser failed: %v", err)
+	}
+	fields, ok := p.Parse("2024-05-01 12:00:00 INFO   [main] server started on :8080")
+	want := map[string]interface{}{"date": "2024-05-01 12:00:00", "level": "INFO", "message": "server started on :8080"}
+	if !ok || !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v (match=%v)", want, fields, ok)
+	}
+	if _, ok := p.Parse("server started"); ok {
+		t.Error("expected a line without the delimiters not to match")
+	}
+
+	for _, pattern := range []string{"no keys", "%{a}%{b}", "%{} %{?skipped}"} {
+		if _, err := NewDissectParser(pattern); err == nil {
+			t.Errorf("expected error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestJSONParser(t *testing.T) {
+	fields, ok := JSONParser{}.Parse(`2024-05-01T12:00:00Z payment {"amount":12,"currency":"EUR"} trailing`)
+	want := map[string]interface{}{"message": "2024-05-01T12:00:00Z payment", "amount": float64(12), "currency": "EUR"}
+	if !ok || !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %v, got %v (match=%v)", want, fields, ok)
+	}
+	for _, line := range []string{"no json here", `broken {"a":}`, `["array"]`} {
+		if _, ok := (JSONParser{}).Parse(line); ok {
+			t.Errorf("expected %q not to match", line)
+		}
+	}
+}
+
+func TestChain_Parse(t *testing.T) {
+	specs, err := ParseSpecs(`[
+		{"type":"grok","pattern":"^%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} \\[%{WORD:source}\\] %{GREEDYDATA:message}$"},
+		{"type":"regex","pattern":"^(?P<level>[A-Z]+): (?P<message>.*)$"},
+		{"type":"logfmt"}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseSpecs failed: %v", err)
+	}
+	chain, err := NewChain(specs)
+	if err != nil {
+		t.Fatalf("NewChain failed: %v", err)
+	}
+
+	event := chain.Parse("2024-05-01T12:00:00Z ERROR [billing] charge failed")
+	if event.Level != "error" || event.Source != "billing" || event.Message != "charge failed" {
+		t.Errorf("unexpected grok event: %+v", event)
+	}
+	if !event.EventTime.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected event time %v", event.EventTime)
+	}
+
+	event = chain.Parse("WARN: disk almost full")
+	if event.Level != "warn" || event.Message != "disk almost full" || len(event.Metadata) != 0 {
+		t.Errorf("unexpected regex event: %+v", event)
+	}
+
+	event = chain.Parse(`ts=1714564800 level=info msg=started port=8080`)
+	var md map[string]interface{}
+	json.Unmarshal(event.Metadata, &md)
+	if event.Message != "started" || event.EventTime.Unix() != 1714564800 || md["port"] != "8080" {
+		t.Errorf("unexpected logfmt event: %+v (metadata %s)", event, event.Metadata)
+	}
+
+	chain, err = NewChain([]Spec{{Type: "grok", Pattern: "%{POSTGRESQL}"}})
+	if err != nil {
+		t.Fatalf("NewChain failed: %v", err)
+	}
+	event = chain.Parse(`2024-05-01 12:00:00.123 UTC [4321] LOG:  checkpoint starting: time`)
+	if event.Level != "log" || event.Message != "checkpoint starting: time" || !event.EventTime.Equal(time.Date(2024, 5, 1, 12, 0, 0, 123e6, time.UTC)) {
+		t.Errorf("unexpected postgres event: %+v", event)
+	}
+
+	event = chain.Parse("just some text")
+	if event.Message != "just some text" || event.Level != "" || len(event.Metadata) != 0 {
+		t.Errorf("expected unmatched line to be kept as message, got %+v", event)
+	}
+}
+
+func TestNewChain_Errors(t *testing.T) {
+	tests := []Spec{
+		{Type: "regex", Pattern: "("},
+		{Type: "regex", Pattern: "no groups"},
+		{Type: "csv"},
+	}
+	for _, spec := range tests {
+		if _, err := NewChain([]Spec{spec}); err == nil {
+			t.Errorf("expected error for spec %+v", spec)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package textparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// RegexParser extracts the named groups of a regular expression. Unnamed groups are ignored.
+type RegexParser struct {
+	re    *regexp.Regexp
+	types map[string]string // Group name to "int", "float" or "json" conversion, used by grok.
+}
+
+// NewRegexParser compiles a regular expression with at least one named group.
+func NewRegexParser(pattern string) (*RegexParser, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	hasNamed := false
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			hasNamed = true
+			break
+		}
+	}
+	if !hasNamed {
+		return nil, errors.New("regex must contain at least one named group")
+	}
+	return &RegexParser{re: re}, nil
+}
+
+// Parse implements Parser. Groups that did not participate in the match are omitted.
+func (p *RegexParser) Parse(line string) (map[string]interface{}, bool) {
+	idx := p.re.FindStringSubmatchIndex(line)
+	if idx == nil {
+		return nil, false
+	}
+	fields := make(map[string]interface{})
+	for i, name := range p.re.SubexpNames() {
+		if name == "" || idx[2*i] < 0 {
+			continue
+		}
+		if _, seen := fields[name]; seen {
+			continue
+		}
+		value := line[idx[2*i]:idx[2*i+1]]
+		fields[name] = convert(value, p.types[name])
+	}
+	return fields, true
+}
+
+func convert(value, typ string) interface{} {
+	switch typ {
+	case "int":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "json":
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
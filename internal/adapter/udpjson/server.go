@@ -0,0 +1,153 @@
+package udpjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/usecase"
+)
+
+const (
+	listenerName       = "udp_json"
+	defaultWorkers     = 4
+	defaultQueueSize   = 1024
+	defaultMaxDatagram = 64 * 1024
+)
+
+// ServerConfig holds the listener settings for the Server.
+type ServerConfig struct {
+	Addr        string
+	Workers     int // Goroutines parsing and buffering datagrams.
+	QueueSize   int // Datagrams waiting for a worker; further datagrams are dropped.
+	MaxDatagram int // Larger datagrams are truncated by the kernel and dropped.
+}
+
+// Server accepts one JSON LogEvent per UDP datagram, statsd style. Senders never wait on
+// ingestion: datagrams are queued for a bounded pool of workers and dropped, with a
+// counter in IngestMetrics, when the queue is full.
+type Server struct {
+	cfg     ServerConfig
+	useCase usecase.IngestLogUseCase
+	logger  *slog.Logger
+	metrics *metrics.IngestMetrics
+
+	queue chan []byte
+	wg    sync.WaitGroup
+}
+
+// NewServer creates a new UDP JSON Server.
+func NewServer(cfg ServerConfig, uc usecase.IngestLogUseCase, logger *slog.Logger, m *metrics.IngestMetrics) *Server {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.MaxDatagram <= 0 {
+		cfg.MaxDatagram = defaultMaxDatagram
+	}
+	return &Server{
+		cfg:     cfg,
+		useCase: uc,
+		logger:  logger.With("component", "udp_json_server"),
+		metrics: m,
+		queue:   make(chan []byte, cfg.QueueSize),
+	}
+}
+
+// Start binds the listener and serves it until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on UDP %s: %w", s.cfg.Addr, err)
+	}
+	s.logger.Info("starting UDP JSON listener", "addr", conn.LocalAddr().String(), "workers", s.cfg.Workers)
+	s.run(ctx, conn)
+	return nil
+}
+
+func (s *Server) run(ctx context.Context, conn net.PacketConn) {
+	var workers sync.WaitGroup
+	for i := 0; i < s.cfg.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for data := range s.queue {
+				s.handleDatagram(ctx, data)
+			}
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serve(ctx, conn)
+		// Let the workers drain what was already accepted.
+		close(s.queue)
+		workers.Wait()
+	}()
+}
+
+// Wait blocks until the listener and all workers have stopped.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) serve(ctx context.Context, conn net.PacketConn) {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	// One extra byte detects datagrams that were truncated to fit the buffer.
+	buf := make([]byte, s.cfg.MaxDatagram+1)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Warn("UDP read failed", "error", err)
+			continue
+		}
+		if n > s.cfg.MaxDatagram {
+			s.metrics.DroppedTotal.WithLabelValues(listenerName, "too_large").Inc()
+			continue
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		select {
+		case s.queue <- data:
+		default:
+			s.metrics.DroppedTotal.WithLabelValues(listenerName, "queue_full").Inc()
+		}
+	}
+}
+
+func (s *Server) handleDatagram(ctx context.Context, data []byte) {
+	s.metrics.BytesTotal.Add(float64(len(data)))
+
+	var event domain.LogEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.metrics.EventsTotal.WithLabelValues("error_parse").Inc()
+		return
+	}
+	event.RawEvent = data
+
+	// The listener context is cancelled on shutdown, but queued events should still be
+	// buffered while the workers drain.
+	if err := s.useCase.Ingest(context.WithoutCancel(ctx), &event); err != nil {
+		s.logger.Error("failed to ingest UDP event", "error", err)
+		s.metrics.EventsTotal.WithLabelValues("error_buffer").Inc()
+		return
+	}
+	s.metrics.EventsTotal.WithLabelValues("accepted").Inc()
+}
@@ -0,0 +1,81 @@
+package udpjson
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/metrics"
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type blockingIngestUseCase struct {
+	mu      sync.Mutex
+	events  []domain.LogEvent
+	release chan struct{}
+}
+
+func (b *blockingIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, *event)
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+
+	uc := &blockingIngestUseCase{release: make(chan struct{})}
+	m := metrics.NewIngestMetrics(prometheus.NewRegistry())
+	s := NewServer(ServerConfig{Workers: 1, QueueSize: 2, MaxDatagram: 64}, uc, logger, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.run(ctx, conn)
+
+	queueFull := m.DroppedTotal.WithLabelValues(listenerName, "queue_full")
+	tooLarge := m.DroppedTotal.WithLabelValues(listenerName, "too_large")
+	droppedBefore, largeBefore := testutil.ToFloat64(queueFull), testutil.ToFloat64(tooLarge)
+
+	// The single worker blocks on the first event, two more fill the queue and the rest are dropped.
+	for i := 0; i < 5; i++ {
+		client.Write([]byte(`{"message": "hello", "source": "udp-test"}`))
+		time.Sleep(20 * time.Millisecond)
+	}
+	client.Write(make([]byte, 100))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(queueFull)-droppedBefore < 2 || testutil.ToFloat64(tooLarge)-largeBefore < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 queue_full and 1 too_large drops, got %v and %v",
+				testutil.ToFloat64(queueFull)-droppedBefore, testutil.ToFloat64(tooLarge)-largeBefore)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(uc.release)
+	cancel()
+	s.Wait()
+
+	if len(uc.events) != 3 {
+		t.Fatalf("expected 3 ingested events, got %d", len(uc.events))
+	}
+	if uc.events[0].Message != "hello" || uc.events[0].Source != "udp-test" || string(uc.events[0].RawEvent) == "" {
+		t.Errorf("unexpected event: %+v", uc.events[0])
+	}
+}
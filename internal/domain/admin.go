@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ConsumerGroupInfo represents information about a Redis Stream consumer group.
 type ConsumerGroupInfo struct {
@@ -33,3 +36,101 @@ type PendingMessageDetail struct {
 	RetryCount int64         `json:"retry_count"`
 }
 
+// WALSegmentInfo describes a single Write-Ahead Log segment file.
+type WALSegmentInfo struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// WALStatus summarizes the contents of a Write-Ahead Log.
+type WALStatus struct {
+	Segments       []WALSegmentInfo `json:"segments"`
+	TotalSizeBytes int64            `json:"total_size_bytes"`
+	Quarantined    []WALSegmentInfo `json:"quarantined,omitempty"`
+	CursorSegment  string           `json:"cursor_segment,omitempty"`
+	CursorOffset   int64            `json:"cursor_offset,omitempty"`
+	// OldestEventTime is when the oldest unreplayed event was received; zero if the WAL is empty.
+	OldestEventTime       time.Time `json:"oldest_event_time,omitempty"`
+	OldestEventAgeSeconds float64   `json:"oldest_event_age_seconds,omitempty"`
+}
+
+// DLQEntry is an event in the dead-letter queue along with where it came from.
+type DLQEntry struct {
+	ID              string          `json:"id"`
+	OriginalEventID string          `json:"original_event_id,omitempty"`
+	OriginalStream  string          `json:"original_stream,omitempty"`
+	Cause           string          `json:"cause,omitempty"`
+	Reason          string          `json:"reason,omitempty"`
+	Attempts        int             `json:"attempts,omitempty"`
+	Consumer        string          `json:"consumer,omitempty"`
+	FailedAt        time.Time       `json:"failed_at,omitempty"`
+	Payload         json.RawMessage `json:"payload"`
+}
+
+// DLQPage is one page of dead-letter queue entries, oldest first.
+type DLQPage struct {
+	Entries []DLQEntry `json:"entries"`
+	// NextAfter is passed as after to fetch the next page; empty on the last page.
+	NextAfter string `json:"next_after,omitempty"`
+}
+
+// DrainStatus reports the progress of draining the ingest service before it is stopped.
+type DrainStatus struct {
+	Draining bool  `json:"draining"`
+	InFlight int64 `json:"in_flight"` // Events being accepted right now.
+	Flushed  bool  `json:"flushed"`   // Events held in process have been buffered.
+	// WALEmpty is whether the WAL has been replayed into the buffer completely.
+	WALEmpty   bool   `json:"wal_empty"`
+	SafeToStop bool   `json:"safe_to_stop"`
+	Error      string `json:"error,omitempty"` // Last error of the drain, which keeps retrying.
+}
+
+// DLQAlertTest is the result of evaluating the DLQ alert on demand, without changing
+// whether it is firing.
+type DLQAlertTest struct {
+	Stream       string `json:"stream"`
+	Depth        int64  `json:"depth"`
+	Growth       int64  `json:"growth"` // Since the monitor's last check.
+	WouldFire    bool   `json:"would_fire"`
+	Reason       string `json:"reason,omitempty"`
+	Firing       bool   `json:"firing"` // Whether the monitor has notified the alert and not yet its resolution.
+	Acknowledged bool   `json:"acknowledged"`
+	Escalated    bool   `json:"escalated"`
+	Notified     bool   `json:"notified"`
+	NotifyError  string `json:"notify_error,omitempty"`
+}
+
+// DLQAlertStatus reports the state of the DLQ alert and of its last evaluation.
+type DLQAlertStatus struct {
+	Stream            string    `json:"stream"`
+	Firing            bool      `json:"firing"`
+	Acknowledged      bool      `json:"acknowledged"`
+	Escalated         bool      `json:"escalated"`
+	LastCheck         time.Time `json:"last_check,omitzero"`
+	LastError         string    `json:"last_error,omitempty"` // Of the last check, empty if it succeeded.
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+}
+
+// APIKey describes an API key. The key itself is only stored as its digest.
+type APIKey struct {
+	KeyHash     string     `json:"key_hash"`
+	Description string     `json:"description"`
+	IsActive    bool       `json:"is_active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // Nil never expires.
+}
+
+// NewAPIKey is an API key that was just created, with the key itself, which cannot be
+// retrieved again.
+type NewAPIKey struct {
+	Key string `json:"key"`
+	APIKey
+}
+
+// GroupPause reports whether the consumers of a group are paused.
+type GroupPause struct {
+	Group    string    `json:"group"`
+	Paused   bool      `json:"paused"`
+	PausedAt time.Time `json:"paused_at,omitempty"`
+}
@@ -16,6 +16,8 @@ type LogEvent struct {
 	Metadata        json.RawMessage `json:"metadata,omitempty"`
 	RawEvent        json.RawMessage `json:"-"` // The original raw event payload, not for final serialization.
 	PIIRedacted     bool            `json:"pii_redacted,omitempty"`
+	APIKeyHash      string          `json:"api_key_hash,omitempty"`
 	StreamMessageID string          `json:"-"` // Transient field for Redis Stream message ID, not serialized.
+	DeliveryCount   int64           `json:"-"` // Times the buffer has delivered the event, set for reclaimed events.
 }
 
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrFieldMappingNotFound is returned when an API key has no field mapping.
+var ErrFieldMappingNotFound = errors.New("field mapping not found")
+
+// ErrInvalidFieldMapping is wrapped by errors for field mappings with unknown targets.
+var ErrInvalidFieldMapping = errors.New("invalid field mapping")
+
+// FieldMapping maps the keys of an API key's payloads onto the fields of LogEvent, for
+// senders whose events say e.g. msg, lvl, ts and svc rather than message, level,
+// event_time and source.
+type FieldMapping struct {
+	KeyHash string `json:"key_hash"` // See HashAPIKey.
+	// Fields maps a payload key, a dotted path for nested objects, to its target: message,
+	// level, event_time, source or event_id, or metadata.<name> to rename it in the
+	// metadata, e.g. {"msg":"message","ts":"event_time","ctx.user":"metadata.user"}.
+	Fields    map[string]string `json:"fields"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// FieldMappingRepository stores the field mappings of API keys.
+type FieldMappingRepository interface {
+	ListFieldMappings(ctx context.Context) ([]FieldMapping, error)
+	GetFieldMapping(ctx context.Context, keyHash string) (*FieldMapping, error)
+	// PutFieldMapping creates or replaces the mapping of a key.
+	PutFieldMapping(ctx context.Context, mapping FieldMapping) (*FieldMapping, error)
+	DeleteFieldMapping(ctx context.Context, keyHash string) error
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/V4T54L/watch-tower/internal/domain"
 )
@@ -14,7 +15,9 @@ type MockLogRepository struct {
 	WrittenEvents   []domain.LogEvent
 	AckedMessageIDs []string
 	DLQEvents       []domain.LogEvent
+	DLQFailures     []domain.DLQFailure
 	ReadBatchResult []domain.LogEvent
+	ClaimResult     []domain.LogEvent
 	BufferErr       error
 	ReadErr         error
 	WriteErr        error
@@ -41,6 +44,19 @@ func (m *MockLogRepository) ReadLogBatch(ctx context.Context, group, consumer st
 	return m.ReadBatchResult, nil
 }
 
+// ClaimStaleLogs returns ClaimResult once, as if the claimed messages now belonged to the
+// caller.
+func (m *MockLogRepository) ClaimStaleLogs(ctx context.Context, group, consumer string, minIdle time.Duration, count int) ([]domain.LogEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ReadErr != nil {
+		return nil, m.ReadErr
+	}
+	claimed := m.ClaimResult
+	m.ClaimResult = nil
+	return claimed, nil
+}
+
 func (m *MockLogRepository) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -61,12 +77,67 @@ func (m *MockLogRepository) AcknowledgeLogs(ctx context.Context, group string, m
 	return nil
 }
 
-func (m *MockLogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent) error {
+func (m *MockLogRepository) MoveToDLQ(ctx context.Context, events []domain.LogEvent, failure domain.DLQFailure) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.DLQErr != nil {
 		return m.DLQErr
 	}
 	m.DLQEvents = append(m.DLQEvents, events...)
+	m.DLQFailures = append(m.DLQFailures, failure)
+	return nil
+}
+
+// MockWALRepository is an in-memory implementation of domain.WALRepository for testing.
+// Replay removes events as the handler accepts them, like the file-based WAL.
+type MockWALRepository struct {
+	mu       sync.Mutex
+	Events   []domain.LogEvent
+	WriteErr error
+}
+
+func (m *MockWALRepository) Write(ctx context.Context, event domain.LogEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.WriteErr != nil {
+		return m.WriteErr
+	}
+	m.Events = append(m.Events, event)
+	return nil
+}
+
+func (m *MockWALRepository) Replay(ctx context.Context, handler func(event domain.LogEvent) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.Events) > 0 {
+		if err := handler(m.Events[0]); err != nil {
+			return err
+		}
+		m.Events = m.Events[1:]
+	}
+	return nil
+}
+
+func (m *MockWALRepository) ReplayBatch(ctx context.Context, batchSize int, handler func(events []domain.LogEvent) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.Events) > 0 {
+		n := min(max(batchSize, 1), len(m.Events))
+		if err := handler(m.Events[:n]); err != nil {
+			return err
+		}
+		m.Events = m.Events[n:]
+	}
+	return nil
+}
+
+func (m *MockWALRepository) Truncate(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Events = nil
+	return nil
+}
+
+func (m *MockWALRepository) Close() error {
 	return nil
 }
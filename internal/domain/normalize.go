@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+// Canonical event levels, from the least to the most severe. Syslog severities, OTLP
+// severity numbers and normalized levels (see normalize.Level) are mapped onto these.
+const (
+	LevelTrace = "trace"
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// ErrEventTimeOutOfRange is returned for events whose time is further from when they were
+// received than the configured skew allows, when such events are rejected.
+var ErrEventTimeOutOfRange = errors.New("event time is out of the accepted range")
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Notification severities.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	SeverityResolved = "resolved"
+)
+
+// Notification is a message raised by watch-tower itself for operators.
+type Notification struct {
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	Severity  string            `json:"severity"`
+	Link      string            `json:"link,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Notifier defines the interface for delivering notifications to an external channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPIIPolicyNotFound is returned when an API key has no PII policy of its own.
+var ErrPIIPolicyNotFound = errors.New("PII policy not found")
+
+// ErrInvalidPIIPolicy is wrapped by errors for PII policies whose rules do not compile.
+var ErrInvalidPIIPolicy = errors.New("invalid PII policy")
+
+// PIIRule is a PII redaction rule; see pii.Rule for how rules match.
+type PIIRule struct {
+	Name     string   `json:"name" yaml:"name"`
+	Fields   []string `json:"fields,omitempty" yaml:"fields"`
+	Pattern  string   `json:"pattern,omitempty" yaml:"pattern"`   // A regular expression, see regexp/syntax.
+	Detector string   `json:"detector,omitempty" yaml:"detector"` // A built-in detector, instead of a pattern.
+	Action   string   `json:"action,omitempty" yaml:"action"`
+}
+
+// PIIPolicy is the PII redaction of the events of one API key, replacing the global
+// rules for them.
+type PIIPolicy struct {
+	KeyHash   string    `json:"key_hash"` // See HashAPIKey.
+	Rules     []PIIRule `json:"rules"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PIIPolicyRepository stores the PII policies of API keys.
+type PIIPolicyRepository interface {
+	ListPIIPolicies(ctx context.Context) ([]PIIPolicy, error)
+	GetPIIPolicy(ctx context.Context, keyHash string) (*PIIPolicy, error)
+	// PutPIIPolicy creates or replaces the policy of a key.
+	PutPIIPolicy(ctx context.Context, policy PIIPolicy) (*PIIPolicy, error)
+	DeletePIIPolicy(ctx context.Context, keyHash string) error
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"sync"
+)
+
+// What the ingest pipeline decided to do with an event.
+const (
+	DecisionKept    = "kept"
+	DecisionSampled = "sampled" // Left out by a sampling rule, which keeps a share of the events like it.
+	DecisionDropped = "dropped" // Left out by a drop rule.
+)
+
+// PipelineDecision is the decision taken on an event, and the rule that took it.
+type PipelineDecision struct {
+	Action string // One of the Decision constants.
+	Rule   string // Name of the rule that sampled or dropped the event; empty for kept events.
+}
+
+// DecisionRecorder tallies the decisions taken on the events of one request, so that the
+// response can echo them.
+type DecisionRecorder struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Record counts a decision.
+func (r *DecisionRecorder) Record(action string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = make(map[string]int)
+	}
+	r.counts[action]++
+}
+
+// Counts returns the number of events of each decision.
+func (r *DecisionRecorder) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int, len(r.counts))
+	for action, n := range r.counts {
+		counts[action] = n
+	}
+	return counts
+}
+
+type decisionRecorderContextKey struct{}
+
+// WithDecisionRecorder returns a copy of ctx carrying the recorder of a request's decisions.
+func WithDecisionRecorder(ctx context.Context, r *DecisionRecorder) context.Context {
+	return context.WithValue(ctx, decisionRecorderContextKey{}, r)
+}
+
+// DecisionRecorderFromContext returns the recorder set by WithDecisionRecorder, or nil.
+func DecisionRecorderFromContext(ctx context.Context) *DecisionRecorder {
+	r, _ := ctx.Value(decisionRecorderContextKey{}).(*DecisionRecorder)
+	return r
+}
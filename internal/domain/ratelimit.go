@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitResult describes the outcome of a single rate limit check.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	ResetAfter time.Duration // Time until the bucket is full again.
+	RetryAfter time.Duration // Time until the request would be allowed; zero if allowed.
+}
+
+// RateLimiter defines the interface for a token bucket rate limiter shared across replicas.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, ratePerSecond float64, burst int64, cost int64) (*RateLimitResult, error)
+}
@@ -2,31 +2,105 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrBufferFull is returned by LogRepository.BufferLog when neither the buffer nor its
+// fallback can accept more events. Callers should ask clients to retry later.
+var ErrBufferFull = errors.New("log buffer is full")
+
+// ErrEventRejected is wrapped by a sink's WriteLogBatch when the batch holds an event the
+// sink can never store, such as invalid data, as opposed to the sink being unavailable.
+var ErrEventRejected = errors.New("sink rejected event")
+
 // LogRepository defines the interface for log event persistence and buffering.
 type LogRepository interface {
 	BufferLog(ctx context.Context, event LogEvent) error
 	ReadLogBatch(ctx context.Context, group, consumer string, count int) ([]LogEvent, error)
 	WriteLogBatch(ctx context.Context, events []LogEvent) error
 	AcknowledgeLogs(ctx context.Context, group string, messageIDs ...string) error
-	MoveToDLQ(ctx context.Context, events []LogEvent) error
+	MoveToDLQ(ctx context.Context, events []LogEvent, failure DLQFailure) error
+}
+
+// DLQFailure records why a batch was moved to the dead-letter queue, kept with each of
+// its entries for triage.
+type DLQFailure struct {
+	Cause    string // One of the DLQCause constants.
+	Reason   string
+	Attempts int    // Sink writes, or deliveries for events that were claimed too often.
+	Consumer string // The consumer that gave up on the batch.
+	FailedAt time.Time
 }
 
+// Causes of a DLQFailure.
+const (
+	DLQCauseSinkFailure   = "sink_failure"   // The sink kept failing the whole batch.
+	DLQCauseRejected      = "rejected"       // The sink rejected the event itself.
+	DLQCauseMaxDeliveries = "max_deliveries" // The event was delivered too often without being acknowledged.
+)
+
 // APIKeyRepository defines the interface for validating API keys.
 type APIKeyRepository interface {
 	IsValid(ctx context.Context, key string) (bool, error)
 }
 
+// ErrAPIKeyNotFound is returned when an API key does not exist.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyAdminRepository defines the interface for managing API keys, identified by their
+// digest (see HashAPIKey).
+type APIKeyAdminRepository interface {
+	CreateAPIKey(ctx context.Context, key APIKey) (*APIKey, error)
+	GetAPIKey(ctx context.Context, keyHash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	// UpdateAPIKey changes the description and expiry of a key; nil leaves them as they are.
+	UpdateAPIKey(ctx context.Context, keyHash string, description *string, expiresAt *time.Time) (*APIKey, error)
+	DeactivateAPIKey(ctx context.Context, keyHash string) error
+}
+
 // WALRepository defines the interface for a Write-Ahead Log.
 type WALRepository interface {
 	Write(ctx context.Context, event LogEvent) error
 	Replay(ctx context.Context, handler func(event LogEvent) error) error
+	// ReplayBatch is Replay for handlers that work on up to batchSize events at a time. A
+	// batch the handler fails is replayed again in full.
+	ReplayBatch(ctx context.Context, batchSize int, handler func(events []LogEvent) error) error
 	Truncate(ctx context.Context) error
 	Close() error
 }
 
+// WALInspector defines read-only access to a Write-Ahead Log for operators.
+type WALInspector interface {
+	Status(ctx context.Context) (*WALStatus, error)
+	// Peek returns up to count of the oldest unreplayed events without removing them.
+	Peek(ctx context.Context, count int) ([]LogEvent, error)
+}
+
+// ErrDLQEntryNotFound is returned when a dead-letter queue entry does not exist.
+var ErrDLQEntryNotFound = errors.New("dlq entry not found")
+
+// DLQRepository defines browsing and re-driving of the dead-letter queue.
+type DLQRepository interface {
+	// ListDLQ returns up to count entries with IDs after the given one, or from the start
+	// if after is empty.
+	ListDLQ(ctx context.Context, after string, count int64) ([]DLQEntry, error)
+	GetDLQEntry(ctx context.Context, id string) (*DLQEntry, error)
+	// RedriveDLQ buffers the events of the given entries again and removes the entries.
+	RedriveDLQ(ctx context.Context, ids ...string) (int64, error)
+	// RedriveAllDLQ re-drives every entry present when it is called.
+	RedriveAllDLQ(ctx context.Context) (int64, error)
+	PurgeDLQ(ctx context.Context, ids ...string) (int64, error)
+	PurgeAllDLQ(ctx context.Context) (int64, error)
+}
+
+// ConsumerControlRepository defines the shared switch operators use to pause the
+// consumers of a group.
+type ConsumerControlRepository interface {
+	SetGroupPaused(ctx context.Context, group string, paused bool) error
+	GetGroupPause(ctx context.Context, group string) (*GroupPause, error)
+}
+
 // StreamAdminRepository defines the interface for administrative operations on a stream.
 type StreamAdminRepository interface {
 	GetGroupInfo(ctx context.Context, stream string) ([]ConsumerGroupInfo, error)
@@ -36,4 +110,5 @@ type StreamAdminRepository interface {
 	ClaimMessages(ctx context.Context, stream, group, consumer string, minIdleTime time.Duration, messageIDs []string) ([]LogEvent, error)
 	AcknowledgeMessages(ctx context.Context, stream, group string, messageIDs ...string) (int64, error)
 	TrimStream(ctx context.Context, stream string, maxLen int64) (int64, error)
+	GetStreamLength(ctx context.Context, stream string) (int64, error)
 }
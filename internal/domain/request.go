@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestInfo describes the HTTP request events were received in, for the enrichers
+// that add the client's location, user agent or Kubernetes workload to them.
+type RequestInfo struct {
+	ClientIP  string      // The peer address; proxies' headers are trusted by the enrichers only if configured to.
+	UserAgent string      // Of the shipper, or of the browser for events sent by one.
+	Header    http.Header // Every request header.
+}
+
+type requestInfoContextKey struct{}
+
+// WithRequestInfo returns a copy of ctx carrying the request its events were received in.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey{}, info)
+}
+
+// RequestInfoFromContext returns the request set by WithRequestInfo, if any.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoContextKey{}).(RequestInfo)
+	return info, ok
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrQuotaExceeded is returned for events of an API key that has used up its hard daily
+// quota. Callers should ask clients to retry the next day.
+var ErrQuotaExceeded = errors.New("daily ingestion quota exceeded")
+
+// Usage is what an API key ingested on one day.
+type Usage struct {
+	KeyHash string `json:"key_hash"` // See HashAPIKey.
+	Day     string `json:"day"`      // UTC, formatted as time.DateOnly.
+	Events  int64  `json:"events"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// Quota levels reported for an API key.
+const (
+	QuotaOK   = ""     // Below the soft quota.
+	QuotaSoft = "soft" // At or above the soft quota; events are still accepted.
+	QuotaHard = "hard" // At or above the hard quota; events are rejected.
+)
+
+// UsageRepository stores the daily usage of API keys.
+type UsageRepository interface {
+	// AddUsage adds usage to the stored totals and returns the new totals of the same
+	// keys and days.
+	AddUsage(ctx context.Context, usage []Usage) ([]Usage, error)
+	// ListUsage returns the usage between the days from and to, inclusive, of one key or,
+	// if keyHash is empty, of every key.
+	ListUsage(ctx context.Context, from, to, keyHash string) ([]Usage, error)
+}
+
+// HashAPIKey returns the digest API keys are stored and identified by: the hex-encoded
+// SHA-256 of the key. API keys are random, so a plain, unsalted digest cannot be
+// reversed and can be looked up by index.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type apiKeyHashContextKey struct{}
+
+// WithAPIKeyHash returns a copy of ctx carrying the digest of the API key a request was
+// authenticated with.
+func WithAPIKeyHash(ctx context.Context, keyHash string) context.Context {
+	return context.WithValue(ctx, apiKeyHashContextKey{}, keyHash)
+}
+
+// APIKeyHashFromContext returns the digest set by WithAPIKeyHash, or "" if there is none.
+func APIKeyHashFromContext(ctx context.Context) string {
+	keyHash, _ := ctx.Value(apiKeyHashContextKey{}).(string)
+	return keyHash
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"log/slog"
 	"time"
 
 	"github.com/caarlos0/env/v10"
@@ -10,19 +11,157 @@ import (
 // Config holds all application configuration parameters.
 type Config struct {
 	LogLevel             string        `env:"LOG_LEVEL" envDefault:"info"`
-	MaxEventSize         int64         `env:"MAX_EVENT_SIZE" envDefault:"1048576"`    // 1MB
-	WALPath              string        `env:"WAL_PATH" envDefault:"./wal"`             // Path for Write-Ahead Log files
-	WALSegmentSize       int64         `env:"WAL_SEGMENT_SIZE" envDefault:"104857600"` // 100MB
-	WALMaxDiskSize       int64         `env:"WAL_MAX_DISK_SIZE" envDefault:"1073741824"` // 1GB
-	BackpressurePolicy   string        `env:"BACKPRESSURE_POLICY" envDefault:"block"`
+	SelfLogEnabled       bool          `env:"SELF_LOG_ENABLED" envDefault:"false"` // Ship the service's own logs into its pipeline
+	SelfLogLevel         slog.Level    `env:"SELF_LOG_LEVEL" envDefault:"info"`
+	SelfLogSampleRate    float64       `env:"SELF_LOG_SAMPLE_RATE" envDefault:"1"` // Fraction of records below warn shipped
+	SelfLogQueueSize     int           `env:"SELF_LOG_QUEUE_SIZE" envDefault:"1000"`
+	MaxEventSize         int64         `env:"MAX_EVENT_SIZE" envDefault:"1048576"`         // 1MB
+	MaxDecompressedSize  int64         `env:"MAX_DECOMPRESSED_SIZE" envDefault:"10485760"` // 10MB after Content-Encoding is removed
+	MaxBatchEvents       int           `env:"MAX_BATCH_EVENTS" envDefault:"1000"`          // Max events in a JSON array payload, 0 disables
+	MaxStreamSize        int64         `env:"MAX_STREAM_SIZE" envDefault:"1073741824"`     // Streamed NDJSON uploads (Accept: application/x-ndjson)
+	MaxBulkSize          int64         `env:"MAX_BULK_SIZE" envDefault:"104857600"`        // 100MB Elasticsearch _bulk bodies, documents are capped at MAX_EVENT_SIZE
+	WALPath              string        `env:"WAL_PATH" envDefault:"./wal"`                 // Path for Write-Ahead Log files
+	WALSegmentSize       int64         `env:"WAL_SEGMENT_SIZE" envDefault:"104857600"`     // 100MB
+	WALMaxDiskSize       int64         `env:"WAL_MAX_DISK_SIZE" envDefault:"1073741824"`   // 1GB
+	WALSegmentMaxAge     time.Duration `env:"WAL_SEGMENT_MAX_AGE" envDefault:"5m"`         // Rotate segments by age as well as size, 0 disables
+	WALRetention         time.Duration `env:"WAL_RETENTION" envDefault:"0"`                // Delete segments older than this unreplayed, 0 keeps them
+	WALEncryptionKey     string        `env:"WAL_ENCRYPTION_KEY"`                          // Base64 AES-128/192/256 key, empty disables encryption
+	WALEncryptionKeyFile string        `env:"WAL_ENCRYPTION_KEY_FILE"`                     // Alternative to WAL_ENCRYPTION_KEY, e.g. a mounted secret
+	WALReplayRate        float64       `env:"WAL_REPLAY_RATE" envDefault:"0"`              // Events/sec replayed into the buffer, 0 is unlimited
+	WALReplayBytesRate   float64       `env:"WAL_REPLAY_BYTES_RATE" envDefault:"0"`        // Bytes/sec replayed into the buffer, 0 is unlimited
+	WALReplayConcurrency int           `env:"WAL_REPLAY_CONCURRENCY" envDefault:"1"`
+	BackpressurePolicy   string        `env:"BACKPRESSURE_POLICY" envDefault:"block"` // "block", "drop_oldest" or "reject"
+	BackpressureTimeout  time.Duration `env:"BACKPRESSURE_BLOCK_TIMEOUT" envDefault:"5s"`
+	BufferBackend        string        `env:"BUFFER_BACKEND" envDefault:"redis"`     // "redis", "kafka" or "memory"
+	MemoryBufferSize     int           `env:"MEMORY_BUFFER_SIZE" envDefault:"10000"` // Events the memory buffer holds before spilling to the WAL
 	RedisAddr            string        `env:"REDIS_ADDR,required"`
+	RedisUsername        string        `env:"REDIS_USERNAME"` // ACL user, overrides the one in REDIS_ADDR
+	RedisPassword        string        `env:"REDIS_PASSWORD"`
+	RedisTLSCAFile       string        `env:"REDIS_TLS_CA_FILE"` // Custom CA bundle; any TLS file enables TLS
+	RedisTLSCertFile     string        `env:"REDIS_TLS_CERT_FILE"`
+	RedisTLSKeyFile      string        `env:"REDIS_TLS_KEY_FILE"`
 	RedisDLQStream       string        `env:"REDIS_DLQ_STREAM" envDefault:"log_events_dlq"`
+	RedisStreamShards    int           `env:"REDIS_STREAM_SHARDS" envDefault:"1"`
+	RedisShardPolicy     string        `env:"REDIS_SHARD_POLICY" envDefault:"round_robin"` // "round_robin" or "source"
+	RedisStreamMaxLen    int64         `env:"REDIS_STREAM_MAX_LEN" envDefault:"0"`         // Approximate entries kept per shard, 0 is unbounded
+	RedisStreamMaxAge    time.Duration `env:"REDIS_STREAM_MAX_AGE" envDefault:"0"`         // Alternative to REDIS_STREAM_MAX_LEN, 0 is unbounded
+	RedisEvictionCheck   time.Duration `env:"REDIS_EVICTION_CHECK_INTERVAL" envDefault:"1m"`
+	RedisStreamCodec     string        `env:"REDIS_STREAM_CODEC" envDefault:"none"` // "none", "snappy" or "zstd"
+	RedisDedupWindow     time.Duration `env:"REDIS_DEDUP_WINDOW" envDefault:"0"`    // Drop events whose ID was buffered this recently, 0 disables
+	RedisXAddBatchSize   int           `env:"REDIS_XADD_BATCH_SIZE" envDefault:"0"` // XADDs pipelined per round-trip, below 2 disables batching
+	RedisXAddBatchDelay  time.Duration `env:"REDIS_XADD_BATCH_DELAY" envDefault:"2ms"`
+	KafkaBrokers         []string      `env:"KAFKA_BROKERS" envSeparator:","`
+	KafkaTopic           string        `env:"KAFKA_TOPIC" envDefault:"log_events"`
+	KafkaDLQTopic        string        `env:"KAFKA_DLQ_TOPIC" envDefault:"log_events_dlq"`
 	PostgresURL          string        `env:"POSTGRES_URL,required"`
+	SinkBackend          string        `env:"SINK_BACKEND" envDefault:"postgres"` // "postgres" or "elasticsearch", where the consumer writes events
+	ElasticsearchURL     string        `env:"ELASTICSEARCH_URL"`
+	ElasticsearchUser    string        `env:"ELASTICSEARCH_USERNAME"`
+	ElasticsearchPass    string        `env:"ELASTICSEARCH_PASSWORD"`
+	ElasticsearchAPIKey  string        `env:"ELASTICSEARCH_API_KEY"` // Used instead of username and password when set
+	ElasticsearchIndex   string        `env:"ELASTICSEARCH_INDEX_PREFIX" envDefault:"logs"`
+	ElasticsearchRetries int           `env:"ELASTICSEARCH_MAX_RETRIES" envDefault:"5"`
+	ElasticsearchBackoff time.Duration `env:"ELASTICSEARCH_RETRY_BACKOFF" envDefault:"500ms"`
 	APIKeyCacheTTL       time.Duration `env:"API_KEY_CACHE_TTL" envDefault:"5m"`
+	APIKeyCacheNegTTL    time.Duration `env:"API_KEY_CACHE_NEGATIVE_TTL" envDefault:"30s"`
+	APIKeyCacheSize      int           `env:"API_KEY_CACHE_SIZE" envDefault:"10000"`
+	APIKeyInvalidations  string        `env:"API_KEY_INVALIDATION_CHANNEL" envDefault:"api_key_invalidations"` // Redis pub/sub channel, empty disables
 	PIIRedactionFields   string        `env:"PII_REDACTION_FIELDS" envDefault:"email,password,credit_card,ssn"`
+	PIIDetectors         string        `env:"PII_DETECTORS" envDefault:"credit_card,ssn"` // Built-in detectors applied to the message and every value
+	PIIDropRawEvent      bool          `env:"PII_DROP_RAW_EVENT"`                         // Drops raw events instead of redacting them
+	PIIRulesFile         string        `env:"PII_RULES_FILE"`                             // YAML redaction rules applied after the fields, see pii.Rule
+	PIIHashKey           string        `env:"PII_HASH_KEY"`                               // HMAC key of the hash redaction action
+	PIIHashKeyFile       string        `env:"PII_HASH_KEY_FILE"`                          // Alternative to PII_HASH_KEY, e.g. a mounted secret
+	PIIPolicyReload      time.Duration `env:"PII_POLICY_RELOAD_INTERVAL" envDefault:"30s"`
+	FieldMappingReload   time.Duration `env:"FIELD_MAPPING_RELOAD_INTERVAL" envDefault:"30s"`
+	Enrichers            string        `env:"ENRICHERS"` // JSON array of GeoIP, user agent and Kubernetes enrichers, see enrich.Spec
+	EnrichOrder          string        `env:"ENRICH_ORDER" envDefault:"before_redaction"`
+	IngestPipelineFile   string        `env:"INGEST_PIPELINE_FILE"` // JSON processors applied to events before they are buffered, empty disables
+	IngestPipelinePoll   time.Duration `env:"INGEST_PIPELINE_RELOAD_INTERVAL" envDefault:"10s"`
+	EventTimeFields      string        `env:"EVENT_TIME_FIELDS" envDefault:"timestamp,@timestamp,time,ts"`
+	EventTimeMaxPast     time.Duration `env:"EVENT_TIME_MAX_PAST"` // 0 accepts events of any age
+	EventTimeMaxFuture   time.Duration `env:"EVENT_TIME_MAX_FUTURE" envDefault:"15m"`
+	EventTimeSkewPolicy  string        `env:"EVENT_TIME_SKEW_POLICY" envDefault:"clamp"` // "clamp" or "reject"
+	NormalizeLevels      bool          `env:"NORMALIZE_LEVELS" envDefault:"true"`
+	TextParsers          string        `env:"TEXT_PARSERS"`        // JSON array of text/plain line parsers, see textparser.Spec
+	MultilineRules       string        `env:"MULTILINE_RULES"`     // JSON array of per-source multiline rules, see usecase.ParseMultilineRules
+	SchemaRegistryURL    string        `env:"SCHEMA_REGISTRY_URL"` // Confluent-compatible registry for Avro writer schemas, empty disables
+	WebhookSources       string        `env:"WEBHOOK_SOURCES"`     // JSON array of /webhooks/{source} providers, see handler.ParseWebhookSources
 	IngestServerAddr     string        `env:"INGEST_SERVER_ADDR" envDefault:":8080"`
+	IngestTLSCertFile    string        `env:"INGEST_TLS_CERT_FILE"` // Serves HTTPS when set; the files are reloaded when they change
+	IngestTLSKeyFile     string        `env:"INGEST_TLS_KEY_FILE"`
+	IngestTLSClientCA    string        `env:"INGEST_TLS_CLIENT_CA_FILE"`    // CAs client certificates are verified against, empty asks for none
+	IngestTLSIdentities  string        `env:"INGEST_TLS_CLIENT_IDENTITIES"` // JSON array mapping client certificates to API keys, see middleware.ParseClientCertIdentities
+	IngestTLSReload      time.Duration `env:"INGEST_TLS_RELOAD_INTERVAL" envDefault:"30s"`
+	SyslogUDPAddr        string        `env:"SYSLOG_UDP_ADDR"` // e.g. ":5514", empty disables
+	SyslogTCPAddr        string        `env:"SYSLOG_TCP_ADDR"` // e.g. ":5514", empty disables
+	SyslogMaxMessageSize int           `env:"SYSLOG_MAX_MESSAGE_SIZE" envDefault:"65536"`
+	SyslogTCPIdleTimeout time.Duration `env:"SYSLOG_TCP_IDLE_TIMEOUT" envDefault:"5m"`
+	SyslogMaxTCPConns    int           `env:"SYSLOG_MAX_TCP_CONNS" envDefault:"1024"`
+	UDPJSONAddr          string        `env:"UDP_JSON_ADDR"` // e.g. ":8125", empty disables
+	UDPJSONWorkers       int           `env:"UDP_JSON_WORKERS" envDefault:"4"`
+	UDPJSONQueueSize     int           `env:"UDP_JSON_QUEUE_SIZE" envDefault:"1024"`
+	GRPCServerAddr       string        `env:"GRPC_SERVER_ADDR"` // e.g. ":9090", empty disables
+	SQSQueueURL          string        `env:"SQS_QUEUE_URL"`    // Empty disables the SQS worker
+	SQSMaxMessages       int32         `env:"SQS_MAX_MESSAGES" envDefault:"10"`
+	SQSWaitTime          time.Duration `env:"SQS_WAIT_TIME" envDefault:"20s"`
+	SQSVisibilityTimeout time.Duration `env:"SQS_VISIBILITY_TIMEOUT" envDefault:"60s"`
+	JournaldGatewayURL   string        `env:"JOURNALD_GATEWAY_URL"` // systemd-journal-gatewayd URL, empty disables
+	JournaldUnits        []string      `env:"JOURNALD_UNITS" envSeparator:","`
+	JournaldCursorFile   string        `env:"JOURNALD_CURSOR_FILE" envDefault:"journald.cursor"`
 	ConsumerRetryCount   int           `env:"CONSUMER_RETRY_COUNT" envDefault:"3"`
+	ConsumerWorkers      int           `env:"CONSUMER_WORKERS" envDefault:"1"` // Batches processed concurrently, each by its own Redis consumer
+	ConsumerBatchMin     int           `env:"CONSUMER_BATCH_MIN" envDefault:"100"`
+	ConsumerBatchMax     int           `env:"CONSUMER_BATCH_MAX" envDefault:"5000"`
+	ConsumerSinkLatency  time.Duration `env:"CONSUMER_SINK_LATENCY_TARGET" envDefault:"500ms"` // Shrink batches whose sink write takes longer, 0 keeps them at 1000
 	ConsumerRetryBackoff time.Duration `env:"CONSUMER_RETRY_BACKOFF" envDefault:"1s"`
+	ConsumerSpoolPath    string        `env:"CONSUMER_SPOOL_PATH"` // Disk spool for batches the sink rejects, empty sends them to the DLQ
+	ConsumerSpoolMaxSize int64         `env:"CONSUMER_SPOOL_MAX_SIZE" envDefault:"1073741824"`
+	ConsumerClaimMinIdle time.Duration `env:"CONSUMER_CLAIM_MIN_IDLE" envDefault:"5m"` // Reclaim messages pending this long, 0 disables
+	ConsumerClaimEvery   time.Duration `env:"CONSUMER_CLAIM_INTERVAL" envDefault:"30s"`
+	ConsumerMaxDelivery  int           `env:"CONSUMER_MAX_DELIVERIES" envDefault:"5"`   // Reclaimed messages delivered more often go to the DLQ, 0 disables
+	ConsumerMetricsAddr  string        `env:"CONSUMER_METRICS_ADDR" envDefault:":9092"` // Prometheus endpoint of the consumer, empty disables
+	ConsumerLagInterval  time.Duration `env:"CONSUMER_LAG_INTERVAL" envDefault:"15s"`
+	ConsumerExactlyOnce  bool          `env:"CONSUMER_EXACTLY_ONCE" envDefault:"false"` // Record written Redis message IDs in Postgres to skip redeliveries
+	ConsumerWrittenTTL   time.Duration `env:"CONSUMER_WRITTEN_ID_RETENTION" envDefault:"24h"`
+	ConsumerPipelineFile string        `env:"CONSUMER_PIPELINE_FILE"` // JSON processors applied to events before the sink, empty disables
+	ConsumerPipelinePoll time.Duration `env:"CONSUMER_PIPELINE_RELOAD_INTERVAL" envDefault:"10s"`
+	RateLimitEnabled     bool          `env:"RATE_LIMIT_ENABLED" envDefault:"false"`
+	RateLimitGlobalRate  float64       `env:"RATE_LIMIT_GLOBAL_RATE" envDefault:"0"` // Requests/sec across all replicas, 0 disables
+	RateLimitGlobalBurst int64         `env:"RATE_LIMIT_GLOBAL_BURST" envDefault:"0"`
+	RateLimitKeyRate     float64       `env:"RATE_LIMIT_KEY_RATE" envDefault:"100"` // Requests/sec per API key, 0 disables
+	RateLimitKeyBurst    int64         `env:"RATE_LIMIT_KEY_BURST" envDefault:"200"`
+	RateLimitKeyLimits   string        `env:"RATE_LIMIT_KEY_LIMITS"` // JSON array of per-key overrides, see middleware.ParseKeyRateLimits
+	UsageMeteringEnabled bool          `env:"USAGE_METERING_ENABLED" envDefault:"false"`
+	UsageFlushInterval   time.Duration `env:"USAGE_FLUSH_INTERVAL" envDefault:"10s"`
+	QuotaSoftEvents      int64         `env:"QUOTA_DAILY_SOFT_EVENTS" envDefault:"0"` // Per API key, 0 disables
+	QuotaHardEvents      int64         `env:"QUOTA_DAILY_HARD_EVENTS" envDefault:"0"`
+	QuotaSoftBytes       int64         `env:"QUOTA_DAILY_SOFT_BYTES" envDefault:"0"`
+	QuotaHardBytes       int64         `env:"QUOTA_DAILY_HARD_BYTES" envDefault:"0"`
+	MetricsAPIKeyLabels  []string      `env:"METRICS_API_KEY_LABELS" envSeparator:","`      // Key digests with their own api_key series, others are counted as "other"
+	SLOIngestLatency     time.Duration `env:"SLO_INGEST_LATENCY_TARGET" envDefault:"250ms"` // Events buffered within this are good for the ingest_latency SLO
+	SLODeliveryLatency   time.Duration `env:"SLO_DELIVERY_LATENCY_TARGET" envDefault:"60s"` // Events committed to the sink within this of being received are good for delivery_latency
+	DLQMonitorEnabled    bool          `env:"DLQ_MONITOR_ENABLED" envDefault:"false"`       // Enable on exactly one ingest replica
+	DLQAlertChannel      string        `env:"DLQ_ALERT_CHANNEL" envDefault:"webhook"`       // "webhook", "slack", "email" or "pagerduty"
+	DLQAlertWebhookURL   string        `env:"DLQ_ALERT_WEBHOOK_URL"`                        // Empty disables webhook and slack alerting
+	DLQAlertWebhookKey   string        `env:"DLQ_ALERT_WEBHOOK_SECRET"`                     // HMAC key signing webhook requests, empty leaves them unsigned
+	DLQAlertSMTPAddr     string        `env:"DLQ_ALERT_SMTP_ADDR"`                          // Empty disables email alerting
+	DLQAlertSMTPUser     string        `env:"DLQ_ALERT_SMTP_USERNAME"`
+	DLQAlertSMTPPass     string        `env:"DLQ_ALERT_SMTP_PASSWORD"`
+	DLQAlertEmailFrom    string        `env:"DLQ_ALERT_EMAIL_FROM"`
+	DLQAlertEmailTo      []string      `env:"DLQ_ALERT_EMAIL_TO" envSeparator:","`
+	DLQAlertPagerDuty    string        `env:"DLQ_ALERT_PAGERDUTY_ROUTING_KEY"`         // Empty disables pagerduty alerting
+	DLQAlertTemplate     string        `env:"DLQ_ALERT_TEMPLATE"`                      // text/template for webhook, slack and email text, empty uses the built-in text
+	DLQAlertEscalateIn   time.Duration `env:"DLQ_ALERT_ESCALATE_AFTER" envDefault:"0"` // Escalate an unacknowledged alert after this long, 0 disables
+	DLQAlertEscalateTo   string        `env:"DLQ_ALERT_ESCALATION_CHANNEL"`
+	DLQAlertEscalateURL  string        `env:"DLQ_ALERT_ESCALATION_WEBHOOK_URL"` // Webhook or slack URL of the escalation, if different
+	DLQAlertAckURL       string        `env:"DLQ_ALERT_ACK_URL"`                // Public URL of /admin/dlq/alert/ack, linked in notifications
+	DLQAlertRetries      int           `env:"DLQ_ALERT_RETRIES" envDefault:"3"`
+	DLQAlertDepth        int64         `env:"DLQ_ALERT_DEPTH_THRESHOLD" envDefault:"1000"`
+	DLQAlertGrowth       int64         `env:"DLQ_ALERT_GROWTH_THRESHOLD" envDefault:"100"` // Entries added per check interval
+	DLQAlertInterval     time.Duration `env:"DLQ_ALERT_CHECK_INTERVAL" envDefault:"1m"`
+	DLQAlertRenotify     time.Duration `env:"DLQ_ALERT_RENOTIFY_INTERVAL" envDefault:"0"` // Repeat a firing alert this often, 0 notifies once
+	DLQBrowserURL        string        `env:"DLQ_BROWSER_URL"`
 }
 
 // Load reads configuration from environment variables.
@@ -36,4 +175,3 @@ func Load() (*Config, error) {
 	}
 	return cfg, nil
 }
-
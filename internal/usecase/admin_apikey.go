@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// APIKeyInvalidator tells every ingest replica to drop a key from its validation cache,
+// so that a change to it takes effect without waiting for the cache TTL.
+type APIKeyInvalidator interface {
+	InvalidateAPIKey(ctx context.Context, keyHash string) error
+}
+
+// AdminAPIKeyUseCase provides use cases for managing the API keys clients ingest with.
+type AdminAPIKeyUseCase struct {
+	repo        domain.APIKeyAdminRepository
+	invalidator APIKeyInvalidator // Nil leaves other replicas to the cache TTL.
+}
+
+// NewAdminAPIKeyUseCase creates a new AdminAPIKeyUseCase.
+func NewAdminAPIKeyUseCase(repo domain.APIKeyAdminRepository, invalidator APIKeyInvalidator) *AdminAPIKeyUseCase {
+	return &AdminAPIKeyUseCase{repo: repo, invalidator: invalidator}
+}
+
+// Create generates a new random API key and stores its digest. The key itself is only
+// returned here.
+func (uc *AdminAPIKeyUseCase) Create(ctx context.Context, description string, expiresAt *time.Time) (*domain.NewAPIKey, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := hex.EncodeToString(b)
+	created, err := uc.repo.CreateAPIKey(ctx, domain.APIKey{KeyHash: domain.HashAPIKey(key), Description: description, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.NewAPIKey{Key: key, APIKey: *created}, nil
+}
+
+func (uc *AdminAPIKeyUseCase) List(ctx context.Context) ([]domain.APIKey, error) {
+	return uc.repo.ListAPIKeys(ctx)
+}
+
+// Update changes the description and expiry of a key; nil leaves them as they are.
+func (uc *AdminAPIKeyUseCase) Update(ctx context.Context, keyHash string, description *string, expiresAt *time.Time) (*domain.APIKey, error) {
+	key, err := uc.repo.UpdateAPIKey(ctx, keyHash, description, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return key, uc.invalidate(ctx, keyHash)
+}
+
+// Rotate replaces a key with a new one with the same description and expiry. The old
+// key keeps working for grace, so clients can switch over, or is revoked at once if
+// grace is 0.
+func (uc *AdminAPIKeyUseCase) Rotate(ctx context.Context, keyHash string, grace time.Duration) (*domain.NewAPIKey, error) {
+	old, err := uc.repo.GetAPIKey(ctx, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	created, err := uc.Create(ctx, old.Description, old.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if grace <= 0 {
+		return created, uc.Revoke(ctx, keyHash)
+	}
+	if end := time.Now().Add(grace); old.ExpiresAt == nil || end.Before(*old.ExpiresAt) {
+		if _, err := uc.Update(ctx, keyHash, nil, &end); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// Revoke deactivates a key. Its requests are rejected as soon as every replica dropped
+// it from its cache.
+func (uc *AdminAPIKeyUseCase) Revoke(ctx context.Context, keyHash string) error {
+	if err := uc.repo.DeactivateAPIKey(ctx, keyHash); err != nil {
+		return err
+	}
+	return uc.invalidate(ctx, keyHash)
+}
+
+func (uc *AdminAPIKeyUseCase) invalidate(ctx context.Context, keyHash string) error {
+	if uc.invalidator == nil {
+		return nil
+	}
+	return uc.invalidator.InvalidateAPIKey(ctx, keyHash)
+}
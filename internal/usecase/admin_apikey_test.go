@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeAPIKeyRepository struct {
+	keys map[string]domain.APIKey
+}
+
+func (f *fakeAPIKeyRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, error) {
+	key.IsActive = true
+	f.keys[key.KeyHash] = key
+	return &key, nil
+}
+
+func (f *fakeAPIKeyRepository) GetAPIKey(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	key, ok := f.keys[keyHash]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return &key, nil
+}
+
+func (f *fakeAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIKeyRepository) UpdateAPIKey(ctx context.Context, keyHash string, description *string, expiresAt *time.Time) (*domain.APIKey, error) {
+	key, ok := f.keys[keyHash]
+	if !ok {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if description != nil {
+		key.Description = *description
+	}
+	if expiresAt != nil {
+		key.ExpiresAt = expiresAt
+	}
+	f.keys[keyHash] = key
+	return &key, nil
+}
+
+func (f *fakeAPIKeyRepository) DeactivateAPIKey(ctx context.Context, keyHash string) error {
+	key, ok := f.keys[keyHash]
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	key.IsActive = false
+	f.keys[keyHash] = key
+	return nil
+}
+
+type fakeAPIKeyInvalidator struct {
+	invalidated []string
+}
+
+func (f *fakeAPIKeyInvalidator) InvalidateAPIKey(ctx context.Context, keyHash string) error {
+	f.invalidated = append(f.invalidated, keyHash)
+	return nil
+}
+
+func TestAdminAPIKeyUseCase(t *testing.T) {
+	repo := &fakeAPIKeyRepository{keys: map[string]domain.APIKey{}}
+	invalidator := &fakeAPIKeyInvalidator{}
+	uc := NewAdminAPIKeyUseCase(repo, invalidator)
+	ctx := context.Background()
+
+	created, err := uc.Create(ctx, "billing", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if created.Key == "" || created.KeyHash != domain.HashAPIKey(created.Key) || repo.keys[created.KeyHash].Description != "billing" {
+		t.Fatalf("expected a key stored by its digest, got %+v", created)
+	}
+
+	rotated, err := uc.Rotate(ctx, created.KeyHash, time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	old := repo.keys[created.KeyHash]
+	if rotated.Key == created.Key || rotated.Description != "billing" || !old.IsActive || old.ExpiresAt == nil {
+		t.Fatalf("expected a new key and the old one expiring after the grace period, got %+v and %+v", rotated, old)
+	}
+
+	if err := uc.Revoke(ctx, rotated.KeyHash); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if repo.keys[rotated.KeyHash].IsActive {
+		t.Error("expected the revoked key deactivated")
+	}
+	if n := len(invalidator.invalidated); n != 2 || invalidator.invalidated[n-1] != rotated.KeyHash {
+		t.Errorf("expected the expiring and the revoked key invalidated, got %v", invalidator.invalidated)
+	}
+
+	if err := uc.Revoke(ctx, "unknown"); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// AdminConsumerUseCase provides use cases for pausing and resuming consumer groups.
+type AdminConsumerUseCase struct {
+	repo domain.ConsumerControlRepository
+}
+
+// NewAdminConsumerUseCase creates a new AdminConsumerUseCase.
+func NewAdminConsumerUseCase(repo domain.ConsumerControlRepository) *AdminConsumerUseCase {
+	return &AdminConsumerUseCase{repo: repo}
+}
+
+// Pause stops the consumers of a group from processing batches. They keep running and
+// keep their pending messages until the group is resumed.
+func (uc *AdminConsumerUseCase) Pause(ctx context.Context, group string) (*domain.GroupPause, error) {
+	if err := uc.repo.SetGroupPaused(ctx, group, true); err != nil {
+		return nil, err
+	}
+	return uc.repo.GetGroupPause(ctx, group)
+}
+
+func (uc *AdminConsumerUseCase) Resume(ctx context.Context, group string) (*domain.GroupPause, error) {
+	if err := uc.repo.SetGroupPaused(ctx, group, false); err != nil {
+		return nil, err
+	}
+	return uc.repo.GetGroupPause(ctx, group)
+}
+
+func (uc *AdminConsumerUseCase) Status(ctx context.Context, group string) (*domain.GroupPause, error) {
+	return uc.repo.GetGroupPause(ctx, group)
+}
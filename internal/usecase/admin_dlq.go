@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// maxDLQPageSize bounds how many entries a single DLQ listing returns.
+const maxDLQPageSize = 1000
+
+// ErrNoDLQEntriesSelected is returned when a re-drive or purge names no entries and does
+// not ask for all of them.
+var ErrNoDLQEntriesSelected = errors.New("no dlq entries selected")
+
+// AdminDLQUseCase provides use cases for browsing, re-driving and purging the dead-letter queue.
+type AdminDLQUseCase struct {
+	repo domain.DLQRepository
+}
+
+// NewAdminDLQUseCase creates a new AdminDLQUseCase.
+func NewAdminDLQUseCase(repo domain.DLQRepository) *AdminDLQUseCase {
+	return &AdminDLQUseCase{repo: repo}
+}
+
+// List returns up to count entries after the given ID, oldest first.
+func (uc *AdminDLQUseCase) List(ctx context.Context, after string, count int64) (*domain.DLQPage, error) {
+	if count <= 0 {
+		count = 100 // Default count
+	}
+	if count > maxDLQPageSize {
+		count = maxDLQPageSize
+	}
+	entries, err := uc.repo.ListDLQ(ctx, after, count)
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.DLQPage{Entries: entries}
+	if int64(len(entries)) == count {
+		page.NextAfter = entries[len(entries)-1].ID
+	}
+	return page, nil
+}
+
+func (uc *AdminDLQUseCase) Get(ctx context.Context, id string) (*domain.DLQEntry, error) {
+	return uc.repo.GetDLQEntry(ctx, id)
+}
+
+// Redrive sends the given entries, or every entry if all is set, back to the buffer and
+// returns how many were re-driven.
+func (uc *AdminDLQUseCase) Redrive(ctx context.Context, ids []string, all bool) (int64, error) {
+	if all {
+		return uc.repo.RedriveAllDLQ(ctx)
+	}
+	if len(ids) == 0 {
+		return 0, ErrNoDLQEntriesSelected
+	}
+	return uc.repo.RedriveDLQ(ctx, ids...)
+}
+
+// Purge deletes the given entries, or every entry if all is set, and returns how many
+// were deleted.
+func (uc *AdminDLQUseCase) Purge(ctx context.Context, ids []string, all bool) (int64, error) {
+	if all {
+		return uc.repo.PurgeAllDLQ(ctx)
+	}
+	if len(ids) == 0 {
+		return 0, ErrNoDLQEntriesSelected
+	}
+	return uc.repo.PurgeDLQ(ctx, ids...)
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeDLQRepo struct {
+	domain.DLQRepository
+	entries    []domain.DLQEntry
+	redriven   []string
+	redriveAll bool
+}
+
+func (f *fakeDLQRepo) ListDLQ(ctx context.Context, after string, count int64) ([]domain.DLQEntry, error) {
+	var page []domain.DLQEntry
+	for _, e := range f.entries {
+		if e.ID > after && int64(len(page)) < count {
+			page = append(page, e)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeDLQRepo) RedriveDLQ(ctx context.Context, ids ...string) (int64, error) {
+	f.redriven = append(f.redriven, ids...)
+	return int64(len(ids)), nil
+}
+
+func (f *fakeDLQRepo) RedriveAllDLQ(ctx context.Context) (int64, error) {
+	f.redriveAll = true
+	return int64(len(f.entries)), nil
+}
+
+func TestAdminDLQUseCase(t *testing.T) {
+	repo := &fakeDLQRepo{entries: []domain.DLQEntry{{ID: "1-0"}, {ID: "2-0"}, {ID: "3-0"}}}
+	uc := NewAdminDLQUseCase(repo)
+
+	t.Run("Pages through entries", func(t *testing.T) {
+		page, err := uc.List(context.Background(), "", 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(page.Entries) != 2 || page.NextAfter != "2-0" {
+			t.Fatalf("expected 2 entries and next_after 2-0, got %d and %q", len(page.Entries), page.NextAfter)
+		}
+		page, _ = uc.List(context.Background(), page.NextAfter, 2)
+		if len(page.Entries) != 1 || page.NextAfter != "" {
+			t.Errorf("expected a last page with 1 entry, got %d and next_after %q", len(page.Entries), page.NextAfter)
+		}
+	})
+
+	t.Run("Redrive requires a selection", func(t *testing.T) {
+		if _, err := uc.Redrive(context.Background(), nil, false); !errors.Is(err, ErrNoDLQEntriesSelected) {
+			t.Errorf("expected ErrNoDLQEntriesSelected, got %v", err)
+		}
+		if n, _ := uc.Redrive(context.Background(), []string{"2-0"}, false); n != 1 || len(repo.redriven) != 1 {
+			t.Errorf("expected 1 entry re-driven, got %d", n)
+		}
+		if _, err := uc.Redrive(context.Background(), nil, true); err != nil || !repo.redriveAll {
+			t.Errorf("expected all entries to be re-driven, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// FieldMappingReloader validates field mappings and applies the stored ones, see
+// fieldmap.Mapper.
+type FieldMappingReloader interface {
+	Validate(fields map[string]string) error
+	Reload(ctx context.Context) error
+}
+
+// AdminFieldMappingUseCase provides use cases for managing the field mappings of API
+// keys. Changes apply on this replica at once and on the others at their next reload.
+type AdminFieldMappingUseCase struct {
+	repo     domain.FieldMappingRepository
+	reloader FieldMappingReloader
+}
+
+// NewAdminFieldMappingUseCase creates a new AdminFieldMappingUseCase.
+func NewAdminFieldMappingUseCase(repo domain.FieldMappingRepository, reloader FieldMappingReloader) *AdminFieldMappingUseCase {
+	return &AdminFieldMappingUseCase{repo: repo, reloader: reloader}
+}
+
+func (uc *AdminFieldMappingUseCase) List(ctx context.Context) ([]domain.FieldMapping, error) {
+	return uc.repo.ListFieldMappings(ctx)
+}
+
+func (uc *AdminFieldMappingUseCase) Get(ctx context.Context, keyHash string) (*domain.FieldMapping, error) {
+	return uc.repo.GetFieldMapping(ctx, keyHash)
+}
+
+// Put creates or replaces the mapping of a key, after checking its targets.
+func (uc *AdminFieldMappingUseCase) Put(ctx context.Context, keyHash string, fields map[string]string) (*domain.FieldMapping, error) {
+	if err := uc.reloader.Validate(fields); err != nil {
+		return nil, err
+	}
+	mapping, err := uc.repo.PutFieldMapping(ctx, domain.FieldMapping{KeyHash: keyHash, Fields: fields})
+	if err != nil {
+		return nil, err
+	}
+	return mapping, uc.reloader.Reload(ctx)
+}
+
+// Delete deletes the mapping of a key, whose payloads are then taken as they are.
+func (uc *AdminFieldMappingUseCase) Delete(ctx context.Context, keyHash string) error {
+	if err := uc.repo.DeleteFieldMapping(ctx, keyHash); err != nil {
+		return err
+	}
+	return uc.reloader.Reload(ctx)
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// PIIPolicyReloader validates PII policies and applies the stored ones, see
+// pii.PolicyRedactor.
+type PIIPolicyReloader interface {
+	Validate(rules []domain.PIIRule) error
+	Reload(ctx context.Context) error
+}
+
+// AdminPIIPolicyUseCase provides use cases for managing the PII policies of API keys.
+// Changes apply on this replica at once and on the others at their next reload.
+type AdminPIIPolicyUseCase struct {
+	repo     domain.PIIPolicyRepository
+	reloader PIIPolicyReloader
+}
+
+// NewAdminPIIPolicyUseCase creates a new AdminPIIPolicyUseCase.
+func NewAdminPIIPolicyUseCase(repo domain.PIIPolicyRepository, reloader PIIPolicyReloader) *AdminPIIPolicyUseCase {
+	return &AdminPIIPolicyUseCase{repo: repo, reloader: reloader}
+}
+
+func (uc *AdminPIIPolicyUseCase) List(ctx context.Context) ([]domain.PIIPolicy, error) {
+	return uc.repo.ListPIIPolicies(ctx)
+}
+
+func (uc *AdminPIIPolicyUseCase) Get(ctx context.Context, keyHash string) (*domain.PIIPolicy, error) {
+	return uc.repo.GetPIIPolicy(ctx, keyHash)
+}
+
+// Put creates or replaces the policy of a key, after checking that its rules compile.
+func (uc *AdminPIIPolicyUseCase) Put(ctx context.Context, keyHash string, rules []domain.PIIRule) (*domain.PIIPolicy, error) {
+	if err := uc.reloader.Validate(rules); err != nil {
+		return nil, err
+	}
+	policy, err := uc.repo.PutPIIPolicy(ctx, domain.PIIPolicy{KeyHash: keyHash, Rules: rules})
+	if err != nil {
+		return nil, err
+	}
+	return policy, uc.reloader.Reload(ctx)
+}
+
+// Delete deletes the policy of a key, whose events are then redacted by the global rules.
+func (uc *AdminPIIPolicyUseCase) Delete(ctx context.Context, keyHash string) error {
+	if err := uc.repo.DeletePIIPolicy(ctx, keyHash); err != nil {
+		return err
+	}
+	return uc.reloader.Reload(ctx)
+}
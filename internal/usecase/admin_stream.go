@@ -50,3 +50,7 @@ func (uc *AdminStreamUseCase) AcknowledgeMessages(ctx context.Context, stream, g
 func (uc *AdminStreamUseCase) TrimStream(ctx context.Context, stream string, maxLen int64) (int64, error) {
 	return uc.repo.TrimStream(ctx, stream, maxLen)
 }
+
+func (uc *AdminStreamUseCase) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	return uc.repo.GetStreamLength(ctx, stream)
+}
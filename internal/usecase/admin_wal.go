@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// maxWALPeekCount bounds how many events a single peek returns.
+const maxWALPeekCount = 1000
+
+// WALReplayer replays the WAL into the buffer it backs up.
+type WALReplayer interface {
+	ReplayWAL(ctx context.Context) error
+}
+
+// AdminWALUseCase provides use cases for inspecting and replaying the Write-Ahead Log.
+type AdminWALUseCase struct {
+	wal      domain.WALInspector
+	replayer WALReplayer
+}
+
+// NewAdminWALUseCase creates a new AdminWALUseCase.
+func NewAdminWALUseCase(wal domain.WALInspector, replayer WALReplayer) *AdminWALUseCase {
+	return &AdminWALUseCase{wal: wal, replayer: replayer}
+}
+
+func (uc *AdminWALUseCase) Status(ctx context.Context) (*domain.WALStatus, error) {
+	return uc.wal.Status(ctx)
+}
+
+func (uc *AdminWALUseCase) Peek(ctx context.Context, count int) ([]domain.LogEvent, error) {
+	if count <= 0 {
+		count = 10 // Default count
+	}
+	if count > maxWALPeekCount {
+		count = maxWALPeekCount
+	}
+	return uc.wal.Peek(ctx, count)
+}
+
+// Replay replays the WAL now instead of waiting for the buffer to recover. It fails if
+// the buffer is still unavailable.
+func (uc *AdminWALUseCase) Replay(ctx context.Context) error {
+	return uc.replayer.ReplayWAL(ctx)
+}
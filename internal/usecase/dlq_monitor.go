@@ -0,0 +1,277 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// DLQMonitorConfig holds the thresholds for DLQ alerting.
+type DLQMonitorConfig struct {
+	Stream          string
+	DepthThreshold  int64  // Alert when the DLQ holds at least this many entries, 0 disables.
+	GrowthThreshold int64  // Alert when the DLQ grows by at least this many entries in one interval, 0 disables.
+	BrowserURL      string // Link included in notifications so operators can inspect the DLQ.
+	// RenotifyInterval repeats the notification of an alert that keeps firing this often,
+	// 0 notifies it once.
+	RenotifyInterval time.Duration
+	AckURL           string // Link included in firing notifications to acknowledge the alert.
+}
+
+// ErrDLQAlertNotFiring is returned when acknowledging a DLQ alert that is not firing.
+var ErrDLQAlertNotFiring = errors.New("DLQ alert is not firing")
+
+// DLQMonitorUseCase watches the dead-letter queue and raises notifications when it
+// crosses the configured depth or growth thresholds.
+type DLQMonitorUseCase struct {
+	repo     domain.StreamAdminRepository
+	notifier domain.Notifier
+	cfg      DLQMonitorConfig
+	logger   *slog.Logger
+
+	mu        sync.Mutex // Check and Test run concurrently.
+	lastDepth int64
+	hasSample bool
+	firing    bool
+	notified  time.Time // When the firing alert was last notified.
+
+	escalation    domain.Notifier // Nil never escalates.
+	escalateAfter time.Duration
+	firingSince   time.Time
+	acknowledged  bool // Stops renotifying and escalating the firing alert.
+	escalated     bool
+
+	lastCheck         time.Time
+	lastError         error
+	consecutiveErrors int
+}
+
+// NewDLQMonitorUseCase creates a new DLQMonitorUseCase.
+func NewDLQMonitorUseCase(repo domain.StreamAdminRepository, notifier domain.Notifier, cfg DLQMonitorConfig, logger *slog.Logger) *DLQMonitorUseCase {
+	return &DLQMonitorUseCase{
+		repo:     repo,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger.With("component", "dlq_monitor"),
+	}
+}
+
+// EnableEscalation makes Check also notify escalation of an alert that has been firing
+// for after without being acknowledged, and of its resolution.
+func (uc *DLQMonitorUseCase) EnableEscalation(escalation domain.Notifier, after time.Duration) {
+	uc.escalation = escalation
+	uc.escalateAfter = after
+}
+
+// Acknowledge marks the firing alert as being handled, which stops renotifying and
+// escalating it until it resolves.
+func (uc *DLQMonitorUseCase) Acknowledge(ctx context.Context) error {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if !uc.firing {
+		return ErrDLQAlertNotFiring
+	}
+	if !uc.acknowledged {
+		uc.logger.Info("DLQ alert acknowledged")
+		uc.acknowledged = true
+	}
+	return nil
+}
+
+// Run checks the DLQ every interval until the context is cancelled.
+func (uc *DLQMonitorUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	uc.logger.Info("Starting DLQ monitor", "stream", uc.cfg.Stream, "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			uc.logger.Info("Stopping DLQ monitor")
+			return
+		case <-ticker.C:
+			if err := uc.Check(ctx); err != nil {
+				uc.logger.Error("DLQ check failed", "error", err)
+			}
+		}
+	}
+}
+
+// Check samples the DLQ depth once and notifies on firing or resolved transitions.
+// A notification is only sent when the state changes, so a DLQ that stays above
+// threshold does not page on every interval, except once every RenotifyInterval if one
+// is set. Failed deliveries are retried on the next check. The outcome is kept for
+// Status, so an alert that keeps failing to evaluate or notify can be spotted.
+func (uc *DLQMonitorUseCase) Check(ctx context.Context) (err error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	defer func() {
+		uc.lastCheck = time.Now()
+		uc.lastError = err
+		if err != nil {
+			uc.consecutiveErrors++
+		} else {
+			uc.consecutiveErrors = 0
+		}
+	}()
+
+	depth, err := uc.repo.GetStreamLength(ctx, uc.cfg.Stream)
+	if err != nil {
+		return err
+	}
+
+	var growth int64
+	if uc.hasSample {
+		growth = depth - uc.lastDepth
+	}
+	uc.lastDepth = depth
+	uc.hasSample = true
+
+	reason := uc.evaluate(depth, growth)
+	switch {
+	case reason != "" && !uc.firing:
+		uc.logger.Warn("DLQ threshold crossed", "depth", depth, "growth", growth)
+		if err := uc.notify(ctx, uc.notifier, domain.SeverityCritical, reason, depth, growth); err != nil {
+			return err
+		}
+		uc.firing = true
+		uc.notified = time.Now()
+		uc.firingSince = uc.notified
+		uc.acknowledged = false
+		uc.escalated = false
+	case reason != "" && !uc.acknowledged:
+		if uc.cfg.RenotifyInterval > 0 && time.Since(uc.notified) >= uc.cfg.RenotifyInterval {
+			uc.logger.Warn("DLQ still above thresholds", "depth", depth, "growth", growth)
+			if err := uc.notify(ctx, uc.notifier, domain.SeverityCritical, "Still firing: "+reason, depth, growth); err != nil {
+				return err
+			}
+			uc.notified = time.Now()
+		}
+		if uc.escalation != nil && !uc.escalated && time.Since(uc.firingSince) >= uc.escalateAfter {
+			uc.logger.Warn("Escalating unacknowledged DLQ alert", "firing_since", uc.firingSince)
+			message := fmt.Sprintf("Unacknowledged for %s: %s", time.Since(uc.firingSince).Round(time.Second), reason)
+			if err := uc.notify(ctx, uc.escalation, domain.SeverityCritical, message, depth, growth); err != nil {
+				return err
+			}
+			uc.escalated = true
+		}
+	case reason == "" && uc.firing:
+		uc.logger.Info("DLQ back below thresholds", "depth", depth)
+		message := fmt.Sprintf("DLQ stream %s is back below thresholds (%d entries)", uc.cfg.Stream, depth)
+		if err := uc.notify(ctx, uc.notifier, domain.SeverityResolved, message, depth, growth); err != nil {
+			return err
+		}
+		if uc.escalated {
+			if err := uc.notify(ctx, uc.escalation, domain.SeverityResolved, message, depth, growth); err != nil {
+				return err
+			}
+		}
+		uc.firing = false
+		uc.escalated = false
+	}
+	return nil
+}
+
+// Status reports the state of the alert and the outcome of the last check.
+func (uc *DLQMonitorUseCase) Status() domain.DLQAlertStatus {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	status := domain.DLQAlertStatus{
+		Stream:            uc.cfg.Stream,
+		Firing:            uc.firing,
+		Acknowledged:      uc.firing && uc.acknowledged,
+		Escalated:         uc.escalated,
+		LastCheck:         uc.lastCheck,
+		ConsecutiveErrors: uc.consecutiveErrors,
+	}
+	if uc.lastError != nil {
+		status.LastError = uc.lastError.Error()
+	}
+	return status
+}
+
+// evaluate returns why the alert fires at depth and growth, or "" if it does not.
+func (uc *DLQMonitorUseCase) evaluate(depth, growth int64) string {
+	switch {
+	case uc.cfg.DepthThreshold > 0 && depth >= uc.cfg.DepthThreshold:
+		return fmt.Sprintf("DLQ stream %s holds %d entries (threshold %d)", uc.cfg.Stream, depth, uc.cfg.DepthThreshold)
+	case uc.cfg.GrowthThreshold > 0 && growth >= uc.cfg.GrowthThreshold:
+		return fmt.Sprintf("DLQ stream %s grew by %d entries since the last check (threshold %d)", uc.cfg.Stream, growth, uc.cfg.GrowthThreshold)
+	}
+	return ""
+}
+
+// Test evaluates the alert against the current DLQ depth and reports what a check would
+// do, without recording the sample or changing whether the alert is firing. With notify,
+// it also sends a test notification, so the channel can be verified before relying on
+// it; a failed delivery is reported in the result rather than as an error.
+func (uc *DLQMonitorUseCase) Test(ctx context.Context, notify bool) (domain.DLQAlertTest, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	depth, err := uc.repo.GetStreamLength(ctx, uc.cfg.Stream)
+	if err != nil {
+		return domain.DLQAlertTest{}, err
+	}
+	var growth int64
+	if uc.hasSample {
+		growth = depth - uc.lastDepth
+	}
+	result := domain.DLQAlertTest{
+		Stream:       uc.cfg.Stream,
+		Depth:        depth,
+		Growth:       growth,
+		Reason:       uc.evaluate(depth, growth),
+		Firing:       uc.firing,
+		Acknowledged: uc.firing && uc.acknowledged,
+		Escalated:    uc.escalated,
+	}
+	result.WouldFire = result.Reason != ""
+
+	if notify {
+		message := "Test notification: the DLQ alert would not fire now"
+		if result.WouldFire {
+			message = "Test notification: " + result.Reason
+		}
+		// A title of its own keeps the test apart from the real alert, e.g. in PagerDuty
+		// incidents, which are keyed by title.
+		if err := uc.send(ctx, uc.notifier, "Dead-letter queue alert (test)", domain.SeverityWarning, message, depth, growth); err != nil {
+			result.NotifyError = err.Error()
+		} else {
+			result.Notified = true
+		}
+	}
+	return result, nil
+}
+
+func (uc *DLQMonitorUseCase) notify(ctx context.Context, notifier domain.Notifier, severity, message string, depth, growth int64) error {
+	return uc.send(ctx, notifier, "Dead-letter queue alert", severity, message, depth, growth)
+}
+
+func (uc *DLQMonitorUseCase) send(ctx context.Context, notifier domain.Notifier, title, severity, message string, depth, growth int64) error {
+	n := domain.Notification{
+		Title:    title,
+		Message:  message,
+		Severity: severity,
+		Link:     uc.cfg.BrowserURL,
+		Labels: map[string]string{
+			"stream": uc.cfg.Stream,
+			"depth":  strconv.FormatInt(depth, 10),
+			"growth": strconv.FormatInt(growth, 10),
+		},
+		Timestamp: time.Now().UTC(),
+	}
+	if severity != domain.SeverityResolved && uc.cfg.AckURL != "" {
+		n.Labels["acknowledge"] = uc.cfg.AckURL
+	}
+	if err := notifier.Notify(ctx, n); err != nil {
+		return fmt.Errorf("failed to send DLQ notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type fakeStreamAdminRepo struct {
+	domain.StreamAdminRepository
+	lengths []int64
+	calls   int
+}
+
+func (f *fakeStreamAdminRepo) GetStreamLength(ctx context.Context, stream string) (int64, error) {
+	l := f.lengths[f.calls]
+	f.calls++
+	return l, nil
+}
+
+type fakeNotifier struct {
+	sent []domain.Notification
+	err  error
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, n domain.Notification) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func TestDLQMonitorUseCase_Check(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100, GrowthThreshold: 10, BrowserURL: "http://admin/dlq"}
+
+	t.Run("Fires once and resolves", func(t *testing.T) {
+		repo := &fakeStreamAdminRepo{lengths: []int64{0, 150, 160, 5}}
+		n := &fakeNotifier{}
+		uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+		for range repo.lengths {
+			if err := uc.Check(context.Background()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		if len(n.sent) != 2 {
+			t.Fatalf("expected 2 notifications, got %d", len(n.sent))
+		}
+		if n.sent[0].Severity != domain.SeverityCritical || n.sent[0].Link != cfg.BrowserURL {
+			t.Errorf("unexpected firing notification: %+v", n.sent[0])
+		}
+		if n.sent[1].Severity != domain.SeverityResolved {
+			t.Errorf("expected resolved notification, got %q", n.sent[1].Severity)
+		}
+	})
+
+	t.Run("Fires on growth rate", func(t *testing.T) {
+		repo := &fakeStreamAdminRepo{lengths: []int64{10, 30}}
+		n := &fakeNotifier{}
+		uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+		_ = uc.Check(context.Background())
+		_ = uc.Check(context.Background())
+
+		if len(n.sent) != 1 {
+			t.Fatalf("expected 1 notification, got %d", len(n.sent))
+		}
+	})
+
+	t.Run("Retries failed delivery", func(t *testing.T) {
+		repo := &fakeStreamAdminRepo{lengths: []int64{200, 200}}
+		n := &fakeNotifier{err: errors.New("webhook down")}
+		uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+		if err := uc.Check(context.Background()); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		n.err = nil
+		if err := uc.Check(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(n.sent) != 1 {
+			t.Fatalf("expected 1 notification after retry, got %d", len(n.sent))
+		}
+	})
+}
+
+
+func TestDLQMonitorUseCase_Test(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100}
+	repo := &fakeStreamAdminRepo{lengths: []int64{150, 150}}
+	n := &fakeNotifier{}
+	uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+	result, err := uc.Test(context.Background(), true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !result.WouldFire || !result.Notified || result.Firing {
+		t.Errorf("expected a would-be firing alert and a test notification, got %+v", result)
+	}
+	if len(n.sent) != 1 || n.sent[0].Title == "Dead-letter queue alert" {
+		t.Fatalf("expected 1 test notification, got %+v", n.sent)
+	}
+
+	// The test left the monitor's state alone, so the real check still fires.
+	if err := uc.Check(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(n.sent) != 2 || n.sent[1].Severity != domain.SeverityCritical {
+		t.Errorf("expected the check to fire, got %+v", n.sent)
+	}
+}
+
+func TestDLQMonitorUseCase_Renotify(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100, RenotifyInterval: time.Hour}
+	repo := &fakeStreamAdminRepo{lengths: []int64{150, 150, 150}}
+	n := &fakeNotifier{}
+	uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+	_ = uc.Check(context.Background())
+	_ = uc.Check(context.Background())
+	if len(n.sent) != 1 {
+		t.Fatalf("expected 1 notification within the interval, got %d", len(n.sent))
+	}
+
+	uc.notified = time.Now().Add(-time.Hour)
+	_ = uc.Check(context.Background())
+	if len(n.sent) != 2 || n.sent[1].Severity != domain.SeverityCritical {
+		t.Errorf("expected the alert notified again after the interval, got %+v", n.sent)
+	}
+}
+
+func TestDLQMonitorUseCase_Escalation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100, AckURL: "http://admin/admin/dlq/alert/ack"}
+
+	t.Run("Escalates until acknowledged and resolves both channels", func(t *testing.T) {
+		repo := &fakeStreamAdminRepo{lengths: []int64{150, 150, 150, 5}}
+		primary, escalation := &fakeNotifier{}, &fakeNotifier{}
+		uc := NewDLQMonitorUseCase(repo, primary, cfg, logger)
+		uc.EnableEscalation(escalation, 0)
+
+		for range repo.lengths {
+			if err := uc.Check(context.Background()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if primary.sent[0].Labels["acknowledge"] != cfg.AckURL {
+			t.Errorf("expected the firing notification to link the acknowledge URL, got %v", primary.sent[0].Labels)
+		}
+		if len(escalation.sent) != 2 || escalation.sent[0].Severity != domain.SeverityCritical || escalation.sent[1].Severity != domain.SeverityResolved {
+			t.Errorf("expected one escalation and its resolution, got %+v", escalation.sent)
+		}
+	})
+
+	t.Run("Acknowledged alert is not escalated", func(t *testing.T) {
+		repo := &fakeStreamAdminRepo{lengths: []int64{150, 150}}
+		escalation := &fakeNotifier{}
+		uc := NewDLQMonitorUseCase(repo, &fakeNotifier{}, cfg, logger)
+		uc.EnableEscalation(escalation, 0)
+
+		if err := uc.Acknowledge(context.Background()); !errors.Is(err, ErrDLQAlertNotFiring) {
+			t.Fatalf("expected ErrDLQAlertNotFiring before the alert fires, got %v", err)
+		}
+		_ = uc.Check(context.Background())
+		if err := uc.Acknowledge(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_ = uc.Check(context.Background())
+		if len(escalation.sent) != 0 {
+			t.Errorf("expected no escalation, got %+v", escalation.sent)
+		}
+	})
+}
+
+func TestDLQMonitorUseCase_Status(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DLQMonitorConfig{Stream: "dlq", DepthThreshold: 100}
+	repo := &fakeStreamAdminRepo{lengths: []int64{150, 150, 150}}
+	n := &fakeNotifier{err: errors.New("channel down")}
+	uc := NewDLQMonitorUseCase(repo, n, cfg, logger)
+
+	for range 2 {
+		if err := uc.Check(context.Background()); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	}
+	status := uc.Status()
+	if status.ConsecutiveErrors != 2 || status.LastError == "" || status.LastCheck.IsZero() || status.Firing {
+		t.Fatalf("expected 2 consecutive errors, got %+v", status)
+	}
+
+	n.err = nil
+	if err := uc.Check(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status := uc.Status(); status.ConsecutiveErrors != 0 || status.LastError != "" || !status.Firing {
+		t.Errorf("expected the errors cleared and the alert firing, got %+v", status)
+	}
+}
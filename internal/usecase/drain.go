@@ -0,0 +1,170 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// drainRetryInterval is how often a drain checks for in-flight events and retries
+// failed steps.
+const drainRetryInterval = time.Second
+
+// ErrDraining is returned for events offered once the service started draining.
+var ErrDraining = errors.New("ingest service is draining")
+
+// DrainUseCase sits in front of the ingest use case so the service can be drained before
+// it is stopped: once Start is called it rejects new events with ErrDraining, waits for
+// the ones being accepted, flushes what is held in process and replays the WAL, and then
+// reports that the process can be killed without losing events.
+type DrainUseCase struct {
+	next     IngestLogUseCase
+	flushers []func(ctx context.Context) error
+	wal      domain.WALInspector
+	replayer WALReplayer
+	logger   *slog.Logger
+
+	draining atomic.Bool
+	inFlight atomic.Int64
+	flushed  atomic.Bool
+	mu       sync.Mutex
+	lastErr  error
+}
+
+// NewDrainUseCase creates a new DrainUseCase in front of next. Flushers push events held
+// in process, such as pending multiline events, into the buffer, or fail while a buffer
+// held in process is not empty; they run in order once no events are in flight, and are
+// retried until all of them succeed.
+func NewDrainUseCase(next IngestLogUseCase, wal domain.WALInspector, replayer WALReplayer, logger *slog.Logger, flushers ...func(ctx context.Context) error) *DrainUseCase {
+	return &DrainUseCase{
+		next:     next,
+		flushers: flushers,
+		wal:      wal,
+		replayer: replayer,
+		logger:   logger.With("component", "drain_usecase"),
+	}
+}
+
+// Ingest passes the event on unless the service is draining.
+func (uc *DrainUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	uc.inFlight.Add(1)
+	defer uc.inFlight.Add(-1)
+	// Checked after counting the event, so a drain never misses one it has to wait for.
+	if uc.draining.Load() {
+		return ErrDraining
+	}
+	return uc.next.Ingest(ctx, event)
+}
+
+// Draining reports whether new events are being rejected.
+func (uc *DrainUseCase) Draining() bool {
+	return uc.draining.Load()
+}
+
+// Start begins draining in the background until ctx is done. Further calls have no effect.
+func (uc *DrainUseCase) Start(ctx context.Context) {
+	if !uc.draining.CompareAndSwap(false, true) {
+		return
+	}
+	uc.logger.Info("Draining ingest service")
+	go uc.drain(ctx)
+}
+
+func (uc *DrainUseCase) drain(ctx context.Context) {
+	ticker := time.NewTicker(drainRetryInterval)
+	defer ticker.Stop()
+	for {
+		if uc.inFlight.Load() == 0 {
+			if !uc.flushed.Load() {
+				uc.flushed.Store(uc.flush(ctx))
+			}
+			if uc.flushed.Load() && uc.replayWAL(ctx) {
+				uc.logger.Info("Ingest service drained, safe to stop")
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flush runs every flusher, reporting whether all of them succeeded.
+func (uc *DrainUseCase) flush(ctx context.Context) bool {
+	for _, flush := range uc.flushers {
+		if err := flush(ctx); err != nil {
+			uc.setError(err)
+			uc.logger.Error("Failed to flush events during drain, retrying", "error", err)
+			return false
+		}
+	}
+	return true
+}
+
+// replayWAL replays the WAL into the buffer, reporting whether it is empty afterwards.
+// An empty WAL is not replayed, as that needs the buffer to be reachable.
+func (uc *DrainUseCase) replayWAL(ctx context.Context) bool {
+	if empty, err := uc.walEmpty(ctx); err == nil && empty {
+		uc.setError(nil)
+		return true
+	}
+	if uc.replayer != nil {
+		if err := uc.replayer.ReplayWAL(ctx); err != nil {
+			uc.setError(err)
+			uc.logger.Warn("Failed to replay WAL during drain, retrying", "error", err)
+			return false
+		}
+	}
+	empty, err := uc.walEmpty(ctx)
+	if err != nil {
+		uc.setError(err)
+		return false
+	}
+	uc.setError(nil)
+	return empty
+}
+
+func (uc *DrainUseCase) walEmpty(ctx context.Context) (bool, error) {
+	if uc.wal == nil {
+		return true, nil
+	}
+	status, err := uc.wal.Status(ctx)
+	if err != nil {
+		return false, err
+	}
+	return status.OldestEventTime.IsZero(), nil
+}
+
+func (uc *DrainUseCase) setError(err error) {
+	uc.mu.Lock()
+	uc.lastErr = err
+	uc.mu.Unlock()
+}
+
+// Status reports how far the drain has got.
+func (uc *DrainUseCase) Status(ctx context.Context) (*domain.DrainStatus, error) {
+	walEmpty, err := uc.walEmpty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &domain.DrainStatus{
+		Draining: uc.draining.Load(),
+		InFlight: uc.inFlight.Load(),
+		Flushed:  uc.flushed.Load(),
+		WALEmpty: walEmpty,
+	}
+	status.SafeToStop = status.Draining && status.InFlight == 0 && status.Flushed && status.WALEmpty
+	uc.mu.Lock()
+	if uc.lastErr != nil {
+		status.Error = uc.lastErr.Error()
+	}
+	uc.mu.Unlock()
+	return status, nil
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+)
+
+type fakeWALInspector struct {
+	oldest atomic.Pointer[time.Time]
+}
+
+func (f *fakeWALInspector) Status(ctx context.Context) (*domain.WALStatus, error) {
+	status := &domain.WALStatus{}
+	if oldest := f.oldest.Load(); oldest != nil {
+		status.OldestEventTime = *oldest
+	}
+	return status, nil
+}
+
+func (f *fakeWALInspector) Peek(ctx context.Context, count int) ([]domain.LogEvent, error) {
+	return nil, nil
+}
+
+type fakeWALReplayer struct {
+	wal *fakeWALInspector
+}
+
+func (f *fakeWALReplayer) ReplayWAL(ctx context.Context) error {
+	f.wal.oldest.Store(nil)
+	return nil
+}
+
+func TestDrainUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &mocks.MockLogRepository{}
+	wal := &fakeWALInspector{}
+	backlog := time.Now()
+	wal.oldest.Store(&backlog)
+	var flushes atomic.Int32
+	flush := func(ctx context.Context) error {
+		flushes.Add(1)
+		return nil
+	}
+	uc := NewDrainUseCase(NewIngestLogUseCase(repo, pii.NewRedactor(nil, logger), logger), wal, &fakeWALReplayer{wal: wal}, logger, flush)
+
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{Message: "before"}); err != nil {
+		t.Fatalf("expected no error before draining, got %v", err)
+	}
+	status, _ := uc.Status(context.Background())
+	if status.Draining || status.SafeToStop {
+		t.Fatalf("expected a running service, got %+v", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uc.Start(ctx)
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{Message: "after"}); !errors.Is(err, ErrDraining) {
+		t.Errorf("expected ErrDraining, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, _ = uc.Status(context.Background())
+		if status.SafeToStop || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !status.SafeToStop || !status.WALEmpty || !status.Flushed {
+		t.Fatalf("expected the drain to finish, got %+v", status)
+	}
+	if flushes.Load() != 1 {
+		t.Errorf("expected 1 flush, got %d", flushes.Load())
+	}
+	if len(repo.BufferedEvents) != 1 {
+		t.Errorf("expected only the event from before the drain buffered, got %d", len(repo.BufferedEvents))
+	}
+}
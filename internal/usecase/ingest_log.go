@@ -5,7 +5,6 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/V4T54L/watch-tower/internal/adapter/pii"
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/google/uuid"
 )
@@ -15,19 +14,51 @@ type IngestLogUseCase interface {
 	Ingest(ctx context.Context, event *domain.LogEvent) error
 }
 
+// Redactor removes PII from an event before it is buffered, see pii.Redactor and
+// pii.PolicyRedactor.
+type Redactor interface {
+	Redact(event *domain.LogEvent) error
+}
+
+// Enricher adds metadata about where an event came from before it is buffered, see
+// enrich.Pipeline.
+type Enricher interface {
+	Enrich(ctx context.Context, event *domain.LogEvent) error
+}
+
+// Orders of enrichment relative to PII redaction.
+const (
+	// EnrichBeforeRedaction enriches the event as received, and redacts what the
+	// enrichers added along with the rest of it.
+	EnrichBeforeRedaction = "before_redaction"
+	// EnrichAfterRedaction enriches the redacted event, so enrichers reading metadata
+	// fields see them redacted, and leaves what they added as it is.
+	EnrichAfterRedaction = "after_redaction"
+)
+
 // ingestLogUseCase handles the business logic for ingesting a log event.
 type ingestLogUseCase struct {
-	repo     domain.LogRepository
-	redactor *pii.Redactor
-	logger   *slog.Logger
+	repo           domain.LogRepository
+	redactor       Redactor
+	enricher       Enricher
+	afterRedaction bool
+	logger         *slog.Logger
 }
 
 // NewIngestLogUseCase creates a new IngestLogUseCase.
-func NewIngestLogUseCase(repo domain.LogRepository, redactor *pii.Redactor, logger *slog.Logger) IngestLogUseCase {
+func NewIngestLogUseCase(repo domain.LogRepository, redactor Redactor, logger *slog.Logger) IngestLogUseCase {
+	return NewEnrichedIngestLogUseCase(repo, redactor, nil, EnrichBeforeRedaction, logger)
+}
+
+// NewEnrichedIngestLogUseCase creates a new IngestLogUseCase that also enriches events,
+// in the given order relative to redaction. A nil enricher enriches nothing.
+func NewEnrichedIngestLogUseCase(repo domain.LogRepository, redactor Redactor, enricher Enricher, order string, logger *slog.Logger) IngestLogUseCase {
 	return &ingestLogUseCase{
-		repo:     repo,
-		redactor: redactor,
-		logger:   logger,
+		repo:           repo,
+		redactor:       redactor,
+		enricher:       enricher,
+		afterRedaction: order == EnrichAfterRedaction,
+		logger:         logger,
 	}
 }
 
@@ -35,15 +66,23 @@ func NewIngestLogUseCase(repo domain.LogRepository, redactor *pii.Redactor, logg
 func (uc *ingestLogUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
 	// 1. Enrich with server-side data
 	event.ReceivedAt = time.Now().UTC()
+	// Always overwritten, so clients cannot attribute events to another key.
+	event.APIKeyHash = domain.APIKeyHashFromContext(ctx)
 	if event.ID == "" {
 		event.ID = uuid.NewString()
 	}
 
-	// 2. Redact PII
+	// 2. Redact PII, enriching before or after
+	if !uc.afterRedaction {
+		uc.enrich(ctx, event)
+	}
 	if err := uc.redactor.Redact(event); err != nil {
 		uc.logger.Warn("failed to redact PII, proceeding with original event", "error", err, "event_id", event.ID)
 		// Non-fatal error, we still ingest the log
 	}
+	if uc.afterRedaction {
+		uc.enrich(ctx, event)
+	}
 
 	// 3. Buffer the log
 	if err := uc.repo.BufferLog(ctx, *event); err != nil {
@@ -54,3 +93,13 @@ func (uc *ingestLogUseCase) Ingest(ctx context.Context, event *domain.LogEvent)
 
 	return nil
 }
+
+func (uc *ingestLogUseCase) enrich(ctx context.Context, event *domain.LogEvent) {
+	if uc.enricher == nil {
+		return
+	}
+	if err := uc.enricher.Enrich(ctx, event); err != nil {
+		// Non-fatal too, the event is ingested without the metadata
+		uc.logger.Warn("failed to enrich log event", "error", err, "event_id", event.ID)
+	}
+}
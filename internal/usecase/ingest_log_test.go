@@ -5,8 +5,10 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"net/http"
 	"testing"
 
+	"github.com/V4T54L/watch-tower/internal/adapter/enrich"
 	"github.com/V4T54L/watch-tower/internal/adapter/pii"
 	"github.com/V4T54L/watch-tower/internal/domain"
 	"github.com/V4T54L/watch-tower/internal/domain/mocks"
@@ -40,6 +42,20 @@ func TestIngestLogUseCase_Ingest(t *testing.T) {
 		}
 	})
 
+	t.Run("API Key Attribution", func(t *testing.T) {
+		mockRepo := &mocks.MockLogRepository{}
+		uc := NewIngestLogUseCase(mockRepo, redactor, logger)
+
+		ctx := domain.WithAPIKeyHash(context.Background(), domain.HashAPIKey("agent"))
+		event := &domain.LogEvent{Message: "test message", APIKeyHash: domain.HashAPIKey("other")}
+		if err := uc.Ingest(ctx, event); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got := mockRepo.BufferedEvents[0].APIKeyHash; got != domain.HashAPIKey("agent") {
+			t.Errorf("expected the event to be attributed to the key it was sent with, got %q", got)
+		}
+	})
+
 	t.Run("Repository Error", func(t *testing.T) {
 		mockRepo := &mocks.MockLogRepository{
 			BufferErr: errors.New("buffer is full"),
@@ -78,4 +94,65 @@ func TestIngestLogUseCase_Ingest(t *testing.T) {
 			t.Errorf("expected metadata to be redacted: got %s, want %s", string(mockRepo.BufferedEvents[0].Metadata), expectedMetadata)
 		}
 	})
+
+	t.Run("Enrichment Order", func(t *testing.T) {
+		for order, want := range map[string]string{
+			EnrichBeforeRedaction: `{"email":"[REDACTED]"}`,
+			EnrichAfterRedaction:  `{"email":"ops@example.com"}`,
+		} {
+			mockRepo := &mocks.MockLogRepository{}
+			uc := NewEnrichedIngestLogUseCase(mockRepo, redactor, enricherFunc(func(_ context.Context, event *domain.LogEvent) error {
+				event.Metadata = []byte(`{"email":"ops@example.com"}`)
+				return nil
+			}), order, logger)
+
+			if err := uc.Ingest(context.Background(), &domain.LogEvent{Message: "user login"}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := string(mockRepo.BufferedEvents[0].Metadata); got != want {
+				t.Errorf("%s: expected metadata %s, got %s", order, want, got)
+			}
+		}
+	})
+}
+
+func TestEnrichedIngestLogUseCase_BehindMultiline(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	enricher, err := enrich.New([]enrich.Spec{{Type: "kubernetes"}})
+	if err != nil {
+		t.Fatalf("failed to create enricher: %v", err)
+	}
+	mockRepo := &mocks.MockLogRepository{}
+	rules, err := ParseMultilineRules(`[{"source": "k8s-*", "start_pattern": "^\\d{4}"}]`)
+	if err != nil {
+		t.Fatalf("ParseMultilineRules failed: %v", err)
+	}
+	uc := NewMultilineUseCase(NewEnrichedIngestLogUseCase(mockRepo, pii.NewRedactor(nil, logger), enricher, EnrichBeforeRedaction, logger), rules, logger)
+	request := func(pod string) context.Context {
+		return domain.WithRequestInfo(context.Background(), domain.RequestInfo{Header: http.Header{"X-Kubernetes-Pod": {pod}}})
+	}
+
+	// The first event is stitched across two requests and flushed by the second.
+	uc.Ingest(request("api-1"), &domain.LogEvent{Source: "k8s-api", Message: "2024 ERROR boom"})
+	uc.Ingest(request("api-2"), &domain.LogEvent{Source: "k8s-api", Message: "\tat a"})
+	uc.Ingest(request("api-2"), &domain.LogEvent{Source: "k8s-api", Message: "2024 INFO next"})
+	// The second is flushed on shutdown, without a request.
+	if err := uc.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(mockRepo.BufferedEvents) != 2 {
+		t.Fatalf("expected 2 stitched events, got %+v", mockRepo.BufferedEvents)
+	}
+	for i, want := range []string{`{"kubernetes":{"pod":"api-1"}}`, `{"kubernetes":{"pod":"api-2"}}`} {
+		if got := string(mockRepo.BufferedEvents[i].Metadata); got != want {
+			t.Errorf("event %d: expected the pod of its first line's request %s, got %s", i, want, got)
+		}
+	}
+}
+
+type enricherFunc func(ctx context.Context, event *domain.LogEvent) error
+
+func (f enricherFunc) Enrich(ctx context.Context, event *domain.LogEvent) error {
+	return f(ctx, event)
 }
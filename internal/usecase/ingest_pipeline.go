@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// EventPipeline transforms events one at a time, deciding whether to keep each, see
+// pipeline.Pipeline.
+type EventPipeline interface {
+	Process(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision)
+}
+
+// TransformIngestUseCase sits in front of the ingest use case and runs every event
+// through a pipeline before it is enriched, redacted and buffered, so that messages are
+// parsed, sampled and dropped once at ingestion rather than by every consumer. Events
+// the pipeline leaves out are accepted and discarded. Decisions are recorded with the
+// request's domain.DecisionRecorder, if any.
+type TransformIngestUseCase struct {
+	next     IngestLogUseCase
+	pipeline EventPipeline
+}
+
+// NewTransformIngestUseCase creates a new TransformIngestUseCase in front of next.
+func NewTransformIngestUseCase(next IngestLogUseCase, pipeline EventPipeline) *TransformIngestUseCase {
+	return &TransformIngestUseCase{next: next, pipeline: pipeline}
+}
+
+// Ingest transforms the event and passes it on, unless it was left out.
+func (uc *TransformIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	transformed, decision := uc.pipeline.Process(*event)
+	if decision.Action == domain.DecisionKept {
+		*event = transformed
+		if err := uc.next.Ingest(ctx, event); err != nil {
+			return err
+		}
+	}
+	if recorder := domain.DecisionRecorderFromContext(ctx); recorder != nil {
+		recorder.Record(decision.Action)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+)
+
+type pipelineFunc func(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision)
+
+func (f pipelineFunc) Process(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision) {
+	return f(event)
+}
+
+func TestTransformIngestUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := &mocks.MockLogRepository{}
+	next := NewIngestLogUseCase(mockRepo, pii.NewRedactor(nil, logger), logger)
+	uc := NewTransformIngestUseCase(next, pipelineFunc(func(event domain.LogEvent) (domain.LogEvent, domain.PipelineDecision) {
+		switch event.Level {
+		case "debug":
+			return event, domain.PipelineDecision{Action: domain.DecisionSampled, Rule: "debug"}
+		case "trace":
+			return event, domain.PipelineDecision{Action: domain.DecisionDropped, Rule: "trace"}
+		}
+		event.Metadata = []byte(`{"parsed":true}`)
+		return event, domain.PipelineDecision{Action: domain.DecisionKept}
+	}))
+
+	recorder := &domain.DecisionRecorder{}
+	ctx := domain.WithDecisionRecorder(context.Background(), recorder)
+	for _, level := range []string{"debug", "trace", "info"} {
+		if err := uc.Ingest(ctx, &domain.LogEvent{Level: level}); err != nil {
+			t.Fatalf("expected %s events to be accepted, got %v", level, err)
+		}
+	}
+	if len(mockRepo.BufferedEvents) != 1 || string(mockRepo.BufferedEvents[0].Metadata) != `{"parsed":true}` {
+		t.Errorf("expected only the transformed info event buffered, got %+v", mockRepo.BufferedEvents)
+	}
+	want := map[string]int{domain.DecisionKept: 1, domain.DecisionSampled: 1, domain.DecisionDropped: 1}
+	if got := recorder.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected decisions %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// FieldMapper maps the payload of an event sent with the API key keyHash onto its
+// fields, see fieldmap.Mapper.
+type FieldMapper interface {
+	Map(keyHash string, event *domain.LogEvent) error
+}
+
+// MapFieldsIngestUseCase sits in front of the ingest use case and maps every event by
+// the field mapping of its API key, taken from the context (see
+// domain.APIKeyHashFromContext), before passing it on.
+type MapFieldsIngestUseCase struct {
+	next   IngestLogUseCase
+	mapper FieldMapper
+}
+
+// NewMapFieldsIngestUseCase creates a new MapFieldsIngestUseCase in front of next.
+func NewMapFieldsIngestUseCase(next IngestLogUseCase, mapper FieldMapper) *MapFieldsIngestUseCase {
+	return &MapFieldsIngestUseCase{next: next, mapper: mapper}
+}
+
+// Ingest maps the event and passes it on.
+func (uc *MapFieldsIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	if err := uc.mapper.Map(domain.APIKeyHashFromContext(ctx), event); err != nil {
+		return err
+	}
+	return uc.next.Ingest(ctx, event)
+}
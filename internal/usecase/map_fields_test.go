@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+)
+
+type fieldMapperFunc func(keyHash string, event *domain.LogEvent) error
+
+func (f fieldMapperFunc) Map(keyHash string, event *domain.LogEvent) error {
+	return f(keyHash, event)
+}
+
+func TestMapFieldsIngestUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := &mocks.MockLogRepository{}
+	next := NewIngestLogUseCase(mockRepo, pii.NewRedactor(nil, logger), logger)
+	errMapping := errors.New("metadata is not a JSON object")
+	uc := NewMapFieldsIngestUseCase(next, fieldMapperFunc(func(keyHash string, event *domain.LogEvent) error {
+		if keyHash == "" {
+			return errMapping
+		}
+		event.Message = "mapped by " + keyHash
+		return nil
+	}))
+
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{}); err != errMapping {
+		t.Fatalf("expected the mapper's error, got %v", err)
+	}
+	ctx := domain.WithAPIKeyHash(context.Background(), "tenant")
+	if err := uc.Ingest(ctx, &domain.LogEvent{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockRepo.BufferedEvents) != 1 || mockRepo.BufferedEvents[0].Message != "mapped by tenant" {
+		t.Errorf("expected only the event mapped by the key in the context buffered, got %+v", mockRepo.BufferedEvents)
+	}
+}
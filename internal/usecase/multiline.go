@@ -0,0 +1,352 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+const (
+	defaultMultilineTimeout  = 2 * time.Second
+	defaultMultilineMaxLines = 500
+)
+
+// MultilineRule configures stitching for the sources it matches.
+type MultilineRule struct {
+	Source       string         // path.Match pattern for the event source, e.g. "billing-*".
+	StartPattern *regexp.Regexp // A message matching this starts a new event; others are continuations.
+	Timeout      time.Duration  // A pending event is flushed after receiving no lines for this long.
+	MaxLines     int            // A pending event is flushed once it holds this many lines.
+}
+
+// multilineRuleSpec is the JSON form of a MultilineRule, e.g.
+// {"source": "billing-*", "start_pattern": "^\\d{4}-\\d{2}-\\d{2}", "timeout": "2s", "max_lines": 500}
+type multilineRuleSpec struct {
+	Source       string `json:"source"`
+	StartPattern string `json:"start_pattern"`
+	Timeout      string `json:"timeout"`
+	MaxLines     int    `json:"max_lines"`
+}
+
+// ParseMultilineRules parses a JSON array of multiline rules. An empty string yields no rules.
+func ParseMultilineRules(s string) ([]MultilineRule, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var specs []multilineRuleSpec
+	if err := json.Unmarshal([]byte(s), &specs); err != nil {
+		return nil, fmt.Errorf("invalid multiline rules: %w", err)
+	}
+
+	rules := make([]MultilineRule, 0, len(specs))
+	for i, spec := range specs {
+		if _, err := path.Match(spec.Source, ""); err != nil || spec.Source == "" {
+			return nil, fmt.Errorf("multiline rule %d: invalid source pattern %q", i, spec.Source)
+		}
+		re, err := regexp.Compile(spec.StartPattern)
+		if err != nil || spec.StartPattern == "" {
+			return nil, fmt.Errorf("multiline rule %d: invalid start_pattern %q", i, spec.StartPattern)
+		}
+		rule := MultilineRule{Source: spec.Source, StartPattern: re, Timeout: defaultMultilineTimeout, MaxLines: defaultMultilineMaxLines}
+		if spec.Timeout != "" {
+			if rule.Timeout, err = time.ParseDuration(spec.Timeout); err != nil || rule.Timeout <= 0 {
+				return nil, fmt.Errorf("multiline rule %d: invalid timeout %q", i, spec.Timeout)
+			}
+		}
+		if spec.MaxLines > 0 {
+			rule.MaxLines = spec.MaxLines
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// pendingEvent is an event whose continuation lines are still being collected.
+type pendingEvent struct {
+	event    domain.LogEvent
+	raw      [][]byte
+	lines    int
+	lastLine time.Time
+	rule     *MultilineRule
+
+	// The request of the first line, which the stitched event is written downstream for.
+	requestInfo    domain.RequestInfo
+	hasRequestInfo bool
+	recorder       *domain.DecisionRecorder
+	requestDone    <-chan struct{} // Closed once the request has been answered.
+}
+
+func newPendingEvent(ctx context.Context, event *domain.LogEvent, rule *MultilineRule) *pendingEvent {
+	p := &pendingEvent{event: *event, rule: rule, recorder: domain.DecisionRecorderFromContext(ctx), requestDone: ctx.Done()}
+	p.requestInfo, p.hasRequestInfo = domain.RequestInfoFromContext(ctx)
+	p.add(event)
+	return p
+}
+
+// requestContext returns a context carrying the request of the first line, cancelled
+// with ctx, which is that of the request or routine flushing the event. Decisions are
+// only recorded while the first line's request is still being answered.
+func (p *pendingEvent) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel)
+	reqCtx = domain.WithAPIKeyHash(reqCtx, p.event.APIKeyHash)
+	if p.hasRequestInfo {
+		reqCtx = domain.WithRequestInfo(reqCtx, p.requestInfo)
+	}
+	if p.recorder != nil && !isDone(p.requestDone) {
+		reqCtx = domain.WithDecisionRecorder(reqCtx, p.recorder)
+	}
+	return reqCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// add appends a continuation line.
+func (p *pendingEvent) add(event *domain.LogEvent) {
+	if p.lines > 0 {
+		p.event.Message += "\n" + event.Message
+	}
+	if len(event.RawEvent) > 0 {
+		p.raw = append(p.raw, event.RawEvent)
+	}
+	p.lines++
+	p.lastLine = time.Now()
+}
+
+// stitched returns the event to send downstream.
+func (p *pendingEvent) stitched() domain.LogEvent {
+	event := p.event
+	// Keep every original payload: the raw events of all stitched lines as a JSON array.
+	if len(p.raw) > 1 {
+		event.RawEvent = append(append([]byte{'['}, bytes.Join(p.raw, []byte{','})...), ']')
+	}
+	return event
+}
+
+// multilineKey identifies a source of one API key; lines sent with different keys are
+// never stitched together.
+type multilineKey struct {
+	keyHash string
+	source  string
+}
+
+// multilineSource holds the pending event of one source. Its lock is never held while an
+// event is written downstream; writes of a source are serialized through flushing instead.
+type multilineSource struct {
+	mu       sync.Mutex
+	pending  *pendingEvent
+	flushing chan struct{} // Non-nil while an event of this source is written downstream.
+	removed  bool          // Set once Run dropped the idle source from the map.
+}
+
+// MultilineUseCase stitches consecutive events of the same source into one event, so that
+// stack traces sent one line per event arrive downstream as a single LogEvent. Events of
+// sources no rule matches are passed straight through.
+//
+// Stitched events are held in memory until the next start line, the timeout or the line
+// limit, so they are acknowledged to clients before they are buffered.
+type MultilineUseCase struct {
+	next   IngestLogUseCase
+	rules  []MultilineRule
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	sources map[multilineKey]*multilineSource
+}
+
+// NewMultilineUseCase creates a new MultilineUseCase in front of next.
+func NewMultilineUseCase(next IngestLogUseCase, rules []MultilineRule, logger *slog.Logger) *MultilineUseCase {
+	return &MultilineUseCase{
+		next:    next,
+		rules:   rules,
+		logger:  logger.With("component", "multiline"),
+		sources: make(map[multilineKey]*multilineSource),
+	}
+}
+
+// Ingest adds the event to the pending event of its source, flushing that event first if
+// this one starts a new entry. If a flush fails the pending event is left as it was and
+// the new event is not kept, so a client retry does not duplicate it.
+func (uc *MultilineUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	rule := uc.ruleFor(event.Source)
+	if rule == nil {
+		return uc.next.Ingest(ctx, event)
+	}
+
+	event.APIKeyHash = domain.APIKeyHashFromContext(ctx)
+	src, err := uc.lockSource(ctx, multilineKey{keyHash: event.APIKeyHash, source: event.Source})
+	if err != nil {
+		return err
+	}
+
+	p := src.pending
+	if p != nil && !rule.StartPattern.MatchString(event.Message) {
+		if p.lines+1 < rule.MaxLines {
+			p.add(event)
+			src.mu.Unlock()
+			return nil
+		}
+		full := *p
+		full.raw = append([][]byte(nil), p.raw...)
+		full.add(event)
+		return uc.flush(ctx, src, &full, p, nil)
+	}
+
+	started := newPendingEvent(ctx, event, rule)
+	if p == nil {
+		src.pending = started
+		src.mu.Unlock()
+		return nil
+	}
+	return uc.flush(ctx, src, p, p, started)
+}
+
+// lockSource returns the state of the source with its lock held, once no event of the
+// source is being written downstream.
+func (uc *MultilineUseCase) lockSource(ctx context.Context, source multilineKey) (*multilineSource, error) {
+	for {
+		uc.mu.Lock()
+		src := uc.sources[source]
+		if src == nil {
+			src = &multilineSource{}
+			uc.sources[source] = src
+		}
+		uc.mu.Unlock()
+
+		src.mu.Lock()
+		if src.removed {
+			src.mu.Unlock()
+			continue
+		}
+		if src.flushing == nil {
+			return src, nil
+		}
+		flushing := src.flushing
+		src.mu.Unlock()
+		select {
+		case <-flushing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// flush writes p downstream without holding the source lock, which it must be called
+// with and releases. Afterwards the source's pending event is onSuccess or onFailure.
+func (uc *MultilineUseCase) flush(ctx context.Context, src *multilineSource, p, onFailure, onSuccess *pendingEvent) error {
+	done := make(chan struct{})
+	src.pending = nil
+	src.flushing = done
+	src.mu.Unlock()
+
+	// Flushes are triggered by a later line, Run or Close, so the request of the first
+	// line is passed on from the pending event.
+	event := p.stitched()
+	reqCtx, cancel := p.requestContext(ctx)
+	err := uc.next.Ingest(reqCtx, &event)
+	cancel()
+
+	src.mu.Lock()
+	src.pending = onSuccess
+	if err != nil {
+		src.pending = onFailure
+	}
+	src.flushing = nil
+	close(done)
+	src.mu.Unlock()
+	return err
+}
+
+// Run flushes pending events that have timed out until the context is cancelled.
+func (uc *MultilineUseCase) Run(ctx context.Context) {
+	interval := defaultMultilineTimeout
+	for _, r := range uc.rules {
+		interval = min(interval, r.Timeout)
+	}
+	ticker := time.NewTicker(max(interval/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for source, src := range uc.snapshot() {
+				src.mu.Lock()
+				p := src.pending
+				if src.flushing != nil || p == nil || now.Sub(p.lastLine) < p.rule.Timeout {
+					src.mu.Unlock()
+					continue
+				}
+				if err := uc.flush(ctx, src, p, p, nil); err != nil {
+					uc.logger.Error("failed to flush multiline event, retrying", "error", err, "source", source.source)
+				}
+			}
+		}
+	}
+}
+
+// snapshot drops idle sources from the map and returns the remaining ones.
+func (uc *MultilineUseCase) snapshot() map[multilineKey]*multilineSource {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	sources := make(map[multilineKey]*multilineSource, len(uc.sources))
+	for source, src := range uc.sources {
+		src.mu.Lock()
+		if src.pending == nil && src.flushing == nil {
+			src.removed = true
+			delete(uc.sources, source)
+		} else {
+			sources[source] = src
+		}
+		src.mu.Unlock()
+	}
+	return sources
+}
+
+// Close flushes every pending event. It is called on shutdown after the listeners stopped.
+func (uc *MultilineUseCase) Close(ctx context.Context) error {
+	var firstErr error
+	for source := range uc.snapshot() {
+		src, err := uc.lockSource(ctx, source)
+		if err != nil {
+			return err
+		}
+		if src.pending == nil {
+			src.mu.Unlock()
+			continue
+		}
+		if err := uc.flush(ctx, src, src.pending, src.pending, nil); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (uc *MultilineUseCase) ruleFor(source string) *MultilineRule {
+	for i := range uc.rules {
+		if ok, _ := path.Match(uc.rules[i].Source, source); ok {
+			return &uc.rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+type recordingIngestUseCase struct {
+	mu     sync.Mutex
+	events []domain.LogEvent
+	err    error
+}
+
+func (r *recordingIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *recordingIngestUseCase) snapshot() []domain.LogEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.LogEvent(nil), r.events...)
+}
+
+func TestMultilineUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules, err := ParseMultilineRules(`[{"source": "java-*", "start_pattern": "^\\d{4}-\\d{2}-\\d{2}", "timeout": "50ms", "max_lines": 3}]`)
+	if err != nil {
+		t.Fatalf("ParseMultilineRules failed: %v", err)
+	}
+	ctx := context.Background()
+
+	ingest := func(uc *MultilineUseCase, source string, lines ...string) {
+		t.Helper()
+		for _, l := range lines {
+			if err := uc.Ingest(ctx, &domain.LogEvent{Source: source, Message: l, RawEvent: []byte(`"` + l + `"`)}); err != nil {
+				t.Fatalf("Ingest failed: %v", err)
+			}
+		}
+	}
+
+	t.Run("Stitches continuation lines", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ingest(uc, "java-api",
+			"2024-05-01 ERROR boom",
+			"java.lang.IllegalStateException: boom",
+			"2024-05-01 INFO recovered",
+		)
+
+		got := next.snapshot()
+		if len(got) != 1 || got[0].Message != "2024-05-01 ERROR boom\njava.lang.IllegalStateException: boom" {
+			t.Fatalf("unexpected flushed events: %+v", got)
+		}
+		if string(got[0].RawEvent) != `["2024-05-01 ERROR boom","java.lang.IllegalStateException: boom"]` {
+			t.Errorf("unexpected raw event %s", got[0].RawEvent)
+		}
+		if err := uc.Close(ctx); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if got := next.snapshot(); len(got) != 2 || got[1].Message != "2024-05-01 INFO recovered" {
+			t.Errorf("expected Close to flush the pending event, got %+v", got)
+		}
+	})
+
+	t.Run("Max lines and pass-through", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ingest(uc, "java-api", "2024-05-01 ERROR boom", "\tat a", "\tat b", "\tat c")
+		ingest(uc, "nginx", "GET /", "GET /health")
+
+		got := next.snapshot()
+		if len(got) != 3 || got[0].Message != "2024-05-01 ERROR boom\n\tat a\n\tat b" || got[1].Message != "GET /" {
+			t.Fatalf("unexpected flushed events: %+v", got)
+		}
+	})
+
+	t.Run("Keeps lines of different API keys apart", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ctxA := domain.WithAPIKeyHash(ctx, domain.HashAPIKey("a"))
+		ctxB := domain.WithAPIKeyHash(ctx, domain.HashAPIKey("b"))
+		for _, l := range []struct {
+			ctx context.Context
+			msg string
+		}{{ctxA, "2024-05-01 ERROR a"}, {ctxB, "2024-05-01 ERROR b"}, {ctxB, "\tat b"}, {ctxA, "\tat a"}} {
+			if err := uc.Ingest(l.ctx, &domain.LogEvent{Source: "java-api", Message: l.msg}); err != nil {
+				t.Fatalf("Ingest failed: %v", err)
+			}
+		}
+		if err := uc.Close(ctx); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		byKey := map[string]string{}
+		for _, e := range next.snapshot() {
+			byKey[e.APIKeyHash] = e.Message
+		}
+		if byKey[domain.HashAPIKey("a")] != "2024-05-01 ERROR a\n\tat a" || byKey[domain.HashAPIKey("b")] != "2024-05-01 ERROR b\n\tat b" {
+			t.Errorf("unexpected flushed events: %+v", next.snapshot())
+		}
+	})
+
+	t.Run("Timeout flush", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go uc.Run(runCtx)
+
+		ingest(uc, "java-worker", "2024-05-01 WARN slow", "\tat x")
+		deadline := time.Now().Add(time.Second)
+		for len(next.snapshot()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("expected pending event to be flushed after the timeout")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("Failed flush keeps pending event", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ingest(uc, "java-api", "2024-05-01 ERROR boom")
+
+		next.err = errors.New("buffer full")
+		if err := uc.Ingest(ctx, &domain.LogEvent{Source: "java-api", Message: "2024-05-01 INFO next"}); err == nil {
+			t.Fatal("expected flush error to be returned")
+		}
+		next.err = nil
+		ingest(uc, "java-api", "2024-05-01 INFO next")
+		if got := next.snapshot(); len(got) != 1 || got[0].Message != "2024-05-01 ERROR boom" {
+			t.Errorf("unexpected flushed events: %+v", got)
+		}
+	})
+
+	t.Run("Failed max lines flush is retried without duplicates", func(t *testing.T) {
+		next := &recordingIngestUseCase{}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ingest(uc, "java-api", "2024-05-01 ERROR boom", "\tat a")
+
+		next.err = errors.New("buffer full")
+		if err := uc.Ingest(ctx, &domain.LogEvent{Source: "java-api", Message: "\tat b"}); err == nil {
+			t.Fatal("expected flush error to be returned")
+		}
+		next.err = nil
+		ingest(uc, "java-api", "\tat b")
+		if got := next.snapshot(); len(got) != 1 || got[0].Message != "2024-05-01 ERROR boom\n\tat a\n\tat b" {
+			t.Errorf("unexpected flushed events: %+v", got)
+		}
+	})
+
+	t.Run("Slow flush does not block other sources", func(t *testing.T) {
+		release := make(chan struct{})
+		next := &blockingIngestUseCase{source: "java-slow", release: release}
+		uc := NewMultilineUseCase(next, rules, logger)
+		ingest(uc, "java-slow", "2024-05-01 ERROR boom")
+		go uc.Ingest(ctx, &domain.LogEvent{Source: "java-slow", Message: "2024-05-01 INFO next"})
+
+		done := make(chan error, 1)
+		go func() { done <- uc.Ingest(ctx, &domain.LogEvent{Source: "java-fast", Message: "2024-05-01 INFO fast"}) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Ingest failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("ingest of another source blocked behind a slow flush")
+		}
+		close(release)
+	})
+}
+
+// requestIngestUseCase records the client of the request each event is written for, and
+// whether decisions would be recorded for it.
+type requestIngestUseCase struct {
+	mu        sync.Mutex
+	clients   []string
+	recorders []bool
+}
+
+func (r *requestIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	info, _ := domain.RequestInfoFromContext(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients = append(r.clients, info.ClientIP)
+	r.recorders = append(r.recorders, domain.DecisionRecorderFromContext(ctx) != nil)
+	return nil
+}
+
+func TestMultilineUseCase_FlushesWithFirstLineRequest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rules, err := ParseMultilineRules(`[{"source": "java-*", "start_pattern": "^\\d{4}", "timeout": "20ms"}]`)
+	if err != nil {
+		t.Fatalf("ParseMultilineRules failed: %v", err)
+	}
+	next := &requestIngestUseCase{}
+	uc := NewMultilineUseCase(next, rules, logger)
+	request := func(clientIP string) (context.Context, context.CancelFunc) {
+		ctx := domain.WithRequestInfo(context.Background(), domain.RequestInfo{ClientIP: clientIP})
+		ctx = domain.WithDecisionRecorder(ctx, &domain.DecisionRecorder{})
+		return context.WithCancel(ctx)
+	}
+
+	first, answered := request("10.0.0.1")
+	uc.Ingest(first, &domain.LogEvent{Source: "java-api", Message: "2024 ERROR boom"})
+	second, answeredSecond := request("10.0.0.2")
+	uc.Ingest(second, &domain.LogEvent{Source: "java-api", Message: "\tat a"})
+	// The start of the next event flushes the first one while its request is still live.
+	uc.Ingest(second, &domain.LogEvent{Source: "java-api", Message: "2024 INFO next"})
+	answered()
+	answeredSecond()
+
+	third, answered := request("10.0.0.3")
+	uc.Ingest(third, &domain.LogEvent{Source: "java-web", Message: "2024 ERROR boom"})
+	answered()
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go uc.Run(runCtx)
+	deadline := time.Now().Add(time.Second)
+	for {
+		next.mu.Lock()
+		n := len(next.clients)
+		next.mu.Unlock()
+		if n == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	next.mu.Lock()
+	defer next.mu.Unlock()
+	// The timeout flushes run in no particular order.
+	clients := map[string]bool{}
+	for _, c := range next.clients {
+		clients[c] = true
+	}
+	if len(next.clients) != 3 || next.clients[0] != "10.0.0.1" || !clients["10.0.0.2"] || !clients["10.0.0.3"] {
+		t.Fatalf("expected every event written for the request of its first line, got %v", next.clients)
+	}
+	if !next.recorders[0] || next.recorders[1] || next.recorders[2] {
+		t.Errorf("expected decisions recorded only while the first line's request is live, got %v", next.recorders)
+	}
+}
+
+// blockingIngestUseCase blocks writes of one source until released.
+type blockingIngestUseCase struct {
+	source  string
+	release chan struct{}
+}
+
+func (b *blockingIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	if event.Source == b.source {
+		<-b.release
+	}
+	return nil
+}
+
+func TestParseMultilineRules_Errors(t *testing.T) {
+	for _, s := range []string{
+		`not json`,
+		`[{"source": "a", "start_pattern": "("}]`,
+		`[{"source": "", "start_pattern": "^x"}]`,
+		`[{"source": "a", "start_pattern": "^x", "timeout": "soon"}]`,
+	} {
+		if _, err := ParseMultilineRules(s); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+}
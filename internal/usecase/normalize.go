@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// EventNormalizer gives an event received at receivedAt a canonical time and level, see
+// normalize.Normalizer. It returns an error for events that must be rejected.
+type EventNormalizer interface {
+	Normalize(event *domain.LogEvent, receivedAt time.Time) error
+}
+
+// NormalizeIngestUseCase sits in front of the ingest use case and normalizes every event
+// before passing it on. Events the normalizer rejects are not passed on.
+type NormalizeIngestUseCase struct {
+	next       IngestLogUseCase
+	normalizer EventNormalizer
+	now        func() time.Time
+}
+
+// NewNormalizeIngestUseCase creates a new NormalizeIngestUseCase in front of next.
+func NewNormalizeIngestUseCase(next IngestLogUseCase, normalizer EventNormalizer) *NormalizeIngestUseCase {
+	return &NormalizeIngestUseCase{next: next, normalizer: normalizer, now: time.Now}
+}
+
+// Ingest normalizes the event and passes it on.
+func (uc *NormalizeIngestUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	if err := uc.normalizer.Normalize(event, uc.now().UTC()); err != nil {
+		return err
+	}
+	return uc.next.Ingest(ctx, event)
+}
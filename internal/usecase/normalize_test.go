@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/adapter/pii"
+	"github.com/V4T54L/watch-tower/internal/domain"
+	"github.com/V4T54L/watch-tower/internal/domain/mocks"
+)
+
+type normalizerFunc func(event *domain.LogEvent, receivedAt time.Time) error
+
+func (f normalizerFunc) Normalize(event *domain.LogEvent, receivedAt time.Time) error {
+	return f(event, receivedAt)
+}
+
+func TestNormalizeIngestUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := &mocks.MockLogRepository{}
+	next := NewIngestLogUseCase(mockRepo, pii.NewRedactor(nil, logger), logger)
+	uc := NewNormalizeIngestUseCase(next, normalizerFunc(func(event *domain.LogEvent, _ time.Time) error {
+		if event.Level == "" {
+			return domain.ErrEventTimeOutOfRange
+		}
+		event.Level = domain.LevelWarn
+		return nil
+	}))
+
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{}); err != domain.ErrEventTimeOutOfRange {
+		t.Fatalf("expected the normalizer's error, got %v", err)
+	}
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{Level: "WARNING"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockRepo.BufferedEvents) != 1 || mockRepo.BufferedEvents[0].Level != domain.LevelWarn {
+		t.Errorf("expected only the normalized event buffered, got %+v", mockRepo.BufferedEvents)
+	}
+}
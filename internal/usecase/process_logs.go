@@ -2,6 +2,8 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"math"
 	"time"
@@ -11,68 +13,350 @@ import (
 
 const (
 	defaultBatchSize = 1000
+	// pausePollInterval is how long ProcessBatch waits while the group is paused, in place
+	// of the blocking read it would otherwise do.
+	pausePollInterval = time.Second
 )
 
 // ProcessLogsUseCase orchestrates reading logs from a buffer and writing to a sink.
 type ProcessLogsUseCase struct {
 	bufferRepo   domain.LogRepository
 	sinkRepo     domain.LogRepository
+	spool        domain.WALRepository
+	spooled      bool // Whether the spool may hold events; it is replayed only then.
 	logger       *slog.Logger
 	group        string
 	consumer     string
 	retryCount   int
 	retryBackoff time.Duration
+
+	claimer       StaleLogClaimer // Nil disables ReclaimStale.
+	claimMinIdle  time.Duration
+	maxDeliveries int
+
+	control domain.ConsumerControlRepository // Nil never pauses.
+	paused  bool
+
+	batchSize     int // Events ProcessBatch reads next, kept between minBatch and maxBatch.
+	minBatch      int
+	maxBatch      int
+	latencyTarget time.Duration // 0 keeps the batch size fixed.
+
+	transformer EventTransformer // Nil writes events as they were read.
+}
+
+// EventTransformer rewrites a batch before it is written to the sink, dropping the events
+// it leaves out. It must not modify the batch it is given.
+type EventTransformer interface {
+	Transform(events []domain.LogEvent) []domain.LogEvent
+}
+
+// StaleLogClaimer takes over messages left pending by consumers that stopped before
+// acknowledging them, setting each event's DeliveryCount.
+type StaleLogClaimer interface {
+	ClaimStaleLogs(ctx context.Context, group, consumer string, minIdle time.Duration, count int) ([]domain.LogEvent, error)
 }
 
 // NewProcessLogsUseCase creates a new ProcessLogsUseCase.
-func NewProcessLogsUseCase(bufferRepo, sinkRepo domain.LogRepository, logger *slog.Logger, group, consumer string, retryCount int, retryBackoff time.Duration) *ProcessLogsUseCase {
+// The spool is optional; pass nil to move batches the sink rejects straight to the DLQ.
+func NewProcessLogsUseCase(bufferRepo, sinkRepo domain.LogRepository, spool domain.WALRepository, logger *slog.Logger, group, consumer string, retryCount int, retryBackoff time.Duration) *ProcessLogsUseCase {
 	return &ProcessLogsUseCase{
 		bufferRepo:   bufferRepo,
 		sinkRepo:     sinkRepo,
+		spool:        spool,
+		spooled:      spool != nil, // It may hold events from before a restart.
 		logger:       logger.With("component", "process_logs_usecase"),
 		group:        group,
 		consumer:     consumer,
 		retryCount:   retryCount,
 		retryBackoff: retryBackoff,
+		batchSize:    defaultBatchSize,
 	}
 }
 
 // ProcessBatch reads a batch of logs, attempts to write them to the sink with retries,
-// moves to DLQ on failure, and acknowledges on success.
+// spools or moves to DLQ on failure, and acknowledges on success. Spooled events are
+// written once the sink accepts writes again.
 func (u *ProcessLogsUseCase) ProcessBatch(ctx context.Context) (int, error) {
-	events, err := u.bufferRepo.ReadLogBatch(ctx, u.group, u.consumer, defaultBatchSize)
+	if u.isPaused(ctx) {
+		select {
+		case <-time.After(pausePollInterval):
+		case <-ctx.Done():
+		}
+		return 0, nil
+	}
+	events, err := u.bufferRepo.ReadLogBatch(ctx, u.group, u.consumer, u.batchSize)
 	if err != nil {
 		u.logger.Error("Failed to read log batch from buffer", "error", err)
 		return 0, err
 	}
 
 	if len(events) == 0 {
+		u.replaySpool(ctx)
 		return 0, nil
 	}
 
 	u.logger.Debug("Read batch from buffer", "count", len(events))
+	return u.processEvents(ctx, events)
+}
+
+// EnablePauseControl makes ProcessBatch and ReclaimStale do nothing while the group is
+// paused through control. Messages stay pending with this consumer meanwhile.
+func (u *ProcessLogsUseCase) EnablePauseControl(control domain.ConsumerControlRepository) {
+	u.control = control
+}
+
+// isPaused reports whether the group is paused. If that cannot be checked, processing
+// goes on; the buffer is then most likely unreachable too.
+func (u *ProcessLogsUseCase) isPaused(ctx context.Context) bool {
+	if u.control == nil {
+		return false
+	}
+	pause, err := u.control.GetGroupPause(ctx, u.group)
+	if err != nil {
+		u.logger.Warn("Failed to check whether the consumer group is paused", "error", err)
+		return false
+	}
+	if pause.Paused != u.paused {
+		u.paused = pause.Paused
+		if u.paused {
+			u.logger.Info("Consumer group paused, not processing batches", "paused_at", pause.PausedAt)
+		} else {
+			u.logger.Info("Consumer group resumed")
+		}
+	}
+	return u.paused
+}
+
+// EnableAdaptiveBatching makes ProcessBatch read between minSize and maxSize events at a
+// time. The batch size doubles while batches come back full, which means the buffer has
+// a backlog, and halves when a sink write fails or takes longer than latencyTarget.
+func (u *ProcessLogsUseCase) EnableAdaptiveBatching(minSize, maxSize int, latencyTarget time.Duration) {
+	u.minBatch = max(minSize, 1)
+	u.maxBatch = max(maxSize, u.minBatch)
+	u.latencyTarget = latencyTarget
+	u.batchSize = min(max(u.batchSize, u.minBatch), u.maxBatch)
+}
+
+// adaptBatchSize adjusts the batch size after a batch of read events was written in
+// writeTime.
+func (u *ProcessLogsUseCase) adaptBatchSize(read int, writeTime time.Duration, writeErr error) {
+	if u.latencyTarget <= 0 {
+		return
+	}
+	prev := u.batchSize
+	switch {
+	case writeErr != nil || writeTime > u.latencyTarget:
+		u.batchSize = max(u.batchSize/2, u.minBatch)
+	case read >= u.batchSize:
+		u.batchSize = min(u.batchSize*2, u.maxBatch)
+	}
+	if u.batchSize != prev {
+		u.logger.Debug("Adjusted batch size", "from", prev, "to", u.batchSize, "write_time", writeTime)
+	}
+}
+
+// EnableTransform makes processed batches go through transformer before they are written.
+// Events it drops are acknowledged without being written.
+func (u *ProcessLogsUseCase) EnableTransform(transformer EventTransformer) {
+	u.transformer = transformer
+}
+
+// EnableStaleReclaim makes ReclaimStale claim messages that have been pending for at
+// least minIdle. Messages delivered more than maxDeliveries times are moved to the DLQ
+// instead of being retried; 0 retries them indefinitely.
+func (u *ProcessLogsUseCase) EnableStaleReclaim(claimer StaleLogClaimer, minIdle time.Duration, maxDeliveries int) {
+	u.claimer = claimer
+	u.claimMinIdle = minIdle
+	u.maxDeliveries = maxDeliveries
+}
 
-	err = u.writeWithRetry(ctx, events)
+// ReclaimStale claims a batch of messages other consumers left pending and processes
+// them like a freshly read batch.
+func (u *ProcessLogsUseCase) ReclaimStale(ctx context.Context) (int, error) {
+	if u.claimer == nil || u.isPaused(ctx) {
+		return 0, nil
+	}
+	events, err := u.claimer.ClaimStaleLogs(ctx, u.group, u.consumer, u.claimMinIdle, defaultBatchSize)
 	if err != nil {
-		u.logger.Error("Failed to write batch to sink after all retries, moving to DLQ", "error", err, "batch_size", len(events))
-		if dlqErr := u.bufferRepo.MoveToDLQ(ctx, events); dlqErr != nil {
+		u.logger.Error("Failed to claim stale messages", "error", err)
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var retry, exhausted []domain.LogEvent
+	for _, event := range events {
+		if u.maxDeliveries > 0 && event.DeliveryCount > int64(u.maxDeliveries) {
+			exhausted = append(exhausted, event)
+		} else {
+			retry = append(retry, event)
+		}
+	}
+	u.logger.Info("Claimed stale messages", "count", len(events), "exhausted_count", len(exhausted))
+
+	if len(exhausted) > 0 {
+		u.logger.Warn("Moving messages delivered too many times to DLQ", "count", len(exhausted), "max_deliveries", u.maxDeliveries)
+		var deliveries int64
+		for _, event := range exhausted {
+			deliveries = max(deliveries, event.DeliveryCount)
+		}
+		failure := u.dlqFailure(domain.DLQCauseMaxDeliveries, fmt.Sprintf("delivered more than %d times without being acknowledged", u.maxDeliveries), int(deliveries))
+		if err := u.bufferRepo.MoveToDLQ(ctx, exhausted, failure); err != nil {
+			u.logger.Error("CRITICAL: Failed to move events to DLQ. Events will be re-processed.", "error", err)
+			return 0, err
+		}
+		if err := u.bufferRepo.AcknowledgeLogs(ctx, u.group, messageIDs(exhausted)...); err != nil {
+			u.logger.Error("Failed to acknowledge processed logs", "error", err)
+			return 0, err
+		}
+	}
+	if len(retry) == 0 {
+		return len(exhausted), nil
+	}
+	processed, err := u.processEvents(ctx, retry)
+	return len(exhausted) + processed, err
+}
+
+// processEvents writes events read from the buffer to the sink, spooling them or moving
+// them to the DLQ if it keeps failing, and acknowledges them.
+func (u *ProcessLogsUseCase) processEvents(ctx context.Context, read []domain.LogEvent) (int, error) {
+	events := read
+	if u.transformer != nil {
+		events = u.transformer.Transform(read)
+		if dropped := len(read) - len(events); dropped > 0 {
+			u.logger.Debug("Pipeline dropped events", "count", dropped)
+		}
+	}
+
+	finalStatus := "SINKED"
+	var err error
+	if len(events) == 0 {
+		finalStatus = "DROPPED"
+	} else {
+		start := time.Now()
+		err = u.writeWithRetry(ctx, events)
+		u.adaptBatchSize(len(read), time.Since(start), err)
+	}
+	if errors.Is(err, domain.ErrEventRejected) {
+		// A single bad event fails the whole batch, so the rest is written without it.
+		rejected, bisectErr := u.bisect(ctx, events, err)
+		if bisectErr == nil {
+			u.logger.Warn("Sink rejected events, moving only those to DLQ", "rejected_count", len(rejected), "batch_size", len(events))
+			for _, r := range rejected {
+				if dlqErr := u.bufferRepo.MoveToDLQ(ctx, []domain.LogEvent{r.event}, u.dlqFailure(domain.DLQCauseRejected, r.err.Error(), 1)); dlqErr != nil {
+					u.logger.Error("CRITICAL: Failed to move events to DLQ. Events will be re-processed.", "error", dlqErr)
+					return 0, dlqErr
+				}
+			}
+			finalStatus = "PARTIALLY_DLQED"
+		}
+		err = bisectErr
+	}
+	if err == nil {
+		u.replaySpool(ctx)
+	} else if spoolErr := u.spoolBatch(ctx, events); spoolErr == nil {
+		u.logger.Warn("Failed to write batch to sink after all retries, spooled to disk", "error", err, "batch_size", len(events))
+		finalStatus = "SPOOLED"
+	} else {
+		finalStatus = "DLQED"
+		u.logger.Error("Failed to write batch to sink after all retries, moving to DLQ", "error", err, "spool_error", spoolErr, "batch_size", len(events))
+		if dlqErr := u.bufferRepo.MoveToDLQ(ctx, events, u.dlqFailure(domain.DLQCauseSinkFailure, err.Error(), u.retryCount)); dlqErr != nil {
 			u.logger.Error("CRITICAL: Failed to move events to DLQ. Events will be re-processed.", "error", dlqErr)
 			return 0, dlqErr
 		}
 	}
 
-	messageIDs := make([]string, len(events))
+	if ackErr := u.bufferRepo.AcknowledgeLogs(ctx, u.group, messageIDs(read)...); ackErr != nil {
+		u.logger.Error("Failed to acknowledge processed logs", "error", ackErr)
+		return 0, ackErr
+	}
+
+	u.logger.Info("Successfully processed batch", "count", len(read), "written_count", len(events), "final_status", finalStatus)
+	return len(read), nil
+}
+
+// rejectedEvent is an event the sink rejected on its own.
+type rejectedEvent struct {
+	event domain.LogEvent
+	err   error
+}
+
+// bisect writes the events of a batch the sink failed with err in halves, down to single
+// events, and returns the events it rejects. It gives up at the first failure that is
+// not a rejection; events written until then are upserted again later.
+func (u *ProcessLogsUseCase) bisect(ctx context.Context, events []domain.LogEvent, err error) ([]rejectedEvent, error) {
+	if !errors.Is(err, domain.ErrEventRejected) {
+		return nil, err
+	}
+	if len(events) == 1 {
+		return []rejectedEvent{{event: events[0], err: err}}, nil
+	}
+	var rejected []rejectedEvent
+	mid := len(events) / 2
+	for _, half := range [][]domain.LogEvent{events[:mid], events[mid:]} {
+		r, err := u.bisect(ctx, half, u.sinkRepo.WriteLogBatch(ctx, half))
+		if err != nil {
+			return nil, err
+		}
+		rejected = append(rejected, r...)
+	}
+	return rejected, nil
+}
+
+func (u *ProcessLogsUseCase) dlqFailure(cause, reason string, attempts int) domain.DLQFailure {
+	return domain.DLQFailure{Cause: cause, Reason: reason, Attempts: attempts, Consumer: u.consumer, FailedAt: time.Now().UTC()}
+}
+
+func messageIDs(events []domain.LogEvent) []string {
+	ids := make([]string, len(events))
 	for i, event := range events {
-		messageIDs[i] = event.StreamMessageID
+		ids[i] = event.StreamMessageID
 	}
+	return ids
+}
 
-	if ackErr := u.bufferRepo.AcknowledgeLogs(ctx, u.group, messageIDs...); ackErr != nil {
-		u.logger.Error("Failed to acknowledge processed logs", "error", ackErr)
-		return 0, ackErr
+// errNoSpool is returned by spoolBatch when no spool is configured.
+var errNoSpool = errors.New("no spool configured")
+
+// spoolBatch writes a batch the sink rejected to the spool. A partially spooled batch is
+// reported as failed and moved to the DLQ as a whole; the sink's upsert on event_id
+// absorbs the duplicates if the spooled part is written later.
+func (u *ProcessLogsUseCase) spoolBatch(ctx context.Context, events []domain.LogEvent) error {
+	if u.spool == nil {
+		return errNoSpool
 	}
+	u.spooled = true
+	for _, event := range events {
+		if err := u.spool.Write(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	u.logger.Info("Successfully processed batch", "count", len(events), "final_status", map[bool]string{true: "SINKED", false: "DLQED"}[err == nil])
-	return len(events), nil
+// replaySpool writes spooled events to the sink.
+func (u *ProcessLogsUseCase) replaySpool(ctx context.Context) {
+	if !u.spooled {
+		return
+	}
+	var replayed int
+	err := u.spool.ReplayBatch(ctx, defaultBatchSize, func(events []domain.LogEvent) error {
+		if err := u.sinkRepo.WriteLogBatch(ctx, events); err != nil {
+			return err
+		}
+		replayed += len(events)
+		return nil
+	})
+	if err != nil {
+		u.logger.Warn("Sink still failing, keeping spooled events", "replayed_count", replayed, "error", err)
+		return
+	}
+	u.spooled = false
+	if replayed > 0 {
+		u.logger.Info("Wrote spooled events to sink", "count", replayed)
+	}
 }
 
 func (u *ProcessLogsUseCase) writeWithRetry(ctx context.Context, events []domain.LogEvent) error {
@@ -85,7 +369,8 @@ func (u *ProcessLogsUseCase) writeWithRetry(ctx context.Context, events []domain
 		}
 		lastErr = err
 
-		if i == u.retryCount-1 || ctx.Err() != nil {
+		// Retrying a rejected batch fails the same way.
+		if i == u.retryCount-1 || ctx.Err() != nil || errors.Is(err, domain.ErrEventRejected) {
 			break
 		}
 
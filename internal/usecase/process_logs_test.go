@@ -3,8 +3,10 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"strconv"
 	"testing"
 	"time"
 
@@ -22,7 +24,7 @@ func TestProcessLogsUseCase_ProcessBatch(t *testing.T) {
 	t.Run("Successful Processing", func(t *testing.T) {
 		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: testEvents}
 		sinkRepo := &mocks.MockLogRepository{}
-		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, logger, "group", "consumer", 3, 1*time.Millisecond)
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, 1*time.Millisecond)
 
 		count, err := uc.ProcessBatch(context.Background())
 
@@ -46,7 +48,7 @@ func TestProcessLogsUseCase_ProcessBatch(t *testing.T) {
 	t.Run("Sink Failure with Retry and DLQ", func(t *testing.T) {
 		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: testEvents}
 		sinkRepo := &mocks.MockLogRepository{WriteErr: errors.New("database is down")}
-		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, logger, "group", "consumer", 2, 1*time.Millisecond)
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 2, 1*time.Millisecond)
 
 		count, err := uc.ProcessBatch(context.Background())
 
@@ -71,7 +73,7 @@ func TestProcessLogsUseCase_ProcessBatch(t *testing.T) {
 	t.Run("Buffer Read Error", func(t *testing.T) {
 		bufferRepo := &mocks.MockLogRepository{ReadErr: errors.New("redis connection failed")}
 		sinkRepo := &mocks.MockLogRepository{}
-		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, logger, "group", "consumer", 3, 1*time.Millisecond)
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, 1*time.Millisecond)
 
 		count, err := uc.ProcessBatch(context.Background())
 
@@ -86,7 +88,7 @@ func TestProcessLogsUseCase_ProcessBatch(t *testing.T) {
 	t.Run("No Events to Process", func(t *testing.T) {
 		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: []domain.LogEvent{}}
 		sinkRepo := &mocks.MockLogRepository{}
-		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, logger, "group", "consumer", 3, 1*time.Millisecond)
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, 1*time.Millisecond)
 
 		count, err := uc.ProcessBatch(context.Background())
 
@@ -101,3 +103,249 @@ func TestProcessLogsUseCase_ProcessBatch(t *testing.T) {
 		}
 	})
 }
+
+func TestProcessLogsUseCase_Spool(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	testEvents := []domain.LogEvent{
+		{ID: "1", StreamMessageID: "msg1", Message: "event 1"},
+		{ID: "2", StreamMessageID: "msg2", Message: "event 2"},
+	}
+
+	bufferRepo := &mocks.MockLogRepository{ReadBatchResult: testEvents}
+	sinkRepo := &mocks.MockLogRepository{WriteErr: errors.New("database is down")}
+	spool := &mocks.MockWALRepository{}
+	uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, spool, logger, "group", "consumer", 2, 1*time.Millisecond)
+
+	if _, err := uc.ProcessBatch(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(spool.Events) != 2 || len(bufferRepo.DLQEvents) != 0 || len(bufferRepo.AckedMessageIDs) != 2 {
+		t.Fatalf("expected the batch spooled and acked, got %d spooled, %d in DLQ, %d acked", len(spool.Events), len(bufferRepo.DLQEvents), len(bufferRepo.AckedMessageIDs))
+	}
+
+	// Nothing is written while the sink is down.
+	bufferRepo.ReadBatchResult = nil
+	uc.ProcessBatch(context.Background())
+	if len(spool.Events) != 2 {
+		t.Fatalf("expected spooled events to be kept, got %d", len(spool.Events))
+	}
+
+	sinkRepo.WriteErr = nil
+	uc.ProcessBatch(context.Background())
+	if len(spool.Events) != 0 || len(sinkRepo.WrittenEvents) != 2 {
+		t.Errorf("expected spooled events written to the sink, got %d left, %d written", len(spool.Events), len(sinkRepo.WrittenEvents))
+	}
+
+	t.Run("Full spool falls back to DLQ", func(t *testing.T) {
+		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: testEvents}
+		sinkRepo := &mocks.MockLogRepository{WriteErr: errors.New("database is down")}
+		spool := &mocks.MockWALRepository{WriteErr: domain.ErrBufferFull}
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, spool, logger, "group", "consumer", 1, 1*time.Millisecond)
+
+		uc.ProcessBatch(context.Background())
+		if len(bufferRepo.DLQEvents) != 2 {
+			t.Errorf("expected 2 events in DLQ, got %d", len(bufferRepo.DLQEvents))
+		}
+		if len(bufferRepo.DLQFailures) != 1 {
+			t.Fatalf("expected 1 DLQ move, got %d", len(bufferRepo.DLQFailures))
+		}
+		failure := bufferRepo.DLQFailures[0]
+		if failure.Reason != "database is down" || failure.Attempts != 1 || failure.Consumer != "consumer" || failure.FailedAt.IsZero() {
+			t.Errorf("unexpected DLQ failure context: %+v", failure)
+		}
+	})
+}
+
+func TestProcessLogsUseCase_ReclaimStale(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bufferRepo := &mocks.MockLogRepository{ClaimResult: []domain.LogEvent{
+		{ID: "1", StreamMessageID: "msg1", DeliveryCount: 2},
+		{ID: "2", StreamMessageID: "msg2", DeliveryCount: 6},
+	}}
+	sinkRepo := &mocks.MockLogRepository{}
+	uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 1, time.Millisecond)
+
+	// Without a claimer nothing is reclaimed.
+	if count, err := uc.ReclaimStale(context.Background()); err != nil || count != 0 {
+		t.Fatalf("expected reclaim to be disabled, got count %d, err %v", count, err)
+	}
+
+	uc.EnableStaleReclaim(bufferRepo, time.Minute, 5)
+	count, err := uc.ReclaimStale(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 reclaimed events, got %d", count)
+	}
+	if len(sinkRepo.WrittenEvents) != 1 || sinkRepo.WrittenEvents[0].ID != "1" {
+		t.Errorf("expected only event 1 written to sink, got %v", sinkRepo.WrittenEvents)
+	}
+	if len(bufferRepo.DLQEvents) != 1 || bufferRepo.DLQEvents[0].ID != "2" {
+		t.Errorf("expected event 2 moved to DLQ after too many deliveries, got %v", bufferRepo.DLQEvents)
+	}
+	if len(bufferRepo.DLQFailures) != 1 || bufferRepo.DLQFailures[0].Attempts != 6 {
+		t.Errorf("expected the DLQ move to record 6 deliveries, got %+v", bufferRepo.DLQFailures)
+	}
+	if len(bufferRepo.AckedMessageIDs) != 2 {
+		t.Errorf("expected 2 messages to be acked, got %d", len(bufferRepo.AckedMessageIDs))
+	}
+}
+
+type fakeConsumerControl struct {
+	paused bool
+}
+
+func (f *fakeConsumerControl) SetGroupPaused(ctx context.Context, group string, paused bool) error {
+	f.paused = paused
+	return nil
+}
+
+func (f *fakeConsumerControl) GetGroupPause(ctx context.Context, group string) (*domain.GroupPause, error) {
+	return &domain.GroupPause{Group: group, Paused: f.paused}, nil
+}
+
+func TestProcessLogsUseCase_Pause(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bufferRepo := &mocks.MockLogRepository{ReadBatchResult: []domain.LogEvent{{ID: "1", StreamMessageID: "msg1"}}}
+	sinkRepo := &mocks.MockLogRepository{}
+	control := &fakeConsumerControl{paused: true}
+	uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 1, time.Millisecond)
+	uc.EnablePauseControl(control)
+
+	if count, err := uc.ProcessBatch(context.Background()); err != nil || count != 0 {
+		t.Fatalf("expected nothing processed while paused, got count %d, err %v", count, err)
+	}
+	if len(sinkRepo.WrittenEvents) != 0 {
+		t.Fatalf("expected no writes while paused, got %d", len(sinkRepo.WrittenEvents))
+	}
+
+	control.paused = false
+	if count, err := uc.ProcessBatch(context.Background()); err != nil || count != 1 {
+		t.Errorf("expected the batch processed after resuming, got count %d, err %v", count, err)
+	}
+}
+
+func TestProcessLogsUseCase_AdaptiveBatching(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	uc := NewProcessLogsUseCase(&mocks.MockLogRepository{}, &mocks.MockLogRepository{}, nil, logger, "group", "consumer", 1, time.Millisecond)
+	uc.EnableAdaptiveBatching(100, 3000, time.Second)
+
+	uc.adaptBatchSize(1000, 10*time.Millisecond, nil)
+	if uc.batchSize != 2000 {
+		t.Errorf("expected a full, fast batch to double the size to 2000, got %d", uc.batchSize)
+	}
+	uc.adaptBatchSize(2000, 10*time.Millisecond, nil)
+	if uc.batchSize != 3000 {
+		t.Errorf("expected the size capped at 3000, got %d", uc.batchSize)
+	}
+	uc.adaptBatchSize(10, 10*time.Millisecond, nil)
+	if uc.batchSize != 3000 {
+		t.Errorf("expected a partial batch to keep the size, got %d", uc.batchSize)
+	}
+	uc.adaptBatchSize(3000, 2*time.Second, nil)
+	if uc.batchSize != 1500 {
+		t.Errorf("expected a slow write to halve the size to 1500, got %d", uc.batchSize)
+	}
+	for range 10 {
+		uc.adaptBatchSize(10, 0, errors.New("database is down"))
+	}
+	if uc.batchSize != 100 {
+		t.Errorf("expected failures to shrink the size to the minimum of 100, got %d", uc.batchSize)
+	}
+}
+
+// rejectingSink fails every batch holding the event with the bad ID as a rejection.
+type rejectingSink struct {
+	mocks.MockLogRepository
+	bad string
+}
+
+func (s *rejectingSink) WriteLogBatch(ctx context.Context, events []domain.LogEvent) error {
+	for _, event := range events {
+		if event.ID == s.bad {
+			return fmt.Errorf("%w: invalid input syntax for type json", domain.ErrEventRejected)
+		}
+	}
+	return s.MockLogRepository.WriteLogBatch(ctx, events)
+}
+
+func TestProcessLogsUseCase_IsolatesRejectedEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var events []domain.LogEvent
+	for i := range 8 {
+		events = append(events, domain.LogEvent{ID: strconv.Itoa(i), StreamMessageID: "msg" + strconv.Itoa(i)})
+	}
+	bufferRepo := &mocks.MockLogRepository{ReadBatchResult: events}
+	sinkRepo := &rejectingSink{bad: "5"}
+	uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, time.Millisecond)
+
+	count, err := uc.ProcessBatch(context.Background())
+	if err != nil || count != 8 {
+		t.Fatalf("expected the batch processed, got count %d, err %v", count, err)
+	}
+	if len(sinkRepo.WrittenEvents) != 7 {
+		t.Errorf("expected the 7 good events written, got %d", len(sinkRepo.WrittenEvents))
+	}
+	if len(bufferRepo.DLQEvents) != 1 || bufferRepo.DLQEvents[0].ID != "5" {
+		t.Errorf("expected only event 5 moved to DLQ, got %v", bufferRepo.DLQEvents)
+	}
+	if len(bufferRepo.DLQFailures) != 1 || bufferRepo.DLQFailures[0].Cause != domain.DLQCauseRejected {
+		t.Errorf("expected the DLQ move marked as a rejection, got %+v", bufferRepo.DLQFailures)
+	}
+	if len(bufferRepo.AckedMessageIDs) != 8 {
+		t.Errorf("expected all 8 messages acked, got %d", len(bufferRepo.AckedMessageIDs))
+	}
+}
+
+// dropDebug is an EventTransformer that leaves out debug events.
+type dropDebug struct{}
+
+func (dropDebug) Transform(events []domain.LogEvent) []domain.LogEvent {
+	var kept []domain.LogEvent
+	for _, event := range events {
+		if event.Level != "debug" {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+func TestProcessLogsUseCase_Transform(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Dropped events are acked but not written", func(t *testing.T) {
+		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: []domain.LogEvent{
+			{ID: "1", Level: "info", StreamMessageID: "msg1"},
+			{ID: "2", Level: "debug", StreamMessageID: "msg2"},
+		}}
+		sinkRepo := &mocks.MockLogRepository{}
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, time.Millisecond)
+		uc.EnableTransform(dropDebug{})
+
+		count, err := uc.ProcessBatch(context.Background())
+		if err != nil || count != 2 {
+			t.Fatalf("expected the batch processed, got count %d, err %v", count, err)
+		}
+		if len(sinkRepo.WrittenEvents) != 1 || sinkRepo.WrittenEvents[0].ID != "1" {
+			t.Errorf("expected only event 1 written, got %v", sinkRepo.WrittenEvents)
+		}
+		if len(bufferRepo.AckedMessageIDs) != 2 {
+			t.Errorf("expected both messages acked, got %d", len(bufferRepo.AckedMessageIDs))
+		}
+	})
+
+	t.Run("Fully dropped batch skips the sink", func(t *testing.T) {
+		bufferRepo := &mocks.MockLogRepository{ReadBatchResult: []domain.LogEvent{{ID: "1", Level: "debug", StreamMessageID: "msg1"}}}
+		sinkRepo := &mocks.MockLogRepository{WriteErr: errors.New("database is down")}
+		uc := NewProcessLogsUseCase(bufferRepo, sinkRepo, nil, logger, "group", "consumer", 3, time.Millisecond)
+		uc.EnableTransform(dropDebug{})
+
+		if _, err := uc.ProcessBatch(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(bufferRepo.DLQEvents) != 0 || len(bufferRepo.AckedMessageIDs) != 1 {
+			t.Errorf("expected the message acked without a DLQ move, got %d acked, %d in DLQ", len(bufferRepo.AckedMessageIDs), len(bufferRepo.DLQEvents))
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// QuotaConfig holds the daily quotas of every API key; 0 disables a quota. Soft quotas
+// only warn, hard quotas reject events with domain.ErrQuotaExceeded.
+type QuotaConfig struct {
+	SoftEvents int64
+	HardEvents int64
+	SoftBytes  int64
+	HardBytes  int64
+}
+
+// usageKey identifies the usage of one key on one day.
+type usageKey struct {
+	keyHash string
+	day     string
+}
+
+// UsageMeterUseCase sits in front of the ingest use case and meters the events and bytes
+// each API key ingests per day, taking the key from the context (see
+// domain.APIKeyHashFromContext); events without one are passed on unmetered. Usage is
+// counted in process and added to the repository by Flush, so quotas are enforced on the
+// totals of every replica as of their last flush.
+type UsageMeterUseCase struct {
+	next   IngestLogUseCase
+	repo   domain.UsageRepository
+	quota  QuotaConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[usageKey]domain.Usage // Not flushed yet.
+	totals  map[usageKey]domain.Usage // As of the last flush.
+}
+
+// NewUsageMeterUseCase creates a new UsageMeterUseCase in front of next.
+func NewUsageMeterUseCase(next IngestLogUseCase, repo domain.UsageRepository, quota QuotaConfig, logger *slog.Logger) *UsageMeterUseCase {
+	return &UsageMeterUseCase{
+		next:    next,
+		repo:    repo,
+		quota:   quota,
+		logger:  logger.With("component", "usage_meter"),
+		now:     time.Now,
+		pending: make(map[usageKey]domain.Usage),
+		totals:  make(map[usageKey]domain.Usage),
+	}
+}
+
+// Ingest passes the event on, unless its key is over its hard quota, and meters it. The
+// bytes of an event are those of its message and metadata.
+func (uc *UsageMeterUseCase) Ingest(ctx context.Context, event *domain.LogEvent) error {
+	keyHash := domain.APIKeyHashFromContext(ctx)
+	if keyHash == "" {
+		return uc.next.Ingest(ctx, event)
+	}
+	if uc.Quota(keyHash) == domain.QuotaHard {
+		return domain.ErrQuotaExceeded
+	}
+	if err := uc.next.Ingest(ctx, event); err != nil {
+		return err
+	}
+
+	k := usageKey{keyHash: keyHash, day: uc.today()}
+	uc.mu.Lock()
+	u := uc.pending[k]
+	u.Events++
+	u.Bytes += int64(len(event.Message) + len(event.Metadata))
+	uc.pending[k] = u
+	uc.mu.Unlock()
+	return nil
+}
+
+// Quota reports how far the key is into today's quotas, as one of the domain.Quota
+// levels.
+func (uc *UsageMeterUseCase) Quota(keyHash string) string {
+	k := usageKey{keyHash: keyHash, day: uc.today()}
+	uc.mu.Lock()
+	total, pending := uc.totals[k], uc.pending[k]
+	uc.mu.Unlock()
+
+	events, bytes := total.Events+pending.Events, total.Bytes+pending.Bytes
+	switch {
+	case exceeds(events, uc.quota.HardEvents) || exceeds(bytes, uc.quota.HardBytes):
+		return domain.QuotaHard
+	case exceeds(events, uc.quota.SoftEvents) || exceeds(bytes, uc.quota.SoftBytes):
+		return domain.QuotaSoft
+	}
+	return domain.QuotaOK
+}
+
+func exceeds(used, quota int64) bool {
+	return quota > 0 && used >= quota
+}
+
+// Flush adds the usage metered since the last flush to the repository and refreshes the
+// totals quotas are enforced on. Usage that fails to be added is kept for the next flush.
+func (uc *UsageMeterUseCase) Flush(ctx context.Context) error {
+	uc.mu.Lock()
+	pending := uc.pending
+	uc.pending = make(map[usageKey]domain.Usage)
+	uc.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]domain.Usage, 0, len(pending))
+	for k, u := range pending {
+		u.KeyHash, u.Day = k.keyHash, k.day
+		usage = append(usage, u)
+	}
+	totals, err := uc.repo.AddUsage(ctx, usage)
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err != nil {
+		for k, u := range pending {
+			p := uc.pending[k]
+			p.Events += u.Events
+			p.Bytes += u.Bytes
+			uc.pending[k] = p
+		}
+		return err
+	}
+	today := uc.today()
+	for k := range uc.totals {
+		if k.day != today {
+			delete(uc.totals, k)
+		}
+	}
+	for _, t := range totals {
+		if t.Day == today {
+			uc.totals[usageKey{keyHash: t.KeyHash, day: t.Day}] = t
+		}
+	}
+	return nil
+}
+
+// Run flushes the metered usage every interval until the context is cancelled, and once
+// more before returning.
+func (uc *UsageMeterUseCase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := uc.Flush(flushCtx); err != nil {
+				uc.logger.Error("failed to flush usage on shutdown", "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := uc.Flush(ctx); err != nil {
+				uc.logger.Error("failed to flush usage", "error", err)
+			}
+		}
+	}
+}
+
+// LiveUsage returns today's usage of the keys with the most events first, at most top
+// of them if top is positive. It includes the usage this replica has not flushed yet,
+// and that of other replicas as of the last flush.
+func (uc *UsageMeterUseCase) LiveUsage(top int) []domain.Usage {
+	today := uc.today()
+	uc.mu.Lock()
+	byKey := make(map[string]domain.Usage)
+	for _, m := range []map[usageKey]domain.Usage{uc.totals, uc.pending} {
+		for k, u := range m {
+			if k.day != today {
+				continue
+			}
+			live := byKey[k.keyHash]
+			live.Events += u.Events
+			live.Bytes += u.Bytes
+			byKey[k.keyHash] = live
+		}
+	}
+	uc.mu.Unlock()
+
+	usage := make([]domain.Usage, 0, len(byKey))
+	for keyHash, u := range byKey {
+		u.KeyHash, u.Day = keyHash, today
+		usage = append(usage, u)
+	}
+	slices.SortFunc(usage, func(a, b domain.Usage) int {
+		return cmp.Or(cmp.Compare(b.Events, a.Events), cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.KeyHash, b.KeyHash))
+	})
+	if top > 0 && len(usage) > top {
+		usage = usage[:top]
+	}
+	return usage
+}
+
+// Usage returns the flushed usage between the days from and to, inclusive, of one key
+// or, if keyHash is empty, of every key.
+func (uc *UsageMeterUseCase) Usage(ctx context.Context, from, to, keyHash string) ([]domain.Usage, error) {
+	return uc.repo.ListUsage(ctx, from, to, keyHash)
+}
+
+func (uc *UsageMeterUseCase) today() string {
+	return uc.now().UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/V4T54L/watch-tower/internal/domain"
+)
+
+// fakeUsageRepository keeps the totals of every key and day, as if several replicas
+// added to them.
+type fakeUsageRepository struct {
+	totals map[usageKey]domain.Usage
+	err    error
+}
+
+func (f *fakeUsageRepository) AddUsage(ctx context.Context, usage []domain.Usage) ([]domain.Usage, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	totals := make([]domain.Usage, len(usage))
+	for i, u := range usage {
+		k := usageKey{keyHash: u.KeyHash, day: u.Day}
+		t := f.totals[k]
+		t.KeyHash, t.Day = u.KeyHash, u.Day
+		t.Events += u.Events
+		t.Bytes += u.Bytes
+		f.totals[k] = t
+		totals[i] = t
+	}
+	return totals, nil
+}
+
+func (f *fakeUsageRepository) ListUsage(ctx context.Context, from, to, keyHash string) ([]domain.Usage, error) {
+	return nil, nil
+}
+
+func TestUsageMeterUseCase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	k := usageKey{keyHash: "key", day: "2024-05-01"}
+	// Another replica already ingested 5 events with this key today.
+	repo := &fakeUsageRepository{totals: map[usageKey]domain.Usage{k: {KeyHash: "key", Day: k.day, Events: 5, Bytes: 50}}}
+	next := &recordingIngestUseCase{}
+	uc := NewUsageMeterUseCase(next, repo, QuotaConfig{SoftEvents: 7, HardEvents: 9}, logger)
+	uc.now = func() time.Time { return day }
+	ctx := domain.WithAPIKeyHash(context.Background(), "key")
+
+	for range 2 {
+		if err := uc.Ingest(ctx, &domain.LogEvent{Message: "hello"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := uc.Ingest(context.Background(), &domain.LogEvent{Message: "unmetered"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := uc.Quota("key"); got != domain.QuotaOK {
+		t.Fatalf("expected the other replica's usage unknown before a flush, got %q", got)
+	}
+
+	if err := uc.Flush(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := repo.totals[k]; got.Events != 7 || got.Bytes != 60 {
+		t.Fatalf("expected 7 events and 60 bytes stored, got %+v", got)
+	}
+	if got := uc.Quota("key"); got != domain.QuotaSoft {
+		t.Fatalf("expected the soft quota reached, got %q", got)
+	}
+
+	for range 2 {
+		uc.Ingest(ctx, &domain.LogEvent{})
+	}
+	if err := uc.Ingest(ctx, &domain.LogEvent{}); !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("expected the hard quota to reject the event, got %v", err)
+	}
+	if len(next.events) != 5 {
+		t.Errorf("expected 5 events passed on, got %d", len(next.events))
+	}
+
+	// The next day starts from zero.
+	uc.now = func() time.Time { return day.Add(24 * time.Hour) }
+	if got := uc.Quota("key"); got != domain.QuotaOK {
+		t.Errorf("expected a fresh quota the next day, got %q", got)
+	}
+
+	t.Run("Failed flushes are retried", func(t *testing.T) {
+		repo := &fakeUsageRepository{totals: map[usageKey]domain.Usage{}, err: errors.New("postgres down")}
+		uc := NewUsageMeterUseCase(&recordingIngestUseCase{}, repo, QuotaConfig{}, logger)
+		uc.Ingest(ctx, &domain.LogEvent{})
+		if err := uc.Flush(context.Background()); err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		repo.err = nil
+		if err := uc.Flush(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(repo.totals) != 1 {
+			t.Errorf("expected the usage kept for the retry, got %+v", repo.totals)
+		}
+	})
+	t.Run("Live usage includes unflushed events", func(t *testing.T) {
+		repo := &fakeUsageRepository{totals: map[usageKey]domain.Usage{}}
+		uc := NewUsageMeterUseCase(&recordingIngestUseCase{}, repo, QuotaConfig{}, logger)
+		quiet, noisy := domain.WithAPIKeyHash(ctx, "quiet"), domain.WithAPIKeyHash(ctx, "noisy")
+		uc.Ingest(quiet, &domain.LogEvent{})
+		uc.Ingest(noisy, &domain.LogEvent{})
+		if err := uc.Flush(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		uc.Ingest(noisy, &domain.LogEvent{})
+
+		live := uc.LiveUsage(1)
+		if len(live) != 1 || live[0].KeyHash != "noisy" || live[0].Events != 2 {
+			t.Errorf("expected the noisy key with 2 events, got %+v", live)
+		}
+	})
+}
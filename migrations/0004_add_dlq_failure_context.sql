@@ -0,0 +1,3 @@
+-- Why a log was dead-lettered, recorded by the consumer that gave up on it
+ALTER TABLE log_dlq ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE log_dlq ADD COLUMN IF NOT EXISTS consumer TEXT;
@@ -0,0 +1,10 @@
+-- Buffer messages whose events the consumer has written to logs, recorded in the same
+-- transaction so that a message redelivered after a failed acknowledgement is skipped
+CREATE TABLE IF NOT EXISTS sink_written_messages (
+    consumer_group TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    written_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (consumer_group, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sink_written_messages_written_at ON sink_written_messages (written_at);
@@ -0,0 +1,13 @@
+-- Store API keys as SHA-256 digests so that a dump of the table does not leak them.
+-- New keys are inserted by digest, e.g.
+--   INSERT INTO api_keys (key_hash, description) VALUES (encode(sha256('<key>'::bytea), 'hex'), '...');
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS key_hash TEXT;
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'api_keys' AND column_name = 'key') THEN
+        UPDATE api_keys SET key_hash = encode(sha256(convert_to(key, 'UTF8')), 'hex') WHERE key_hash IS NULL;
+        ALTER TABLE api_keys DROP COLUMN key;
+    END IF;
+END $$;
+ALTER TABLE api_keys ALTER COLUMN key_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
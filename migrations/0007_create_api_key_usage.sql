@@ -0,0 +1,11 @@
+-- Events and bytes ingested per API key and UTC day, added to by every ingest replica.
+-- Rows are kept when their key is deleted, for billing.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_hash TEXT NOT NULL,
+    day DATE NOT NULL,
+    events BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_hash, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_day ON api_key_usage (day);
@@ -0,0 +1,7 @@
+-- The API key each log was ingested with, by digest (api_keys.key_hash); NULL for logs
+-- from listeners without authentication, such as syslog.
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS api_key_hash TEXT;
+ALTER TABLE log_buffer ADD COLUMN IF NOT EXISTS api_key_hash TEXT;
+ALTER TABLE log_dlq ADD COLUMN IF NOT EXISTS api_key_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_logs_api_key_hash_event_time ON logs (api_key_hash, event_time);
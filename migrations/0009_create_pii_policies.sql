@@ -0,0 +1,7 @@
+-- PII redaction rules of individual API keys, replacing the global rules for their
+-- events. Ingest replicas reload them periodically.
+CREATE TABLE IF NOT EXISTS pii_policies (
+    key_hash TEXT PRIMARY KEY,
+    rules JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
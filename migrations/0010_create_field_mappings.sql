@@ -0,0 +1,7 @@
+-- Mappings of the payload keys of individual API keys onto the fields of log events.
+-- Ingest replicas reload them periodically.
+CREATE TABLE IF NOT EXISTS field_mappings (
+    key_hash TEXT PRIMARY KEY,
+    fields JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
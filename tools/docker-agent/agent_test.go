@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDemuxStream(t *testing.T) {
+	var stream bytes.Buffer
+	frame := func(kind byte, payload string) {
+		header := [8]byte{kind}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		stream.Write(header[:])
+		stream.WriteString(payload)
+	}
+	frame(1, "out 1\n")
+	frame(2, "err 1\n")
+	frame(1, "out 2\n")
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxStream(&stream, &stdout, &stderr); err == nil {
+		t.Fatal("expected EOF at end of stream")
+	}
+	if stdout.String() != "out 1\nout 2\n" || stderr.String() != "err 1\n" {
+		t.Errorf("unexpected demux result: stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestJSONFileCollector_HandleLine(t *testing.T) {
+	shipper := NewShipper("http://localhost/ingest", "key", 10, time.Second)
+	c := NewJSONFileCollector(t.TempDir(), time.Second, false, shipper)
+	info := &containerInfo{ID: "abc123", Name: "web", Image: "nginx:1.25", Labels: map[string]string{"team": "edge"}}
+
+	lines := []string{
+		`{"log":"GET / 200\n","stream":"stdout","time":"2024-05-01T12:00:00.5Z"}`,
+		`{"log":"very long ","stream":"stderr","time":"2024-05-01T12:00:01Z"}`,
+		`{"log":"line\n","stream":"stderr","time":"2024-05-01T12:00:01Z"}`,
+		`not json`,
+	}
+	var partial strings.Builder
+	for _, l := range lines {
+		c.handleLine(context.Background(), []byte(l+"\n"), info, &partial)
+	}
+
+	if len(shipper.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(shipper.events))
+	}
+	first, second := <-shipper.events, <-shipper.events
+	if first.Message != "GET / 200" || first.Source != "web" || first.Level != "info" || first.EventTime.Nanosecond() != 500000000 {
+		t.Errorf("unexpected first event: %+v", first)
+	}
+	if first.Metadata["container_id"] != "abc123" || first.Metadata["image"] != "nginx:1.25" {
+		t.Errorf("unexpected metadata: %v", first.Metadata)
+	}
+	if second.Message != "very long line" || second.Level != "error" {
+		t.Errorf("expected partial lines to be joined, got %+v", second)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APICollector attaches to the log streams of running containers through the Docker
+// Engine API, and to new containers as the events API reports them starting.
+type APICollector struct {
+	client  *http.Client
+	baseURL string
+	shipper *Shipper
+
+	mu        sync.Mutex
+	attached  map[string]bool
+	startedAt time.Time
+}
+
+// NewAPICollector creates a collector for the daemon at host, either unix:///path/to/socket
+// or tcp://host:port.
+func NewAPICollector(host string, shipper *Shipper) (*APICollector, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	transport := &http.Transport{}
+	baseURL := "http://docker"
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+	case "tcp", "http":
+		baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+
+	return &APICollector{
+		client:    &http.Client{Transport: transport},
+		baseURL:   baseURL,
+		shipper:   shipper,
+		attached:  make(map[string]bool),
+		startedAt: time.Now(),
+	}, nil
+}
+
+type apiContainer struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+}
+
+type apiEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Time   int64  `json:"time"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// Run attaches to every running container and then follows container start events
+// until the context is cancelled.
+func (c *APICollector) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	attach := func(id string, since time.Time) {
+		c.mu.Lock()
+		if c.attached[id] {
+			c.mu.Unlock()
+			return
+		}
+		c.attached[id] = true
+		c.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				c.mu.Lock()
+				delete(c.attached, id)
+				c.mu.Unlock()
+			}()
+			if err := c.follow(ctx, id, since); err != nil && ctx.Err() == nil {
+				log.Printf("stopped following container %s: %v", id, err)
+			}
+		}()
+	}
+
+	var running []struct {
+		ID string `json:"Id"`
+	}
+	if err := c.getJSON(ctx, "/containers/json", &running); err != nil {
+		return err
+	}
+	for _, rc := range running {
+		attach(rc.ID, c.startedAt)
+	}
+
+	filters := url.QueryEscape(`{"type":["container"],"event":["start"]}`)
+	resp, err := c.get(ctx, "/events?filters="+filters)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev apiEvent
+		if err := dec.Decode(&ev); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("events stream ended: %w", err)
+		}
+		if ev.Type == "container" && ev.Action == "start" {
+			// Read the new container's logs from its start so nothing is missed, without
+			// replaying the output of earlier runs of a restarted container.
+			attach(ev.Actor.ID, time.Unix(ev.Time, 0))
+		}
+	}
+}
+
+// follow streams one container's stdout and stderr until the container stops.
+func (c *APICollector) follow(ctx context.Context, id string, since time.Time) error {
+	var ac apiContainer
+	if err := c.getJSON(ctx, "/containers/"+id+"/json", &ac); err != nil {
+		return err
+	}
+	info := &containerInfo{
+		ID:     ac.ID,
+		Name:   strings.TrimPrefix(ac.Name, "/"),
+		Image:  ac.Config.Image,
+		Labels: ac.Config.Labels,
+	}
+
+	query := "follow=1&stdout=1&stderr=1&timestamps=1"
+	query += "&since=" + strconv.FormatInt(since.Unix(), 10)
+	resp, err := c.get(ctx, "/containers/"+id+"/logs?"+query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Containers with a TTY produce a raw stream; otherwise stdout and stderr are
+	// multiplexed with an 8-byte header per frame.
+	if ac.Config.Tty {
+		return c.readLines(ctx, resp.Body, "stdout", info)
+	}
+
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, p := range []struct {
+		r      *io.PipeReader
+		stream string
+	}{{stdout, "stdout"}, {stderr, "stderr"}} {
+		go func() {
+			defer wg.Done()
+			if err := c.readLines(ctx, p.r, p.stream, info); err != nil && !errors.Is(err, io.EOF) {
+				log.Printf("failed to read %s of container %s: %v", p.stream, info.Name, err)
+			}
+			// Keep draining so the demultiplexer never blocks on this stream.
+			io.Copy(io.Discard, p.r)
+		}()
+	}
+
+	err = demuxStream(resp.Body, stdoutW, stderrW)
+	stdoutW.CloseWithError(err)
+	stderrW.CloseWithError(err)
+	wg.Wait()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// readLines ships each "TIMESTAMP MESSAGE" line produced by the logs endpoint.
+func (c *APICollector) readLines(ctx context.Context, r io.Reader, stream string, info *containerInfo) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ts, message := splitTimestamp(scanner.Text())
+		c.shipper.Send(ctx, newLogEvent(info, stream, strings.TrimRight(message, "\r"), ts))
+	}
+	return scanner.Err()
+}
+
+// demuxStream splits a multiplexed logs stream into stdout and stderr. Each frame starts
+// with [stream, 0, 0, 0, size(4 bytes, big endian)].
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		var w io.Writer
+		switch header[0] {
+		case 1:
+			w = stdout
+		case 2:
+			w = stderr
+		default:
+			w = io.Discard
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+func splitTimestamp(line string) (time.Time, string) {
+	if sp := strings.IndexByte(line, ' '); sp > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, line[:sp]); err == nil {
+			return ts, line[sp+1:]
+		}
+	}
+	return time.Now(), line
+}
+
+func (c *APICollector) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker API %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("docker API %s returned %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *APICollector) getJSON(ctx context.Context, path string, v interface{}) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsonFileLine is one line written by Docker's json-file logging driver.
+type jsonFileLine struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// containerConfig is the subset of <root>/<id>/config.v2.json the agent needs.
+type containerConfig struct {
+	ID     string `json:"ID"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// JSONFileCollector tails the json-file logs of every container under the Docker data root.
+type JSONFileCollector struct {
+	root          string
+	pollInterval  time.Duration
+	fromBeginning bool
+	shipper       *Shipper
+
+	mu      sync.Mutex
+	tailing map[string]bool
+}
+
+// NewJSONFileCollector creates a collector for containers under root
+// (usually /var/lib/docker/containers).
+func NewJSONFileCollector(root string, pollInterval time.Duration, fromBeginning bool, shipper *Shipper) *JSONFileCollector {
+	return &JSONFileCollector{
+		root:          root,
+		pollInterval:  pollInterval,
+		fromBeginning: fromBeginning,
+		shipper:       shipper,
+		tailing:       make(map[string]bool),
+	}
+}
+
+// Run discovers containers on every poll and starts a tailer for each new one.
+func (c *JSONFileCollector) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	// Containers present at startup follow -from-beginning; ones started later are read in full.
+	fromBeginning := c.fromBeginning
+	for {
+		entries, err := os.ReadDir(c.root)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", c.root, err)
+		}
+		for _, entry := range entries {
+			id := entry.Name()
+			if !entry.IsDir() || c.isTailing(id) {
+				continue
+			}
+			info, err := readContainerConfig(filepath.Join(c.root, id, "config.v2.json"))
+			if err != nil {
+				continue
+			}
+			c.setTailing(id, true)
+			wg.Add(1)
+			go func(fromBeginning bool) {
+				defer wg.Done()
+				defer c.setTailing(id, false)
+				path := filepath.Join(c.root, id, id+"-json.log")
+				if err := c.tail(ctx, path, info, fromBeginning); err != nil {
+					log.Printf("stopped tailing container %s: %v", info.Name, err)
+				}
+			}(fromBeginning)
+		}
+		fromBeginning = true
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *JSONFileCollector) isTailing(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tailing[id]
+}
+
+func (c *JSONFileCollector) setTailing(id string, on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if on {
+		c.tailing[id] = true
+	} else {
+		delete(c.tailing, id)
+	}
+}
+
+// tail follows a json-file log across rotations until the file disappears for good,
+// which happens when the container is removed.
+func (c *JSONFileCollector) tail(ctx context.Context, path string, info *containerInfo, fromBeginning bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	if !fromBeginning {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	r := bufio.NewReader(f)
+	var partial strings.Builder
+	var missing int
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && err == nil {
+			c.handleLine(ctx, line, info, &partial)
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		// Keep an incomplete trailing line for the next read.
+		if len(line) > 0 {
+			r = bufio.NewReader(io.MultiReader(strings.NewReader(string(line)), f))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.pollInterval):
+		}
+
+		rotated, gone := fileRotated(f, path)
+		if gone {
+			if missing++; missing > 10 {
+				return errors.New("log file removed")
+			}
+			continue
+		}
+		missing = 0
+		if rotated {
+			f.Close()
+			if f, err = os.Open(path); err != nil {
+				return err
+			}
+			r = bufio.NewReader(f)
+		}
+	}
+}
+
+// handleLine decodes a json-file line and ships it. Docker splits lines longer than 16KiB
+// into several entries, only the last of which ends in a newline.
+func (c *JSONFileCollector) handleLine(ctx context.Context, raw []byte, info *containerInfo, partial *strings.Builder) {
+	entry, err := parseJSONFileLine(raw)
+	if err != nil {
+		log.Printf("skipping malformed json-file line for container %s: %v", info.Name, err)
+		return
+	}
+	if !strings.HasSuffix(entry.Log, "\n") {
+		partial.WriteString(entry.Log)
+		return
+	}
+	message := entry.Log
+	if partial.Len() > 0 {
+		message = partial.String() + message
+		partial.Reset()
+	}
+	c.shipper.Send(ctx, newLogEvent(info, entry.Stream, strings.TrimRight(message, "\r\n"), entry.Time))
+}
+
+func parseJSONFileLine(raw []byte) (*jsonFileLine, error) {
+	var entry jsonFileLine
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// fileRotated reports whether path now refers to a different file than f, or no file at all.
+func fileRotated(f *os.File, path string) (rotated, gone bool) {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false, true
+	}
+	open, err := f.Stat()
+	if err != nil {
+		return true, false
+	}
+	if !os.SameFile(open, current) {
+		return true, false
+	}
+	// Truncated in place (copytruncate style rotation).
+	pos, err := f.Seek(0, io.SeekCurrent)
+	return err == nil && current.Size() < pos, false
+}
+
+func readContainerConfig(path string) (*containerInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg containerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &containerInfo{
+		ID:     cfg.ID,
+		Name:   strings.TrimPrefix(cfg.Name, "/"),
+		Image:  cfg.Config.Image,
+		Labels: cfg.Config.Labels,
+	}, nil
+}
@@ -0,0 +1,66 @@
+// Command docker-agent ships container stdout/stderr to the watch-tower ingest endpoint,
+// enriching each event with the container ID, name, image and labels.
+//
+// It runs in one of two modes:
+//   - json-file: tails the json-file driver logs under the Docker data root (run it with
+//     /var/lib/docker/containers mounted read-only).
+//   - api: attaches to container log streams through the Docker Engine API socket.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func main() {
+	targetURL := flag.String("url", "http://localhost:8080/ingest", "Target URL for ingestion")
+	apiKey := flag.String("api-key", "supersecretkey", "API Key for authentication")
+	mode := flag.String("mode", "json-file", "Collection mode: json-file or api")
+	dockerRoot := flag.String("docker-root", "/var/lib/docker/containers", "Docker containers directory (json-file mode)")
+	dockerHost := flag.String("docker-host", "unix:///var/run/docker.sock", "Docker Engine API address (api mode)")
+	fromBeginning := flag.Bool("from-beginning", false, "Ship existing log lines of already running containers (json-file mode)")
+	pollInterval := flag.Duration("poll-interval", time.Second, "How often to check for new containers and log lines (json-file mode)")
+	batchSize := flag.Int("batch-size", 500, "Maximum events per ingest request")
+	flushInterval := flag.Duration("flush-interval", 2*time.Second, "Maximum time an event waits before being shipped")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shipper := NewShipper(*targetURL, *apiKey, *batchSize, *flushInterval)
+	shipCtx, stopShipping := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		shipper.Run(shipCtx)
+	}()
+
+	log.Printf("Starting docker agent in %s mode, shipping to %s", *mode, *targetURL)
+
+	var err error
+	switch *mode {
+	case "json-file":
+		err = NewJSONFileCollector(*dockerRoot, *pollInterval, *fromBeginning, shipper).Run(ctx)
+	case "api":
+		var collector *APICollector
+		if collector, err = NewAPICollector(*dockerHost, shipper); err == nil {
+			err = collector.Run(ctx)
+		}
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+
+	// Flush events that were already collected before exiting.
+	stopShipping()
+	wg.Wait()
+	if err != nil {
+		log.Fatalf("docker agent failed: %v", err)
+	}
+	log.Println("Docker agent stopped.")
+}
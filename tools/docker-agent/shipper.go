@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// containerInfo is the container metadata attached to every shipped event.
+type containerInfo struct {
+	ID     string
+	Name   string
+	Image  string
+	Labels map[string]string
+}
+
+// logEvent mirrors the JSON payload accepted by POST /ingest.
+type logEvent struct {
+	EventTime time.Time              `json:"event_time"`
+	Source    string                 `json:"source,omitempty"`
+	Level     string                 `json:"level,omitempty"`
+	Message   string                 `json:"message"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func newLogEvent(c *containerInfo, stream, message string, ts time.Time) logEvent {
+	metadata := map[string]interface{}{
+		"container_id":   c.ID,
+		"container_name": c.Name,
+		"image":          c.Image,
+		"stream":         stream,
+	}
+	if len(c.Labels) > 0 {
+		metadata["labels"] = c.Labels
+	}
+	level := "info"
+	if stream == "stderr" {
+		level = "error"
+	}
+	return logEvent{
+		EventTime: ts,
+		Source:    c.Name,
+		Level:     level,
+		Message:   message,
+		Metadata:  metadata,
+	}
+}
+
+// Shipper batches events and posts them to the ingest endpoint as NDJSON.
+type Shipper struct {
+	url           string
+	apiKey        string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+	events        chan logEvent
+}
+
+// NewShipper creates a new Shipper.
+func NewShipper(url, apiKey string, batchSize int, flushInterval time.Duration) *Shipper {
+	return &Shipper{
+		url:           url,
+		apiKey:        apiKey,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		events:        make(chan logEvent, batchSize*4),
+	}
+}
+
+// Send queues an event, blocking when the shipper falls behind so that tailers slow down
+// instead of dropping logs.
+func (s *Shipper) Send(ctx context.Context, e logEvent) {
+	select {
+	case s.events <- e:
+	case <-ctx.Done():
+	}
+}
+
+// Run ships batches until the context is cancelled, flushing whatever is pending on exit.
+func (s *Shipper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	var buf bytes.Buffer
+	var pending int
+	enc := json.NewEncoder(&buf)
+	flush := func() {
+		if pending == 0 {
+			return
+		}
+		if err := s.post(buf.Bytes()); err != nil {
+			log.Printf("failed to ship %d events: %v", pending, err)
+		}
+		buf.Reset()
+		pending = 0
+	}
+
+	for {
+		select {
+		case e := <-s.events:
+			enc.Encode(e)
+			pending++
+			if pending >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-s.events:
+					enc.Encode(e)
+					pending++
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// post sends one NDJSON batch, retrying with backoff on network errors and 5xx/429 responses.
+func (s *Shipper) post(body []byte) error {
+	backoff := 500 * time.Millisecond
+	var lastErr error
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		req.Header.Set("X-API-Key", s.apiKey)
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("ingest returned %s", resp.Status)
+		default:
+			return fmt.Errorf("ingest returned %s", resp.Status)
+		}
+	}
+	return lastErr
+}